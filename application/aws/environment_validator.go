@@ -18,7 +18,7 @@ type infrastructureManager interface {
 }
 
 type boshClientProvider interface {
-	Client(jumpbox storage.Jumpbox, directorAddress, directorUsername, directorPassword, directorCACert string) (bosh.Client, error)
+	Client(jumpbox storage.Jumpbox, network storage.Network, directorAddress, directorUsername, directorPassword, directorCACert string) (bosh.Client, error)
 }
 
 func NewEnvironmentValidator(infrastructureManager infrastructureManager, boshClientProvider boshClientProvider) EnvironmentValidator {
@@ -45,7 +45,7 @@ func (e EnvironmentValidator) Validate(state storage.State) error {
 	}
 
 	if !state.NoDirector {
-		boshClient, err := e.boshClientProvider.Client(state.Jumpbox, state.BOSH.DirectorAddress, state.BOSH.DirectorUsername, state.BOSH.DirectorPassword, state.BOSH.DirectorSSLCA)
+		boshClient, err := e.boshClientProvider.Client(state.Jumpbox, state.Network, state.BOSH.DirectorAddress, state.BOSH.DirectorUsername, state.BOSH.DirectorPassword, state.BOSH.DirectorSSLCA)
 		if err != nil {
 			return fmt.Errorf("bosh client provider: %s", err)
 		}
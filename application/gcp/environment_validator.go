@@ -11,7 +11,7 @@ type EnvironmentValidator struct {
 }
 
 type boshClientProvider interface {
-	Client(jumpbox storage.Jumpbox, directorAddress, directorUsername, directorPassword, directorCACert string) (bosh.Client, error)
+	Client(jumpbox storage.Jumpbox, network storage.Network, directorAddress, directorUsername, directorPassword, directorCACert string) (bosh.Client, error)
 }
 
 func NewEnvironmentValidator(boshClientProvider boshClientProvider) EnvironmentValidator {
@@ -26,7 +26,7 @@ func (e EnvironmentValidator) Validate(state storage.State) error {
 	}
 
 	if !state.NoDirector {
-		boshClient, err := e.boshClientProvider.Client(state.Jumpbox, state.BOSH.DirectorAddress, state.BOSH.DirectorUsername, state.BOSH.DirectorPassword, state.BOSH.DirectorSSLCA)
+		boshClient, err := e.boshClientProvider.Client(state.Jumpbox, state.Network, state.BOSH.DirectorAddress, state.BOSH.DirectorUsername, state.BOSH.DirectorPassword, state.BOSH.DirectorSSLCA)
 		if err != nil {
 			return err //not tested
 		}
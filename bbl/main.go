@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/application"
 	"github.com/cloudfoundry/bosh-bootloader/aws"
@@ -27,6 +29,7 @@ import (
 	"github.com/cloudfoundry/bosh-bootloader/stack"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 	"github.com/cloudfoundry/bosh-bootloader/terraform"
+	"github.com/cloudfoundry/bosh-bootloader/workspace"
 
 	awsapplication "github.com/cloudfoundry/bosh-bootloader/application/aws"
 	gcpapplication "github.com/cloudfoundry/bosh-bootloader/application/gcp"
@@ -52,6 +55,8 @@ func main() {
 
 	loadedState := parsedFlags.State
 
+	workspace.Keep(parsedFlags.KeepWorkspaces)
+
 	// Utilities
 	envIDGenerator := helpers.NewEnvIDGenerator(rand.Reader)
 	envGetter := helpers.NewEnvGetter()
@@ -61,10 +66,30 @@ func main() {
 	// Usage Command
 	usage := commands.NewUsage(logger)
 
-	storage.GetStateLogger = stderrLogger
+	stateLocation := parsedFlags.StateDir
+	if parsedFlags.StateBackend != "" {
+		stateLocation = parsedFlags.StateBackend
+	}
+
+	stateBackend, err := storage.NewBackend(stateLocation)
+	if err != nil {
+		log.Fatalf("\n\n%s\n", err)
+	}
+
+	commandName := ""
+	if len(parsedFlags.RemainingArgs) > 0 {
+		commandName = parsedFlags.RemainingArgs[0]
+	}
+
+	// bbl proxy uses no workspaces and cleans up after itself when it is
+	// interrupted, which exiting here would cut short.
+	if commandName != "proxy" {
+		workspace.RemoveOnInterrupt()
+	}
 
-	stateStore := storage.NewStore(parsedFlags.StateDir)
-	stateValidator := application.NewStateValidator(parsedFlags.StateDir)
+	stateStore := storage.NewStore(stateBackend).ForCommand(commandName)
+	stateValidator := application.NewStateValidator(stateBackend)
+	stateLocker := storage.NewLocker(stateBackend, stderrLogger)
 
 	// Amazon
 	awsConfiguration := aws.Config{
@@ -103,8 +128,15 @@ func main() {
 	// Terraform
 	terraformOutputBuffer := bytes.NewBuffer([]byte{})
 
-	terraformCmd := terraform.NewCmd(os.Stderr, terraformOutputBuffer)
-	terraformExecutor := terraform.NewExecutor(terraformCmd, parsedFlags.Debug)
+	// Providers are downloaded once into a cache shared by all environments,
+	// unless they are installed from a plugin directory.
+	var terraformPluginCacheDir string
+	if parsedFlags.TFPluginDir == "" && os.Getenv("HOME") != "" {
+		terraformPluginCacheDir = filepath.Join(os.Getenv("HOME"), ".bbl", "terraform-plugin-cache")
+	}
+
+	terraformCmd := terraform.NewCmd(os.Stderr, terraformOutputBuffer, terraformPluginCacheDir)
+	terraformExecutor := terraform.NewExecutor(terraformCmd, parsedFlags.Debug, parsedFlags.TFPluginDir)
 
 	gcpTemplateGenerator := gcpterraform.NewTemplateGenerator()
 	gcpInputGenerator := gcpterraform.NewInputGenerator()
@@ -132,13 +164,23 @@ func main() {
 		TerraformOutputBuffer: terraformOutputBuffer,
 		Logger:                logger,
 		StackMigrator:         stackMigrator,
+		Retries:               parsedFlags.TFRetries,
 	})
 
 	// BOSH
 	hostKeyGetter := proxy.NewHostKeyGetter()
+
+	// bbl proxy keeps its files in ~/.bbl, like the terraform plugin cache.
+	// Without a home directory there is nowhere private to keep them, so it
+	// does not run.
+	proxyDir := ""
+	if os.Getenv("HOME") != "" {
+		proxyDir = filepath.Join(os.Getenv("HOME"), ".bbl", "proxy")
+	}
+
 	socks5Proxy := proxy.NewSocks5Proxy(logger, hostKeyGetter, 0)
 	boshCommand := bosh.NewCmd(os.Stderr)
-	boshExecutor := bosh.NewExecutor(boshCommand, ioutil.TempDir, ioutil.ReadFile, json.Unmarshal,
+	boshExecutor := bosh.NewExecutor(boshCommand, workspace.Create, ioutil.ReadFile, json.Unmarshal,
 		json.Marshal, ioutil.WriteFile)
 	boshManager := bosh.NewManager(boshExecutor, logger, socks5Proxy)
 	boshClientProvider := bosh.NewClientProvider(socks5Proxy)
@@ -154,7 +196,7 @@ func main() {
 	awsTerraformOpsGenerator := awscloudconfig.NewTerraformOpsGenerator(terraformManager)
 	gcpOpsGenerator := gcpcloudconfig.NewOpsGenerator(terraformManager)
 	cloudConfigOpsGenerator := cloudconfig.NewOpsGenerator(awsCloudFormationOpsGenerator, awsTerraformOpsGenerator, gcpOpsGenerator)
-	cloudConfigManager := cloudconfig.NewManager(logger, boshCommand, cloudConfigOpsGenerator, boshClientProvider, socks5Proxy, terraformManager, sshKeyGetter)
+	cloudConfigManager := cloudconfig.NewManager(logger, cloudConfigOpsGenerator, boshClientProvider, socks5Proxy, terraformManager, sshKeyGetter)
 
 	// Subcommands
 	awsUp := commands.NewAWSUp(boshManager, cloudConfigManager, stateStore, awsClientProvider, envIDManager, terraformManager, awsBrokenEnvironmentValidator)
@@ -190,18 +232,18 @@ func main() {
 	commandSet := application.CommandSet{}
 	commandSet["help"] = usage
 	commandSet["version"] = commands.NewVersion(Version, logger)
-	commandSet["up"] = up
+	commandSet["up"] = commands.NewLocked("up", up, stderrLogger, stateLocker, stateStore)
 	sshKeyDeleter := bosh.NewSSHKeyDeleter()
-	commandSet["rotate"] = commands.NewRotate(stateValidator, sshKeyDeleter, up)
-	commandSet["destroy"] = commands.NewDestroy(
+	commandSet["rotate"] = commands.NewLocked("rotate", commands.NewRotate(stateValidator, sshKeyDeleter, up), stderrLogger, stateLocker, stateStore)
+	commandSet["destroy"] = commands.NewLocked("destroy", commands.NewDestroy(
 		logger, os.Stdin, boshManager, vpcStatusChecker, stackManager,
 		infrastructureManager, certificateDeleter,
 		stateStore, stateValidator, terraformManager, gcpNetworkInstancesChecker,
-	)
+	), stderrLogger, stateLocker, stateStore)
 	commandSet["down"] = commandSet["destroy"]
-	commandSet["create-lbs"] = commands.NewCreateLBs(awsCreateLBs, gcpCreateLBs, stateValidator, certificateValidator, boshManager)
-	commandSet["update-lbs"] = commands.NewUpdateLBs(awsUpdateLBs, gcpUpdateLBs, certificateValidator, stateValidator, logger, boshManager)
-	commandSet["delete-lbs"] = commands.NewDeleteLBs(gcpDeleteLBs, awsDeleteLBs, logger, stateValidator, boshManager)
+	commandSet["create-lbs"] = commands.NewLocked("create-lbs", commands.NewCreateLBs(awsCreateLBs, gcpCreateLBs, stateValidator, certificateValidator, boshManager), stderrLogger, stateLocker, stateStore)
+	commandSet["update-lbs"] = commands.NewLocked("update-lbs", commands.NewUpdateLBs(awsUpdateLBs, gcpUpdateLBs, certificateValidator, stateValidator, logger, boshManager), stderrLogger, stateLocker, stateStore)
+	commandSet["delete-lbs"] = commands.NewLocked("delete-lbs", commands.NewDeleteLBs(gcpDeleteLBs, awsDeleteLBs, logger, stateValidator, boshManager), stderrLogger, stateLocker, stateStore)
 	commandSet["lbs"] = commands.NewLBs(gcpLBs, awsLBs, stateValidator, logger)
 	commandSet["jumpbox-address"] = commands.NewStateQuery(logger, stateValidator, terraformManager, infrastructureManager, commands.JumpboxAddressPropertyName)
 	commandSet["director-address"] = commands.NewStateQuery(logger, stateValidator, terraformManager, infrastructureManager, commands.DirectorAddressPropertyName)
@@ -211,9 +253,24 @@ func main() {
 	commandSet["ssh-key"] = commands.NewSSHKey(logger, stateValidator, sshKeyGetter)
 	commandSet["env-id"] = commands.NewStateQuery(logger, stateValidator, terraformManager, infrastructureManager, commands.EnvIDPropertyName)
 	commandSet["latest-error"] = commands.NewLatestError(logger, stateValidator)
-	commandSet["print-env"] = commands.NewPrintEnv(logger, stateValidator, terraformManager)
+	commandSet["print-env"] = commands.NewPrintEnv(logger, stateValidator, terraformManager, proxyDir)
+	commandSet["proxy"] = commands.NewProxy(logger, stateValidator, proxy.NewSocks5Proxy(logger, hostKeyGetter, 0), proxyDir)
 	commandSet["cloud-config"] = commands.NewCloudConfig(logger, stateValidator, cloudConfigManager)
+	commandSet["plan"] = commands.NewPlan(logger, stateValidator, terraformManager, boshManager)
+	commandSet["drift"] = commands.NewDrift(logger, stateValidator, terraformManager)
+	commandSet["adopt-resource"] = commands.NewLocked("adopt-resource", commands.NewAdoptResource(stateValidator, terraformManager, stateStore), stderrLogger, stateLocker, stateStore)
+	commandSet["ops-files"] = commands.NewLocked("ops-files", commands.NewOpsFiles(logger, stateValidator, stateStore), stderrLogger, stateLocker, stateStore)
 	commandSet["bosh-deployment-vars"] = commands.NewBOSHDeploymentVars(logger, boshManager, stateValidator, terraformManager)
+	commandSet["encrypt-state"] = commands.NewLocked("encrypt-state", commands.NewEncryptState(logger, stateValidator, stateStore), stderrLogger, stateLocker, stateStore)
+	commandSet["decrypt-state"] = commands.NewLocked("decrypt-state", commands.NewDecryptState(logger, stateValidator, stateStore), stderrLogger, stateLocker, stateStore)
+	commandSet["force-unlock"] = commands.NewForceUnlock(logger, stateLocker)
+	commandSet["migrate-state"] = commands.NewMigrateState(logger, stateValidator, stateStore, stateLocker)
+	commandSet["state"] = commands.NewState(
+		commands.NewStateHistory(logger, stateValidator, stateStore),
+		commands.NewLocked("state rollback", commands.NewStateRollback(logger, stateValidator, stateStore), stderrLogger, stateLocker, stateStore),
+		commands.NewLocked("state split", commands.NewStateSplit(logger, stateValidator, stateStore), stderrLogger, stateLocker, stateStore),
+		commands.NewLocked("state join", commands.NewStateJoin(logger, stateValidator, stateStore), stderrLogger, stateLocker, stateStore),
+	)
 
 	commandConfiguration := &application.Configuration{
 		Global: application.GlobalConfiguration{
@@ -244,6 +301,14 @@ func main() {
 	app := application.New(commandSet, *commandConfiguration, usage)
 
 	err = app.Run()
+
+	if parsedFlags.KeepWorkspaces {
+		for _, dir := range workspace.Active() {
+			stderrLogger.Println(fmt.Sprintf("kept workspace %s", dir))
+		}
+	}
+	workspace.RemoveAll()
+
 	if err != nil {
 		log.Fatalf("\n\n%s\n", err)
 	}
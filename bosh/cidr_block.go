@@ -49,3 +49,44 @@ func (c CIDRBlock) GetFirstIP() IP {
 func (c CIDRBlock) GetLastIP() IP {
 	return c.firstIP.Add(c.CIDRSize - 1)
 }
+
+func (c CIDRBlock) MaskBits() int {
+	maskBits := 32
+	for size := c.CIDRSize; size > 1; size >>= 1 {
+		maskBits--
+	}
+	return maskBits
+}
+
+func (c CIDRBlock) String() string {
+	return fmt.Sprintf("%s/%d", c.firstIP, c.MaskBits())
+}
+
+// Contains reports whether other lies entirely within the block.
+func (c CIDRBlock) Contains(other CIDRBlock) bool {
+	return other.firstIP.ip >= c.firstIP.ip && other.GetLastIP().ip <= c.GetLastIP().ip
+}
+
+// Overlaps reports whether the two blocks share any address.
+func (c CIDRBlock) Overlaps(other CIDRBlock) bool {
+	return c.firstIP.ip <= other.GetLastIP().ip && other.firstIP.ip <= c.GetLastIP().ip
+}
+
+// Subnet works like the cidrsubnet function of terraform: it extends the
+// mask by newBits and returns the netNum-th block of that size.
+func (c CIDRBlock) Subnet(newBits, netNum int) (CIDRBlock, error) {
+	maskBits := c.MaskBits() + newBits
+	if newBits < 0 || maskBits > 32 {
+		return CIDRBlock{}, fmt.Errorf("cannot extend %s by %d bits", c, newBits)
+	}
+
+	if netNum < 0 || netNum >= 1<<uint(newBits) {
+		return CIDRBlock{}, fmt.Errorf("%s has no room for subnet %d of /%d", c, netNum, maskBits)
+	}
+
+	size := 1 << (32 - uint(maskBits))
+	return CIDRBlock{
+		CIDRSize: size,
+		firstIP:  c.firstIP.Add(netNum * size),
+	}, nil
+}
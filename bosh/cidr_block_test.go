@@ -32,6 +32,56 @@ var _ = Describe("CIDRBlock", func() {
 		})
 	})
 
+	Describe("String", func() {
+		It("returns the cidr block in cidr notation", func() {
+			Expect(cidrBlock.String()).To(Equal("10.0.16.0/20"))
+			Expect(cidrBlock.MaskBits()).To(Equal(20))
+		})
+	})
+
+	Describe("Contains", func() {
+		It("returns true for blocks that lie within the cidr block", func() {
+			inner, err := bosh.ParseCIDRBlock("10.0.31.0/24")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cidrBlock.Contains(inner)).To(BeTrue())
+
+			outer, err := bosh.ParseCIDRBlock("10.0.0.0/16")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cidrBlock.Contains(outer)).To(BeFalse())
+		})
+	})
+
+	Describe("Overlaps", func() {
+		It("reports whether the blocks share an address", func() {
+			for cidr, overlaps := range map[string]bool{
+				"10.0.31.0/24": true,
+				"10.0.0.0/16":  true,
+				"10.0.0.0/20":  false,
+				"10.0.32.0/24": false,
+			} {
+				other, err := bosh.ParseCIDRBlock(cidr)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cidrBlock.Overlaps(other)).To(Equal(overlaps), cidr)
+			}
+		})
+	})
+
+	Describe("Subnet", func() {
+		It("returns the numbered subnet like terraform's cidrsubnet", func() {
+			subnet, err := cidrBlock.Subnet(4, 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subnet.String()).To(Equal("10.0.18.0/24"))
+		})
+
+		It("returns an error when the subnet does not fit", func() {
+			_, err := cidrBlock.Subnet(4, 16)
+			Expect(err).To(MatchError("10.0.16.0/20 has no room for subnet 16 of /24"))
+
+			_, err = cidrBlock.Subnet(13, 0)
+			Expect(err).To(MatchError("cannot extend 10.0.16.0/20 by 13 bits"))
+		})
+	})
+
 	Describe("ParseCIDRBlock", func() {
 		Context("failure cases", func() {
 			It("returns an error when input string is not a valid CIDR block", func() {
@@ -26,7 +26,9 @@ func NewClientProvider(socks5Proxy socks5Proxy) ClientProvider {
 	}
 }
 
-func (c ClientProvider) Dialer(jumpbox storage.Jumpbox) (proxy.Dialer, error) {
+// Dialer connects through the jumpbox, when there is one, to the director
+// ports of the network.
+func (c ClientProvider) Dialer(jumpbox storage.Jumpbox, network storage.Network) (proxy.Dialer, error) {
 	if !jumpbox.Enabled {
 		return &net.Dialer{}, nil
 	}
@@ -36,13 +38,18 @@ func (c ClientProvider) Dialer(jumpbox storage.Jumpbox) (proxy.Dialer, error) {
 		return nil, fmt.Errorf("get jumpbox ssh key: %s", err)
 	}
 
+	allowlist, err := proxyAllowlist(network)
+	if err != nil {
+		return nil, err
+	}
+
 	// 	m.logger.Step("starting socks5 proxy")
-	err = c.socks5Proxy.Start(privateKey, jumpbox.URL)
+	err = c.socks5Proxy.Start(privateKey, jumpbox.HostKey, jumpbox.URL, allowlist)
 	if err != nil {
 		return nil, fmt.Errorf("start proxy: %s", err)
 	}
 
-	socks5Dialer, err := proxySOCKS5("tcp", c.socks5Proxy.Addr(), nil, proxy.Direct)
+	socks5Dialer, err := proxySOCKS5("tcp", c.socks5Proxy.Addr(), c.socks5Proxy.Auth(), proxy.Direct)
 	if err != nil {
 		return nil, fmt.Errorf("create socks5 client: %s", err)
 	}
@@ -64,8 +71,8 @@ func (ClientProvider) HTTPClient(dialer proxy.Dialer, directorCACert []byte) *ht
 	}
 }
 
-func (c ClientProvider) Client(jumpbox storage.Jumpbox, directorAddress, directorUsername, directorPassword, directorCACert string) (Client, error) {
-	dialer, err := c.Dialer(jumpbox)
+func (c ClientProvider) Client(jumpbox storage.Jumpbox, network storage.Network, directorAddress, directorUsername, directorPassword, directorCACert string) (Client, error) {
+	dialer, err := c.Dialer(jumpbox, network)
 	if err != nil {
 		// not tested
 		return client{}, err
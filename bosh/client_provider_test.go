@@ -9,6 +9,7 @@ import (
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	bblproxy "github.com/cloudfoundry/bosh-bootloader/proxy"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -38,6 +39,7 @@ var _ = Describe("Client Provider", func() {
 		)
 		BeforeEach(func() {
 			socks5Proxy.AddrCall.Returns.Addr = "some-socks-proxy-addr"
+			socks5Proxy.AuthCall.Returns.Auth = &proxy.Auth{User: "bbl", Password: "some-password"}
 			bosh.SetProxySOCKS5(func(network, addr string, auth *proxy.Auth, forward proxy.Dialer) (proxy.Dialer, error) {
 				socks5Network = network
 				socks5Addr = addr
@@ -69,14 +71,14 @@ var _ = Describe("Client Provider", func() {
 			})
 
 			It("does not start a socks5 proxy", func() {
-				_, err := clientProvider.Dialer(noJumpbox)
+				_, err := clientProvider.Dialer(noJumpbox, storage.Network{})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(socks5Proxy.StartCall.CallCount).To(Equal(0))
 				Expect(socks5Proxy.AddrCall.CallCount).To(Equal(0))
 			})
 
 			It("returns the default http dialer", func() {
-				socks5Client, err := clientProvider.Dialer(noJumpbox)
+				socks5Client, err := clientProvider.Dialer(noJumpbox, storage.Network{})
 				Expect(err).NotTo(HaveOccurred())
 
 				_, err = socks5Client.Dial("tcp", addr)
@@ -86,24 +88,30 @@ var _ = Describe("Client Provider", func() {
 
 		Context("when using a jumpbox", func() {
 			BeforeEach(func() {
-				jumpbox = storage.Jumpbox{Enabled: true, URL: "https://some-jumpbox", Variables: "jumpbox_ssh: { private_key: some-private-key }"}
+				jumpbox = storage.Jumpbox{Enabled: true, URL: "https://some-jumpbox", HostKey: "ssh-rsa some-host-key", Variables: "jumpbox_ssh: { private_key: some-private-key }"}
 				clientProvider = bosh.NewClientProvider(socks5Proxy)
 			})
 
 			It("starts the socks 5 proxy to the jumpbox and returns a socks 5 client", func() {
-				socks5Client, err := clientProvider.Dialer(jumpbox)
+				socks5Client, err := clientProvider.Dialer(jumpbox, storage.Network{CIDR: "192.168.0.0/16"})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(socks5Client).To(Equal(fakeSocks5Client))
 
 				Expect(socks5Proxy.StartCall.CallCount).To(Equal(1))
 				Expect(socks5Proxy.StartCall.Receives.JumpboxPrivateKey).To(Equal("some-private-key"))
+				Expect(socks5Proxy.StartCall.Receives.JumpboxHostKey).To(Equal("ssh-rsa some-host-key"))
 				Expect(socks5Proxy.StartCall.Receives.JumpboxExternalURL).To(Equal("https://some-jumpbox"))
 
+				allowlist := socks5Proxy.StartCall.Receives.Allowlist
+				Expect(allowlist.Ports).To(Equal(bblproxy.DirectorPorts))
+				Expect(allowlist.Networks).To(HaveLen(1))
+				Expect(allowlist.Networks[0].String()).To(Equal("192.168.0.0/16"))
+
 				Expect(socks5Proxy.AddrCall.CallCount).To(Equal(1))
 
 				Expect(socks5Network).To(Equal("tcp"))
 				Expect(socks5Addr).To(Equal("some-socks-proxy-addr"))
-				Expect(socks5Auth).To(BeNil())
+				Expect(socks5Auth).To(Equal(&proxy.Auth{User: "bbl", Password: "some-password"}))
 				Expect(socks5Forward).To(Equal(proxy.Direct))
 			})
 
@@ -113,7 +121,7 @@ var _ = Describe("Client Provider", func() {
 					clientProvider = bosh.NewClientProvider(socks5Proxy)
 				})
 				It("returns an error", func() {
-					_, err := clientProvider.Dialer(jumpbox)
+					_, err := clientProvider.Dialer(jumpbox, storage.Network{})
 					Expect(err).To(MatchError(ContainSubstring("get jumpbox ssh key: private key not found")))
 				})
 			})
@@ -124,17 +132,25 @@ var _ = Describe("Client Provider", func() {
 					clientProvider = bosh.NewClientProvider(socks5Proxy)
 				})
 				It("returns an error", func() {
-					_, err := clientProvider.Dialer(jumpbox)
+					_, err := clientProvider.Dialer(jumpbox, storage.Network{})
 					Expect(err).To(MatchError(ContainSubstring("get jumpbox ssh key: yaml")))
 				})
 			})
 
+			Context("when the network is invalid", func() {
+				It("returns an error", func() {
+					_, err := clientProvider.Dialer(jumpbox, storage.Network{CIDR: "banana"})
+					Expect(err).To(MatchError(`network cidr: "banana" cannot parse CIDR block`))
+					Expect(socks5Proxy.StartCall.CallCount).To(Equal(0))
+				})
+			})
+
 			Context("when starting the proxy returns an error", func() {
 				BeforeEach(func() {
 					socks5Proxy.StartCall.Returns.Error = errors.New("coconut")
 				})
 				It("returns an error", func() {
-					_, err := clientProvider.Dialer(jumpbox)
+					_, err := clientProvider.Dialer(jumpbox, storage.Network{})
 					Expect(err).To(MatchError(ContainSubstring("start proxy: coconut")))
 				})
 			})
@@ -146,7 +162,7 @@ var _ = Describe("Client Provider", func() {
 					})
 				})
 				It("returns an error", func() {
-					_, err := clientProvider.Dialer(jumpbox)
+					_, err := clientProvider.Dialer(jumpbox, storage.Network{})
 					Expect(err).To(MatchError(ContainSubstring("create socks5 client: banana")))
 				})
 			})
@@ -0,0 +1,130 @@
+package bosh
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DirectorSizing overrides the VM type, the persistent disk size and the
+// stemcell that the cpi.yml files of bosh-deployment give the director.
+// Empty fields keep the bosh-deployment defaults.
+type DirectorSizing struct {
+	VMType       string
+	DiskSizeGB   int
+	StemcellURL  string
+	StemcellSHA1 string
+}
+
+type vmTypeFamilies struct {
+	pattern  *regexp.Regexp
+	families string
+}
+
+var directorVMTypes = map[string]vmTypeFamilies{
+	"aws": {
+		pattern:  regexp.MustCompile(`^(t2|m4|m5|c4|c5|r4|r5|x1|x1e|i3|d2)\.(nano|micro|small|medium|large|[0-9]*xlarge)$`),
+		families: "t2, m4, m5, c4, c5, r4, r5, x1, x1e, i3 or d2",
+	},
+	"gcp": {
+		pattern:  regexp.MustCompile(`^(f1-micro|g1-small|n1-(standard|highmem|highcpu|megamem|ultramem)-[0-9]+|custom-[0-9]+-[0-9]+)$`),
+		families: "f1, g1, n1-standard, n1-highmem, n1-highcpu, n1-megamem, n1-ultramem or custom",
+	},
+	"azure": {
+		pattern:  regexp.MustCompile(`^Standard_(A[0-9]+(_v2)?|A[0-9]+m_v2|D[0-9]+(_v2|_v3)?|DS[0-9]+(_v2)?|D[0-9]+s_v3|E[0-9]+s?_v3|F[0-9]+s?(_v2)?|G[0-9]+|GS[0-9]+)$`),
+		families: "A, D, DS, E, F, G or GS",
+	},
+}
+
+var sha1Regexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Validate checks the VM type against the instance families of the IAAS,
+// so that a typo fails before terraform runs instead of during create-env.
+func (s DirectorSizing) Validate(iaas string) error {
+	if s.VMType != "" {
+		vmTypes, ok := directorVMTypes[iaas]
+		if ok && !vmTypes.pattern.MatchString(s.VMType) {
+			return fmt.Errorf("%q is not a known %s director vm type, use a vm type of the %s families", s.VMType, iaas, vmTypes.families)
+		}
+	}
+
+	if s.DiskSizeGB < 0 {
+		return fmt.Errorf("director disk size must be a positive number of GB, got %d", s.DiskSizeGB)
+	}
+
+	if s.StemcellSHA1 != "" {
+		if s.StemcellURL == "" {
+			return errors.New("a director stemcell sha1 requires a director stemcell")
+		}
+
+		if !sha1Regexp.MatchString(s.StemcellSHA1) {
+			return fmt.Errorf("director stemcell sha1 %q is not a sha1", s.StemcellSHA1)
+		}
+	}
+
+	if s.StemcellURL != "" && !strings.HasPrefix(s.StemcellURL, "file://") && s.StemcellSHA1 == "" {
+		return errors.New("a director stemcell url requires a director stemcell sha1")
+	}
+
+	return nil
+}
+
+type sizingOp struct {
+	Type  string      `yaml:"type"`
+	Path  string      `yaml:"path"`
+	Value interface{} `yaml:"value"`
+}
+
+type sizingStemcell struct {
+	URL  string `yaml:"url"`
+	SHA1 string `yaml:"sha1,omitempty"`
+}
+
+// ops returns an ops file that applies the sizing to the manifest of
+// bosh-deployment, or an empty string when nothing is overridden.
+func (s DirectorSizing) ops(iaas string) (string, error) {
+	var ops []sizingOp
+
+	if s.VMType != "" {
+		property := "instance_type"
+		if iaas == "gcp" {
+			property = "machine_type"
+		}
+
+		ops = append(ops, sizingOp{
+			Type:  "replace",
+			Path:  fmt.Sprintf("/resource_pools/name=vms/cloud_properties/%s", property),
+			Value: s.VMType,
+		})
+	}
+
+	if s.DiskSizeGB != 0 {
+		ops = append(ops, sizingOp{
+			Type:  "replace",
+			Path:  "/disk_pools/name=disks/disk_size",
+			Value: s.DiskSizeGB * 1024,
+		})
+	}
+
+	if s.StemcellURL != "" {
+		ops = append(ops, sizingOp{
+			Type:  "replace",
+			Path:  "/resource_pools/name=vms/stemcell",
+			Value: sizingStemcell{URL: s.StemcellURL, SHA1: s.StemcellSHA1},
+		})
+	}
+
+	if len(ops) == 0 {
+		return "", nil
+	}
+
+	opsYAML, err := yaml.Marshal(ops)
+	if err != nil {
+		return "", err //not tested
+	}
+
+	return string(opsYAML), nil
+}
@@ -0,0 +1,48 @@
+package bosh_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DirectorSizing", func() {
+	DescribeTable("valid sizing",
+		func(iaas string, sizing bosh.DirectorSizing) {
+			Expect(sizing.Validate(iaas)).To(Succeed())
+		},
+		Entry("no overrides", "aws", bosh.DirectorSizing{}),
+		Entry("aws instance type", "aws", bosh.DirectorSizing{VMType: "m4.2xlarge"}),
+		Entry("gcp machine type", "gcp", bosh.DirectorSizing{VMType: "n1-highmem-8"}),
+		Entry("gcp custom machine type", "gcp", bosh.DirectorSizing{VMType: "custom-4-16384"}),
+		Entry("azure instance type", "azure", bosh.DirectorSizing{VMType: "Standard_DS4_v2"}),
+		Entry("disk size", "gcp", bosh.DirectorSizing{DiskSizeGB: 200}),
+		Entry("stemcell url and sha1", "aws", bosh.DirectorSizing{
+			StemcellURL:  "https://example.com/stemcell.tgz",
+			StemcellSHA1: "316a699d44f49d69493b1545d4addd17b78b5840",
+		}),
+		Entry("local stemcell", "aws", bosh.DirectorSizing{StemcellURL: "file:///tmp/stemcell.tgz"}),
+	)
+
+	DescribeTable("invalid sizing",
+		func(iaas string, sizing bosh.DirectorSizing, expectedError string) {
+			Expect(sizing.Validate(iaas)).To(MatchError(expectedError))
+		},
+		Entry("unknown aws instance type", "aws", bosh.DirectorSizing{VMType: "n1-standard-4"},
+			`"n1-standard-4" is not a known aws director vm type, use a vm type of the t2, m4, m5, c4, c5, r4, r5, x1, x1e, i3 or d2 families`),
+		Entry("unknown gcp machine type", "gcp", bosh.DirectorSizing{VMType: "m4.xlarge"},
+			`"m4.xlarge" is not a known gcp director vm type, use a vm type of the f1, g1, n1-standard, n1-highmem, n1-highcpu, n1-megamem, n1-ultramem or custom families`),
+		Entry("unknown azure instance type", "azure", bosh.DirectorSizing{VMType: "Basic_A1"},
+			`"Basic_A1" is not a known azure director vm type, use a vm type of the A, D, DS, E, F, G or GS families`),
+		Entry("negative disk size", "aws", bosh.DirectorSizing{DiskSizeGB: -1},
+			"director disk size must be a positive number of GB, got -1"),
+		Entry("sha1 without a stemcell", "aws", bosh.DirectorSizing{StemcellSHA1: "316a699d44f49d69493b1545d4addd17b78b5840"},
+			"a director stemcell sha1 requires a director stemcell"),
+		Entry("malformed sha1", "aws", bosh.DirectorSizing{StemcellURL: "https://example.com/stemcell.tgz", StemcellSHA1: "abc"},
+			`director stemcell sha1 "abc" is not a sha1`),
+		Entry("remote stemcell without a sha1", "aws", bosh.DirectorSizing{StemcellURL: "https://example.com/stemcell.tgz"},
+			"a director stemcell url requires a director stemcell sha1"),
+	)
+})
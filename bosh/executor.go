@@ -10,6 +10,8 @@ import (
 	"regexp"
 
 	"github.com/cloudfoundry/bosh-bootloader/helpers"
+	"github.com/cloudfoundry/bosh-bootloader/interpolate"
+	"github.com/cloudfoundry/bosh-bootloader/workspace"
 )
 
 const gcpBoshDirectorEphemeralIPOps = `
@@ -39,7 +41,11 @@ type InterpolateInput struct {
 	JumpboxDeploymentVars string
 	BOSHState             map[string]interface{}
 	Variables             string
-	OpsFile               string
+	OpsFiles              []string
+	VarsFiles             []string
+	DirectorSizing        DirectorSizing
+	ExternalDatabase      bool
+	ExternalBlobstore     bool
 }
 
 type InterpolateOutput struct {
@@ -88,51 +94,22 @@ func NewExecutor(cmd command, tempDir func(string, string) (string, error), read
 }
 
 func (e Executor) JumpboxInterpolate(interpolateInput InterpolateInput) (JumpboxInterpolateOutput, error) {
-	tempDir, err := e.tempDir("", "")
+	output, err := interpolate.Interpolate(interpolate.Input{
+		Manifest: string(MustAsset("vendor/github.com/cppforlife/jumpbox-deployment/jumpbox.yml")),
+		OpsFiles: []string{
+			string(MustAsset(fmt.Sprintf("vendor/github.com/cppforlife/jumpbox-deployment/%s/cpi.yml", interpolateInput.IAAS))),
+		},
+		VarsFiles:     []string{interpolateInput.JumpboxDeploymentVars},
+		VarsStore:     interpolateInput.Variables,
+		ExpectAllKeys: true,
+	})
 	if err != nil {
-		return JumpboxInterpolateOutput{}, fmt.Errorf("create temp dir: %s", err)
-	}
-
-	var jumpboxSetupFiles = map[string][]byte{
-		"jumpbox-deployment-vars.yml": []byte(interpolateInput.JumpboxDeploymentVars),
-		"jumpbox.yml":                 MustAsset("vendor/github.com/cppforlife/jumpbox-deployment/jumpbox.yml"),
-		"cpi.yml":                     MustAsset(fmt.Sprintf("vendor/github.com/cppforlife/jumpbox-deployment/%s/cpi.yml", interpolateInput.IAAS)),
-	}
-
-	if interpolateInput.Variables != "" {
-		jumpboxSetupFiles["variables.yml"] = []byte(interpolateInput.Variables)
-	}
-
-	for path, contents := range jumpboxSetupFiles {
-		err = e.writeFile(filepath.Join(tempDir, path), contents, os.ModePerm)
-		if err != nil {
-			//not tested
-			return JumpboxInterpolateOutput{}, fmt.Errorf("write file: %s", err)
-		}
-	}
-
-	args := []string{
-		"interpolate", filepath.Join(tempDir, "jumpbox.yml"),
-		"--var-errs",
-		"--vars-store", filepath.Join(tempDir, "variables.yml"),
-		"--vars-file", filepath.Join(tempDir, "jumpbox-deployment-vars.yml"),
-		"-o", filepath.Join(tempDir, "cpi.yml"),
-	}
-
-	buffer := bytes.NewBuffer([]byte{})
-	err = e.command.Run(buffer, tempDir, args)
-	if err != nil {
-		return JumpboxInterpolateOutput{}, fmt.Errorf("bosh interpolate: %s: %s", err, buffer)
-	}
-
-	varsStore, err := e.readFile(filepath.Join(tempDir, "variables.yml"))
-	if err != nil {
-		return JumpboxInterpolateOutput{}, fmt.Errorf("read file: %s", err)
+		return JumpboxInterpolateOutput{}, fmt.Errorf("bosh interpolate: %s", err)
 	}
 
 	return JumpboxInterpolateOutput{
-		Variables: string(varsStore),
-		Manifest:  buffer.String(),
+		Variables: output.VarsStore,
+		Manifest:  output.Manifest,
 	}, nil
 }
 
@@ -145,122 +122,177 @@ const awsEncryptDiskOps = `---
     kms_key_arn: ((kms_key_arn))
 `
 
-func (e Executor) DirectorInterpolate(interpolateInput InterpolateInput) (InterpolateOutput, error) {
-	tempDir, err := e.tempDir("", "")
-	if err != nil {
-		//not tested
-		return InterpolateOutput{}, err
-	}
+const externalDatabaseOps = `---
+- type: replace
+  path: /instance_groups/name=bosh/properties/director/db
+  value:
+    adapter: postgres
+    host: ((external_db_host))
+    port: 5432
+    user: ((external_db_user))
+    password: ((external_db_password))
+    database: ((external_db_name))
+`
 
-	var directorSetupFiles = map[string][]byte{
-		"deployment-vars.yml":                    []byte(interpolateInput.DeploymentVars),
-		"user-ops-file.yml":                      []byte(interpolateInput.OpsFile),
-		"bosh.yml":                               MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/bosh.yml"),
-		"cpi.yml":                                MustAsset(fmt.Sprintf("vendor/github.com/cloudfoundry/bosh-deployment/%s/cpi.yml", interpolateInput.IAAS)),
-		"iam-instance-profile.yml":               MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/aws/iam-instance-profile.yml"),
-		"gcp-bosh-director-ephemeral-ip-ops.yml": []byte(gcpBoshDirectorEphemeralIPOps),
-		"aws-bosh-director-ephemeral-ip-ops.yml": []byte(awsBoshDirectorEphemeralIPOps),
-		"aws-bosh-director-encrypt-disk-ops.yml": []byte(awsEncryptDiskOps),
-		"jumpbox-user.yml":                       MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/jumpbox-user.yml"),
-		"gcp-external-ip-not-recommended.yml":    MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/external-ip-not-recommended.yml"),
-		"aws-external-ip-not-recommended.yml":    MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/external-ip-with-registry-not-recommended.yml"),
-		"uaa.yml":     MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/uaa.yml"),
-		"credhub.yml": MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/credhub.yml"),
-	}
+const externalDatabaseRegistryOps = `---
+- type: replace
+  path: /instance_groups/name=bosh/properties/registry/db
+  value:
+    adapter: postgres
+    host: ((external_db_host))
+    port: 5432
+    user: ((external_db_user))
+    password: ((external_db_password))
+    database: ((external_db_name))
+`
 
-	if interpolateInput.Variables != "" {
-		directorSetupFiles["variables.yml"] = []byte(interpolateInput.Variables)
-	}
+const removeLocalDatabaseOps = `---
+- type: remove
+  path: /instance_groups/name=bosh/jobs/name=postgres-9.4
 
-	for path, contents := range directorSetupFiles {
-		err = e.writeFile(filepath.Join(tempDir, path), contents, os.ModePerm)
-		if err != nil {
-			//not tested
-			return InterpolateOutput{}, err
-		}
-	}
+- type: remove
+  path: /instance_groups/name=bosh/properties/postgres
+`
+
+const awsExternalBlobstoreOps = `---
+- type: remove
+  path: /instance_groups/name=bosh/jobs/name=blobstore
+
+- type: replace
+  path: /instance_groups/name=bosh/properties/blobstore
+  value:
+    provider: s3
+    bucket_name: ((director_blobstore_bucket))
+    s3_region: ((region))
+    credentials_source: env_or_profile
+
+- type: replace
+  path: /instance_groups/name=bosh/properties/aws/default_iam_instance_profile?
+  value: ((iam_instance_profile))
+`
+
+const gcpExternalBlobstoreOps = `---
+- type: remove
+  path: /instance_groups/name=bosh/jobs/name=blobstore
 
-	var args = []string{
-		"interpolate", filepath.Join(tempDir, "bosh.yml"),
-		"--var-errs",
-		"--var-errs-unused",
-		"--vars-store", filepath.Join(tempDir, "variables.yml"),
-		"--vars-file", filepath.Join(tempDir, "deployment-vars.yml"),
-		"-o", filepath.Join(tempDir, "cpi.yml"),
+- type: replace
+  path: /instance_groups/name=bosh/properties/blobstore
+  value:
+    provider: gcs
+    bucket_name: ((director_blobstore_bucket))
+    credentials_source: static
+    json_key: ((gcp_credentials_json))
+`
+
+func (e Executor) DirectorInterpolate(interpolateInput InterpolateInput) (InterpolateOutput, error) {
+	opsFiles := []string{
+		string(MustAsset(fmt.Sprintf("vendor/github.com/cloudfoundry/bosh-deployment/%s/cpi.yml", interpolateInput.IAAS))),
 	}
 
 	if interpolateInput.JumpboxDeploymentVars == "" {
-		args = append(args,
-			"-o", filepath.Join(tempDir, "jumpbox-user.yml"),
-		)
+		opsFiles = append(opsFiles, string(MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/jumpbox-user.yml")))
 
 		switch interpolateInput.IAAS {
 		case "gcp":
-			args = append(args, "-o", filepath.Join(tempDir, "gcp-external-ip-not-recommended.yml"))
+			opsFiles = append(opsFiles, string(MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/external-ip-not-recommended.yml")))
 		case "aws":
-			args = append(args, "-o", filepath.Join(tempDir, "aws-external-ip-not-recommended.yml"))
+			opsFiles = append(opsFiles, string(MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/external-ip-with-registry-not-recommended.yml")))
 		}
 	} else {
-		args = append(args,
-			"-o", filepath.Join(tempDir, "uaa.yml"),
-			"-o", filepath.Join(tempDir, "credhub.yml"),
+		opsFiles = append(opsFiles,
+			string(MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/uaa.yml")),
+			string(MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/credhub.yml")),
 		)
+
 		switch interpolateInput.IAAS {
 		case "gcp":
-			args = append(args, "-o", filepath.Join(tempDir, "gcp-bosh-director-ephemeral-ip-ops.yml"))
+			opsFiles = append(opsFiles, gcpBoshDirectorEphemeralIPOps)
 		case "aws":
-			args = append(args, "-o", filepath.Join(tempDir, "aws-bosh-director-ephemeral-ip-ops.yml"))
+			opsFiles = append(opsFiles, awsBoshDirectorEphemeralIPOps)
 		}
 	}
 
 	if interpolateInput.IAAS == "aws" {
-		args = append(args,
-			"-o", filepath.Join(tempDir, "iam-instance-profile.yml"),
-			"-o", filepath.Join(tempDir, "aws-bosh-director-encrypt-disk-ops.yml"),
+		opsFiles = append(opsFiles,
+			string(MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/aws/iam-instance-profile.yml")),
+			awsEncryptDiskOps,
 		)
 	}
 
-	buffer := bytes.NewBuffer([]byte{})
-	err = e.command.Run(buffer, tempDir, args)
+	sizingOps, err := interpolateInput.DirectorSizing.ops(interpolateInput.IAAS)
 	if err != nil {
-		return InterpolateOutput{}, err
+		return InterpolateOutput{}, err //not tested
 	}
 
-	if interpolateInput.OpsFile != "" {
-		err = e.writeFile(filepath.Join(tempDir, "bosh.yml"), buffer.Bytes(), os.ModePerm)
-		if err != nil {
-			//not tested
-			return InterpolateOutput{}, err
+	if sizingOps != "" {
+		opsFiles = append(opsFiles, sizingOps)
+	}
+
+	if interpolateInput.ExternalDatabase {
+		opsFiles = append(opsFiles, externalDatabaseOps)
+
+		if interpolateInput.IAAS == "aws" || interpolateInput.IAAS == "azure" {
+			opsFiles = append(opsFiles, externalDatabaseRegistryOps)
 		}
 
-		args = []string{
-			"interpolate", filepath.Join(tempDir, "bosh.yml"),
-			"--var-errs",
-			"--vars-store", filepath.Join(tempDir, "variables.yml"),
-			"--vars-file", filepath.Join(tempDir, "deployment-vars.yml"),
-			"-o", filepath.Join(tempDir, "user-ops-file.yml"),
+		// UAA and CredHub keep their databases in the local postgres, which
+		// is only deployed alongside them.
+		if interpolateInput.JumpboxDeploymentVars == "" {
+			opsFiles = append(opsFiles, removeLocalDatabaseOps)
 		}
+	}
 
-		buffer = bytes.NewBuffer([]byte{})
-		err = e.command.Run(buffer, tempDir, args)
-		if err != nil {
-			return InterpolateOutput{}, err
+	if interpolateInput.ExternalBlobstore {
+		switch interpolateInput.IAAS {
+		case "aws":
+			opsFiles = append(opsFiles, awsExternalBlobstoreOps)
+		case "gcp":
+			opsFiles = append(opsFiles, gcpExternalBlobstoreOps)
 		}
 	}
 
-	varsStore, err := e.readFile(filepath.Join(tempDir, "variables.yml"))
+	output, err := interpolate.Interpolate(interpolate.Input{
+		Manifest:          string(MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/bosh.yml")),
+		OpsFiles:          opsFiles,
+		VarsFiles:         []string{interpolateInput.DeploymentVars},
+		VarsStore:         interpolateInput.Variables,
+		ExpectAllKeys:     true,
+		ExpectAllVarsUsed: true,
+	})
 	if err != nil {
 		return InterpolateOutput{}, err
 	}
 
+	// The user ops files are applied in order to the interpolated manifest, so
+	// that their paths can refer to values that are variables in
+	// bosh-deployment. The user vars files fill in the variables they add.
+	if len(interpolateInput.OpsFiles) > 0 {
+		output, err = interpolate.Interpolate(interpolate.Input{
+			Manifest:      output.Manifest,
+			OpsFiles:      interpolateInput.OpsFiles,
+			VarsFiles:     append([]string{interpolateInput.DeploymentVars}, interpolateInput.VarsFiles...),
+			VarsStore:     output.VarsStore,
+			ExpectAllKeys: true,
+		})
+		if err != nil {
+			return InterpolateOutput{}, err
+		}
+	}
+
 	return InterpolateOutput{
-		Variables: string(varsStore),
-		Manifest:  buffer.String(),
+		Variables: output.VarsStore,
+		Manifest:  output.Manifest,
 	}, nil
 }
 
 func (e Executor) CreateEnv(createEnvInput CreateEnvInput) (CreateEnvOutput, error) {
-	tempDir, err := e.writePreviousFiles(createEnvInput.State, createEnvInput.Variables, createEnvInput.Manifest)
+	tempDir, err := e.tempDir("", "")
+	if err != nil {
+		return CreateEnvOutput{}, err
+	}
+	defer workspace.Remove(tempDir)
+
+	err = e.writePreviousFiles(tempDir, createEnvInput.State, createEnvInput.Variables, createEnvInput.Manifest)
 	if err != nil {
 		return CreateEnvOutput{}, err
 	}
@@ -314,7 +346,13 @@ func (e Executor) readBOSHState(statePath string) (map[string]interface{}, error
 }
 
 func (e Executor) DeleteEnv(deleteEnvInput DeleteEnvInput) error {
-	tempDir, err := e.writePreviousFiles(deleteEnvInput.State, deleteEnvInput.Variables, deleteEnvInput.Manifest)
+	tempDir, err := e.tempDir("", "")
+	if err != nil {
+		return err
+	}
+	defer workspace.Remove(tempDir)
+
+	err = e.writePreviousFiles(tempDir, deleteEnvInput.State, deleteEnvInput.Variables, deleteEnvInput.Manifest)
 	if err != nil {
 		return err
 	}
@@ -349,6 +387,7 @@ func (e Executor) Version() (string, error) {
 	if err != nil {
 		return "", err
 	}
+	defer workspace.Remove(tempDir)
 
 	args := []string{"-v"}
 
@@ -369,12 +408,7 @@ func (e Executor) Version() (string, error) {
 	return version, nil
 }
 
-func (e Executor) writePreviousFiles(state map[string]interface{}, variables, manifest string) (string, error) {
-	tempDir, err := e.tempDir("", "")
-	if err != nil {
-		return "", err
-	}
-
+func (e Executor) writePreviousFiles(tempDir string, state map[string]interface{}, variables, manifest string) error {
 	statePath := fmt.Sprintf("%s/state.json", tempDir)
 	variablesPath := fmt.Sprintf("%s/variables.yml", tempDir)
 	boshManifestPath := filepath.Join(tempDir, "manifest.yml")
@@ -382,25 +416,25 @@ func (e Executor) writePreviousFiles(state map[string]interface{}, variables, ma
 	if state != nil {
 		boshStateContents, err := e.marshalJSON(state)
 		if err != nil {
-			return "", err
+			return err
 		}
-		err = e.writeFile(statePath, boshStateContents, os.ModePerm)
+		err = e.writeFile(statePath, boshStateContents, workspace.FileMode)
 		if err != nil {
-			return "", err
+			return err
 		}
 	}
 
-	err = e.writeFile(variablesPath, []byte(variables), os.ModePerm)
+	err := e.writeFile(variablesPath, []byte(variables), workspace.FileMode)
 	if err != nil {
 		// not tested
-		return "", err
+		return err
 	}
 
-	err = e.writeFile(boshManifestPath, []byte(manifest), os.ModePerm)
+	err = e.writeFile(boshManifestPath, []byte(manifest), workspace.FileMode)
 	if err != nil {
 		// not tested
-		return "", err
+		return err
 	}
 
-	return tempDir, nil
+	return nil
 }
@@ -7,10 +7,12 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
-	"github.com/pivotal-cf-experimental/gomegamatchers"
+	yaml "gopkg.in/yaml.v2"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -19,395 +21,363 @@ import (
 var _ = Describe("Executor", func() {
 	Describe("DirectorInterpolate", func() {
 		var (
-			cmd *fakes.BOSHCommand
-
-			tempDir          string
-			tempDirFunc      func(string, string) (string, error)
-			tempDirCallCount int
-
+			cmd      *fakes.BOSHCommand
 			executor bosh.Executor
 
-			stateJSONContents    string
-			variablesYMLContents string
-			awsInterpolateInput  bosh.InterpolateInput
-			gcpInterpolateInput  bosh.InterpolateInput
+			awsInterpolateInput bosh.InterpolateInput
+			gcpInterpolateInput bosh.InterpolateInput
 		)
 
 		BeforeEach(func() {
 			cmd = &fakes.BOSHCommand{}
 
-			var err error
-			tempDir, err = ioutil.TempDir("", "")
-			Expect(err).NotTo(HaveOccurred())
-
-			tempDirFunc = func(prefix, dir string) (string, error) {
-				tempDirCallCount++
-				return tempDir, nil
-			}
-
-			stateJSONContents = `{"key":"value"}`
-			variablesYMLContents = "key: value"
-
 			awsInterpolateInput = bosh.InterpolateInput{
-				IAAS:           "aws",
-				DeploymentVars: "internal_cidr: 10.0.0.0/24",
-				BOSHState: map[string]interface{}{
-					"key": "value",
-				},
-				Variables: variablesYMLContents,
-				OpsFile:   "some-ops-file",
+				IAAS: "aws",
+				DeploymentVars: `internal_cidr: 10.0.0.0/24
+internal_gw: 10.0.0.1
+internal_ip: 10.0.0.6
+director_name: bosh-some-env-id
+external_ip: 1.2.3.4
+az: some-az
+subnet_id: some-subnet-id
+access_key_id: some-access-key-id
+secret_access_key: some-secret-access-key
+iam_instance_profile: some-instance-profile
+default_key_name: some-key-name
+default_security_groups: [some-security-group]
+region: some-region
+private_key: some-private-key
+kms_key_arn: some-kms-key-arn
+`,
 			}
 
-			gcpInterpolateInput = awsInterpolateInput
-			gcpInterpolateInput.IAAS = "gcp"
-
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
-		})
+			gcpInterpolateInput = bosh.InterpolateInput{
+				IAAS: "gcp",
+				DeploymentVars: `internal_cidr: 10.0.0.0/24
+internal_gw: 10.0.0.1
+internal_ip: 10.0.0.6
+director_name: bosh-some-env-id
+external_ip: 1.2.3.4
+zone: some-zone
+network: some-network
+subnetwork: some-subnetwork
+tags: [some-bosh-tag, some-internal-tag]
+project_id: some-project-id
+gcp_credentials_json: some-credential-json
+`,
+			}
 
-		AfterEach(func() {
-			tempDirCallCount = 0
+			executor = bosh.NewExecutor(cmd, ioutil.TempDir, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
 		})
 
 		Context("aws", func() {
-			It("generates a bosh manifest", func() {
-				cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
-					stdout.Write([]byte("some-manifest"))
-					return nil
-				}
-
+			It("generates a bosh manifest without running the bosh cli", func() {
 				interpolateOutput, err := executor.DirectorInterpolate(awsInterpolateInput)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(cmd.RunCallCount()).To(Equal(2))
-				Expect(tempDirCallCount).To(Equal(1))
-
-				expectedArgs := append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--var-errs-unused",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
-					"-o", fmt.Sprintf("%s/jumpbox-user.yml", tempDir),
-					"-o", fmt.Sprintf("%s/aws-external-ip-not-recommended.yml", tempDir),
-					"-o", fmt.Sprintf("%s/iam-instance-profile.yml", tempDir),
-					"-o", fmt.Sprintf("%s/aws-bosh-director-encrypt-disk-ops.yml", tempDir),
-				})
-
-				_, _, args := cmd.RunArgsForCall(0)
-				Expect(args).To(Equal(expectedArgs))
+				Expect(cmd.RunCallCount()).To(Equal(0))
 
-				expectedArgs = append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/user-ops-file.yml", tempDir),
-				})
-
-				_, _, args = cmd.RunArgsForCall(1)
-				Expect(args).To(Equal(expectedArgs))
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "name")).To(Equal("bosh"))
+				Expect(valueAt(manifest, "resource_pools", "0", "cloud_properties", "iam_instance_profile")).To(Equal("some-instance-profile"))
+				Expect(valueAt(manifest, "disk_pools", "0", "cloud_properties", "kms_key_arn")).To(Equal("some-kms-key-arn"))
+				Expect(valueAt(manifest, "cloud_provider", "mbus")).To(ContainSubstring("@1.2.3.4:6868"))
+				Expect(interpolateOutput.Manifest).NotTo(ContainSubstring("(("))
 
-				Expect(interpolateOutput.Manifest).To(Equal("some-manifest"))
-				Expect(interpolateOutput.Variables).To(gomegamatchers.MatchYAML(variablesYMLContents))
+				variables := parseYAML(interpolateOutput.Variables)
+				Expect(variables).To(HaveKey("admin_password"))
+				Expect(variables).To(HaveKey("director_ssl"))
+				Expect(variables).To(HaveKey("jumpbox_ssh"))
 			})
 
 			Context("when there are jumpbox deployment vars", func() {
-				It("interpolates the jumpbox and bosh manifests", func() {
+				It("deploys uaa and credhub on the director instead of a jumpbox user", func() {
 					awsInterpolateInput.JumpboxDeploymentVars = "internal_cidr: 10.0.0.0/24"
-					awsInterpolateInput.OpsFile = ""
-
-					cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
-						stdout.Write([]byte("some-manifest"))
-						return nil
-					}
+					awsInterpolateInput.DeploymentVars = strings.Replace(awsInterpolateInput.DeploymentVars, "external_ip: 1.2.3.4\n", "", 1)
 
-					jumpboxInterpolateOutput, err := executor.JumpboxInterpolate(awsInterpolateInput)
+					interpolateOutput, err := executor.DirectorInterpolate(awsInterpolateInput)
 					Expect(err).NotTo(HaveOccurred())
 
-					Expect(cmd.RunCallCount()).To(Equal(1))
-					Expect(tempDirCallCount).To(Equal(1))
+					manifest := parseYAML(interpolateOutput.Manifest)
+					Expect(valueAt(manifest, "resource_pools", "0", "cloud_properties", "auto_assign_public_ip")).To(Equal(true))
 
-					expectedArgs := append([]string{
-						"interpolate", fmt.Sprintf("%s/jumpbox.yml", tempDir),
-						"--var-errs",
-						"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-						"--vars-file", fmt.Sprintf("%s/jumpbox-deployment-vars.yml", tempDir),
-						"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
-					})
+					variables := parseYAML(interpolateOutput.Variables)
+					Expect(variables).To(HaveKey("credhub_tls"))
+					Expect(variables).To(HaveKey("uaa_ssl"))
+					Expect(variables).NotTo(HaveKey("jumpbox_ssh"))
+				})
+			})
+		})
 
-					_, _, args := cmd.RunArgsForCall(0)
-					Expect(args).To(Equal(expectedArgs))
+		Context("gcp", func() {
+			It("generates a bosh manifest without running the bosh cli", func() {
+				interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
 
-					Expect(jumpboxInterpolateOutput.Manifest).To(Equal("some-manifest"))
-					Expect(jumpboxInterpolateOutput.Variables).To(gomegamatchers.MatchYAML("key: value"))
+				Expect(cmd.RunCallCount()).To(Equal(0))
 
-					interpolateOutput, err := executor.DirectorInterpolate(awsInterpolateInput)
-					Expect(err).NotTo(HaveOccurred())
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "networks", "0", "subnets", "0", "cloud_properties", "tags")).To(Equal([]interface{}{"some-bosh-tag", "some-internal-tag"}))
+				Expect(valueAt(manifest, "cloud_provider", "mbus")).To(ContainSubstring("@1.2.3.4:6868"))
+				Expect(interpolateOutput.Manifest).NotTo(ContainSubstring("(("))
 
-					Expect(cmd.RunCallCount()).To(Equal(2))
-					Expect(tempDirCallCount).To(Equal(2))
-
-					expectedArgs = append([]string{
-						"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-						"--var-errs",
-						"--var-errs-unused",
-						"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-						"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-						"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
-						"-o", fmt.Sprintf("%s/uaa.yml", tempDir),
-						"-o", fmt.Sprintf("%s/credhub.yml", tempDir),
-						"-o", fmt.Sprintf("%s/aws-bosh-director-ephemeral-ip-ops.yml", tempDir),
-						"-o", fmt.Sprintf("%s/iam-instance-profile.yml", tempDir),
-						"-o", fmt.Sprintf("%s/aws-bosh-director-encrypt-disk-ops.yml", tempDir),
-					})
+				variables := parseYAML(interpolateOutput.Variables)
+				Expect(variables).To(HaveKey("director_ssl"))
+				Expect(variables).To(HaveKey("jumpbox_ssh"))
+			})
+
+			Context("when there are jumpbox deployment vars", func() {
+				It("gives the director an ephemeral external ip", func() {
+					gcpInterpolateInput.JumpboxDeploymentVars = "internal_cidr: 10.0.0.0/24"
+					gcpInterpolateInput.DeploymentVars = strings.Replace(gcpInterpolateInput.DeploymentVars, "external_ip: 1.2.3.4\n", "", 1)
+
+					interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+					Expect(err).NotTo(HaveOccurred())
 
-					_, _, args = cmd.RunArgsForCall(1)
-					Expect(args).To(Equal(expectedArgs))
+					manifest := parseYAML(interpolateOutput.Manifest)
+					Expect(valueAt(manifest, "networks", "0", "subnets", "0", "cloud_properties", "ephemeral_external_ip")).To(Equal(true))
 
-					Expect(interpolateOutput.Manifest).To(Equal("some-manifest"))
-					Expect(jumpboxInterpolateOutput.Variables).To(gomegamatchers.MatchYAML("key: value"))
+					variables := parseYAML(interpolateOutput.Variables)
+					Expect(variables).To(HaveKey("credhub_tls"))
 				})
 			})
 		})
 
-		Context("gcp", func() {
-			It("generates a bosh manifest", func() {
-				cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
-					stdout.Write([]byte("some-manifest"))
-					return nil
+		It("keeps the variables that are already in the vars store", func() {
+			gcpInterpolateInput.Variables = "admin_password: some-admin-password\nsome-old-variable: some-value"
+
+			interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+			Expect(err).NotTo(HaveOccurred())
+
+			variables := parseYAML(interpolateOutput.Variables)
+			Expect(variables["admin_password"]).To(Equal("some-admin-password"))
+			Expect(variables["some-old-variable"]).To(Equal("some-value"))
+			Expect(interpolateOutput.Manifest).To(ContainSubstring("some-admin-password"))
+		})
+
+		Context("when the director is sized", func() {
+			It("overrides the vm type, disk size and stemcell of bosh-deployment", func() {
+				awsInterpolateInput.DirectorSizing = bosh.DirectorSizing{
+					VMType:       "m4.2xlarge",
+					DiskSizeGB:   100,
+					StemcellURL:  "https://example.com/stemcell.tgz",
+					StemcellSHA1: "some-sha1",
 				}
 
-				interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				interpolateOutput, err := executor.DirectorInterpolate(awsInterpolateInput)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(cmd.RunCallCount()).To(Equal(2))
-				Expect(tempDirCallCount).To(Equal(1))
-
-				expectedArgs := append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--var-errs-unused",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
-					"-o", fmt.Sprintf("%s/jumpbox-user.yml", tempDir),
-					"-o", fmt.Sprintf("%s/gcp-external-ip-not-recommended.yml", tempDir)})
-
-				_, _, args := cmd.RunArgsForCall(0)
-				Expect(args).To(Equal(expectedArgs))
-
-				expectedArgs = append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/user-ops-file.yml", tempDir)})
-
-				_, _, args = cmd.RunArgsForCall(1)
-				Expect(args).To(Equal(expectedArgs))
-
-				Expect(interpolateOutput.Manifest).To(Equal("some-manifest"))
-				Expect(interpolateOutput.Variables).To(gomegamatchers.MatchYAML(variablesYMLContents))
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "resource_pools", "0", "cloud_properties", "instance_type")).To(Equal("m4.2xlarge"))
+				Expect(valueAt(manifest, "disk_pools", "0", "disk_size")).To(Equal(102400))
+				Expect(valueAt(manifest, "resource_pools", "0", "stemcell")).To(Equal(map[interface{}]interface{}{
+					"url":  "https://example.com/stemcell.tgz",
+					"sha1": "some-sha1",
+				}))
 			})
 
-			Context("when there are jumpbox deployment vars", func() {
-				It("interpolates the jumpbox and bosh manifests", func() {
-					gcpInterpolateInput.JumpboxDeploymentVars = "internal_cidr: 10.0.0.0/24"
-					gcpInterpolateInput.OpsFile = ""
+			It("sets the machine type on gcp", func() {
+				gcpInterpolateInput.DirectorSizing = bosh.DirectorSizing{VMType: "n1-standard-8"}
 
-					cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
-						stdout.Write([]byte("some-manifest"))
-						return nil
-					}
+				interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
 
-					jumpboxInterpolateOutput, err := executor.JumpboxInterpolate(gcpInterpolateInput)
-					Expect(err).NotTo(HaveOccurred())
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "resource_pools", "0", "cloud_properties", "machine_type")).To(Equal("n1-standard-8"))
+				Expect(valueAt(manifest, "disk_pools", "0", "disk_size")).To(Equal(32768))
+			})
+		})
 
-					Expect(cmd.RunCallCount()).To(Equal(1))
-					Expect(tempDirCallCount).To(Equal(1))
+		Context("when the director database is external", func() {
+			var externalDBVars = `external_db_host: some-db-host
+external_db_user: some-db-user
+external_db_password: some-db-password
+external_db_name: some-db-name
+`
 
-					expectedArgs := append([]string{
-						"interpolate", fmt.Sprintf("%s/jumpbox.yml", tempDir),
-						"--var-errs",
-						"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-						"--vars-file", fmt.Sprintf("%s/jumpbox-deployment-vars.yml", tempDir),
-						"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
-					})
+			expectedDB := map[interface{}]interface{}{
+				"adapter":  "postgres",
+				"host":     "some-db-host",
+				"port":     5432,
+				"user":     "some-db-user",
+				"password": "some-db-password",
+				"database": "some-db-name",
+			}
 
-					_, _, args := cmd.RunArgsForCall(0)
-					Expect(args).To(Equal(expectedArgs))
+			It("points the director and the registry at it and removes the local postgres", func() {
+				awsInterpolateInput.ExternalDatabase = true
+				awsInterpolateInput.DeploymentVars += externalDBVars
 
-					Expect(jumpboxInterpolateOutput.Manifest).To(Equal("some-manifest"))
-					Expect(jumpboxInterpolateOutput.Variables).To(gomegamatchers.MatchYAML("key: value"))
+				interpolateOutput, err := executor.DirectorInterpolate(awsInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
 
-					interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
-					Expect(err).NotTo(HaveOccurred())
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "instance_groups", "0", "properties", "director", "db")).To(Equal(expectedDB))
+				Expect(valueAt(manifest, "instance_groups", "0", "properties", "registry", "db")).To(Equal(expectedDB))
+				Expect(valueAt(manifest, "instance_groups", "0", "properties")).NotTo(HaveKey("postgres"))
+				Expect(jobNames(manifest)).NotTo(ContainElement("postgres-9.4"))
+			})
 
-					Expect(cmd.RunCallCount()).To(Equal(2))
-					Expect(tempDirCallCount).To(Equal(2))
-
-					expectedArgs = append([]string{
-						"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-						"--var-errs",
-						"--var-errs-unused",
-						"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-						"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-						"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
-						"-o", fmt.Sprintf("%s/uaa.yml", tempDir),
-						"-o", fmt.Sprintf("%s/credhub.yml", tempDir),
-						"-o", fmt.Sprintf("%s/gcp-bosh-director-ephemeral-ip-ops.yml", tempDir),
-					})
+			It("keeps the local postgres for uaa and credhub when there is a jumpbox", func() {
+				gcpInterpolateInput.ExternalDatabase = true
+				gcpInterpolateInput.JumpboxDeploymentVars = "internal_cidr: 10.0.0.0/24"
+				gcpInterpolateInput.DeploymentVars = strings.Replace(gcpInterpolateInput.DeploymentVars, "external_ip: 1.2.3.4\n", "", 1) + externalDBVars
 
-					_, _, args = cmd.RunArgsForCall(1)
-					Expect(args).To(Equal(expectedArgs))
+				interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
 
-					Expect(interpolateOutput.Manifest).To(Equal("some-manifest"))
-					Expect(jumpboxInterpolateOutput.Variables).To(gomegamatchers.MatchYAML("key: value"))
-				})
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "instance_groups", "0", "properties", "director", "db")).To(Equal(expectedDB))
+				Expect(valueAt(manifest, "instance_groups", "0", "properties")).NotTo(HaveKey("registry"))
+				Expect(jobNames(manifest)).To(ContainElement("postgres-9.4"))
 			})
 		})
 
-		Context("when a user opsfile is provided", func() {
-			It("re-interpolates the bosh manifest", func() {
-				interpolateInput := bosh.InterpolateInput{
-					IAAS: "gcp",
-					DeploymentVars: `internal_cidr: 10.0.0.0/24
-		tags: [some-bosh-tag, some-internal-tag]'`,
-					BOSHState: map[string]interface{}{
-						"key": "value",
-					},
-					Variables: variablesYMLContents,
-					OpsFile: `
----
-- type: replace
-path: /networks/name=default/subnets/0/cloud_properties/tags/-
-value: sabeti-bosh-isolation
-		`,
-				}
+		Context("when the director blobstore is external", func() {
+			It("uses the s3 bucket on aws", func() {
+				awsInterpolateInput.ExternalBlobstore = true
+				awsInterpolateInput.DeploymentVars += "director_blobstore_bucket: some-bucket\n"
 
-				manifest := `
----
-networks
-- name: default
-  subnets:
-  - az: z1
-  cloud_properties:
-    tags:
-      - some-bosh-tag
-      - some-internal-tag
-`
-				manifestWithUserOpsFile := `
----
-networks
-- name: default
-  subnets:
-  - az: z1
-  cloud_properties:
-    tags:
-      - some-bosh-tag
-      - some-internal-tag
-      - sabeti-bosh-isolation
-`
+				interpolateOutput, err := executor.DirectorInterpolate(awsInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
 
-				writtenManifest := []byte{}
-				cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
-					for _, arg := range args {
-						if arg == fmt.Sprintf("%s/user-ops-file.yml", tempDir) {
-							var err error
-							writtenManifest, err = ioutil.ReadFile(fmt.Sprintf("%s/bosh.yml", tempDir))
-							if err != nil {
-								return err
-							}
-							stdout.Write([]byte(manifestWithUserOpsFile))
-							return nil
-						}
-					}
-					stdout.Write([]byte(manifest))
-					return nil
-				}
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "instance_groups", "0", "properties", "blobstore")).To(Equal(map[interface{}]interface{}{
+					"provider":           "s3",
+					"bucket_name":        "some-bucket",
+					"s3_region":          "some-region",
+					"credentials_source": "env_or_profile",
+				}))
+				Expect(valueAt(manifest, "instance_groups", "0", "properties", "aws", "default_iam_instance_profile")).To(Equal("some-instance-profile"))
+				Expect(jobNames(manifest)).NotTo(ContainElement("blobstore"))
+			})
 
-				interpolateOutput, err := executor.DirectorInterpolate(interpolateInput)
-				Expect(err).NotTo(HaveOccurred())
+			It("uses the gcs bucket on gcp", func() {
+				gcpInterpolateInput.ExternalBlobstore = true
+				gcpInterpolateInput.DeploymentVars += "director_blobstore_bucket: some-bucket\n"
 
-				Expect(cmd.RunCallCount()).To(Equal(2))
-				Expect(tempDirCallCount).To(Equal(1))
-
-				expectedArgs := append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--var-errs-unused",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
-					"-o", fmt.Sprintf("%s/jumpbox-user.yml", tempDir),
-					"-o", fmt.Sprintf("%s/gcp-external-ip-not-recommended.yml", tempDir)})
-
-				_, _, args := cmd.RunArgsForCall(0)
-				Expect(args).To(Equal(expectedArgs))
-
-				expectedArgsWithUserOpsfile := append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/user-ops-file.yml", tempDir)})
-
-				_, _, args = cmd.RunArgsForCall(1)
-				Expect(args).To(Equal(expectedArgsWithUserOpsfile))
-
-				opsFileContents, err := ioutil.ReadFile(fmt.Sprintf("%s/user-ops-file.yml", tempDir))
+				interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(string(opsFileContents)).To(Equal(interpolateInput.OpsFile))
-				Expect(string(writtenManifest)).To(Equal(manifest))
 
-				Expect(interpolateOutput.Manifest).To(Equal(manifestWithUserOpsFile))
-				Expect(interpolateOutput.Variables).To(gomegamatchers.MatchYAML("key: value"))
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "instance_groups", "0", "properties", "blobstore")).To(Equal(map[interface{}]interface{}{
+					"provider":           "gcs",
+					"bucket_name":        "some-bucket",
+					"credentials_source": "static",
+					"json_key":           "some-credential-json",
+				}))
+				Expect(jobNames(manifest)).NotTo(ContainElement("blobstore"))
 			})
 		})
 
-		It("does not pass in false to run command on interpolate", func() {
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
-			_, err := executor.DirectorInterpolate(awsInterpolateInput)
-			Expect(err).NotTo(HaveOccurred())
+		Context("when user ops files are provided", func() {
+			It("applies them in order after the ops files of bbl", func() {
+				gcpInterpolateInput.OpsFiles = []string{`
+---
+- type: replace
+  path: /networks/name=default/subnets/0/cloud_properties/tags/-
+  value: sabeti-bosh-isolation
+`, `
+- type: replace
+  path: /networks/name=default/subnets/0/cloud_properties/tags/-
+  value: ((extra_tag))
+`}
+				gcpInterpolateInput.VarsFiles = []string{"extra_tag: some-extra-tag"}
+
+				interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
+
+				manifest := parseYAML(interpolateOutput.Manifest)
+				Expect(valueAt(manifest, "networks", "0", "subnets", "0", "cloud_properties", "tags")).To(Equal([]interface{}{
+					"some-bosh-tag", "some-internal-tag", "sabeti-bosh-isolation", "some-extra-tag",
+				}))
+			})
 		})
 
 		Describe("failure cases", func() {
-			It("fails when trying to run command", func() {
-				cmd.RunReturnsOnCall(0, errors.New("failed to run command"))
+			It("fails when a variable is missing", func() {
+				gcpInterpolateInput.DeploymentVars = strings.Replace(gcpInterpolateInput.DeploymentVars, "zone: some-zone\n", "", 1)
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
-				_, err := executor.DirectorInterpolate(bosh.InterpolateInput{
-					IAAS: "aws",
-				})
-				Expect(err).To(MatchError("failed to run command"))
+				_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).To(MatchError("Expected to find variables:\n  - zone"))
 			})
 
-			It("fails when trying to run the command to interpolate with the user opsfile", func() {
-				cmd.RunReturnsOnCall(1, errors.New("failed to run command"))
+			It("fails when a deployment var is not used", func() {
+				gcpInterpolateInput.DeploymentVars += "some-unused-var: some-value\n"
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
-				_, err := executor.DirectorInterpolate(bosh.InterpolateInput{
-					IAAS:    "aws",
-					OpsFile: "some-ops-file",
-				})
-				Expect(err).To(MatchError("failed to run command"))
+				_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).To(MatchError("Expected to use variables:\n  - some-unused-var"))
 			})
 
-			It("fails when the variables file fails to be read", func() {
-				readFileFunc := func(path string) ([]byte, error) {
-					return []byte{}, errors.New("failed to read variables file")
-				}
+			It("fails when a user ops file cannot be applied", func() {
+				gcpInterpolateInput.OpsFiles = []string{"- type: remove\n  path: /some-missing-key"}
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, readFileFunc, json.Unmarshal, json.Marshal, ioutil.WriteFile)
-				_, err := executor.DirectorInterpolate(bosh.InterpolateInput{
-					IAAS: "aws",
-				})
-				Expect(err).To(MatchError("failed to read variables file"))
+				_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).To(MatchError(`remove /some-missing-key: expected to find map key "some-missing-key"`))
+			})
+
+			It("fails when a variable of a user ops file is missing", func() {
+				gcpInterpolateInput.OpsFiles = []string{"- type: replace\n  path: /name\n  value: ((some-name))"}
+
+				_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+				Expect(err).To(MatchError("Expected to find variables:\n  - some-name"))
 			})
 		})
 	})
 
+	Describe("JumpboxInterpolate", func() {
+		var (
+			cmd      *fakes.BOSHCommand
+			executor bosh.Executor
+
+			interpolateInput bosh.InterpolateInput
+		)
+
+		BeforeEach(func() {
+			cmd = &fakes.BOSHCommand{}
+
+			interpolateInput = bosh.InterpolateInput{
+				IAAS: "gcp",
+				JumpboxDeploymentVars: `internal_cidr: 10.0.0.0/24
+internal_gw: 10.0.0.1
+internal_ip: 10.0.0.5
+director_name: bosh-some-env-id
+external_ip: 1.2.3.4
+zone: some-zone
+network: some-network
+subnetwork: some-subnetwork
+tags: [some-jumpbox-tag]
+project_id: some-project-id
+gcp_credentials_json: some-credential-json
+`,
+			}
+
+			executor = bosh.NewExecutor(cmd, ioutil.TempDir, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+		})
+
+		It("generates a jumpbox manifest without running the bosh cli", func() {
+			interpolateOutput, err := executor.JumpboxInterpolate(interpolateInput)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmd.RunCallCount()).To(Equal(0))
+
+			manifest := parseYAML(interpolateOutput.Manifest)
+			Expect(valueAt(manifest, "name")).To(Equal("jumpbox"))
+			Expect(valueAt(manifest, "resource_pools", "0", "cloud_properties", "zone")).To(Equal("some-zone"))
+
+			variables := parseYAML(interpolateOutput.Variables)
+			Expect(valueAt(variables, "jumpbox_ssh", "private_key")).To(ContainSubstring("BEGIN RSA PRIVATE KEY"))
+			Expect(interpolateOutput.Manifest).To(ContainSubstring(valueAt(variables, "jumpbox_ssh", "public_key").(string)))
+		})
+
+		It("fails when a variable is missing", func() {
+			interpolateInput.JumpboxDeploymentVars = strings.Replace(interpolateInput.JumpboxDeploymentVars, "zone: some-zone\n", "", 1)
+
+			_, err := executor.JumpboxInterpolate(interpolateInput)
+			Expect(err).To(MatchError("bosh interpolate: Expected to find variables:\n  - zone"))
+		})
+	})
+
 	var createEnvDeleteEnvFailureCases = func(callback func(executor bosh.Executor) error) {
 		var (
 			cmd *fakes.BOSHCommand
@@ -822,3 +792,38 @@ networks
 		})
 	})
 })
+
+func parseYAML(contents string) map[interface{}]interface{} {
+	var result map[interface{}]interface{}
+	err := yaml.Unmarshal([]byte(contents), &result)
+	Expect(err).NotTo(HaveOccurred())
+	return result
+}
+
+// jobNames lists the jobs of the bosh instance group.
+func jobNames(manifest map[interface{}]interface{}) []string {
+	var names []string
+	for _, job := range valueAt(manifest, "instance_groups", "0", "jobs").([]interface{}) {
+		names = append(names, job.(map[interface{}]interface{})["name"].(string))
+	}
+	return names
+}
+
+// valueAt follows keys and array indexes through a parsed YAML document.
+func valueAt(node interface{}, path ...string) interface{} {
+	for _, key := range path {
+		switch n := node.(type) {
+		case map[interface{}]interface{}:
+			Expect(n).To(HaveKey(key))
+			node = n[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(n)).To(BeNumerically(">", index))
+			node = n[index]
+		default:
+			Fail(fmt.Sprintf("expected a map or an array at %q, found %T", key, node))
+		}
+	}
+	return node
+}
@@ -0,0 +1,25 @@
+package bosh
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// Fingerprint identifies what create-env was last run with, so that an
+// unchanged jumpbox or director does not have to be deployed again. The
+// create-env state is part of it so that a failed or interrupted deploy is
+// never mistaken for a finished one.
+func Fingerprint(manifest, variables string, state map[string]interface{}) (string, error) {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("marshal create-env state: %s", err)
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{manifest, variables, string(stateJSON)} {
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
@@ -6,7 +6,9 @@ import (
 
 	yaml "gopkg.in/yaml.v2"
 
+	bblproxy "github.com/cloudfoundry/bosh-bootloader/proxy"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"golang.org/x/net/proxy"
 )
 
 var (
@@ -15,8 +17,7 @@ var (
 )
 
 const (
-	DIRECTOR_USERNAME    = "admin"
-	DIRECTOR_INTERNAL_IP = "10.0.0.6"
+	DIRECTOR_USERNAME = "admin"
 )
 
 type Manager struct {
@@ -60,6 +61,8 @@ type sharedDeploymentVarsYAML struct {
 	AWSYAML      AWSYAML   `yaml:",inline"`
 	GCPYAML      GCPYAML   `yaml:",inline"`
 	AzureYAML    AzureYAML `yaml:",inline"`
+
+	ExternalStoresYAML ExternalStoresYAML `yaml:",inline"`
 }
 
 type AWSYAML struct {
@@ -96,6 +99,17 @@ type AzureYAML struct {
 	DefaultSecurityGroup string `yaml:"default_security_group,omitempty"`
 }
 
+// ExternalStoresYAML holds the terraform outputs of the director database and
+// blobstore that bbl up --director-external-db and
+// --director-external-blobstore provision.
+type ExternalStoresYAML struct {
+	ExternalDBHost          string `yaml:"external_db_host,omitempty"`
+	ExternalDBUser          string `yaml:"external_db_user,omitempty"`
+	ExternalDBPassword      string `yaml:"external_db_password,omitempty"`
+	ExternalDBName          string `yaml:"external_db_name,omitempty"`
+	DirectorBlobstoreBucket string `yaml:"director_blobstore_bucket,omitempty"`
+}
+
 type executor interface {
 	DirectorInterpolate(InterpolateInput) (InterpolateOutput, error)
 	JumpboxInterpolate(InterpolateInput) (JumpboxInterpolateOutput, error)
@@ -110,8 +124,11 @@ type logger interface {
 }
 
 type socks5Proxy interface {
-	Start(string, string) error
+	Start(string, string, string, bblproxy.Allowlist) error
 	Addr() string
+	URL() string
+	Auth() *proxy.Auth
+	HostKey() string
 }
 
 func NewManager(executor executor, logger logger, socks5Proxy socks5Proxy) *Manager {
@@ -135,49 +152,69 @@ func (m *Manager) CreateJumpbox(state storage.State, terraformOutputs map[string
 	var err error
 	m.logger.Step("creating jumpbox")
 
-	iaasInputs := InterpolateInput{
-		IAAS: state.IAAS,
-		JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
-		DeploymentVars:        m.GetDeploymentVars(state, terraformOutputs),
-		Variables:             state.Jumpbox.Variables,
+	interpolateInput, err := m.jumpboxInterpolateInput(state, terraformOutputs)
+	if err != nil {
+		return storage.State{}, err
 	}
 
-	interpolateOutputs, err := m.executor.JumpboxInterpolate(iaasInputs)
+	interpolateOutputs, err := m.executor.JumpboxInterpolate(interpolateInput)
 	if err != nil {
 		return storage.State{}, fmt.Errorf("jumpbox interpolate: %s", err)
 	}
 
-	variables, err := yaml.Marshal(interpolateOutputs.Variables)
+	fingerprint, err := Fingerprint(interpolateOutputs.Manifest, interpolateOutputs.Variables, state.Jumpbox.State)
 	if err != nil {
-		return storage.State{}, fmt.Errorf("marshal yaml: %s", err)
+		return storage.State{}, err
 	}
 
-	osUnsetenv("BOSH_ALL_PROXY")
-	createEnvOutputs, err := m.executor.CreateEnv(CreateEnvInput{
-		Manifest:  interpolateOutputs.Manifest,
-		State:     state.Jumpbox.State,
-		Variables: string(variables),
-	})
-	switch err.(type) {
-	case CreateEnvError:
-		ceErr := err.(CreateEnvError)
-		state.Jumpbox = storage.Jumpbox{
-			Enabled:   true,
-			Variables: interpolateOutputs.Variables,
-			State:     ceErr.BOSHState(),
+	jumpboxState := state.Jumpbox.State
+	hostKey := state.Jumpbox.HostKey
+	if state.Jumpbox.Fingerprint != "" && state.Jumpbox.Fingerprint == fingerprint {
+		m.logger.Step("jumpbox is unchanged, skipping create-env")
+	} else {
+		variables, err := yaml.Marshal(interpolateOutputs.Variables)
+		if err != nil {
+			return storage.State{}, fmt.Errorf("marshal yaml: %s", err)
+		}
+
+		osUnsetenv("BOSH_ALL_PROXY")
+		createEnvOutputs, err := m.executor.CreateEnv(CreateEnvInput{
 			Manifest:  interpolateOutputs.Manifest,
+			State:     state.Jumpbox.State,
+			Variables: string(variables),
+		})
+		switch err.(type) {
+		case CreateEnvError:
+			ceErr := err.(CreateEnvError)
+			state.Jumpbox = storage.Jumpbox{
+				Enabled:   true,
+				Variables: interpolateOutputs.Variables,
+				State:     ceErr.BOSHState(),
+				Manifest:  interpolateOutputs.Manifest,
+			}
+			return storage.State{}, fmt.Errorf("create env error: %s", NewManagerCreateError(state, err))
+		case error:
+			return storage.State{}, fmt.Errorf("create env: %s", err)
+		}
+
+		// create-env may have replaced the VM, so the host key it presents
+		// now is the one to record.
+		hostKey = ""
+
+		jumpboxState = createEnvOutputs.State
+		fingerprint, err = Fingerprint(interpolateOutputs.Manifest, interpolateOutputs.Variables, jumpboxState)
+		if err != nil {
+			return storage.State{}, err
 		}
-		return storage.State{}, fmt.Errorf("create env error: %s", NewManagerCreateError(state, err))
-	case error:
-		return storage.State{}, fmt.Errorf("create env: %s", err)
 	}
 
 	state.Jumpbox = storage.Jumpbox{
-		Enabled:   true,
-		Variables: interpolateOutputs.Variables,
-		State:     createEnvOutputs.State,
-		Manifest:  interpolateOutputs.Manifest,
-		URL:       terraformOutputs["jumpbox_url"].(string),
+		Enabled:     true,
+		Variables:   interpolateOutputs.Variables,
+		State:       jumpboxState,
+		Manifest:    interpolateOutputs.Manifest,
+		URL:         terraformOutputs["jumpbox_url"].(string),
+		Fingerprint: fingerprint,
 	}
 
 	m.logger.Step("created jumpbox")
@@ -188,12 +225,19 @@ func (m *Manager) CreateJumpbox(state storage.State, terraformOutputs map[string
 		return storage.State{}, fmt.Errorf("jumpbox key: %s", err)
 	}
 
-	err = m.socks5Proxy.Start(jumpboxPrivateKey, state.Jumpbox.URL)
+	allowlist, err := proxyAllowlist(state.Network)
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	err = m.socks5Proxy.Start(jumpboxPrivateKey, hostKey, state.Jumpbox.URL, allowlist)
 	if err != nil {
 		return storage.State{}, fmt.Errorf("start proxy: %s", err)
 	}
 
-	osSetenv("BOSH_ALL_PROXY", fmt.Sprintf("socks5://%s", m.socks5Proxy.Addr()))
+	state.Jumpbox.HostKey = m.socks5Proxy.HostKey()
+
+	osSetenv("BOSH_ALL_PROXY", m.socks5Proxy.URL())
 
 	return state, nil
 }
@@ -201,36 +245,57 @@ func (m *Manager) CreateJumpbox(state storage.State, terraformOutputs map[string
 func (m *Manager) CreateDirector(state storage.State, terraformOutputs map[string]interface{}) (storage.State, error) {
 	m.logger.Step("creating bosh director")
 
-	iaasInputs := InterpolateInput{
-		IAAS:                  state.IAAS,
-		DeploymentVars:        m.GetDeploymentVars(state, terraformOutputs),
-		JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
-		Variables:             state.BOSH.Variables,
-		OpsFile:               state.BOSH.UserOpsFile,
+	network, err := NewNetwork(state.Network)
+	if err != nil {
+		return storage.State{}, err
 	}
 
-	interpolateOutputs, err := m.executor.DirectorInterpolate(iaasInputs)
+	interpolateInput, err := m.directorInterpolateInput(state, terraformOutputs)
 	if err != nil {
 		return storage.State{}, err
 	}
 
-	createEnvOutputs, err := m.executor.CreateEnv(CreateEnvInput{
-		Manifest:  interpolateOutputs.Manifest,
-		State:     state.BOSH.State,
-		Variables: interpolateOutputs.Variables,
-	})
+	userConfig := state.BOSH
 
-	switch err.(type) {
-	case CreateEnvError:
-		ceErr := err.(CreateEnvError)
-		state.BOSH = storage.BOSH{
-			Variables: interpolateOutputs.Variables,
-			State:     ceErr.BOSHState(),
+	interpolateOutputs, err := m.executor.DirectorInterpolate(interpolateInput)
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	fingerprint, err := Fingerprint(interpolateOutputs.Manifest, interpolateOutputs.Variables, state.BOSH.State)
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	directorState := state.BOSH.State
+	if state.BOSH.Fingerprint != "" && state.BOSH.Fingerprint == fingerprint {
+		m.logger.Step("bosh director is unchanged, skipping create-env")
+	} else {
+		createEnvOutputs, err := m.executor.CreateEnv(CreateEnvInput{
 			Manifest:  interpolateOutputs.Manifest,
+			State:     state.BOSH.State,
+			Variables: interpolateOutputs.Variables,
+		})
+
+		switch err.(type) {
+		case CreateEnvError:
+			ceErr := err.(CreateEnvError)
+			state.BOSH = storage.BOSH{
+				Variables: interpolateOutputs.Variables,
+				State:     ceErr.BOSHState(),
+				Manifest:  interpolateOutputs.Manifest,
+			}
+			state.BOSH = withUserConfig(state.BOSH, userConfig)
+			return storage.State{}, NewManagerCreateError(state, err)
+		case error:
+			return storage.State{}, err
+		}
+
+		directorState = createEnvOutputs.State
+		fingerprint, err = Fingerprint(interpolateOutputs.Manifest, interpolateOutputs.Variables, directorState)
+		if err != nil {
+			return storage.State{}, err
 		}
-		return storage.State{}, NewManagerCreateError(state, err)
-	case error:
-		return storage.State{}, err
 	}
 
 	directorVars, err := getDirectorVars(interpolateOutputs.Variables)
@@ -241,7 +306,7 @@ func (m *Manager) CreateDirector(state storage.State, terraformOutputs map[strin
 	var directorAddress string
 	directorAddress = terraformOutputs["director_address"].(string)
 	if state.Jumpbox.Enabled {
-		directorAddress = fmt.Sprintf("https://%s:25555", DIRECTOR_INTERNAL_IP)
+		directorAddress = fmt.Sprintf("https://%s:25555", network.DirectorIP())
 	}
 
 	state.BOSH = storage.BOSH{
@@ -253,21 +318,93 @@ func (m *Manager) CreateDirector(state storage.State, terraformOutputs map[strin
 		DirectorSSLCertificate: directorVars.directorSSLCertificate,
 		DirectorSSLPrivateKey:  directorVars.directorSSLPrivateKey,
 		Variables:              interpolateOutputs.Variables,
-		State:                  createEnvOutputs.State,
+		State:                  directorState,
 		Manifest:               interpolateOutputs.Manifest,
-		UserOpsFile:            state.BOSH.UserOpsFile,
+		Fingerprint:            fingerprint,
 	}
+	state.BOSH = withUserConfig(state.BOSH, userConfig)
 
 	m.logger.Step("created bosh director")
 	return state, nil
 }
 
+// InterpolateJumpbox renders the jumpbox manifest CreateJumpbox would deploy,
+// without deploying it.
+func (m *Manager) InterpolateJumpbox(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
+	interpolateInput, err := m.jumpboxInterpolateInput(state, terraformOutputs)
+	if err != nil {
+		return "", err
+	}
+
+	interpolateOutputs, err := m.executor.JumpboxInterpolate(interpolateInput)
+	if err != nil {
+		return "", fmt.Errorf("jumpbox interpolate: %s", err)
+	}
+
+	return interpolateOutputs.Manifest, nil
+}
+
+// InterpolateDirector renders the director manifest CreateDirector would
+// deploy, without deploying it.
+func (m *Manager) InterpolateDirector(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
+	interpolateInput, err := m.directorInterpolateInput(state, terraformOutputs)
+	if err != nil {
+		return "", err
+	}
+
+	interpolateOutputs, err := m.executor.DirectorInterpolate(interpolateInput)
+	if err != nil {
+		return "", err
+	}
+
+	return interpolateOutputs.Manifest, nil
+}
+
+func (m *Manager) jumpboxInterpolateInput(state storage.State, terraformOutputs map[string]interface{}) (InterpolateInput, error) {
+	jumpboxDeploymentVars, err := m.GetJumpboxDeploymentVars(state, terraformOutputs)
+	if err != nil {
+		return InterpolateInput{}, err
+	}
+
+	deploymentVars, err := m.GetDeploymentVars(state, terraformOutputs)
+	if err != nil {
+		return InterpolateInput{}, err
+	}
+
+	return InterpolateInput{
+		IAAS:                  state.IAAS,
+		JumpboxDeploymentVars: jumpboxDeploymentVars,
+		DeploymentVars:        deploymentVars,
+		Variables:             state.Jumpbox.Variables,
+	}, nil
+}
+
+func (m *Manager) directorInterpolateInput(state storage.State, terraformOutputs map[string]interface{}) (InterpolateInput, error) {
+	input, err := m.jumpboxInterpolateInput(state, terraformOutputs)
+	if err != nil {
+		return InterpolateInput{}, err
+	}
+
+	input.Variables = state.BOSH.Variables
+	input.OpsFiles = fileContents(state.BOSH.UserOpsFiles)
+	input.VarsFiles = fileContents(state.BOSH.UserVarsFiles)
+	input.DirectorSizing = directorSizing(state.BOSH)
+	input.ExternalDatabase = state.BOSH.ExternalDatabase
+	input.ExternalBlobstore = state.BOSH.ExternalBlobstore
+
+	return input, nil
+}
+
 func (m *Manager) Delete(state storage.State, terraformOutputs map[string]interface{}) error {
 	iaasInputs := InterpolateInput{
-		IAAS:      state.IAAS,
-		BOSHState: state.BOSH.State,
-		Variables: state.BOSH.Variables,
-		OpsFile:   state.BOSH.UserOpsFile,
+		IAAS:              state.IAAS,
+		BOSHState:         state.BOSH.State,
+		Variables:         state.BOSH.Variables,
+		OpsFiles:          fileContents(state.BOSH.UserOpsFiles),
+		VarsFiles:         fileContents(state.BOSH.UserVarsFiles),
+		DirectorSizing:    directorSizing(state.BOSH),
+		ExternalDatabase:  state.BOSH.ExternalDatabase,
+		ExternalBlobstore: state.BOSH.ExternalBlobstore,
 	}
 
 	if state.Jumpbox.Enabled {
@@ -276,17 +413,29 @@ func (m *Manager) Delete(state storage.State, terraformOutputs map[string]interf
 			return err
 		}
 
-		err = m.socks5Proxy.Start(jumpboxPrivateKey, state.Jumpbox.URL)
+		allowlist, err := proxyAllowlist(state.Network)
+		if err != nil {
+			return err
+		}
+
+		err = m.socks5Proxy.Start(jumpboxPrivateKey, state.Jumpbox.HostKey, state.Jumpbox.URL, allowlist)
 		if err != nil {
 			return err
 		}
 
-		osSetenv("BOSH_ALL_PROXY", fmt.Sprintf("socks5://%s", m.socks5Proxy.Addr()))
+		osSetenv("BOSH_ALL_PROXY", m.socks5Proxy.URL())
 
-		iaasInputs.JumpboxDeploymentVars = m.GetJumpboxDeploymentVars(state, terraformOutputs)
+		iaasInputs.JumpboxDeploymentVars, err = m.GetJumpboxDeploymentVars(state, terraformOutputs)
+		if err != nil {
+			return err
+		}
 	}
 
-	iaasInputs.DeploymentVars = m.GetDeploymentVars(state, terraformOutputs)
+	var err error
+	iaasInputs.DeploymentVars, err = m.GetDeploymentVars(state, terraformOutputs)
+	if err != nil {
+		return err
+	}
 
 	interpolateOutputs, err := m.executor.DirectorInterpolate(iaasInputs)
 	if err != nil {
@@ -317,10 +466,15 @@ func (m *Manager) DeleteJumpbox(state storage.State, terraformOutputs map[string
 
 	m.logger.Step("destroying jumpbox")
 
+	jumpboxDeploymentVars, err := m.GetJumpboxDeploymentVars(state, terraformOutputs)
+	if err != nil {
+		return err
+	}
+
 	iaasInputs := InterpolateInput{
 		IAAS:                  state.IAAS,
 		Variables:             state.BOSH.Variables,
-		JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
+		JumpboxDeploymentVars: jumpboxDeploymentVars,
 	}
 
 	interpolateOutputs, err := m.executor.JumpboxInterpolate(iaasInputs)
@@ -345,15 +499,20 @@ func (m *Manager) DeleteJumpbox(state storage.State, terraformOutputs map[string
 	return nil
 }
 
-func (m *Manager) GetJumpboxDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) string {
+func (m *Manager) GetJumpboxDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
 	if !state.Jumpbox.Enabled {
-		return ""
+		return "", nil
+	}
+
+	network, err := NewNetwork(state.Network)
+	if err != nil {
+		return "", err
 	}
 
 	vars := sharedDeploymentVarsYAML{
-		InternalCIDR: "10.0.0.0/24",
-		InternalGW:   "10.0.0.1",
-		InternalIP:   "10.0.0.5",
+		InternalCIDR: network.BOSHSubnet.String(),
+		InternalGW:   network.Gateway(),
+		InternalIP:   network.JumpboxIP(),
 		DirectorName: fmt.Sprintf("bosh-%s", state.EnvID),
 		ExternalIP:   getTerraformOutput("external_ip", terraformOutputs),
 	}
@@ -382,7 +541,7 @@ func (m *Manager) GetJumpboxDeploymentVars(state storage.State, terraformOutputs
 		}
 	}
 
-	return string(mustMarshal(vars))
+	return string(mustMarshal(vars)), nil
 }
 
 func mustMarshal(yamlStruct interface{}) []byte {
@@ -394,6 +553,37 @@ func mustMarshal(yamlStruct interface{}) []byte {
 	return yamlBytes
 }
 
+// withUserConfig copies what the user chose with bbl up from one BOSH state
+// to another, since CreateDirector replaces the rest of it.
+func withUserConfig(b storage.BOSH, from storage.BOSH) storage.BOSH {
+	b.UserOpsFiles = from.UserOpsFiles
+	b.UserVarsFiles = from.UserVarsFiles
+	b.DirectorVMType = from.DirectorVMType
+	b.DirectorDiskSizeGB = from.DirectorDiskSizeGB
+	b.DirectorStemcellURL = from.DirectorStemcellURL
+	b.DirectorStemcellSHA1 = from.DirectorStemcellSHA1
+	b.ExternalDatabase = from.ExternalDatabase
+	b.ExternalBlobstore = from.ExternalBlobstore
+	return b
+}
+
+func directorSizing(b storage.BOSH) DirectorSizing {
+	return DirectorSizing{
+		VMType:       b.DirectorVMType,
+		DiskSizeGB:   b.DirectorDiskSizeGB,
+		StemcellURL:  b.DirectorStemcellURL,
+		StemcellSHA1: b.DirectorStemcellSHA1,
+	}
+}
+
+func fileContents(files []storage.NamedFile) []string {
+	var contents []string
+	for _, file := range files {
+		contents = append(contents, file.Contents)
+	}
+	return contents
+}
+
 func getTerraformOutput(key string, outputs map[string]interface{}) string {
 	if value, ok := outputs[key]; ok {
 		return fmt.Sprintf("%s", value)
@@ -401,11 +591,16 @@ func getTerraformOutput(key string, outputs map[string]interface{}) string {
 	return ""
 }
 
-func (m *Manager) GetDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) string {
+func (m *Manager) GetDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
+	network, err := NewNetwork(state.Network)
+	if err != nil {
+		return "", err
+	}
+
 	vars := sharedDeploymentVarsYAML{
-		InternalCIDR: "10.0.0.0/24",
-		InternalGW:   "10.0.0.1",
-		InternalIP:   DIRECTOR_INTERNAL_IP,
+		InternalCIDR: network.BOSHSubnet.String(),
+		InternalGW:   network.Gateway(),
+		InternalIP:   network.DirectorIP(),
 		DirectorName: fmt.Sprintf("bosh-%s", state.EnvID),
 		ExternalIP:   getTerraformOutput("external_ip", terraformOutputs),
 	}
@@ -453,7 +648,18 @@ func (m *Manager) GetDeploymentVars(state storage.State, terraformOutputs map[st
 		}
 	}
 
-	return string(mustMarshal(vars))
+	if state.BOSH.ExternalDatabase {
+		vars.ExternalStoresYAML.ExternalDBHost = getTerraformOutput("external_db_host", terraformOutputs)
+		vars.ExternalStoresYAML.ExternalDBUser = getTerraformOutput("external_db_user", terraformOutputs)
+		vars.ExternalStoresYAML.ExternalDBPassword = getTerraformOutput("external_db_password", terraformOutputs)
+		vars.ExternalStoresYAML.ExternalDBName = getTerraformOutput("external_db_name", terraformOutputs)
+	}
+
+	if state.BOSH.ExternalBlobstore {
+		vars.ExternalStoresYAML.DirectorBlobstoreBucket = getTerraformOutput("director_blobstore_bucket", terraformOutputs)
+	}
+
+	return string(mustMarshal(vars)), nil
 }
 
 func getJumpboxPrivateKey(v string) (string, error) {
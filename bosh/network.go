@@ -0,0 +1,240 @@
+package bosh
+
+import (
+	"fmt"
+
+	bblproxy "github.com/cloudfoundry/bosh-bootloader/proxy"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+const (
+	DefaultNetworkCIDR = "10.0.0.0/16"
+
+	maxNetworkMaskBits    = 20
+	maxBOSHSubnetMaskBits = 28
+	maxLBSubnetMaskBits   = 27
+
+	gatewayOffset   = 1
+	jumpboxIPOffset = 5
+	directorOffset  = 6
+	natIPOffset     = 7
+)
+
+// Network is the address plan of an environment. The jumpbox, the director
+// and the AWS NAT instance get fixed addresses in the BOSH subnet, which
+// defaults to the first block of the network split in 256, 10.0.0.0/24 for
+// 10.0.0.0/16. Every availability zone gets an internal subnet and, on AWS, a
+// load balancer subnet, carved out of the network the same way the terraform
+// templates always have unless they are given explicitly. None of the subnets
+// may overlap.
+type Network struct {
+	CIDR       CIDRBlock
+	BOSHSubnet CIDRBlock
+
+	internalSubnets []CIDRBlock
+	lbSubnets       []CIDRBlock
+}
+
+func NewNetwork(network storage.Network) (Network, error) {
+	networkCIDR := network.CIDR
+	if networkCIDR == "" {
+		networkCIDR = DefaultNetworkCIDR
+	}
+
+	cidr, err := parseNetworkAddress(networkCIDR)
+	if err != nil {
+		return Network{}, fmt.Errorf("network cidr: %s", err)
+	}
+
+	if cidr.MaskBits() > maxNetworkMaskBits {
+		return Network{}, fmt.Errorf("network cidr %s is too small, use a /%d or larger network", cidr, maxNetworkMaskBits)
+	}
+
+	n := Network{CIDR: cidr}
+
+	if network.BOSHSubnetCIDR == "" {
+		n.BOSHSubnet, err = cidr.Subnet(8, 0)
+		if err != nil {
+			return Network{}, err //not tested
+		}
+	} else {
+		n.BOSHSubnet, err = n.parseSubnet("bosh subnet cidr", network.BOSHSubnetCIDR)
+		if err != nil {
+			return Network{}, err
+		}
+
+		if n.BOSHSubnet.MaskBits() > maxBOSHSubnetMaskBits {
+			return Network{}, fmt.Errorf("bosh subnet cidr %s is too small, use a /%d or larger subnet", n.BOSHSubnet, maxBOSHSubnetMaskBits)
+		}
+	}
+
+	for _, subnet := range network.InternalSubnetCIDRs {
+		block, err := n.parseSubnet("internal subnet cidr", subnet)
+		if err != nil {
+			return Network{}, err
+		}
+		n.internalSubnets = append(n.internalSubnets, block)
+	}
+
+	for _, subnet := range network.LBSubnetCIDRs {
+		block, err := n.parseSubnet("lb subnet cidr", subnet)
+		if err != nil {
+			return Network{}, err
+		}
+
+		if block.MaskBits() > maxLBSubnetMaskBits {
+			return Network{}, fmt.Errorf("lb subnet cidr %s is too small, use a /%d or larger subnet", block, maxLBSubnetMaskBits)
+		}
+		n.lbSubnets = append(n.lbSubnets, block)
+	}
+
+	// AWS load balancers need a /27 or larger subnet, which the default lb
+	// subnets, a 256th of the network, are not for networks smaller than a /19.
+	if len(n.lbSubnets) == 0 && cidr.MaskBits()+8 > maxLBSubnetMaskBits {
+		return Network{}, fmt.Errorf("network cidr %s is too small for the default lb subnets, use a /%d or larger network or provide --lb-subnet-cidr", cidr, maxLBSubnetMaskBits-8)
+	}
+
+	err = n.checkOverlaps()
+	if err != nil {
+		return Network{}, err
+	}
+
+	return n, nil
+}
+
+// checkOverlaps makes sure the BOSH subnet and the explicit internal and lb
+// subnets are disjoint. The default subnets are checked when they are
+// carved out, since how many there are depends on the availability zones.
+func (n Network) checkOverlaps() error {
+	subnets := n.namedSubnets()
+	for i := range subnets {
+		for j := i + 1; j < len(subnets); j++ {
+			if subnets[i].block.Overlaps(subnets[j].block) {
+				return fmt.Errorf("%s %s overlaps with %s %s", subnets[j].name, subnets[j].block, subnets[i].name, subnets[i].block)
+			}
+		}
+	}
+
+	return nil
+}
+
+type namedSubnet struct {
+	name  string
+	block CIDRBlock
+}
+
+func (n Network) namedSubnets() []namedSubnet {
+	subnets := []namedSubnet{{name: "bosh subnet cidr", block: n.BOSHSubnet}}
+	for _, block := range n.internalSubnets {
+		subnets = append(subnets, namedSubnet{name: "internal subnet cidr", block: block})
+	}
+	for _, block := range n.lbSubnets {
+		subnets = append(subnets, namedSubnet{name: "lb subnet cidr", block: block})
+	}
+
+	return subnets
+}
+
+func (n Network) Gateway() string {
+	return n.BOSHSubnet.GetFirstIP().Add(gatewayOffset).String()
+}
+
+func (n Network) JumpboxIP() string {
+	return n.BOSHSubnet.GetFirstIP().Add(jumpboxIPOffset).String()
+}
+
+func (n Network) DirectorIP() string {
+	return n.BOSHSubnet.GetFirstIP().Add(directorOffset).String()
+}
+
+func (n Network) NATIP() string {
+	return n.BOSHSubnet.GetFirstIP().Add(natIPOffset).String()
+}
+
+// ProxyAllowlist confines a socks5 proxy through the jumpbox to the director
+// ports of the network.
+func (n Network) ProxyAllowlist() (bblproxy.Allowlist, error) {
+	return bblproxy.NewAllowlist([]string{n.CIDR.String()}, bblproxy.DirectorPorts)
+}
+
+// proxyAllowlist is the allowlist of the network of an environment.
+func proxyAllowlist(network storage.Network) (bblproxy.Allowlist, error) {
+	n, err := NewNetwork(network)
+	if err != nil {
+		return bblproxy.Allowlist{}, err
+	}
+
+	return n.ProxyAllowlist()
+}
+
+// InternalSubnets returns the internal subnet of each of count availability
+// zones. By default zone i gets block i+1 of the network split in sixteen,
+// 10.0.16.0/20 onwards for 10.0.0.0/16.
+func (n Network) InternalSubnets(count int) ([]string, error) {
+	return n.subnets("internal", n.internalSubnets, count, 4, 1, 15)
+}
+
+// LBSubnets returns the load balancer subnet of each of count availability
+// zones. By default zone i gets block i+2 of the network split in 256,
+// 10.0.2.0/24 onwards for 10.0.0.0/16, which keeps them next to the BOSH
+// subnet and clear of the internal subnets.
+func (n Network) LBSubnets(count int) ([]string, error) {
+	return n.subnets("lb", n.lbSubnets, count, 8, 2, 15)
+}
+
+func (n Network) subnets(kind string, explicit []CIDRBlock, count, newBits, firstNetNum, lastNetNum int) ([]string, error) {
+	var subnets []string
+	for i := 0; i < count; i++ {
+		if i < len(explicit) {
+			subnets = append(subnets, explicit[i].String())
+			continue
+		}
+
+		if firstNetNum+i > lastNetNum {
+			return nil, fmt.Errorf("there is no default %s subnet for %d availability zones, provide --%s-subnet-cidr for each zone", kind, count, kind)
+		}
+
+		subnet, err := n.CIDR.Subnet(newBits, firstNetNum+i)
+		if err != nil {
+			return nil, err //not tested
+		}
+
+		for _, other := range n.namedSubnets() {
+			if subnet.Overlaps(other.block) {
+				return nil, fmt.Errorf("the default %s subnet %s overlaps with %s %s, provide --%s-subnet-cidr for each zone", kind, subnet, other.name, other.block, kind)
+			}
+		}
+
+		subnets = append(subnets, subnet.String())
+	}
+
+	return subnets, nil
+}
+
+func (n Network) parseSubnet(name, subnet string) (CIDRBlock, error) {
+	block, err := parseNetworkAddress(subnet)
+	if err != nil {
+		return CIDRBlock{}, fmt.Errorf("%s: %s", name, err)
+	}
+
+	if !n.CIDR.Contains(block) {
+		return CIDRBlock{}, fmt.Errorf("%s %s is not within the network cidr %s", name, block, n.CIDR)
+	}
+
+	return block, nil
+}
+
+// parseNetworkAddress parses a CIDR block and makes sure it starts at the
+// first address of its range, which is what the IaaSes require.
+func parseNetworkAddress(cidr string) (CIDRBlock, error) {
+	block, err := ParseCIDRBlock(cidr)
+	if err != nil {
+		return CIDRBlock{}, err
+	}
+
+	if block.firstIP.ip%block.CIDRSize != 0 {
+		return CIDRBlock{}, fmt.Errorf("%s does not start at the first address of its range", cidr)
+	}
+
+	return block, nil
+}
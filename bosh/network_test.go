@@ -0,0 +1,145 @@
+package bosh_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network", func() {
+	Context("when no ranges have been provided", func() {
+		var network bosh.Network
+
+		BeforeEach(func() {
+			var err error
+			network, err = bosh.NewNetwork(storage.Network{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("uses the ranges bbl has always used", func() {
+			Expect(network.CIDR.String()).To(Equal("10.0.0.0/16"))
+			Expect(network.BOSHSubnet.String()).To(Equal("10.0.0.0/24"))
+			Expect(network.Gateway()).To(Equal("10.0.0.1"))
+			Expect(network.JumpboxIP()).To(Equal("10.0.0.5"))
+			Expect(network.DirectorIP()).To(Equal("10.0.0.6"))
+			Expect(network.NATIP()).To(Equal("10.0.0.7"))
+
+			internalSubnets, err := network.InternalSubnets(3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(internalSubnets).To(Equal([]string{"10.0.16.0/20", "10.0.32.0/20", "10.0.48.0/20"}))
+
+			lbSubnets, err := network.LBSubnets(3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lbSubnets).To(Equal([]string{"10.0.2.0/24", "10.0.3.0/24", "10.0.4.0/24"}))
+		})
+
+		It("returns an error when there are more zones than default subnets", func() {
+			_, err := network.InternalSubnets(16)
+			Expect(err).To(MatchError("there is no default internal subnet for 16 availability zones, provide --internal-subnet-cidr for each zone"))
+
+			_, err = network.LBSubnets(16)
+			Expect(err).To(MatchError("there is no default lb subnet for 16 availability zones, provide --lb-subnet-cidr for each zone"))
+		})
+	})
+
+	Context("when ranges have been provided", func() {
+		It("derives the addresses from the provided ranges", func() {
+			network, err := bosh.NewNetwork(storage.Network{
+				CIDR:                "192.168.0.0/16",
+				BOSHSubnetCIDR:      "192.168.1.0/26",
+				InternalSubnetCIDRs: []string{"192.168.64.0/18"},
+				LBSubnetCIDRs:       []string{"192.168.2.0/24", "192.168.3.0/24"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(network.Gateway()).To(Equal("192.168.1.1"))
+			Expect(network.JumpboxIP()).To(Equal("192.168.1.5"))
+			Expect(network.DirectorIP()).To(Equal("192.168.1.6"))
+			Expect(network.NATIP()).To(Equal("192.168.1.7"))
+
+			internalSubnets, err := network.InternalSubnets(2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(internalSubnets).To(Equal([]string{"192.168.64.0/18", "192.168.32.0/20"}))
+
+			lbSubnets, err := network.LBSubnets(1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lbSubnets).To(Equal([]string{"192.168.2.0/24"}))
+		})
+	})
+
+	Context("when a provided range is in the way of a default subnet", func() {
+		It("returns an error", func() {
+			network, err := bosh.NewNetwork(storage.Network{BOSHSubnetCIDR: "10.0.32.0/24"})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = network.InternalSubnets(2)
+			Expect(err).To(MatchError("the default internal subnet 10.0.32.0/20 overlaps with bosh subnet cidr 10.0.32.0/24, provide --internal-subnet-cidr for each zone"))
+		})
+	})
+
+	DescribeTable("invalid ranges",
+		func(network storage.Network, expectedError string) {
+			_, err := bosh.NewNetwork(network)
+			Expect(err).To(MatchError(expectedError))
+		},
+		Entry("unparseable network cidr",
+			storage.Network{CIDR: "banana"},
+			`network cidr: "banana" cannot parse CIDR block`,
+		),
+		Entry("network cidr that is not a network address",
+			storage.Network{CIDR: "10.0.0.1/16"},
+			"network cidr: 10.0.0.1/16 does not start at the first address of its range",
+		),
+		Entry("network cidr that is too small",
+			storage.Network{CIDR: "10.0.0.0/24"},
+			"network cidr 10.0.0.0/24 is too small, use a /20 or larger network",
+		),
+		Entry("bosh subnet outside of the network",
+			storage.Network{CIDR: "10.0.0.0/16", BOSHSubnetCIDR: "10.1.0.0/24"},
+			"bosh subnet cidr 10.1.0.0/24 is not within the network cidr 10.0.0.0/16",
+		),
+		Entry("bosh subnet that is too small",
+			storage.Network{BOSHSubnetCIDR: "10.0.0.0/29"},
+			"bosh subnet cidr 10.0.0.0/29 is too small, use a /28 or larger subnet",
+		),
+		Entry("internal subnet outside of the network",
+			storage.Network{InternalSubnetCIDRs: []string{"10.0.16.0/20", "172.16.0.0/20"}},
+			"internal subnet cidr 172.16.0.0/20 is not within the network cidr 10.0.0.0/16",
+		),
+		Entry("unparseable lb subnet",
+			storage.Network{LBSubnetCIDRs: []string{"banana"}},
+			`lb subnet cidr: "banana" cannot parse CIDR block`,
+		),
+		Entry("lb subnet outside of the network",
+			storage.Network{LBSubnetCIDRs: []string{"10.1.2.0/24"}},
+			"lb subnet cidr 10.1.2.0/24 is not within the network cidr 10.0.0.0/16",
+		),
+		Entry("lb subnet that is too small for an AWS load balancer",
+			storage.Network{LBSubnetCIDRs: []string{"10.0.2.0/28"}},
+			"lb subnet cidr 10.0.2.0/28 is too small, use a /27 or larger subnet",
+		),
+		Entry("network whose default lb subnets are too small for an AWS load balancer",
+			storage.Network{CIDR: "10.0.0.0/20"},
+			"network cidr 10.0.0.0/20 is too small for the default lb subnets, use a /19 or larger network or provide --lb-subnet-cidr",
+		),
+		Entry("internal subnet overlapping with the bosh subnet",
+			storage.Network{InternalSubnetCIDRs: []string{"10.0.0.0/20"}},
+			"internal subnet cidr 10.0.0.0/20 overlaps with bosh subnet cidr 10.0.0.0/24",
+		),
+		Entry("lb subnet overlapping with the bosh subnet",
+			storage.Network{BOSHSubnetCIDR: "10.0.2.0/24", LBSubnetCIDRs: []string{"10.0.2.0/27"}},
+			"lb subnet cidr 10.0.2.0/27 overlaps with bosh subnet cidr 10.0.2.0/24",
+		),
+		Entry("lb subnet overlapping with an internal subnet",
+			storage.Network{InternalSubnetCIDRs: []string{"10.0.16.0/20"}, LBSubnetCIDRs: []string{"10.0.17.0/24"}},
+			"lb subnet cidr 10.0.17.0/24 overlaps with internal subnet cidr 10.0.16.0/20",
+		),
+		Entry("internal subnets overlapping with each other",
+			storage.Network{InternalSubnetCIDRs: []string{"10.0.16.0/20", "10.0.16.0/21"}},
+			"internal subnet cidr 10.0.16.0/21 overlaps with internal subnet cidr 10.0.16.0/20",
+		),
+	)
+})
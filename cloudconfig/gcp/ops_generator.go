@@ -118,9 +118,18 @@ func (o *OpsGenerator) generateGCPOps(state storage.State) ([]op, error) {
 		}))
 	}
 
+	bblNetwork, err := bosh.NewNetwork(state.Network)
+	if err != nil {
+		return []op{}, err
+	}
+
+	cidrs, err := bblNetwork.InternalSubnets(len(state.GCP.Zones))
+	if err != nil {
+		return []op{}, err
+	}
+
 	var subnets []networkSubnet
-	for i, _ := range state.GCP.Zones {
-		cidr := fmt.Sprintf("10.0.%d.0/20", 16*(i+1))
+	for i, cidr := range cidrs {
 		subnet, err := generateNetworkSubnet(
 			fmt.Sprintf("z%d", i+1),
 			cidr,
@@ -101,6 +101,23 @@ var _ = Describe("GCPOpsGenerator", func() {
 				}),
 		)
 
+		Context("when a network has been configured", func() {
+			It("places the internal subnets in the configured ranges", func() {
+				incomingState.Network = storage.Network{
+					CIDR:                "192.168.0.0/16",
+					InternalSubnetCIDRs: []string{"192.168.64.0/20"},
+				}
+
+				opsYAML, err := opsGenerator.Generate(incomingState)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(opsYAML).To(ContainSubstring("range: 192.168.64.0/20"))
+				Expect(opsYAML).To(ContainSubstring("range: 192.168.32.0/20"))
+				Expect(opsYAML).To(ContainSubstring("range: 192.168.48.0/20"))
+				Expect(opsYAML).NotTo(ContainSubstring("10.0."))
+			})
+		})
+
 		Context("failure cases", func() {
 			It("returns an error when terraform output provider fails to retrieve", func() {
 				terraformManager.GetOutputsCall.Returns.Error = errors.New("failed to output")
@@ -108,6 +125,14 @@ var _ = Describe("GCPOpsGenerator", func() {
 				Expect(err).To(MatchError("failed to output"))
 			})
 
+			It("returns an error when there are more zones than internal subnets", func() {
+				incomingState.Network.CIDR = "10.0.0.0/19"
+				incomingState.GCP.Zones = make([]string, 16)
+
+				_, err := opsGenerator.Generate(incomingState)
+				Expect(err).To(MatchError("there is no default internal subnet for 16 availability zones, provide --internal-subnet-cidr for each zone"))
+			})
+
 			It("returns an error when ops fail to marshal", func() {
 				gcp.SetMarshal(func(interface{}) ([]byte, error) {
 					return []byte{}, errors.New("failed to marshal")
@@ -1,28 +1,20 @@
 package cloudconfig
 
 import (
-	"bytes"
-	"fmt"
-	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
-
 	"golang.org/x/net/proxy"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/interpolate"
+	bblproxy "github.com/cloudfoundry/bosh-bootloader/proxy"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 )
 
 var (
-	tempDir     func(string, string) (string, error)                                  = ioutil.TempDir
-	writeFile   func(string, []byte, os.FileMode) error                               = ioutil.WriteFile
 	proxySOCKS5 func(string, string, *proxy.Auth, proxy.Dialer) (proxy.Dialer, error) = proxy.SOCKS5
 )
 
 type Manager struct {
 	logger             logger
-	command            command
 	opsGenerator       opsGenerator
 	boshClientProvider boshClientProvider
 	socks5Proxy        socks5Proxy
@@ -34,20 +26,16 @@ type logger interface {
 	Step(string, ...interface{})
 }
 
-type command interface {
-	Run(stdout io.Writer, workingDirectory string, args []string) error
-}
-
 type opsGenerator interface {
 	Generate(state storage.State) (string, error)
 }
 
 type boshClientProvider interface {
-	Client(jumpbox storage.Jumpbox, directorAddress, directorUsername, directorPassword, caCert string) (bosh.Client, error)
+	Client(jumpbox storage.Jumpbox, network storage.Network, directorAddress, directorUsername, directorPassword, caCert string) (bosh.Client, error)
 }
 
 type socks5Proxy interface {
-	Start(string, string) error
+	Start(string, string, string, bblproxy.Allowlist) error
 	Addr() string
 }
 
@@ -59,11 +47,10 @@ type sshKeyGetter interface {
 	Get(storage.State) (string, error)
 }
 
-func NewManager(logger logger, cmd command, opsGenerator opsGenerator, boshClientProvider boshClientProvider,
+func NewManager(logger logger, opsGenerator opsGenerator, boshClientProvider boshClientProvider,
 	socks5Proxy socks5Proxy, terraformManager terraformManager, sshKeyGetter sshKeyGetter) Manager {
 	return Manager{
 		logger:             logger,
-		command:            cmd,
 		opsGenerator:       opsGenerator,
 		boshClientProvider: boshClientProvider,
 		socks5Proxy:        socks5Proxy,
@@ -73,42 +60,24 @@ func NewManager(logger logger, cmd command, opsGenerator opsGenerator, boshClien
 }
 
 func (m Manager) Generate(state storage.State) (string, error) {
-	buf := bytes.NewBuffer([]byte{})
-	workingDir, err := tempDir("", "")
-	if err != nil {
-		return "", err
-	}
-
-	err = writeFile(filepath.Join(workingDir, "cloud-config.yml"), []byte(BaseCloudConfig), os.ModePerm)
-	if err != nil {
-		return "", err
-	}
-
 	ops, err := m.opsGenerator.Generate(state)
 	if err != nil {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(workingDir, "ops.yml"), []byte(ops), os.ModePerm)
-	if err != nil {
-		return "", err
-	}
-
-	args := []string{
-		"interpolate", fmt.Sprintf("%s/cloud-config.yml", workingDir),
-		"-o", fmt.Sprintf("%s/ops.yml", workingDir),
-	}
-
-	err = m.command.Run(buf, workingDir, args)
+	output, err := interpolate.Interpolate(interpolate.Input{
+		Manifest: BaseCloudConfig,
+		OpsFiles: []string{ops},
+	})
 	if err != nil {
 		return "", err
 	}
 
-	return buf.String(), nil
+	return output.Manifest, nil
 }
 
 func (m Manager) Update(state storage.State) error {
-	boshClient, err := m.boshClientProvider.Client(state.Jumpbox, state.BOSH.DirectorAddress, state.BOSH.DirectorUsername, state.BOSH.DirectorPassword, state.BOSH.DirectorSSLCA)
+	boshClient, err := m.boshClientProvider.Client(state.Jumpbox, state.Network, state.BOSH.DirectorAddress, state.BOSH.DirectorUsername, state.BOSH.DirectorPassword, state.BOSH.DirectorSSLCA)
 	if err != nil {
 		return err // not tested
 	}
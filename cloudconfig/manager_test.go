@@ -2,15 +2,13 @@ package cloudconfig_test
 
 import (
 	"errors"
-	"fmt"
-	"io"
 	"io/ioutil"
-	"os"
 	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/cloudconfig"
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/pivotal-cf-experimental/gomegamatchers"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -19,7 +17,6 @@ import (
 var _ = Describe("Manager", func() {
 	var (
 		logger             *fakes.Logger
-		cmd                *fakes.BOSHCommand
 		opsGenerator       *fakes.CloudConfigOpsGenerator
 		boshClientProvider *fakes.BOSHClientProvider
 		boshClient         *fakes.BOSHClient
@@ -28,15 +25,13 @@ var _ = Describe("Manager", func() {
 		sshKeyGetter       *fakes.SSHKeyGetter
 		manager            cloudconfig.Manager
 
-		tempDir       string
 		incomingState storage.State
 
-		baseCloudConfig []byte
+		expectedCloudConfig string
 	)
 
 	BeforeEach(func() {
 		logger = &fakes.Logger{}
-		cmd = &fakes.BOSHCommand{}
 		opsGenerator = &fakes.CloudConfigOpsGenerator{}
 		boshClient = &fakes.BOSHClient{}
 		boshClientProvider = &fakes.BOSHClientProvider{}
@@ -46,19 +41,6 @@ var _ = Describe("Manager", func() {
 
 		boshClientProvider.ClientCall.Returns.Client = boshClient
 
-		var err error
-		tempDir, err = ioutil.TempDir("", "")
-		Expect(err).NotTo(HaveOccurred())
-
-		cloudconfig.SetTempDir(func(string, string) (string, error) {
-			return tempDir, nil
-		})
-
-		cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
-			stdout.Write([]byte("some-cloud-config"))
-			return nil
-		}
-
 		incomingState = storage.State{
 			IAAS: "gcp",
 			BOSH: storage.BOSH{
@@ -68,84 +50,26 @@ var _ = Describe("Manager", func() {
 			},
 		}
 
-		opsGenerator.GenerateCall.Returns.OpsYAML = "some-ops"
+		opsGenerator.GenerateCall.Returns.OpsYAML = "- type: replace\n  path: /azs/-\n  value:\n    name: z1"
 
-		baseCloudConfig, err = ioutil.ReadFile("fixtures/base-cloud-config.yml")
+		baseCloudConfig, err := ioutil.ReadFile("fixtures/base-cloud-config.yml")
 		Expect(err).NotTo(HaveOccurred())
+		expectedCloudConfig = strings.Replace(string(baseCloudConfig), "azs: []", "azs:\n- name: z1", 1)
 
-		manager = cloudconfig.NewManager(logger, cmd, opsGenerator, boshClientProvider, socks5Proxy, terraformManager, sshKeyGetter)
-	})
-
-	AfterEach(func() {
-		cloudconfig.ResetTempDir()
+		manager = cloudconfig.NewManager(logger, opsGenerator, boshClientProvider, socks5Proxy, terraformManager, sshKeyGetter)
 	})
 
 	Describe("Generate", func() {
 		It("returns a cloud config yaml provided a valid bbl state", func() {
-			expectedArgs := []string{
-				"interpolate", fmt.Sprintf("%s/cloud-config.yml", tempDir),
-				"-o", fmt.Sprintf("%s/ops.yml", tempDir),
-			}
-
 			cloudConfigYAML, err := manager.Generate(incomingState)
 			Expect(err).NotTo(HaveOccurred())
 
-			cloudConfig, err := ioutil.ReadFile(fmt.Sprintf("%s/cloud-config.yml", tempDir))
-			Expect(err).NotTo(HaveOccurred())
-			Expect(cloudConfig).To(Equal(baseCloudConfig))
-
 			Expect(opsGenerator.GenerateCall.Receives.State).To(Equal(incomingState))
 
-			ops, err := ioutil.ReadFile(fmt.Sprintf("%s/ops.yml", tempDir))
-			Expect(err).NotTo(HaveOccurred())
-			Expect(string(ops)).To(Equal("some-ops"))
-
-			Expect(cmd.RunCallCount()).To(Equal(1))
-			_, workingDirectory, args := cmd.RunArgsForCall(0)
-			Expect(workingDirectory).To(Equal(tempDir))
-			Expect(args).To(Equal(expectedArgs))
-
-			Expect(cloudConfigYAML).To(Equal("some-cloud-config"))
+			Expect(cloudConfigYAML).To(gomegamatchers.MatchYAML(expectedCloudConfig))
 		})
 
 		Context("failure cases", func() {
-			Context("when temp dir fails", func() {
-				BeforeEach(func() {
-					cloudconfig.SetTempDir(func(string, string) (string, error) {
-						return "", errors.New("failed to create temp dir")
-					})
-				})
-
-				AfterEach(func() {
-					cloudconfig.ResetTempDir()
-				})
-
-				It("returns an error", func() {
-					_, err := manager.Generate(storage.State{})
-					Expect(err).To(MatchError("failed to create temp dir"))
-				})
-			})
-
-			Context("when write file fails to write cloud-config.yml", func() {
-				BeforeEach(func() {
-					cloudconfig.SetWriteFile(func(filename string, body []byte, mode os.FileMode) error {
-						if strings.Contains(filename, "cloud-config.yml") {
-							return errors.New("failed to write file")
-						}
-						return nil
-					})
-				})
-
-				AfterEach(func() {
-					cloudconfig.ResetWriteFile()
-				})
-
-				It("returns an error", func() {
-					_, err := manager.Generate(storage.State{})
-					Expect(err).To(MatchError("failed to write file"))
-				})
-			})
-
 			Context("when ops generator fails to generate", func() {
 				BeforeEach(func() {
 					opsGenerator.GenerateCall.Returns.Error = errors.New("failed to generate")
@@ -157,34 +81,14 @@ var _ = Describe("Manager", func() {
 				})
 			})
 
-			Context("when write file fails to write ops.yml", func() {
+			Context("when the ops cannot be applied", func() {
 				BeforeEach(func() {
-					cloudconfig.SetWriteFile(func(filename string, body []byte, mode os.FileMode) error {
-						if strings.Contains(filename, "ops.yml") {
-							return errors.New("failed to write file")
-						}
-						return nil
-					})
-				})
-
-				AfterEach(func() {
-					cloudconfig.ResetWriteFile()
+					opsGenerator.GenerateCall.Returns.OpsYAML = "- type: replace\n  path: /missing/key\n  value: 1"
 				})
 
 				It("returns an error", func() {
 					_, err := manager.Generate(storage.State{})
-					Expect(err).To(MatchError("failed to write file"))
-				})
-			})
-
-			Context("when command fails to run", func() {
-				BeforeEach(func() {
-					cmd.RunReturns(errors.New("failed to run"))
-				})
-
-				It("returns an error", func() {
-					_, err := manager.Generate(storage.State{})
-					Expect(err).To(MatchError("failed to run"))
+					Expect(err).To(MatchError(`replace /missing/key: expected to find map key "missing"`))
 				})
 			})
 		})
@@ -208,18 +112,18 @@ var _ = Describe("Manager", func() {
 			Expect(boshClientProvider.ClientCall.Receives.DirectorUsername).To(Equal("some-director-username"))
 			Expect(boshClientProvider.ClientCall.Receives.DirectorPassword).To(Equal("some-director-password"))
 
-			Expect(boshClient.UpdateCloudConfigCall.Receives.Yaml).To(Equal([]byte("some-cloud-config")))
+			Expect(boshClient.UpdateCloudConfigCall.Receives.Yaml).To(gomegamatchers.MatchYAML(expectedCloudConfig))
 		})
 
 		Context("failure cases", func() {
-			Context("when manager generate's command fails to run", func() {
+			Context("when the cloud config cannot be generated", func() {
 				BeforeEach(func() {
-					cmd.RunReturns(errors.New("failed to run"))
+					opsGenerator.GenerateCall.Returns.Error = errors.New("failed to generate")
 				})
 
 				It("returns an error", func() {
 					err := manager.Update(storage.State{})
-					Expect(err).To(MatchError("failed to run"))
+					Expect(err).To(MatchError("failed to generate"))
 				})
 			})
 
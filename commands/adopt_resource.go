@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type resourceImporter interface {
+	ValidateVersion() error
+	Import(bblState storage.State, address, id string) (storage.State, error)
+}
+
+type AdoptResource struct {
+	stateValidator stateValidator
+	terraform      resourceImporter
+	stateStore     stateStore
+}
+
+type adoptResourceConfig struct {
+	address string
+	id      string
+}
+
+func NewAdoptResource(stateValidator stateValidator, terraform resourceImporter, stateStore stateStore) AdoptResource {
+	return AdoptResource{
+		stateValidator: stateValidator,
+		terraform:      terraform,
+		stateStore:     stateStore,
+	}
+}
+
+func (a AdoptResource) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	_, err := a.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	err = a.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	if state.Stack.Name != "" {
+		return errors.New("bbl adopt-resource needs infrastructure created by terraform, run bbl up to migrate from cloudformation first")
+	}
+
+	return a.terraform.ValidateVersion()
+}
+
+// Execute imports an existing cloud resource into the terraform state under
+// the given address, so that the next bbl up manages it instead of creating
+// a new one.
+func (a AdoptResource) Execute(subcommandFlags []string, state storage.State) error {
+	config, err := a.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	state, err = a.terraform.Import(state, config.address, config.id)
+	if err != nil {
+		return err
+	}
+
+	return a.stateStore.Set(state)
+}
+
+func (AdoptResource) parseFlags(subcommandFlags []string) (adoptResourceConfig, error) {
+	var config adoptResourceConfig
+
+	adoptFlags := flags.New("adopt-resource")
+	adoptFlags.String(&config.address, "address", "")
+	adoptFlags.String(&config.id, "id", "")
+
+	err := adoptFlags.Parse(subcommandFlags)
+	if err != nil {
+		return config, err
+	}
+
+	if config.address == "" {
+		return config, errors.New("--address is required")
+	}
+
+	if config.id == "" {
+		return config, errors.New("--id is required")
+	}
+
+	return config, nil
+}
@@ -0,0 +1,123 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AdoptResource", func() {
+	var (
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+		stateStore       *fakes.StateStore
+
+		command commands.AdoptResource
+		state   storage.State
+		flags   []string
+	)
+
+	BeforeEach(func() {
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+		stateStore = &fakes.StateStore{}
+
+		state = storage.State{
+			IAAS:    "gcp",
+			TFState: "some-tf-state",
+		}
+		flags = []string{"--address", "google_compute_network.bbl-network", "--id", "some-network"}
+
+		command = commands.NewAdoptResource(stateValidator, terraformManager, stateStore)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("validates the state and the terraform version", func() {
+			err := command.CheckFastFails(flags, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateValidator.ValidateCall.CallCount).To(Equal(1))
+			Expect(terraformManager.ValidateVersionCall.CallCount).To(Equal(1))
+		})
+
+		It("returns an error when --address is missing", func() {
+			err := command.CheckFastFails([]string{"--id", "some-network"}, state)
+			Expect(err).To(MatchError("--address is required"))
+		})
+
+		It("returns an error when --id is missing", func() {
+			err := command.CheckFastFails([]string{"--address", "google_compute_network.bbl-network"}, state)
+			Expect(err).To(MatchError("--id is required"))
+		})
+
+		It("returns an error when the flags cannot be parsed", func() {
+			err := command.CheckFastFails([]string{"--unknown-flag"}, state)
+			Expect(err).To(MatchError("flag provided but not defined: -unknown-flag"))
+		})
+
+		It("returns an error when the state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("failed to validate state")
+
+			err := command.CheckFastFails(flags, state)
+			Expect(err).To(MatchError("failed to validate state"))
+		})
+
+		It("returns an error when the environment still uses cloudformation", func() {
+			state.Stack.Name = "some-stack"
+
+			err := command.CheckFastFails(flags, state)
+			Expect(err).To(MatchError("bbl adopt-resource needs infrastructure created by terraform, run bbl up to migrate from cloudformation first"))
+		})
+
+		It("returns an error when the terraform version is not supported", func() {
+			terraformManager.ValidateVersionCall.Returns.Error = errors.New("terraform too old")
+
+			err := command.CheckFastFails(flags, state)
+			Expect(err).To(MatchError("terraform too old"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("imports the resource and saves the updated state", func() {
+			terraformManager.ImportCall.Returns.BBLState = storage.State{
+				IAAS:    "gcp",
+				TFState: "some-imported-tf-state",
+			}
+
+			err := command.Execute(flags, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.ImportCall.CallCount).To(Equal(1))
+			Expect(terraformManager.ImportCall.Receives.BBLState).To(Equal(state))
+			Expect(terraformManager.ImportCall.Receives.Address).To(Equal("google_compute_network.bbl-network"))
+			Expect(terraformManager.ImportCall.Receives.ID).To(Equal("some-network"))
+
+			Expect(stateStore.SetCall.CallCount).To(Equal(1))
+			Expect(stateStore.SetCall.Receives[0].State).To(Equal(storage.State{
+				IAAS:    "gcp",
+				TFState: "some-imported-tf-state",
+			}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error and does not save the state when the import fails", func() {
+				terraformManager.ImportCall.Returns.Error = errors.New("failed to import")
+
+				err := command.Execute(flags, state)
+				Expect(err).To(MatchError("failed to import"))
+				Expect(stateStore.SetCall.CallCount).To(Equal(0))
+			})
+
+			It("returns an error when the state cannot be saved", func() {
+				stateStore.SetCall.Returns = []fakes.SetCallReturn{{Error: errors.New("failed to set state")}}
+
+				err := command.Execute(flags, state)
+				Expect(err).To(MatchError("failed to set state"))
+			})
+		})
+	})
+})
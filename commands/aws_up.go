@@ -2,7 +2,6 @@ package commands
 
 import (
 	"errors"
-	"io/ioutil"
 
 	"github.com/cloudfoundry/bosh-bootloader/aws"
 	"github.com/cloudfoundry/bosh-bootloader/aws/cloudformation"
@@ -55,7 +54,8 @@ type AWSUpConfig struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	Region          string
-	OpsFilePath     string
+	OpsFilePaths    []string
+	VarsFilePaths   []string
 	BOSHAZ          string
 	Name            string
 	NoDirector      bool
@@ -136,14 +136,15 @@ func (u AWSUp) Execute(config AWSUpConfig, state storage.State) error {
 	}
 
 	if !state.NoDirector {
-		opsFile := []byte{}
-		if config.OpsFilePath != "" {
-			opsFile, err = ioutil.ReadFile(config.OpsFilePath)
-			if err != nil {
-				return err
-			}
+		state.BOSH.UserOpsFiles, err = readNamedFiles(state.BOSH.UserOpsFiles, config.OpsFilePaths)
+		if err != nil {
+			return err
+		}
+
+		state.BOSH.UserVarsFiles, err = readNamedFiles(state.BOSH.UserVarsFiles, config.VarsFilePaths)
+		if err != nil {
+			return err
 		}
-		state.BOSH.UserOpsFile = string(opsFile)
 
 		if config.Jumpbox {
 			state.Jumpbox.Enabled = true
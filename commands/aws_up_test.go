@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/aws"
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
@@ -338,27 +339,45 @@ var _ = Describe("AWSUp", func() {
 			Expect(boshManager.CreateDirectorCall.Receives.State).To(Equal(incomingState))
 		})
 
-		Context("when ops file are passed in via --ops-file flag", func() {
-			It("passes the ops file contents to the bosh manager", func() {
-				opsFile, err := ioutil.TempFile("", "ops-file")
+		Context("when ops files and vars files are passed in via the --ops-file and --vars-file flags", func() {
+			It("adds their contents to the state passed to the bosh manager", func() {
+				tempDir, err := ioutil.TempDir("", "")
 				Expect(err).NotTo(HaveOccurred())
 
-				opsFilePath := opsFile.Name()
-				opsFileContents := "some-ops-file-contents"
-				err = ioutil.WriteFile(opsFilePath, []byte(opsFileContents), os.ModePerm)
+				opsFilePath := filepath.Join(tempDir, "some-ops-file.yml")
+				err = ioutil.WriteFile(opsFilePath, []byte("some-new-ops-file-contents"), os.ModePerm)
 				Expect(err).NotTo(HaveOccurred())
 
+				otherOpsFilePath := filepath.Join(tempDir, "some-other-ops-file.yml")
+				err = ioutil.WriteFile(otherOpsFilePath, []byte("some-other-ops-file-contents"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				varsFilePath := filepath.Join(tempDir, "some-vars-file.yml")
+				err = ioutil.WriteFile(varsFilePath, []byte("some-vars-file-contents"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				terraformManager.ApplyCall.Returns.BBLState.BOSH.UserOpsFiles = []storage.NamedFile{
+					{Name: "some-ops-file.yml", Contents: "some-ops-file-contents"},
+				}
+
 				err = command.Execute(commands.AWSUpConfig{
 					AccessKeyID:     "some-aws-access-key-id",
 					SecretAccessKey: "some-aws-secret-access-key",
 					Region:          "some-aws-region",
-					OpsFilePath:     opsFilePath,
+					OpsFilePaths:    []string{otherOpsFilePath, opsFilePath},
+					VarsFilePaths:   []string{varsFilePath},
 				}, storage.State{
 					EnvID: "bbl-lake-time-stamp",
 				})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserOpsFile).To(Equal("some-ops-file-contents"))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserOpsFiles).To(Equal([]storage.NamedFile{
+					{Name: "some-ops-file.yml", Contents: "some-new-ops-file-contents"},
+					{Name: "some-other-ops-file.yml", Contents: "some-other-ops-file-contents"},
+				}))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserVarsFiles).To(Equal([]storage.NamedFile{
+					{Name: "some-vars-file.yml", Contents: "some-vars-file-contents"},
+				}))
 			})
 		})
 
@@ -571,7 +590,14 @@ var _ = Describe("AWSUp", func() {
 
 			It("returns an error when the ops file cannot be read", func() {
 				err := command.Execute(commands.AWSUpConfig{
-					OpsFilePath: "some/fake/path",
+					OpsFilePaths: []string{"some/fake/path"},
+				}, storage.State{})
+				Expect(err).To(MatchError("open some/fake/path: no such file or directory"))
+			})
+
+			It("returns an error when the vars file cannot be read", func() {
+				err := command.Execute(commands.AWSUpConfig{
+					VarsFilePaths: []string{"some/fake/path"},
 				}, storage.State{})
 				Expect(err).To(MatchError("open some/fake/path: no such file or directory"))
 			})
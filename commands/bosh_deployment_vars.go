@@ -44,7 +44,11 @@ func (b BOSHDeploymentVars) Execute(args []string, state storage.State) error {
 		return fmt.Errorf("get terraform outputs: %s", err)
 	}
 
-	vars := b.boshManager.GetDeploymentVars(state, terraformOutputs)
+	vars, err := b.boshManager.GetDeploymentVars(state, terraformOutputs)
+	if err != nil {
+		return fmt.Errorf("get deployment vars: %s", err)
+	}
+
 	b.logger.Println(vars)
 	return nil
 }
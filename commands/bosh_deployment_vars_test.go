@@ -82,6 +82,14 @@ var _ = Describe("BOSHDeploymentVars", func() {
 				err := boshDeploymentVars.Execute([]string{}, storage.State{})
 				Expect(err).To(MatchError("get terraform outputs: coconut"))
 			})
+
+			It("returns an error when the deployment vars cannot be generated", func() {
+				terraformManager.GetOutputsCall.Returns.Error = nil
+				boshManager.GetDeploymentVarsCall.Returns.Error = errors.New("pineapple")
+
+				err := boshDeploymentVars.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("get deployment vars: pineapple"))
+			})
 		})
 	})
 })
@@ -5,8 +5,21 @@ const (
 
   --iaas                     IAAS to deploy your BOSH director onto. Valid options: "gcp", "aws" (Defaults to environment variable BBL_IAAS)
   [--name]                   Name to assign to your BOSH director (optional, will be randomly generated)
-  [--ops-file]               Path to BOSH ops file (optional)
+  [--ops-file]               Path to a BOSH ops file to apply to the director, can be repeated (optional)
+  [--vars-file]              Path to a BOSH vars file for variables in the ops files, can be repeated (optional)
   [--no-director]            Skips creating BOSH environment
+  [--terraform-override]     Directory of .tf files to add to the generated terraform template (optional)
+  [--recreate]               Runs create-env for the jumpbox and BOSH director even if they have not changed
+  [--network-cidr]           CIDR of the network to create, defaults to 10.0.0.0/16 (optional)
+  [--bosh-subnet-cidr]       CIDR of the subnet for the jumpbox and BOSH director, defaults to the first /24 of the network (optional)
+  [--internal-subnet-cidr]   CIDR of the internal subnet of an availability zone, can be repeated (optional)
+  [--lb-subnet-cidr]         CIDR of the AWS load balancer subnet of an availability zone, can be repeated (optional)
+  [--director-vm-type]       VM type of the BOSH director, e.g. "m4.2xlarge" or "n1-standard-4" (optional)
+  [--director-disk-size]     Persistent disk size of the BOSH director in GB (optional)
+  [--director-stemcell]      URL or path to a local .tgz of the stemcell for the BOSH director (optional)
+  [--director-stemcell-sha1] SHA1 of the stemcell at the --director-stemcell URL (optional)
+  [--director-external-db]   Provisions a managed database for the BOSH director when the environment is created (optional)
+  [--director-external-blobstore] Provisions a bucket for the BOSH director blobstore when the environment is created, not on azure (optional)
 
   --aws-access-key-id        AWS Access Key ID to use (Defaults to environment variable BBL_AWS_ACCESS_KEY_ID)
   --aws-secret-access-key    AWS Secret Access Key to use (Defaults to environment variable BBL_AWS_SECRET_ACCESS_KEY)
@@ -87,6 +100,79 @@ const (
 	BOSHDeploymentVarsCommandUsage = "Prints required variables for BOSH deployment"
 
 	CloudConfigUsage = "Prints suggested cloud configuration for BOSH environment"
+
+	EncryptStateCommandUsage = `Encrypts bbl-state.json with the state key
+
+  The state key is read from the environment variable BBL_STATE_KEY, or from the file named by the environment variable BBL_STATE_KEY_FILE.
+  Once encrypted, bbl keeps bbl-state.json encrypted and requires the state key for every command.`
+
+	DecryptStateCommandUsage = `Decrypts bbl-state.json with the state key
+
+  The state key is read from the environment variable BBL_STATE_KEY, or from the file named by the environment variable BBL_STATE_KEY_FILE.`
+
+	StateCommandUsage = `Manages snapshots and the layout of bbl-state.json
+
+  bbl state history          Lists the snapshots with the command that produced each one
+  bbl state rollback <id>    Restores bbl-state.json from a snapshot
+  bbl state split            Moves the terraform state, vars stores and manifests into their own files
+  bbl state join             Moves the component files back into bbl-state.json
+
+  A snapshot is taken every time bbl writes bbl-state.json, and the latest 20 are kept.`
+
+	StateHistoryCommandUsage = "Lists the snapshots of bbl-state.json with the command that produced each one"
+
+	StateRollbackCommandUsage = `Restores bbl-state.json from a snapshot
+
+  <id>    The id of a snapshot listed by bbl state history`
+
+	StateSplitCommandUsage = `Moves the terraform state, vars stores and manifests into their own files
+
+  bbl-state.json is kept as an index pointing at files under terraform/, create-env/, vars/ and manifests/.`
+
+	StateJoinCommandUsage = "Moves the component files back into bbl-state.json"
+
+	MigrateStateCommandUsage = `Upgrades bbl-state.json to the current schema version
+
+  --dry-run    Prints the migrations that would run and the resulting changes without writing them
+
+  Other commands migrate bbl-state.json automatically, keeping the previous file as bbl-state.json.v<version>.backup.`
+
+	PlanCommandUsage = `Prints the changes bbl up would make without making them
+
+  Runs terraform plan against the stored terraform state, and compares freshly interpolated jumpbox and director manifests with the deployed ones.
+  Nothing is created, changed or written to bbl-state.json.`
+
+	DriftCommandUsage = `Reports infrastructure that was changed or deleted outside of bbl
+
+  --json    Prints the drift as JSON
+
+  Refreshes a copy of the stored terraform state and compares the live attributes of every resource with the stored ones.
+  Exits with an error when any drift is found. Nothing is changed or written to bbl-state.json.`
+
+	AdoptResourceCommandUsage = `Imports an existing resource into the terraform state
+
+  --address    Terraform address of the resource in the bbl template, e.g. google_compute_network.bbl-network
+  --id         ID of the existing resource in the IaaS
+
+  Works on AWS, GCP and Azure. The next bbl up manages the adopted resource instead of creating a new one.`
+
+	OpsFilesCommandUsage = `Lists the ops files and vars files bbl up applies to the BOSH director, in order
+
+  [--remove]    Name of an ops file or vars file to stop applying
+
+  Files are added with bbl up --ops-file and --vars-file, and passing a file with the same name again replaces it.`
+
+	ProxyCommandUsage = `Serves a SOCKS5 proxy through the jumpbox until it is stopped
+
+  [--port]    Port to listen on, derived from the env id by default
+
+  The proxy asks for credentials that are generated every time it starts, and bbl print-env includes them in BOSH_ALL_PROXY while it runs.
+  It only forwards connections to the director ports of the network of the environment, and records them in ~/.bbl/proxy/<env-id>.log.`
+
+	ForceUnlockCommandUsage = `Removes the lock on bbl-state.json
+
+  up, destroy, create-lbs, update-lbs, delete-lbs, rotate, encrypt-state, decrypt-state and the other commands that change it lock the state while they run.
+  Only use this when the bbl run holding the lock is no longer running.`
 )
 
 func (Up) Usage() string { return UpCommandUsage }
@@ -117,6 +203,34 @@ func (SSHKey) Usage() string { return SSHKeyCommandUsage }
 
 func (Rotate) Usage() string { return RotateCommandUsage }
 
+func (EncryptState) Usage() string { return EncryptStateCommandUsage }
+
+func (DecryptState) Usage() string { return DecryptStateCommandUsage }
+
+func (ForceUnlock) Usage() string { return ForceUnlockCommandUsage }
+
+func (MigrateState) Usage() string { return MigrateStateCommandUsage }
+
+func (Plan) Usage() string { return PlanCommandUsage }
+
+func (Drift) Usage() string { return DriftCommandUsage }
+
+func (AdoptResource) Usage() string { return AdoptResourceCommandUsage }
+
+func (OpsFiles) Usage() string { return OpsFilesCommandUsage }
+
+func (Proxy) Usage() string { return ProxyCommandUsage }
+
+func (State) Usage() string { return StateCommandUsage }
+
+func (StateHistory) Usage() string { return StateHistoryCommandUsage }
+
+func (StateRollback) Usage() string { return StateRollbackCommandUsage }
+
+func (StateSplit) Usage() string { return StateSplitCommandUsage }
+
+func (StateJoin) Usage() string { return StateJoinCommandUsage }
+
 func (s StateQuery) Usage() string {
 	switch s.propertyName {
 	case EnvIDPropertyName:
@@ -18,8 +18,21 @@ var _ = Describe("Commands Usage", func() {
 
   --iaas                     IAAS to deploy your BOSH director onto. Valid options: "gcp", "aws" (Defaults to environment variable BBL_IAAS)
   [--name]                   Name to assign to your BOSH director (optional, will be randomly generated)
-  [--ops-file]               Path to BOSH ops file (optional)
+  [--ops-file]               Path to a BOSH ops file to apply to the director, can be repeated (optional)
+  [--vars-file]              Path to a BOSH vars file for variables in the ops files, can be repeated (optional)
   [--no-director]            Skips creating BOSH environment
+  [--terraform-override]     Directory of .tf files to add to the generated terraform template (optional)
+  [--recreate]               Runs create-env for the jumpbox and BOSH director even if they have not changed
+  [--network-cidr]           CIDR of the network to create, defaults to 10.0.0.0/16 (optional)
+  [--bosh-subnet-cidr]       CIDR of the subnet for the jumpbox and BOSH director, defaults to the first /24 of the network (optional)
+  [--internal-subnet-cidr]   CIDR of the internal subnet of an availability zone, can be repeated (optional)
+  [--lb-subnet-cidr]         CIDR of the AWS load balancer subnet of an availability zone, can be repeated (optional)
+  [--director-vm-type]       VM type of the BOSH director, e.g. "m4.2xlarge" or "n1-standard-4" (optional)
+  [--director-disk-size]     Persistent disk size of the BOSH director in GB (optional)
+  [--director-stemcell]      URL or path to a local .tgz of the stemcell for the BOSH director (optional)
+  [--director-stemcell-sha1] SHA1 of the stemcell at the --director-stemcell URL (optional)
+  [--director-external-db]   Provisions a managed database for the BOSH director when the environment is created (optional)
+  [--director-external-blobstore] Provisions a bucket for the BOSH director blobstore when the environment is created, not on azure (optional)
 
   --aws-access-key-id        AWS Access Key ID to use (Defaults to environment variable BBL_AWS_ACCESS_KEY_ID)
   --aws-secret-access-key    AWS Secret Access Key to use (Defaults to environment variable BBL_AWS_SECRET_ACCESS_KEY)
@@ -134,6 +147,20 @@ var _ = Describe("Commands Usage", func() {
 		Entry("bosh-deployment-vars", commands.BOSHDeploymentVars{}, "Prints required variables for BOSH deployment"),
 		Entry("version", commands.Version{}, "Prints version"),
 		Entry("cloud-config", commands.CloudConfig{}, "Prints suggested cloud configuration for BOSH environment"),
+		Entry("encrypt-state", commands.EncryptState{}, commands.EncryptStateCommandUsage),
+		Entry("decrypt-state", commands.DecryptState{}, commands.DecryptStateCommandUsage),
+		Entry("force-unlock", commands.ForceUnlock{}, commands.ForceUnlockCommandUsage),
+		Entry("migrate-state", commands.MigrateState{}, commands.MigrateStateCommandUsage),
+		Entry("plan", commands.Plan{}, commands.PlanCommandUsage),
+		Entry("drift", commands.Drift{}, commands.DriftCommandUsage),
+		Entry("adopt-resource", commands.AdoptResource{}, commands.AdoptResourceCommandUsage),
+		Entry("ops-files", commands.OpsFiles{}, commands.OpsFilesCommandUsage),
+		Entry("proxy", commands.Proxy{}, commands.ProxyCommandUsage),
+		Entry("state", commands.State{}, commands.StateCommandUsage),
+		Entry("state history", commands.StateHistory{}, commands.StateHistoryCommandUsage),
+		Entry("state rollback", commands.StateRollback{}, commands.StateRollbackCommandUsage),
+		Entry("state split", commands.StateSplit{}, commands.StateSplitCommandUsage),
+		Entry("state join", commands.StateJoin{}, commands.StateJoinCommandUsage),
 	)
 })
 
@@ -0,0 +1,38 @@
+package commands
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type DecryptState struct {
+	logger         logger
+	stateValidator stateValidator
+	stateEncrypter stateEncrypter
+}
+
+func NewDecryptState(logger logger, stateValidator stateValidator, stateEncrypter stateEncrypter) DecryptState {
+	return DecryptState{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateEncrypter: stateEncrypter,
+	}
+}
+
+func (d DecryptState) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	err := d.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d DecryptState) Execute(subcommandFlags []string, state storage.State) error {
+	d.logger.Step("decrypting bbl-state.json")
+
+	err := d.stateEncrypter.Decrypt()
+	if err != nil {
+		return err
+	}
+
+	d.logger.Step("decrypted bbl-state.json")
+	return nil
+}
@@ -0,0 +1,58 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DecryptState", func() {
+	var (
+		command commands.DecryptState
+
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateEncrypter *fakes.StateEncrypter
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateEncrypter = &fakes.StateEncrypter{}
+
+		command = commands.NewDecryptState(logger, stateValidator, stateEncrypter)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state validator failed")
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("state validator failed"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("decrypts the state file", func() {
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateEncrypter.DecryptCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(Equal([]string{
+				"decrypting bbl-state.json",
+				"decrypted bbl-state.json",
+			}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the state file cannot be decrypted", func() {
+				stateEncrypter.DecryptCall.Returns.Error = errors.New("failed to decrypt")
+				err := command.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("failed to decrypt"))
+			})
+		})
+	})
+})
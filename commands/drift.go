@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+)
+
+type driftDetector interface {
+	ValidateVersion() error
+	Drift(storage.State) ([]terraform.ResourceDrift, error)
+}
+
+type Drift struct {
+	logger         logger
+	stateValidator stateValidator
+	terraform      driftDetector
+}
+
+type driftConfig struct {
+	json bool
+}
+
+func NewDrift(logger logger, stateValidator stateValidator, terraform driftDetector) Drift {
+	return Drift{
+		logger:         logger,
+		stateValidator: stateValidator,
+		terraform:      terraform,
+	}
+}
+
+func (d Drift) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	_, err := d.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	err = d.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	if state.TFState == "" {
+		return errors.New("bbl drift needs infrastructure created by terraform, run bbl up first")
+	}
+
+	return d.terraform.ValidateVersion()
+}
+
+// Execute reports the resources that were changed or deleted outside of bbl
+// since the last bbl up, and fails when there are any.
+func (d Drift) Execute(subcommandFlags []string, state storage.State) error {
+	config, err := d.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	drift, err := d.terraform.Drift(state)
+	if err != nil {
+		return err
+	}
+
+	if drift == nil {
+		drift = []terraform.ResourceDrift{}
+	}
+
+	if config.json {
+		contents, err := json.MarshalIndent(drift, "", "  ")
+		if err != nil {
+			return err // not tested
+		}
+		d.logger.Println(string(contents))
+	} else {
+		d.printDrift(drift)
+	}
+
+	if len(drift) > 0 {
+		return fmt.Errorf("drift detected in %d resource(s)", len(drift))
+	}
+
+	return nil
+}
+
+func (d Drift) printDrift(drift []terraform.ResourceDrift) {
+	if len(drift) == 0 {
+		d.logger.Println("no drift detected")
+		return
+	}
+
+	for _, resource := range drift {
+		if resource.Deleted {
+			d.logger.Println(fmt.Sprintf("%s has been deleted", resource.Address))
+			continue
+		}
+
+		d.logger.Println(fmt.Sprintf("%s has changed:", resource.Address))
+		for _, attribute := range resource.Attributes {
+			d.logger.Println(fmt.Sprintf("  %s: %q => %q", attribute.Name, attribute.Stored, attribute.Live))
+		}
+	}
+}
+
+func (Drift) parseFlags(subcommandFlags []string) (driftConfig, error) {
+	var config driftConfig
+
+	driftFlags := flags.New("drift")
+	driftFlags.Bool(&config.json, "", "json", false)
+
+	err := driftFlags.Parse(subcommandFlags)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
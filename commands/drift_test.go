@@ -0,0 +1,143 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drift", func() {
+	var (
+		logger           *fakes.Logger
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+
+		command commands.Drift
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+
+		state = storage.State{
+			IAAS:    "aws",
+			TFState: "some-tf-state",
+		}
+
+		command = commands.NewDrift(logger, stateValidator, terraformManager)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("validates the state and the terraform version", func() {
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateValidator.ValidateCall.CallCount).To(Equal(1))
+			Expect(terraformManager.ValidateVersionCall.CallCount).To(Equal(1))
+		})
+
+		It("returns an error when the state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("failed to validate state")
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("failed to validate state"))
+		})
+
+		It("returns an error when there is no terraform state", func() {
+			err := command.CheckFastFails([]string{}, storage.State{IAAS: "aws"})
+			Expect(err).To(MatchError("bbl drift needs infrastructure created by terraform, run bbl up first"))
+		})
+
+		It("returns an error when the terraform version is not supported", func() {
+			terraformManager.ValidateVersionCall.Returns.Error = errors.New("terraform too old")
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("terraform too old"))
+		})
+
+		It("returns an error when the flags cannot be parsed", func() {
+			err := command.CheckFastFails([]string{"--unknown-flag"}, state)
+			Expect(err).To(MatchError("flag provided but not defined: -unknown-flag"))
+		})
+	})
+
+	Describe("Execute", func() {
+		Context("when nothing has drifted", func() {
+			It("says so and succeeds", func() {
+				err := command.Execute([]string{}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(terraformManager.DriftCall.Receives.BBLState).To(Equal(state))
+				Expect(logger.PrintlnCall.Messages).To(Equal([]string{"no drift detected"}))
+			})
+
+			It("prints an empty json list with --json", func() {
+				err := command.Execute([]string{"--json"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.PrintlnCall.Messages).To(HaveLen(1))
+				Expect(logger.PrintlnCall.Messages[0]).To(MatchJSON("[]"))
+			})
+		})
+
+		Context("when resources have drifted", func() {
+			BeforeEach(func() {
+				terraformManager.DriftCall.Returns.Drift = []terraform.ResourceDrift{
+					{
+						Address: "aws_security_group.internal_security_group",
+						Attributes: []terraform.AttributeDrift{
+							{Name: "ingress.#", Stored: "2", Live: "3"},
+						},
+					},
+					{
+						Address: "aws_elb.cf_router_lb",
+						Deleted: true,
+					},
+				}
+			})
+
+			It("prints the drifted resources and returns an error", func() {
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("drift detected in 2 resource(s)"))
+
+				Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+					"aws_security_group.internal_security_group has changed:",
+					`  ingress.#: "2" => "3"`,
+					"aws_elb.cf_router_lb has been deleted",
+				}))
+			})
+
+			It("prints the drifted resources as json with --json", func() {
+				err := command.Execute([]string{"--json"}, state)
+				Expect(err).To(MatchError("drift detected in 2 resource(s)"))
+
+				Expect(logger.PrintlnCall.Messages).To(HaveLen(1))
+				Expect(logger.PrintlnCall.Messages[0]).To(MatchJSON(`[
+					{
+						"address": "aws_security_group.internal_security_group",
+						"deleted": false,
+						"attributes": [{"name": "ingress.#", "stored": "2", "live": "3"}]
+					},
+					{
+						"address": "aws_elb.cf_router_lb",
+						"deleted": true
+					}
+				]`))
+			})
+		})
+
+		It("returns an error when drift cannot be detected", func() {
+			terraformManager.DriftCall.Returns.Error = errors.New("failed to refresh")
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("failed to refresh"))
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package commands
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type stateEncrypter interface {
+	Encrypt() error
+	Decrypt() error
+}
+
+type EncryptState struct {
+	logger         logger
+	stateValidator stateValidator
+	stateEncrypter stateEncrypter
+}
+
+func NewEncryptState(logger logger, stateValidator stateValidator, stateEncrypter stateEncrypter) EncryptState {
+	return EncryptState{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateEncrypter: stateEncrypter,
+	}
+}
+
+func (e EncryptState) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	err := e.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (e EncryptState) Execute(subcommandFlags []string, state storage.State) error {
+	e.logger.Step("encrypting bbl-state.json")
+
+	err := e.stateEncrypter.Encrypt()
+	if err != nil {
+		return err
+	}
+
+	e.logger.Step("encrypted bbl-state.json")
+	return nil
+}
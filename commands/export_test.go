@@ -1,6 +1,11 @@
 package commands
 
-import yaml "gopkg.in/yaml.v2"
+import (
+	"os"
+	"os/signal"
+
+	yaml "gopkg.in/yaml.v2"
+)
 
 func SetMarshal(f func(interface{}) ([]byte, error)) {
 	marshal = f
@@ -17,3 +22,11 @@ func SetUnmarshal(f func([]byte, interface{}) error) {
 func ResetUnmarshal() {
 	unmarshal = yaml.Unmarshal
 }
+
+func SetSignalNotify(f func(c chan<- os.Signal, sig ...os.Signal)) {
+	signalNotify = f
+}
+
+func ResetSignalNotify() {
+	signalNotify = signal.Notify
+}
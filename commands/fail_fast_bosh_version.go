@@ -7,7 +7,11 @@ import (
 	"github.com/coreos/go-semver/semver"
 )
 
-func fastFailBOSHVersion(boshManager boshManager) error {
+type boshVersioner interface {
+	Version() (string, error)
+}
+
+func fastFailBOSHVersion(boshManager boshVersioner) error {
 	version, err := boshManager.Version()
 	switch err.(type) {
 	case bosh.BOSHVersionError:
@@ -0,0 +1,37 @@
+package commands
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type stateUnlocker interface {
+	ForceUnlock() (storage.LockInfo, error)
+}
+
+type ForceUnlock struct {
+	logger        logger
+	stateUnlocker stateUnlocker
+}
+
+func NewForceUnlock(logger logger, stateUnlocker stateUnlocker) ForceUnlock {
+	return ForceUnlock{
+		logger:        logger,
+		stateUnlocker: stateUnlocker,
+	}
+}
+
+func (f ForceUnlock) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	return nil
+}
+
+func (f ForceUnlock) Execute(subcommandFlags []string, state storage.State) error {
+	holder, err := f.stateUnlocker.ForceUnlock()
+	if err == storage.NotFound {
+		f.logger.Println("bbl-state.json is not locked")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	f.logger.Step("removed lock held by %s", holder)
+	return nil
+}
@@ -0,0 +1,66 @@
+package commands_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ForceUnlock", func() {
+	var (
+		command commands.ForceUnlock
+
+		logger      *fakes.Logger
+		stateLocker *fakes.StateLocker
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateLocker = &fakes.StateLocker{}
+
+		command = commands.NewForceUnlock(logger, stateLocker)
+	})
+
+	Describe("Execute", func() {
+		It("removes the lock and reports who held it", func() {
+			stateLocker.ForceUnlockCall.Returns.LockInfo = storage.LockInfo{
+				Host:      "some-host",
+				User:      "some-user",
+				PID:       1234,
+				Command:   "up",
+				StartedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC),
+			}
+
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateLocker.ForceUnlockCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(Equal([]string{
+				"removed lock held by `bbl up` run by some-user on some-host (pid 1234) since 2017-06-01T12:00:00Z",
+			}))
+		})
+
+		It("reports when the state is not locked", func() {
+			stateLocker.ForceUnlockCall.Returns.Error = storage.NotFound
+
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Receives.Message).To(Equal("bbl-state.json is not locked"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the lock cannot be removed", func() {
+				stateLocker.ForceUnlockCall.Returns.Error = errors.New("failed to remove lock")
+
+				err := command.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("failed to remove lock"))
+			})
+		})
+	})
+})
@@ -3,7 +3,6 @@ package commands
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	yaml "gopkg.in/yaml.v2"
@@ -35,7 +34,8 @@ type GCPUpConfig struct {
 	ProjectID         string
 	Zone              string
 	Region            string
-	OpsFilePath       string
+	OpsFilePaths      []string
+	VarsFilePaths     []string
 	Name              string
 	NoDirector        bool
 	Jumpbox           bool
@@ -51,7 +51,7 @@ type boshManager interface {
 	CreateJumpbox(bblState storage.State, terraformOutputs map[string]interface{}) (storage.State, error)
 	Delete(bblState storage.State, terraformOutputs map[string]interface{}) error
 	DeleteJumpbox(bblState storage.State, terraformOutputs map[string]interface{}) error
-	GetDeploymentVars(bblState storage.State, terraformOutputs map[string]interface{}) string
+	GetDeploymentVars(bblState storage.State, terraformOutputs map[string]interface{}) (string, error)
 	Version() (string, error)
 }
 
@@ -93,12 +93,14 @@ func (u GCPUp) Execute(upConfig GCPUpConfig, state storage.State) error {
 		return err
 	}
 
-	var opsFileContents []byte
-	if upConfig.OpsFilePath != "" {
-		opsFileContents, err = ioutil.ReadFile(upConfig.OpsFilePath)
-		if err != nil {
-			return fmt.Errorf("error reading ops-file contents: %v", err)
-		}
+	userOpsFiles, err := readNamedFiles(state.BOSH.UserOpsFiles, upConfig.OpsFilePaths)
+	if err != nil {
+		return fmt.Errorf("error reading ops-file contents: %v", err)
+	}
+
+	userVarsFiles, err := readNamedFiles(state.BOSH.UserVarsFiles, upConfig.VarsFilePaths)
+	if err != nil {
+		return fmt.Errorf("error reading vars-file contents: %v", err)
 	}
 
 	if upConfig.NoDirector {
@@ -146,7 +148,8 @@ func (u GCPUp) Execute(upConfig GCPUpConfig, state storage.State) error {
 	}
 
 	if !state.NoDirector {
-		state.BOSH.UserOpsFile = string(opsFileContents)
+		state.BOSH.UserOpsFiles = userOpsFiles
+		state.BOSH.UserVarsFiles = userVarsFiles
 
 		if upConfig.Jumpbox {
 			state.Jumpbox.Enabled = true
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/commands"
@@ -221,18 +222,22 @@ var _ = Describe("GCPUp", func() {
 			})
 		})
 
-		Context("when ops file are passed in", func() {
-			It("passes the ops file contents to the bosh manager", func() {
-				opsFile, err := ioutil.TempFile("", "ops-file")
+		Context("when ops files and vars files are passed in", func() {
+			It("adds their contents to the state passed to the bosh manager", func() {
+				tempDir, err := ioutil.TempDir("", "")
 				Expect(err).NotTo(HaveOccurred())
 
-				opsFilePath := opsFile.Name()
-				opsFileContents := "some-ops-file-contents"
-				err = ioutil.WriteFile(opsFilePath, []byte(opsFileContents), os.ModePerm)
+				opsFilePath := filepath.Join(tempDir, "some-ops-file.yml")
+				err = ioutil.WriteFile(opsFilePath, []byte("some-ops-file-contents"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				varsFilePath := filepath.Join(tempDir, "some-vars-file.yml")
+				err = ioutil.WriteFile(varsFilePath, []byte("some-vars-file-contents"), os.ModePerm)
 				Expect(err).NotTo(HaveOccurred())
 
 				err = gcpUp.Execute(commands.GCPUpConfig{
-					OpsFilePath: opsFilePath,
+					OpsFilePaths:  []string{opsFilePath},
+					VarsFilePaths: []string{varsFilePath},
 				}, storage.State{
 					GCP: storage.GCP{
 						ServiceAccountKey: serviceAccountKeyPath,
@@ -240,10 +245,21 @@ var _ = Describe("GCPUp", func() {
 						Zone:              "some-zone",
 						Region:            "some-region",
 					},
+					BOSH: storage.BOSH{
+						UserOpsFiles: []storage.NamedFile{
+							{Name: "some-previous-ops-file.yml", Contents: "some-previous-ops-file-contents"},
+						},
+					},
 				})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserOpsFile).To(Equal("some-ops-file-contents"))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserOpsFiles).To(Equal([]storage.NamedFile{
+					{Name: "some-previous-ops-file.yml", Contents: "some-previous-ops-file-contents"},
+					{Name: "some-ops-file.yml", Contents: "some-ops-file-contents"},
+				}))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserVarsFiles).To(Equal([]storage.NamedFile{
+					{Name: "some-vars-file.yml", Contents: "some-vars-file-contents"},
+				}))
 			})
 		})
 
@@ -402,11 +418,19 @@ var _ = Describe("GCPUp", func() {
 			It("returns an error when the ops file cannot be read", func() {
 				err := gcpUp.Execute(commands.GCPUpConfig{
 					ServiceAccountKey: serviceAccountKeyPath,
-					OpsFilePath:       "some/fake/path",
+					OpsFilePaths:      []string{"some/fake/path"},
 				}, storage.State{})
 				Expect(err).To(MatchError("error reading ops-file contents: open some/fake/path: no such file or directory"))
 			})
 
+			It("returns an error when the vars file cannot be read", func() {
+				err := gcpUp.Execute(commands.GCPUpConfig{
+					ServiceAccountKey: serviceAccountKeyPath,
+					VarsFilePaths:     []string{"some/fake/path"},
+				}, storage.State{})
+				Expect(err).To(MatchError("error reading vars-file contents: open some/fake/path: no such file or directory"))
+			})
+
 			Context("when a bbl environment exists with a bosh director", func() {
 				It("fast fails before creating any infrastructure", func() {
 					err := gcpUp.Execute(commands.GCPUpConfig{
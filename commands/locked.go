@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type stateLocker interface {
+	Lock(command string) (storage.LockInfo, error)
+	Unlock(storage.LockInfo) error
+}
+
+type lockedStateStore interface {
+	Get() (storage.State, error)
+	Migrate() (storage.MigrationPlan, error)
+}
+
+// Locked holds the state lock while a mutating command executes, so that
+// concurrent bbl runs against the same environment cannot overwrite each
+// other's state. An older bbl-state.json is only read in memory until then,
+// and is migrated on disk once the lock is held.
+type Locked struct {
+	name        string
+	command     Command
+	logger      logger
+	stateLocker stateLocker
+	stateStore  lockedStateStore
+}
+
+func NewLocked(name string, command Command, logger logger, stateLocker stateLocker, stateStore lockedStateStore) Locked {
+	return Locked{
+		name:        name,
+		command:     command,
+		logger:      logger,
+		stateLocker: stateLocker,
+		stateStore:  stateStore,
+	}
+}
+
+func (l Locked) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	return l.command.CheckFastFails(subcommandFlags, state)
+}
+
+func (l Locked) Execute(subcommandFlags []string, state storage.State) (err error) {
+	lock, err := l.stateLocker.Lock(l.name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		unlockErr := l.stateLocker.Unlock(lock)
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+
+	plan, err := l.stateStore.Migrate()
+	if err != nil {
+		return err
+	}
+
+	if !plan.Empty() {
+		l.logger.Println(fmt.Sprintf("migrated bbl-state.json from version %d to %d, the previous file was saved as %s", plan.FromVersion, storage.STATE_VERSION, plan.BackupFile))
+	}
+
+	persistedState, err := l.stateStore.Get()
+	if err != nil {
+		return err
+	}
+
+	if stateChanged(state, persistedState) {
+		return errors.New("bbl-state.json was changed by another bbl command while this one was starting, run it again")
+	}
+
+	return l.command.Execute(subcommandFlags, state)
+}
+
+func (l Locked) Usage() string {
+	return l.command.Usage()
+}
+
+// stateChanged compares the parts of the state that bbl commands write, the
+// rest may legitimately differ because of global flags.
+func stateChanged(loaded, persisted storage.State) bool {
+	return loaded.EnvID != persisted.EnvID ||
+		loaded.TFState != persisted.TFState ||
+		!reflect.DeepEqual(loaded.BOSH, persisted.BOSH) ||
+		!reflect.DeepEqual(loaded.Jumpbox, persisted.Jumpbox) ||
+		!reflect.DeepEqual(loaded.KeyPair, persisted.KeyPair) ||
+		loaded.Stack != persisted.Stack ||
+		loaded.LB != persisted.LB
+}
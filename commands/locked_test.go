@@ -0,0 +1,158 @@
+package commands_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Locked", func() {
+	var (
+		command commands.Locked
+
+		innerCommand *fakes.Command
+		logger       *fakes.Logger
+		stateLocker  *fakes.StateLocker
+		stateStore   *fakes.StateStore
+		state        storage.State
+		lockInfo     storage.LockInfo
+	)
+
+	BeforeEach(func() {
+		innerCommand = &fakes.Command{}
+		logger = &fakes.Logger{}
+		stateLocker = &fakes.StateLocker{}
+		stateStore = &fakes.StateStore{}
+
+		state = storage.State{
+			IAAS:    "gcp",
+			EnvID:   "some-env-id",
+			TFState: "some-tf-state",
+		}
+		stateStore.GetCall.Returns.State = storage.State{
+			IAAS:    "gcp",
+			EnvID:   "some-env-id",
+			TFState: "some-tf-state",
+		}
+
+		lockInfo = storage.LockInfo{Host: "some-host", PID: 1234, Command: "up"}
+		stateLocker.LockCall.Returns.LockInfo = lockInfo
+
+		command = commands.NewLocked("up", innerCommand, logger, stateLocker, stateStore)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("delegates to the command without taking the lock", func() {
+			innerCommand.CheckFastFailsCall.Returns.Error = errors.New("failed fast")
+
+			err := command.CheckFastFails([]string{"--some-flag"}, state)
+			Expect(err).To(MatchError("failed fast"))
+
+			Expect(innerCommand.CheckFastFailsCall.Receives.SubcommandFlags).To(Equal([]string{"--some-flag"}))
+			Expect(stateLocker.LockCall.CallCount).To(Equal(0))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("executes the command while holding the lock", func() {
+			err := command.Execute([]string{"--some-flag"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateLocker.LockCall.Receives.Command).To(Equal("up"))
+			Expect(innerCommand.ExecuteCall.Receives.SubcommandFlags).To(Equal([]string{"--some-flag"}))
+			Expect(innerCommand.ExecuteCall.Receives.State).To(Equal(state))
+			Expect(stateLocker.UnlockCall.CallCount).To(Equal(1))
+			Expect(stateLocker.UnlockCall.Receives.LockInfo).To(Equal(lockInfo))
+		})
+
+		It("migrates an older state file once it holds the lock", func() {
+			stateStore.MigrateCall.Returns.MigrationPlan = storage.MigrationPlan{
+				FromVersion: 7,
+				BackupFile:  "bbl-state.json.v7.backup",
+				Applied:     []storage.MigrationRecord{{From: 7, To: 8}},
+			}
+
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateStore.MigrateCall.CallCount).To(Equal(1))
+			Expect(logger.PrintlnCall.Receives.Message).To(Equal(fmt.Sprintf("migrated bbl-state.json from version 7 to %d, the previous file was saved as bbl-state.json.v7.backup", storage.STATE_VERSION)))
+			Expect(innerCommand.ExecuteCall.CallCount).To(Equal(1))
+		})
+
+		It("does not log anything when the state file is up to date", func() {
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateStore.MigrateCall.CallCount).To(Equal(1))
+			Expect(logger.PrintlnCall.CallCount).To(Equal(0))
+		})
+
+		It("releases the lock when the command fails", func() {
+			innerCommand.ExecuteCall.Returns.Error = errors.New("failed to execute")
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("failed to execute"))
+
+			Expect(stateLocker.UnlockCall.CallCount).To(Equal(1))
+		})
+
+		It("ignores differences in the state that come from global flags", func() {
+			state.GCP.Zone = "some-zone-from-a-flag"
+
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("failure cases", func() {
+			It("returns an error without executing when the state is locked", func() {
+				stateLocker.LockCall.Returns.Error = errors.New("bbl-state.json is locked")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("bbl-state.json is locked"))
+
+				Expect(innerCommand.ExecuteCall.CallCount).To(Equal(0))
+				Expect(stateLocker.UnlockCall.CallCount).To(Equal(0))
+			})
+
+			It("returns an error when the state changed after it was loaded", func() {
+				stateStore.GetCall.Returns.State.TFState = "some-other-tf-state"
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("bbl-state.json was changed by another bbl command while this one was starting, run it again"))
+
+				Expect(innerCommand.ExecuteCall.CallCount).To(Equal(0))
+				Expect(stateLocker.UnlockCall.CallCount).To(Equal(1))
+			})
+
+			It("returns an error without executing when the state cannot be migrated", func() {
+				stateStore.MigrateCall.Returns.Error = errors.New("failed to migrate")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("failed to migrate"))
+
+				Expect(innerCommand.ExecuteCall.CallCount).To(Equal(0))
+				Expect(stateLocker.UnlockCall.CallCount).To(Equal(1))
+			})
+
+			It("returns an error when the state cannot be read", func() {
+				stateStore.GetCall.Returns.Error = errors.New("failed to get state")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("failed to get state"))
+			})
+
+			It("returns an error when the lock cannot be released", func() {
+				stateLocker.UnlockCall.Returns.Error = errors.New("failed to unlock")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("failed to unlock"))
+			})
+		})
+	})
+})
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type stateMigrator interface {
+	PlanMigration() (storage.MigrationPlan, error)
+	Migrate() (storage.MigrationPlan, error)
+}
+
+type MigrateState struct {
+	logger         logger
+	stateValidator stateValidator
+	stateMigrator  stateMigrator
+	stateLocker    stateLocker
+}
+
+type migrateStateConfig struct {
+	dryRun bool
+}
+
+func NewMigrateState(logger logger, stateValidator stateValidator, stateMigrator stateMigrator, stateLocker stateLocker) MigrateState {
+	return MigrateState{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateMigrator:  stateMigrator,
+		stateLocker:    stateLocker,
+	}
+}
+
+func (m MigrateState) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	_, err := m.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	return m.stateValidator.Validate()
+}
+
+func (m MigrateState) Execute(subcommandFlags []string, state storage.State) error {
+	config, err := m.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	if config.dryRun {
+		return m.dryRun()
+	}
+
+	lock, err := m.stateLocker.Lock("migrate-state")
+	if err != nil {
+		return err
+	}
+	defer m.stateLocker.Unlock(lock)
+
+	plan, err := m.stateMigrator.Migrate()
+	if err != nil {
+		return err
+	}
+
+	if plan.Empty() {
+		m.logger.Println(fmt.Sprintf("bbl-state.json is already at version %d", storage.STATE_VERSION))
+		return nil
+	}
+
+	m.logger.Step("migrated bbl-state.json from version %d to %d, the previous file was saved as %s", plan.FromVersion, storage.STATE_VERSION, plan.BackupFile)
+	return nil
+}
+
+func (m MigrateState) dryRun() error {
+	plan, err := m.stateMigrator.PlanMigration()
+	if err != nil {
+		return err
+	}
+
+	if plan.Empty() {
+		m.logger.Println(fmt.Sprintf("bbl-state.json is already at version %d", storage.STATE_VERSION))
+		return nil
+	}
+
+	for _, migration := range plan.Applied {
+		m.logger.Println(fmt.Sprintf("version %d -> %d: %s", migration.From, migration.To, migration.Description))
+	}
+	m.logger.Println(helpers.LineDiff(string(plan.Before), string(plan.After)))
+
+	return nil
+}
+
+func (MigrateState) parseFlags(subcommandFlags []string) (migrateStateConfig, error) {
+	var config migrateStateConfig
+
+	migrateFlags := flags.New("migrate-state")
+	migrateFlags.Bool(&config.dryRun, "", "dry-run", false)
+
+	err := migrateFlags.Parse(subcommandFlags)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
@@ -0,0 +1,118 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MigrateState", func() {
+	var (
+		command commands.MigrateState
+
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateMigrator  *fakes.StateMigrator
+		stateLocker    *fakes.StateLocker
+		plan           storage.MigrationPlan
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateMigrator = &fakes.StateMigrator{}
+		stateLocker = &fakes.StateLocker{}
+
+		plan = storage.MigrationPlan{
+			FromVersion: 8,
+			Before:      []byte("{\n\t\"version\": 8\n}"),
+			After:       []byte("{\n\t\"version\": 9\n}"),
+			Applied: []storage.MigrationRecord{
+				{From: 8, To: 9, Description: "some-description"},
+			},
+			BackupFile: "bbl-state.json.v8.backup",
+		}
+
+		command = commands.NewMigrateState(logger, stateValidator, stateMigrator, stateLocker)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state validator failed")
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("state validator failed"))
+		})
+
+		It("returns an error when an unknown flag is passed", func() {
+			err := command.CheckFastFails([]string{"--some-flag"}, storage.State{})
+			Expect(err).To(MatchError("flag provided but not defined: -some-flag"))
+		})
+	})
+
+	Describe("Execute", func() {
+		Context("when --dry-run is passed", func() {
+			It("prints the migrations and the resulting diff without migrating", func() {
+				stateMigrator.PlanMigrationCall.Returns.MigrationPlan = plan
+
+				err := command.Execute([]string{"--dry-run"}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stateMigrator.MigrateCall.CallCount).To(Equal(0))
+				Expect(stateLocker.LockCall.CallCount).To(Equal(0))
+				Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+					"version 8 -> 9: some-description",
+					"  {\n- \t\"version\": 8\n+ \t\"version\": 9\n  }",
+				}))
+			})
+
+			It("reports when there is nothing to migrate", func() {
+				err := command.Execute([]string{"--dry-run"}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.PrintlnCall.Messages).To(Equal([]string{"bbl-state.json is already at version 10"}))
+			})
+
+			It("returns an error when the migration cannot be planned", func() {
+				stateMigrator.PlanMigrationCall.Returns.Error = errors.New("failed to plan")
+
+				err := command.Execute([]string{"--dry-run"}, storage.State{})
+				Expect(err).To(MatchError("failed to plan"))
+			})
+		})
+
+		It("migrates the state while holding the lock", func() {
+			stateMigrator.MigrateCall.Returns.MigrationPlan = plan
+
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateLocker.LockCall.Receives.Command).To(Equal("migrate-state"))
+			Expect(stateMigrator.MigrateCall.CallCount).To(Equal(1))
+			Expect(stateLocker.UnlockCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(Equal([]string{
+				"migrated bbl-state.json from version 8 to 10, the previous file was saved as bbl-state.json.v8.backup",
+			}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the state is locked", func() {
+				stateLocker.LockCall.Returns.Error = errors.New("bbl-state.json is locked")
+
+				err := command.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("bbl-state.json is locked"))
+				Expect(stateMigrator.MigrateCall.CallCount).To(Equal(0))
+			})
+
+			It("returns an error when the migration fails", func() {
+				stateMigrator.MigrateCall.Returns.Error = errors.New("failed to migrate")
+
+				err := command.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("failed to migrate"))
+			})
+		})
+	})
+})
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type OpsFiles struct {
+	logger         logger
+	stateValidator stateValidator
+	stateStore     stateStore
+}
+
+type opsFilesConfig struct {
+	remove string
+}
+
+func NewOpsFiles(logger logger, stateValidator stateValidator, stateStore stateStore) OpsFiles {
+	return OpsFiles{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateStore:     stateStore,
+	}
+}
+
+func (o OpsFiles) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	_, err := o.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	return o.stateValidator.Validate()
+}
+
+// Execute lists the ops files and vars files bbl up applies to the director,
+// in the order they are applied, or removes one of them by name. A removed
+// file stops being applied on the next bbl up.
+func (o OpsFiles) Execute(subcommandFlags []string, state storage.State) error {
+	config, err := o.parseFlags(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	if config.remove != "" {
+		return o.removeFile(config.remove, state)
+	}
+
+	if len(state.BOSH.UserOpsFiles) == 0 && len(state.BOSH.UserVarsFiles) == 0 {
+		o.logger.Println("no ops files or vars files have been provided to bbl up")
+		return nil
+	}
+
+	o.logger.Println(fmt.Sprintf("%-10s  %s", "TYPE", "NAME"))
+	for _, file := range state.BOSH.UserOpsFiles {
+		o.logger.Println(fmt.Sprintf("%-10s  %s", "ops-file", file.Name))
+	}
+	for _, file := range state.BOSH.UserVarsFiles {
+		o.logger.Println(fmt.Sprintf("%-10s  %s", "vars-file", file.Name))
+	}
+
+	return nil
+}
+
+func (o OpsFiles) removeFile(name string, state storage.State) error {
+	opsFiles, removedOpsFile := withoutFile(state.BOSH.UserOpsFiles, name)
+	varsFiles, removedVarsFile := withoutFile(state.BOSH.UserVarsFiles, name)
+
+	if !removedOpsFile && !removedVarsFile {
+		return fmt.Errorf("there is no ops file or vars file named %q, run bbl ops-files to list them", name)
+	}
+
+	state.BOSH.UserOpsFiles = opsFiles
+	state.BOSH.UserVarsFiles = varsFiles
+
+	err := o.stateStore.Set(state)
+	if err != nil {
+		return err
+	}
+
+	o.logger.Println(fmt.Sprintf("removed %s, run bbl up to update the director", name))
+	return nil
+}
+
+func withoutFile(files []storage.NamedFile, name string) ([]storage.NamedFile, bool) {
+	var kept []storage.NamedFile
+	removed := false
+
+	for _, file := range files {
+		if file.Name == name {
+			removed = true
+			continue
+		}
+		kept = append(kept, file)
+	}
+
+	return kept, removed
+}
+
+func (OpsFiles) parseFlags(subcommandFlags []string) (opsFilesConfig, error) {
+	var config opsFilesConfig
+
+	opsFilesFlags := flags.New("ops-files")
+	opsFilesFlags.String(&config.remove, "remove", "")
+
+	err := opsFilesFlags.Parse(subcommandFlags)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
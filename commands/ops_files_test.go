@@ -0,0 +1,114 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OpsFiles", func() {
+	var (
+		command commands.OpsFiles
+
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateStore     *fakes.StateStore
+
+		state storage.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateStore = &fakes.StateStore{}
+
+		command = commands.NewOpsFiles(logger, stateValidator, stateStore)
+
+		state = storage.State{
+			EnvID: "some-env-id",
+			BOSH: storage.BOSH{
+				UserOpsFiles: []storage.NamedFile{
+					{Name: "ldap.yml", Contents: "some-ldap-ops"},
+					{Name: "syslog.yml", Contents: "some-syslog-ops"},
+				},
+				UserVarsFiles: []storage.NamedFile{
+					{Name: "ldap-vars.yml", Contents: "some-ldap-vars"},
+				},
+			},
+		}
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state validator failed")
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("state validator failed"))
+		})
+
+		It("returns an error when the flags cannot be parsed", func() {
+			err := command.CheckFastFails([]string{"--some-unknown-flag"}, storage.State{})
+			Expect(err).To(MatchError("flag provided but not defined: -some-unknown-flag"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("lists the ops files and vars files in the order they are applied", func() {
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+				"TYPE        NAME",
+				"ops-file    ldap.yml",
+				"ops-file    syslog.yml",
+				"vars-file   ldap-vars.yml",
+			}))
+			Expect(stateStore.SetCall.CallCount).To(Equal(0))
+		})
+
+		It("reports when there are no ops files or vars files", func() {
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{"no ops files or vars files have been provided to bbl up"}))
+		})
+
+		Context("when the --remove flag is provided", func() {
+			It("removes the ops file with that name and saves the state", func() {
+				err := command.Execute([]string{"--remove", "ldap.yml"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stateStore.SetCall.CallCount).To(Equal(1))
+				Expect(stateStore.SetCall.Receives[0].State.BOSH.UserOpsFiles).To(Equal([]storage.NamedFile{
+					{Name: "syslog.yml", Contents: "some-syslog-ops"},
+				}))
+				Expect(stateStore.SetCall.Receives[0].State.BOSH.UserVarsFiles).To(Equal(state.BOSH.UserVarsFiles))
+				Expect(logger.PrintlnCall.Messages).To(Equal([]string{"removed ldap.yml, run bbl up to update the director"}))
+			})
+
+			It("removes the vars file with that name", func() {
+				err := command.Execute([]string{"--remove", "ldap-vars.yml"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stateStore.SetCall.Receives[0].State.BOSH.UserOpsFiles).To(Equal(state.BOSH.UserOpsFiles))
+				Expect(stateStore.SetCall.Receives[0].State.BOSH.UserVarsFiles).To(BeEmpty())
+			})
+
+			It("returns an error when there is no file with that name", func() {
+				err := command.Execute([]string{"--remove", "some-missing.yml"}, state)
+				Expect(err).To(MatchError(`there is no ops file or vars file named "some-missing.yml", run bbl ops-files to list them`))
+				Expect(stateStore.SetCall.CallCount).To(Equal(0))
+			})
+
+			It("returns an error when the state cannot be saved", func() {
+				stateStore.SetCall.Returns = []fakes.SetCallReturn{{Error: errors.New("failed to save state")}}
+
+				err := command.Execute([]string{"--remove", "ldap.yml"}, state)
+				Expect(err).To(MatchError("failed to save state"))
+			})
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type terraformPlanner interface {
+	ValidateVersion() error
+	GetOutputs(storage.State) (map[string]interface{}, error)
+	Plan(storage.State) (string, error)
+}
+
+type boshPlanner interface {
+	InterpolateJumpbox(storage.State, map[string]interface{}) (string, error)
+	InterpolateDirector(storage.State, map[string]interface{}) (string, error)
+	Version() (string, error)
+}
+
+type Plan struct {
+	logger         logger
+	stateValidator stateValidator
+	terraform      terraformPlanner
+	boshManager    boshPlanner
+}
+
+func NewPlan(logger logger, stateValidator stateValidator, terraform terraformPlanner, boshManager boshPlanner) Plan {
+	return Plan{
+		logger:         logger,
+		stateValidator: stateValidator,
+		terraform:      terraform,
+		boshManager:    boshManager,
+	}
+}
+
+func (p Plan) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	err := p.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = p.terraform.ValidateVersion()
+	if err != nil {
+		return err
+	}
+
+	if !state.NoDirector {
+		err = fastFailBOSHVersion(p.boshManager)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Execute reports what `bbl up` would change without changing anything: the
+// terraform plan for the infrastructure, then the differences between the
+// stored jumpbox and director manifests and freshly interpolated ones.
+func (p Plan) Execute(subcommandFlags []string, state storage.State) error {
+	if state.Stack.Name != "" {
+		p.logger.Println(fmt.Sprintf("warning: the CloudFormation stack %s will be migrated to terraform by the next bbl up, which this plan does not show", state.Stack.Name))
+	}
+
+	plan, err := p.terraform.Plan(state)
+	if err != nil {
+		return err
+	}
+	p.logger.Println(plan)
+
+	if state.NoDirector {
+		return nil
+	}
+
+	terraformOutputs, err := p.terraform.GetOutputs(state)
+	if err != nil {
+		return fmt.Errorf("get terraform outputs: %s", err)
+	}
+
+	if state.Jumpbox.Enabled {
+		p.logger.Step("interpolating jumpbox manifest")
+		manifest, err := p.boshManager.InterpolateJumpbox(state, terraformOutputs)
+		if err != nil {
+			return err
+		}
+		p.printManifestDiff("jumpbox", state.Jumpbox.Manifest, manifest)
+	}
+
+	p.logger.Step("interpolating director manifest")
+	manifest, err := p.boshManager.InterpolateDirector(state, terraformOutputs)
+	if err != nil {
+		return err
+	}
+	p.printManifestDiff("director", state.BOSH.Manifest, manifest)
+
+	return nil
+}
+
+func (p Plan) printManifestDiff(name, current, planned string) {
+	diff := helpers.LineDiff(current, planned)
+	if diff == "" {
+		p.logger.Println(fmt.Sprintf("%s manifest: no changes", name))
+		return
+	}
+
+	p.logger.Println(fmt.Sprintf("%s manifest changes:\n%s", name, diff))
+}
@@ -0,0 +1,149 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan", func() {
+	var (
+		logger           *fakes.Logger
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+		boshManager      *fakes.BOSHManager
+
+		command commands.Plan
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+		boshManager = &fakes.BOSHManager{}
+
+		boshManager.VersionCall.Returns.Version = "2.0.24"
+		terraformManager.PlanCall.Returns.Plan = "Plan: 0 to add, 1 to change, 0 to destroy."
+		terraformManager.GetOutputsCall.Returns.Outputs = map[string]interface{}{"some-name": "some-output"}
+
+		state = storage.State{
+			IAAS:    "gcp",
+			TFState: "some-tf-state",
+			Jumpbox: storage.Jumpbox{
+				Enabled:  true,
+				Manifest: "name: jumpbox\n",
+			},
+			BOSH: storage.BOSH{
+				Manifest: "name: bosh\ninstance_groups: 1\n",
+			},
+		}
+
+		command = commands.NewPlan(logger, stateValidator, terraformManager, boshManager)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when the state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("failed to validate state")
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("failed to validate state"))
+		})
+
+		It("returns an error when the terraform version is not supported", func() {
+			terraformManager.ValidateVersionCall.Returns.Error = errors.New("terraform too old")
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("terraform too old"))
+		})
+
+		It("returns an error when the bosh version is not supported", func() {
+			boshManager.VersionCall.Returns.Version = "1.9.0"
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("BOSH version must be at least v2.0.24"))
+		})
+
+		It("does not check the bosh version when there is no director", func() {
+			boshManager.VersionCall.Returns.Version = "1.9.0"
+
+			err := command.CheckFastFails([]string{}, storage.State{NoDirector: true})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Execute", func() {
+		It("prints the terraform plan and the manifest changes", func() {
+			boshManager.InterpolateJumpboxCall.Returns.Manifest = "name: jumpbox\n"
+			boshManager.InterpolateDirectorCall.Returns.Manifest = "name: bosh\ninstance_groups: 2\n"
+
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.PlanCall.Receives.BBLState).To(Equal(state))
+			Expect(boshManager.InterpolateJumpboxCall.Receives.TerraformOutputs).To(HaveKeyWithValue("some-name", "some-output"))
+			Expect(boshManager.InterpolateDirectorCall.Receives.State).To(Equal(state))
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+				"Plan: 0 to add, 1 to change, 0 to destroy.",
+				"jumpbox manifest: no changes",
+				"director manifest changes:\n  name: bosh\n- instance_groups: 1\n+ instance_groups: 2",
+			}))
+		})
+
+		It("does not change anything", func() {
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.ApplyCall.CallCount).To(Equal(0))
+			Expect(boshManager.CreateJumpboxCall.CallCount).To(Equal(0))
+			Expect(boshManager.CreateDirectorCall.CallCount).To(Equal(0))
+		})
+
+		It("only plans the infrastructure when there is no director", func() {
+			state.NoDirector = true
+
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshManager.InterpolateDirectorCall.CallCount).To(Equal(0))
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{"Plan: 0 to add, 1 to change, 0 to destroy."}))
+		})
+
+		It("warns that a CloudFormation stack migration is not part of the plan", func() {
+			state.Stack.Name = "some-stack"
+
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Messages[0]).To(Equal("warning: the CloudFormation stack some-stack will be migrated to terraform by the next bbl up, which this plan does not show"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the terraform plan fails", func() {
+				terraformManager.PlanCall.Returns.Error = errors.New("failed to plan")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("failed to plan"))
+			})
+
+			It("returns an error when the terraform outputs cannot be read", func() {
+				terraformManager.GetOutputsCall.Returns.Error = errors.New("failed to get outputs")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("get terraform outputs: failed to get outputs"))
+			})
+
+			It("returns an error when the director manifest cannot be interpolated", func() {
+				boshManager.InterpolateDirectorCall.Returns.Error = errors.New("failed to interpolate")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("failed to interpolate"))
+			})
+		})
+	})
+})
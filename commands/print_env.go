@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -16,17 +17,19 @@ type PrintEnv struct {
 	stateValidator   stateValidator
 	logger           logger
 	terraformManager terraformOutputter
+	proxyDir         string
 }
 
 type envSetter interface {
 	Set(key, value string) error
 }
 
-func NewPrintEnv(logger logger, stateValidator stateValidator, terraformManager terraformOutputter) PrintEnv {
+func NewPrintEnv(logger logger, stateValidator stateValidator, terraformManager terraformOutputter, proxyDir string) PrintEnv {
 	return PrintEnv{
 		stateValidator:   stateValidator,
 		logger:           logger,
 		terraformManager: terraformManager,
+		proxyDir:         proxyDir,
 	}
 }
 
@@ -55,42 +58,84 @@ func (p PrintEnv) Execute(args []string, state storage.State) error {
 	p.logger.Println(fmt.Sprintf("export BOSH_ENVIRONMENT=%s", state.BOSH.DirectorAddress))
 	p.logger.Println(fmt.Sprintf("export BOSH_CA_CERT='%s'", state.BOSH.DirectorSSLCA))
 
+	// The URL of bbl proxy holds the credentials of its session, so it is
+	// only known while bbl proxy runs. Without it the ssh tunnel to the
+	// jumpbox is printed as before bbl proxy existed.
 	if state.Jumpbox.Enabled {
-		portNumber, err := p.getPort()
+		proxyURL, err := readProxyURL(p.proxyDir, state.EnvID)
 		if err != nil {
-			// not tested
-			return err
+			p.logger.Println(`# bbl proxy is not running, run "bbl proxy" or the ssh command below to reach the director through the jumpbox`)
+			return p.printGatewayEnv(state)
 		}
 
-		dir, err := ioutil.TempDir("", "bosh-jumpbox")
-		if err != nil {
-			// not tested
-			return err
-		}
+		p.logger.Println(fmt.Sprintf("export BOSH_ALL_PROXY=%s", proxyURL))
+	}
 
-		privateKeyPath := filepath.Join(dir, "bosh_jumpbox_private.key")
+	return nil
+}
 
-		privateKeyContents, err := p.privateKeyFromJumpboxVariables(state.Jumpbox.Variables)
-		if err != nil {
-			return err
-		}
+func (p PrintEnv) printGatewayEnv(state storage.State) error {
+	portNumber, err := p.getPort()
+	if err != nil {
+		// not tested
+		return err
+	}
 
-		err = ioutil.WriteFile(privateKeyPath, []byte(privateKeyContents), 0600)
-		if err != nil {
-			// not tested
-			return err
-		}
+	dir, err := ioutil.TempDir("", "bosh-jumpbox")
+	if err != nil {
+		// not tested
+		return err
+	}
 
-		jumpboxURL := strings.Split(state.Jumpbox.URL, ":")[0]
+	privateKeyPath := filepath.Join(dir, "bosh_jumpbox_private.key")
 
-		p.logger.Println(fmt.Sprintf("export BOSH_ALL_PROXY=socks5://localhost:%s", portNumber))
-		p.logger.Println(fmt.Sprintf("export BOSH_GW_PRIVATE_KEY=%s", privateKeyPath))
-		p.logger.Println(fmt.Sprintf("ssh -f -N -o StrictHostKeyChecking=no -D %s jumpbox@%s -i $BOSH_GW_PRIVATE_KEY", portNumber, jumpboxURL))
+	privateKeyContents, err := p.privateKeyFromJumpboxVariables(state.Jumpbox.Variables)
+	if err != nil {
+		return err
 	}
 
+	err = ioutil.WriteFile(privateKeyPath, []byte(privateKeyContents), 0600)
+	if err != nil {
+		// not tested
+		return err
+	}
+
+	if state.Jumpbox.HostKey == "" {
+		return errors.New(`The jumpbox host key is not in the state, run "bbl up" to record it.`)
+	}
+
+	knownHostsPath := filepath.Join(dir, "known_hosts")
+
+	err = ioutil.WriteFile(knownHostsPath, []byte(knownHostsLine(state.Jumpbox.URL, state.Jumpbox.HostKey)), 0600)
+	if err != nil {
+		// not tested
+		return err
+	}
+
+	jumpboxURL := strings.Split(state.Jumpbox.URL, ":")[0]
+
+	p.logger.Println(fmt.Sprintf("export BOSH_ALL_PROXY=socks5://localhost:%s", portNumber))
+	p.logger.Println(fmt.Sprintf("export BOSH_GW_PRIVATE_KEY=%s", privateKeyPath))
+	p.logger.Println(fmt.Sprintf("ssh -f -N -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes -D %s jumpbox@%s -i $BOSH_GW_PRIVATE_KEY", knownHostsPath, portNumber, jumpboxURL))
+
 	return nil
 }
 
+// knownHostsLine pins hostKey for the jumpbox at url, in the known_hosts
+// format that ssh expects for the host and port.
+func knownHostsLine(url, hostKey string) string {
+	host, port, err := net.SplitHostPort(url)
+	if err != nil {
+		return fmt.Sprintf("%s %s\n", url, hostKey)
+	}
+
+	if port != "22" {
+		host = fmt.Sprintf("[%s]:%s", host, port)
+	}
+
+	return fmt.Sprintf("%s %s\n", host, hostKey)
+}
+
 func (p PrintEnv) getExternalIP(state storage.State) (string, error) {
 	terraformOutputs, err := p.terraformManager.GetOutputs(state)
 	if err != nil {
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/proxy"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+const keepAliveInterval = 30 * time.Second
+
+var signalNotify = signal.Notify
+
+type Proxy struct {
+	logger         logger
+	stateValidator stateValidator
+	socks5Proxy    socks5Proxy
+	proxyDir       string
+}
+
+type socks5Proxy interface {
+	StartOnPort(key, hostKey, url string, port int, allowlist proxy.Allowlist, auditLog io.Writer) error
+	URL() string
+	KeepAlive(interval time.Duration, stop <-chan struct{})
+}
+
+type proxyConfig struct {
+	port int
+}
+
+func NewProxy(logger logger, stateValidator stateValidator, socks5Proxy socks5Proxy, proxyDir string) Proxy {
+	return Proxy{
+		logger:         logger,
+		stateValidator: stateValidator,
+		socks5Proxy:    socks5Proxy,
+		proxyDir:       proxyDir,
+	}
+}
+
+func (p Proxy) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	err := p.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = p.parseFlags(subcommandFlags, state)
+	if err != nil {
+		return err
+	}
+
+	if p.proxyDir == "" {
+		return errors.New("bbl proxy keeps its files in ~/.bbl/proxy, set HOME to run it.")
+	}
+
+	if !state.Jumpbox.Enabled {
+		return errors.New(`This environment has no jumpbox to proxy through, only environments created with "bbl up --credhub" have one.`)
+	}
+
+	if state.Jumpbox.HostKey == "" {
+		return errors.New(`The jumpbox host key is not in the state, run "bbl up" to record it.`)
+	}
+
+	return nil
+}
+
+// Execute serves a socks5 proxy through the jumpbox until it is interrupted.
+// The private key of the jumpbox stays in memory. The proxy only forwards
+// connections to the director ports of the network of the environment, and
+// records every connection in an audit log in the proxy directory. Its URL,
+// with the credentials of the session, is kept next to the pid file for
+// print-env, and both are removed when it stops.
+func (p Proxy) Execute(subcommandFlags []string, state storage.State) error {
+	config, err := p.parseFlags(subcommandFlags, state)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := privateKeyFromJumpboxVariables(state.Jumpbox.Variables)
+	if err != nil {
+		return err
+	}
+
+	network, err := bosh.NewNetwork(state.Network)
+	if err != nil {
+		return err
+	}
+
+	allowlist, err := network.ProxyAllowlist()
+	if err != nil {
+		return err //not tested
+	}
+
+	pidFile := storage.NewPIDFile(filepath.Join(p.proxyDir, fmt.Sprintf("%s.pid", state.EnvID)))
+	err = pidFile.Acquire()
+	if err != nil {
+		return err
+	}
+	defer pidFile.Release()
+
+	auditLogPath := filepath.Join(p.proxyDir, fmt.Sprintf("%s.log", state.EnvID))
+	auditLog, err := os.OpenFile(auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %s", err) //not tested
+	}
+	defer auditLog.Close()
+
+	err = p.socks5Proxy.StartOnPort(privateKey, state.Jumpbox.HostKey, state.Jumpbox.URL, config.port, allowlist, auditLog)
+	if err != nil {
+		return err
+	}
+
+	urlPath := proxyURLPath(p.proxyDir, state.EnvID)
+	err = ioutil.WriteFile(urlPath, []byte(p.socks5Proxy.URL()), 0600)
+	if err != nil {
+		return fmt.Errorf("write proxy url: %s", err) //not tested
+	}
+	defer os.Remove(urlPath)
+
+	p.logger.Println(fmt.Sprintf("export BOSH_ALL_PROXY=%s", p.socks5Proxy.URL()))
+	p.logger.Println(fmt.Sprintf("proxying through the jumpbox at %s, logging connections to %s, press Ctrl-C to stop", state.Jumpbox.URL, auditLogPath))
+
+	signals := make(chan os.Signal, 1)
+	signalNotify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	stop := make(chan struct{})
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	p.socks5Proxy.KeepAlive(keepAliveInterval, stop)
+
+	return nil
+}
+
+func (Proxy) parseFlags(subcommandFlags []string, state storage.State) (proxyConfig, error) {
+	var config proxyConfig
+
+	proxyFlags := flags.New("proxy")
+	proxyFlags.Int(&config.port, "port", proxy.DefaultPort(state.EnvID))
+
+	err := proxyFlags.Parse(subcommandFlags)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// proxyURLPath is where bbl proxy keeps its URL while it runs.
+func proxyURLPath(proxyDir, envID string) string {
+	return filepath.Join(proxyDir, fmt.Sprintf("%s.url", envID))
+}
+
+// readProxyURL returns the URL of the bbl proxy that runs for the
+// environment, or an error when there is none.
+func readProxyURL(proxyDir, envID string) (string, error) {
+	if proxyDir == "" {
+		return "", errors.New("bbl proxy does not run without HOME")
+	}
+
+	contents, err := ioutil.ReadFile(proxyURLPath(proxyDir, envID))
+	if err != nil {
+		return "", err
+	}
+
+	return string(contents), nil
+}
+
+func privateKeyFromJumpboxVariables(jumpboxVariables string) (string, error) {
+	var jumpboxVars struct {
+		JumpboxSSH struct {
+			PrivateKey string `yaml:"private_key"`
+		} `yaml:"jumpbox_ssh"`
+	}
+
+	err := yaml.Unmarshal([]byte(jumpboxVariables), &jumpboxVars)
+	if err != nil {
+		return "", fmt.Errorf("error unmarshalling jumpbox variables: %v", err)
+	}
+
+	return jumpboxVars.JumpboxSSH.PrivateKey, nil
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+// State dispatches `bbl state <subcommand>` to the history, rollback, split
+// and join commands.
+type State struct {
+	subcommands map[string]Command
+}
+
+func NewState(history Command, rollback Command, split Command, join Command) State {
+	return State{
+		subcommands: map[string]Command{
+			"history":  history,
+			"rollback": rollback,
+			"split":    split,
+			"join":     join,
+		},
+	}
+}
+
+func (s State) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	subcommand, err := s.subcommand(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	return subcommand.CheckFastFails(subcommandFlags[1:], state)
+}
+
+func (s State) Execute(subcommandFlags []string, state storage.State) error {
+	subcommand, err := s.subcommand(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	return subcommand.Execute(subcommandFlags[1:], state)
+}
+
+func (s State) subcommand(subcommandFlags []string) (Command, error) {
+	if len(subcommandFlags) == 0 {
+		return nil, fmt.Errorf("bbl state requires a subcommand: history, rollback, split or join")
+	}
+
+	subcommand, ok := s.subcommands[subcommandFlags[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown state subcommand %q, use history, rollback, split or join", subcommandFlags[0])
+	}
+
+	return subcommand, nil
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type stateHistorian interface {
+	History() ([]storage.Snapshot, error)
+	Rollback(id string) error
+}
+
+type StateHistory struct {
+	logger         logger
+	stateValidator stateValidator
+	stateHistorian stateHistorian
+}
+
+func NewStateHistory(logger logger, stateValidator stateValidator, stateHistorian stateHistorian) StateHistory {
+	return StateHistory{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateHistorian: stateHistorian,
+	}
+}
+
+func (s StateHistory) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	err := s.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s StateHistory) Execute(subcommandFlags []string, state storage.State) error {
+	snapshots, err := s.stateHistorian.History()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		s.logger.Println("no snapshots of bbl-state.json have been taken yet")
+		return nil
+	}
+
+	s.logger.Println(fmt.Sprintf("%-20s  %-20s  %s", "ID", "CREATED", "COMMAND"))
+	for _, snapshot := range snapshots {
+		s.logger.Println(fmt.Sprintf("%-20s  %-20s  %s", snapshot.ID, snapshot.CreatedAt.Format(time.RFC3339), snapshot.Command))
+	}
+
+	return nil
+}
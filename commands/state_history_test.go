@@ -0,0 +1,72 @@
+package commands_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StateHistory", func() {
+	var (
+		command commands.StateHistory
+
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateHistorian *fakes.StateHistorian
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateHistorian = &fakes.StateHistorian{}
+
+		command = commands.NewStateHistory(logger, stateValidator, stateHistorian)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state validator failed")
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("state validator failed"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("lists the snapshots with the command that produced each one", func() {
+			stateHistorian.HistoryCall.Returns.Snapshots = []storage.Snapshot{
+				{ID: "20170601T120000Z", Command: "up", CreatedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)},
+				{ID: "20170601T120000Z-1", Command: "create-lbs", CreatedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)},
+			}
+
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+				"ID                    CREATED               COMMAND",
+				"20170601T120000Z      2017-06-01T12:00:00Z  up",
+				"20170601T120000Z-1    2017-06-01T12:00:00Z  create-lbs",
+			}))
+		})
+
+		It("reports when there are no snapshots", func() {
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{"no snapshots of bbl-state.json have been taken yet"}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the history cannot be read", func() {
+				stateHistorian.HistoryCall.Returns.Error = errors.New("failed to read history")
+
+				err := command.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("failed to read history"))
+			})
+		})
+	})
+})
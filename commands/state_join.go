@@ -0,0 +1,33 @@
+package commands
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type StateJoin struct {
+	logger         logger
+	stateValidator stateValidator
+	stateLayouter  stateLayouter
+}
+
+func NewStateJoin(logger logger, stateValidator stateValidator, stateLayouter stateLayouter) StateJoin {
+	return StateJoin{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateLayouter:  stateLayouter,
+	}
+}
+
+func (s StateJoin) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	return s.stateValidator.Validate()
+}
+
+func (s StateJoin) Execute(subcommandFlags []string, state storage.State) error {
+	s.logger.Step("joining the component files into bbl-state.json")
+
+	err := s.stateLayouter.Join()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Step("joined bbl-state.json")
+	return nil
+}
@@ -0,0 +1,59 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StateJoin", func() {
+	var (
+		command commands.StateJoin
+
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateLayouter  *fakes.StateLayouter
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateLayouter = &fakes.StateLayouter{}
+
+		command = commands.NewStateJoin(logger, stateValidator, stateLayouter)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state validator failed")
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("state validator failed"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("joins the state", func() {
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateLayouter.JoinCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(Equal([]string{
+				"joining the component files into bbl-state.json",
+				"joined bbl-state.json",
+			}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the join fails", func() {
+				stateLayouter.JoinCall.Returns.Error = errors.New("failed to join")
+
+				err := command.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("failed to join"))
+			})
+		})
+	})
+})
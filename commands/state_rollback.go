@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type StateRollback struct {
+	logger         logger
+	stateValidator stateValidator
+	stateHistorian stateHistorian
+}
+
+func NewStateRollback(logger logger, stateValidator stateValidator, stateHistorian stateHistorian) StateRollback {
+	return StateRollback{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateHistorian: stateHistorian,
+	}
+}
+
+func (s StateRollback) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	if len(subcommandFlags) != 1 {
+		return errors.New("bbl state rollback requires the id of a snapshot, run `bbl state history` to list them")
+	}
+
+	err := s.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s StateRollback) Execute(subcommandFlags []string, state storage.State) error {
+	id := subcommandFlags[0]
+
+	s.logger.Step("rolling back bbl-state.json to snapshot %s", id)
+
+	err := s.stateHistorian.Rollback(id)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Step("rolled back bbl-state.json")
+	return nil
+}
@@ -0,0 +1,64 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StateRollback", func() {
+	var (
+		command commands.StateRollback
+
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateHistorian *fakes.StateHistorian
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateHistorian = &fakes.StateHistorian{}
+
+		command = commands.NewStateRollback(logger, stateValidator, stateHistorian)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when no snapshot id is given", func() {
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("bbl state rollback requires the id of a snapshot, run `bbl state history` to list them"))
+		})
+
+		It("returns an error when state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state validator failed")
+			err := command.CheckFastFails([]string{"some-id"}, storage.State{})
+			Expect(err).To(MatchError("state validator failed"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("restores the snapshot", func() {
+			err := command.Execute([]string{"some-id"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateHistorian.RollbackCall.Receives.ID).To(Equal("some-id"))
+			Expect(logger.StepCall.Messages).To(Equal([]string{
+				"rolling back bbl-state.json to snapshot some-id",
+				"rolled back bbl-state.json",
+			}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the rollback fails", func() {
+				stateHistorian.RollbackCall.Returns.Error = errors.New("failed to roll back")
+
+				err := command.Execute([]string{"some-id"}, storage.State{})
+				Expect(err).To(MatchError("failed to roll back"))
+			})
+		})
+	})
+})
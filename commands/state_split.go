@@ -0,0 +1,38 @@
+package commands
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type stateLayouter interface {
+	Split() error
+	Join() error
+}
+
+type StateSplit struct {
+	logger         logger
+	stateValidator stateValidator
+	stateLayouter  stateLayouter
+}
+
+func NewStateSplit(logger logger, stateValidator stateValidator, stateLayouter stateLayouter) StateSplit {
+	return StateSplit{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateLayouter:  stateLayouter,
+	}
+}
+
+func (s StateSplit) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	return s.stateValidator.Validate()
+}
+
+func (s StateSplit) Execute(subcommandFlags []string, state storage.State) error {
+	s.logger.Step("splitting bbl-state.json into component files")
+
+	err := s.stateLayouter.Split()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Step("split bbl-state.json")
+	return nil
+}
@@ -0,0 +1,59 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StateSplit", func() {
+	var (
+		command commands.StateSplit
+
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateLayouter  *fakes.StateLayouter
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateLayouter = &fakes.StateLayouter{}
+
+		command = commands.NewStateSplit(logger, stateValidator, stateLayouter)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state validator failed")
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("state validator failed"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("splits the state", func() {
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateLayouter.SplitCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(Equal([]string{
+				"splitting bbl-state.json into component files",
+				"split bbl-state.json",
+			}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the split fails", func() {
+				stateLayouter.SplitCall.Returns.Error = errors.New("failed to split")
+
+				err := command.Execute([]string{}, storage.State{})
+				Expect(err).To(MatchError("failed to split"))
+			})
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("State", func() {
+	var (
+		command commands.State
+
+		history  *fakes.Command
+		rollback *fakes.Command
+		split    *fakes.Command
+		join     *fakes.Command
+		state    storage.State
+	)
+
+	BeforeEach(func() {
+		history = &fakes.Command{}
+		rollback = &fakes.Command{}
+		split = &fakes.Command{}
+		join = &fakes.Command{}
+		state = storage.State{EnvID: "some-env-id"}
+
+		command = commands.NewState(history, rollback, split, join)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("checks the subcommand with the remaining arguments", func() {
+			rollback.CheckFastFailsCall.Returns.Error = errors.New("rollback failed fast")
+
+			err := command.CheckFastFails([]string{"rollback", "some-id"}, state)
+			Expect(err).To(MatchError("rollback failed fast"))
+
+			Expect(rollback.CheckFastFailsCall.Receives.SubcommandFlags).To(Equal([]string{"some-id"}))
+			Expect(rollback.CheckFastFailsCall.Receives.State).To(Equal(state))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when no subcommand is given", func() {
+				err := command.CheckFastFails([]string{}, state)
+				Expect(err).To(MatchError("bbl state requires a subcommand: history, rollback, split or join"))
+			})
+
+			It("returns an error when the subcommand is unknown", func() {
+				err := command.CheckFastFails([]string{"some-subcommand"}, state)
+				Expect(err).To(MatchError(`unknown state subcommand "some-subcommand", use history, rollback, split or join`))
+			})
+		})
+	})
+
+	Describe("Execute", func() {
+		It("executes the subcommand with the remaining arguments", func() {
+			err := command.Execute([]string{"history"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(history.ExecuteCall.CallCount).To(Equal(1))
+			Expect(history.ExecuteCall.Receives.SubcommandFlags).To(Equal([]string{}))
+			Expect(rollback.ExecuteCall.CallCount).To(Equal(0))
+		})
+
+		It("executes the split and join subcommands", func() {
+			err := command.Execute([]string{"split"}, state)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(split.ExecuteCall.CallCount).To(Equal(1))
+
+			err = command.Execute([]string{"join"}, state)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(join.ExecuteCall.CallCount).To(Equal(1))
+		})
+	})
+})
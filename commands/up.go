@@ -6,7 +6,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/flags"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 )
@@ -36,10 +39,26 @@ type envGetter interface {
 }
 
 type upConfig struct {
-	name       string
-	opsFile    string
-	noDirector bool
-	jumpbox    bool
+	name              string
+	opsFiles          []string
+	varsFiles         []string
+	noDirector        bool
+	jumpbox           bool
+	terraformOverride string
+	recreate          bool
+
+	networkCIDR         string
+	boshSubnetCIDR      string
+	internalSubnetCIDRs []string
+	lbSubnetCIDRs       []string
+
+	directorVMType       string
+	directorDiskSizeGB   int
+	directorStemcell     string
+	directorStemcellSHA1 string
+
+	externalDatabase  bool
+	externalBlobstore bool
 }
 
 func NewUp(awsUp awsUp, gcpUp gcpUp, azureUp azureUp, envGetter envGetter, boshManager boshManager) Up {
@@ -73,6 +92,28 @@ func (u Up) CheckFastFails(args []string, state storage.State) error {
 		return fmt.Errorf("The director name cannot be changed for an existing environment. Current name is %s.", state.EnvID)
 	}
 
+	if config.terraformOverride != "" {
+		_, err = readTerraformOverrides(config.terraformOverride)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = networkFromConfig(config, state)
+	if err != nil {
+		return err
+	}
+
+	_, err = directorSizingFromConfig(config, state)
+	if err != nil {
+		return err
+	}
+
+	_, err = externalStoresFromConfig(config, state)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -82,20 +123,51 @@ func (u Up) Execute(args []string, state storage.State) error {
 		return err
 	}
 
+	if config.terraformOverride != "" {
+		state.TerraformOverrides, err = readTerraformOverrides(config.terraformOverride)
+		if err != nil {
+			return err
+		}
+	}
+
+	state.Network, err = networkFromConfig(config, state)
+	if err != nil {
+		return err
+	}
+
+	state.BOSH, err = directorSizingFromConfig(config, state)
+	if err != nil {
+		return err
+	}
+
+	state.BOSH, err = externalStoresFromConfig(config, state)
+	if err != nil {
+		return err
+	}
+
+	if config.recreate {
+		// Without a fingerprint to compare against, the bosh manager runs
+		// create-env for the jumpbox and the director.
+		state.Jumpbox.Fingerprint = ""
+		state.BOSH.Fingerprint = ""
+	}
+
 	switch state.IAAS {
 	case "aws":
 		err = u.awsUp.Execute(AWSUpConfig{
-			OpsFilePath: config.opsFile,
-			Name:        config.name,
-			NoDirector:  config.noDirector,
-			Jumpbox:     config.jumpbox,
+			OpsFilePaths:  config.opsFiles,
+			VarsFilePaths: config.varsFiles,
+			Name:          config.name,
+			NoDirector:    config.noDirector,
+			Jumpbox:       config.jumpbox,
 		}, state)
 	case "gcp":
 		err = u.gcpUp.Execute(GCPUpConfig{
-			OpsFilePath: config.opsFile,
-			Name:        config.name,
-			NoDirector:  config.noDirector,
-			Jumpbox:     config.jumpbox,
+			OpsFilePaths:  config.opsFiles,
+			VarsFilePaths: config.varsFiles,
+			Name:          config.name,
+			NoDirector:    config.noDirector,
+			Jumpbox:       config.jumpbox,
 		}, state)
 	case "azure":
 		err = u.azureUp.Execute(AzureUpConfig{
@@ -114,28 +186,218 @@ func (u Up) Execute(args []string, state storage.State) error {
 func (u Up) parseArgs(state storage.State, args []string) (upConfig, error) {
 	var config upConfig
 
-	tempDir, err := ioutil.TempDir("", "")
-	if err != nil {
-		return upConfig{}, err //not tested
-	}
-
-	prevOpsFilePath := filepath.Join(tempDir, "user-ops-file")
-	err = ioutil.WriteFile(prevOpsFilePath, []byte(state.BOSH.UserOpsFile), os.ModePerm)
-	if err != nil {
-		return upConfig{}, err //not tested
-	}
-
 	upFlags := flags.New("up")
 
 	upFlags.String(&config.name, "name", "")
-	upFlags.String(&config.opsFile, "ops-file", prevOpsFilePath)
+	upFlags.Strings(&config.opsFiles, "ops-file")
+	upFlags.Strings(&config.varsFiles, "vars-file")
 	upFlags.Bool(&config.noDirector, "", "no-director", state.NoDirector)
 	upFlags.Bool(&config.jumpbox, "", "credhub", state.Jumpbox.Enabled)
+	upFlags.String(&config.terraformOverride, "terraform-override", "")
+	upFlags.Bool(&config.recreate, "", "recreate", false)
+	upFlags.String(&config.networkCIDR, "network-cidr", "")
+	upFlags.String(&config.boshSubnetCIDR, "bosh-subnet-cidr", "")
+	upFlags.Strings(&config.internalSubnetCIDRs, "internal-subnet-cidr")
+	upFlags.Strings(&config.lbSubnetCIDRs, "lb-subnet-cidr")
+	upFlags.String(&config.directorVMType, "director-vm-type", "")
+	upFlags.Int(&config.directorDiskSizeGB, "director-disk-size", 0)
+	upFlags.String(&config.directorStemcell, "director-stemcell", "")
+	upFlags.String(&config.directorStemcellSHA1, "director-stemcell-sha1", "")
+	upFlags.Bool(&config.externalDatabase, "", "director-external-db", state.BOSH.ExternalDatabase)
+	upFlags.Bool(&config.externalBlobstore, "", "director-external-blobstore", state.BOSH.ExternalBlobstore)
 
-	err = upFlags.Parse(args)
+	err := upFlags.Parse(args)
 	if err != nil {
 		return upConfig{}, err
 	}
 
 	return config, nil
 }
+
+// networkFromConfig applies the network flags to the network in the state.
+// The ranges cannot change once the environment exists, since the subnets
+// and the addresses of the jumpbox and the director are already in use.
+func networkFromConfig(config upConfig, state storage.State) (storage.Network, error) {
+	network := state.Network
+	if config.networkCIDR != "" {
+		network.CIDR = config.networkCIDR
+	}
+	if config.boshSubnetCIDR != "" {
+		network.BOSHSubnetCIDR = config.boshSubnetCIDR
+	}
+	if len(config.internalSubnetCIDRs) > 0 {
+		network.InternalSubnetCIDRs = config.internalSubnetCIDRs
+	}
+	if len(config.lbSubnetCIDRs) > 0 {
+		network.LBSubnetCIDRs = config.lbSubnetCIDRs
+	}
+
+	if state.EnvID != "" && !reflect.DeepEqual(network, state.Network) {
+		return storage.Network{}, errors.New("The network cannot be changed for an existing environment.")
+	}
+
+	_, err := bosh.NewNetwork(network)
+	if err != nil {
+		return storage.Network{}, err
+	}
+
+	return network, nil
+}
+
+// directorSizingFromConfig applies the director sizing flags to the BOSH
+// state. Omitted flags keep what an earlier bbl up stored.
+func directorSizingFromConfig(config upConfig, state storage.State) (storage.BOSH, error) {
+	if config.directorVMType == "" && config.directorDiskSizeGB == 0 && config.directorStemcell == "" && config.directorStemcellSHA1 == "" {
+		return state.BOSH, nil
+	}
+
+	if config.noDirector {
+		return storage.BOSH{}, errors.New(`The director cannot be sized for an environment with "--no-director".`)
+	}
+
+	b := state.BOSH
+	if config.directorVMType != "" {
+		b.DirectorVMType = config.directorVMType
+	}
+	if config.directorDiskSizeGB != 0 {
+		b.DirectorDiskSizeGB = config.directorDiskSizeGB
+	}
+	if config.directorStemcell != "" {
+		stemcellURL, err := directorStemcellURL(config.directorStemcell)
+		if err != nil {
+			return storage.BOSH{}, err
+		}
+		b.DirectorStemcellURL = stemcellURL
+		b.DirectorStemcellSHA1 = config.directorStemcellSHA1
+	} else if config.directorStemcellSHA1 != "" {
+		b.DirectorStemcellSHA1 = config.directorStemcellSHA1
+	}
+
+	err := bosh.DirectorSizing{
+		VMType:       b.DirectorVMType,
+		DiskSizeGB:   b.DirectorDiskSizeGB,
+		StemcellURL:  b.DirectorStemcellURL,
+		StemcellSHA1: b.DirectorStemcellSHA1,
+	}.Validate(state.IAAS)
+	if err != nil {
+		return storage.BOSH{}, err
+	}
+
+	return b, nil
+}
+
+// externalStoresFromConfig applies the external database and blobstore flags
+// to the BOSH state. They are decided when the environment is created, since
+// moving them would leave the director without its data.
+func externalStoresFromConfig(config upConfig, state storage.State) (storage.BOSH, error) {
+	if config.externalDatabase == state.BOSH.ExternalDatabase && config.externalBlobstore == state.BOSH.ExternalBlobstore {
+		return state.BOSH, nil
+	}
+
+	if config.noDirector {
+		return storage.BOSH{}, errors.New(`The director database and blobstore cannot be external for an environment with "--no-director".`)
+	}
+
+	if state.EnvID != "" {
+		return storage.BOSH{}, errors.New("The director database and blobstore cannot be moved for an existing environment.")
+	}
+
+	if config.externalBlobstore && state.IAAS == "azure" {
+		return storage.BOSH{}, errors.New(`"--director-external-blobstore" is not supported on azure.`)
+	}
+
+	b := state.BOSH
+	b.ExternalDatabase = config.externalDatabase
+	b.ExternalBlobstore = config.externalBlobstore
+
+	return b, nil
+}
+
+// directorStemcellURL returns stemcell as is when it is a URL. Otherwise it
+// is the path of a local stemcell tarball, which create-env reads through a
+// file URL.
+func directorStemcellURL(stemcell string) (string, error) {
+	for _, scheme := range []string{"http://", "https://", "file://"} {
+		if strings.HasPrefix(stemcell, scheme) {
+			return stemcell, nil
+		}
+	}
+
+	if !strings.HasSuffix(stemcell, ".tgz") {
+		return "", fmt.Errorf("director stemcell %s is neither a url nor a .tgz file", stemcell)
+	}
+
+	path, err := filepath.Abs(stemcell)
+	if err != nil {
+		return "", err //not tested
+	}
+
+	_, err = os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("director stemcell: %s", err)
+	}
+
+	return "file://" + path, nil
+}
+
+// readTerraformOverrides reads the .tf and .tf.json files in dir. They are
+// kept in the state and written next to the generated template.tf whenever
+// terraform runs, so an empty dir removes previously stored overrides.
+func readTerraformOverrides(dir string) (map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read terraform overrides: %s", err)
+	}
+
+	overrides := map[string]string{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !(strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")) {
+			continue
+		}
+
+		if name == "template.tf" {
+			return nil, fmt.Errorf("terraform override %s would replace the template generated by bbl, rename it", filepath.Join(dir, name))
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read terraform overrides: %s", err)
+		}
+
+		overrides[name] = string(contents)
+	}
+
+	return overrides, nil
+}
+
+// readNamedFiles adds the files at paths to files, in order. A file replaces
+// the entry with the same base name, keeping its position, so that passing an
+// updated ops file again does not apply it twice.
+func readNamedFiles(files []storage.NamedFile, paths []string) ([]storage.NamedFile, error) {
+	var result []storage.NamedFile
+	result = append(result, files...)
+
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		file := storage.NamedFile{Name: filepath.Base(path), Contents: string(contents)}
+
+		replaced := false
+		for i := range result {
+			if result[i].Name == file.Name {
+				result[i] = file
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			result = append(result, file)
+		}
+	}
+
+	return result, nil
+}
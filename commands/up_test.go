@@ -2,7 +2,10 @@ package commands_test
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/commands"
@@ -102,10 +105,148 @@ var _ = Describe("Up", func() {
 					Expect(err).To(MatchError("The director name cannot be changed for an existing environment. Current name is some-name."))
 				})
 			})
+
+			Context("when the network flags match the network in the state", func() {
+				It("returns no error", func() {
+					err := command.CheckFastFails([]string{
+						"--network-cidr", "192.168.0.0/16",
+					}, storage.State{
+						EnvID:   "some-name",
+						Network: storage.Network{CIDR: "192.168.0.0/16"},
+					})
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			Context("when the network flags do not match the network in the state", func() {
+				It("returns an error", func() {
+					err := command.CheckFastFails([]string{
+						"--bosh-subnet-cidr", "10.0.8.0/24",
+					}, storage.State{EnvID: "some-name"})
+					Expect(err).To(MatchError("The network cannot be changed for an existing environment."))
+				})
+			})
+		})
+
+		Context("when the director sizing flags are invalid", func() {
+			It("returns an error for an unknown vm type", func() {
+				err := command.CheckFastFails([]string{
+					"--director-vm-type", "m4.enormous",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).To(MatchError(ContainSubstring(`"m4.enormous" is not a known aws director vm type`)))
+			})
+
+			It("returns an error for a missing local stemcell", func() {
+				err := command.CheckFastFails([]string{
+					"--director-stemcell", "/some/missing/stemcell.tgz",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).To(MatchError("director stemcell: stat /some/missing/stemcell.tgz: no such file or directory"))
+			})
+
+			It("returns an error when the director is not deployed", func() {
+				err := command.CheckFastFails([]string{
+					"--no-director",
+					"--director-disk-size", "100",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).To(MatchError(`The director cannot be sized for an environment with "--no-director".`))
+			})
+		})
+
+		Context("when the external database and blobstore flags are invalid", func() {
+			It("returns an error when the director is not deployed", func() {
+				err := command.CheckFastFails([]string{
+					"--no-director",
+					"--director-external-db",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).To(MatchError(`The director database and blobstore cannot be external for an environment with "--no-director".`))
+			})
+
+			It("returns an error when the environment already exists", func() {
+				err := command.CheckFastFails([]string{
+					"--director-external-blobstore",
+				}, storage.State{IAAS: "gcp", EnvID: "some-name"})
+				Expect(err).To(MatchError("The director database and blobstore cannot be moved for an existing environment."))
+
+				err = command.CheckFastFails([]string{
+					"--director-external-db=false",
+				}, storage.State{IAAS: "gcp", EnvID: "some-name", BOSH: storage.BOSH{ExternalDatabase: true}})
+				Expect(err).To(MatchError("The director database and blobstore cannot be moved for an existing environment."))
+			})
+
+			It("returns an error for an external blobstore on azure", func() {
+				err := command.CheckFastFails([]string{
+					"--director-external-blobstore",
+				}, storage.State{IAAS: "azure"})
+				Expect(err).To(MatchError(`"--director-external-blobstore" is not supported on azure.`))
+			})
+		})
+
+		Context("when the network flags are invalid", func() {
+			It("returns an error", func() {
+				err := command.CheckFastFails([]string{
+					"--network-cidr", "10.0.0.0/16",
+					"--internal-subnet-cidr", "10.1.16.0/20",
+				}, storage.State{})
+				Expect(err).To(MatchError("internal subnet cidr 10.1.16.0/20 is not within the network cidr 10.0.0.0/16"))
+			})
 		})
 	})
 
 	Describe("Execute", func() {
+		Context("when the --terraform-override flag is specified", func() {
+			var overrideDir string
+
+			BeforeEach(func() {
+				var err error
+				overrideDir, err = ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				err = ioutil.WriteFile(filepath.Join(overrideDir, "firewall.tf"), []byte("some-firewall-rules"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = ioutil.WriteFile(filepath.Join(overrideDir, "network_override.tf.json"), []byte("some-network-override"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = ioutil.WriteFile(filepath.Join(overrideDir, "README.md"), []byte("some-readme"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("stores the terraform files from the directory in the state", func() {
+				err := command.Execute([]string{"--terraform-override", overrideDir}, storage.State{IAAS: "gcp"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGCPUp.ExecuteCall.Receives.State.TerraformOverrides).To(Equal(map[string]string{
+					"firewall.tf":              "some-firewall-rules",
+					"network_override.tf.json": "some-network-override",
+				}))
+			})
+
+			It("keeps the stored overrides when the flag is not specified", func() {
+				err := command.Execute([]string{}, storage.State{
+					IAAS:               "gcp",
+					TerraformOverrides: map[string]string{"firewall.tf": "some-firewall-rules"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGCPUp.ExecuteCall.Receives.State.TerraformOverrides).To(Equal(map[string]string{"firewall.tf": "some-firewall-rules"}))
+			})
+
+			Context("failure cases", func() {
+				It("returns an error when the directory cannot be read", func() {
+					err := command.CheckFastFails([]string{"--terraform-override", "/some/missing/dir"}, storage.State{IAAS: "gcp"})
+					Expect(err).To(MatchError(ContainSubstring("read terraform overrides: open /some/missing/dir")))
+				})
+
+				It("returns an error when an override would replace the generated template", func() {
+					err := ioutil.WriteFile(filepath.Join(overrideDir, "template.tf"), []byte("some-template"), os.ModePerm)
+					Expect(err).NotTo(HaveOccurred())
+
+					err = command.CheckFastFails([]string{"--terraform-override", overrideDir}, storage.State{IAAS: "gcp"})
+					Expect(err).To(MatchError(fmt.Sprintf("terraform override %s/template.tf would replace the template generated by bbl, rename it", overrideDir)))
+				})
+			})
+		})
+
 		Context("when the iaas is aws", func() {
 			It("it works", func() {
 				err := command.Execute([]string{}, storage.State{IAAS: "aws"})
@@ -133,63 +274,29 @@ var _ = Describe("Up", func() {
 			})
 		})
 
-		Context("when the --ops-file flag is specified", func() {
-			Context("aws", func() {
-				It("populates the aws config with the correct ops-file path", func() {
-					err := command.Execute([]string{
-						"--ops-file", "some-ops-file-path",
-					}, storage.State{IAAS: "aws"})
-					Expect(err).NotTo(HaveOccurred())
-
-					Expect(fakeAWSUp.ExecuteCall.Receives.AWSUpConfig.OpsFilePath).To(Equal("some-ops-file-path"))
-				})
-
-				Context("when the --ops-file flag is not specified", func() {
-					It("creates a default ops-file with the contents of state.BOSH.UserOpsFile", func() {
-						err := command.Execute([]string{}, storage.State{
-							IAAS: "aws",
-							BOSH: storage.BOSH{
-								UserOpsFile: "some-ops-file-contents",
-							},
-						})
-						Expect(err).NotTo(HaveOccurred())
-
-						filePath := fakeAWSUp.ExecuteCall.Receives.AWSUpConfig.OpsFilePath
-						fileContents, err := ioutil.ReadFile(filePath)
-						Expect(err).NotTo(HaveOccurred())
+		Context("when the --ops-file and --vars-file flags are specified", func() {
+			It("populates the aws config with the ops file and vars file paths in order", func() {
+				err := command.Execute([]string{
+					"--ops-file", "some-ops-file-path",
+					"--vars-file", "some-vars-file-path",
+					"--ops-file", "some-other-ops-file-path",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).NotTo(HaveOccurred())
 
-						Expect(string(fileContents)).To(Equal("some-ops-file-contents"))
-					})
-				})
+				Expect(fakeAWSUp.ExecuteCall.Receives.AWSUpConfig.OpsFilePaths).To(Equal([]string{"some-ops-file-path", "some-other-ops-file-path"}))
+				Expect(fakeAWSUp.ExecuteCall.Receives.AWSUpConfig.VarsFilePaths).To(Equal([]string{"some-vars-file-path"}))
 			})
 
-			Context("gcp", func() {
-				It("populates the gcp config with the correct ops-file path", func() {
-					err := command.Execute([]string{
-						"--ops-file", "some-ops-file-path",
-					}, storage.State{IAAS: "gcp"})
-					Expect(err).NotTo(HaveOccurred())
-
-					Expect(fakeGCPUp.ExecuteCall.Receives.GCPUpConfig.OpsFilePath).To(Equal("some-ops-file-path"))
-				})
-
-				Context("when the --ops-file flag is not specified", func() {
-					It("creates a default ops-file with the contents of state.BOSH.UserOpsFile", func() {
-						err := command.Execute([]string{}, storage.State{
-							IAAS: "gcp",
-							BOSH: storage.BOSH{
-								UserOpsFile: "some-ops-file-contents",
-							},
-						})
-						Expect(err).NotTo(HaveOccurred())
-
-						filePath := fakeGCPUp.ExecuteCall.Receives.GCPUpConfig.OpsFilePath
-						fileContents, err := ioutil.ReadFile(filePath)
-						Expect(err).NotTo(HaveOccurred())
+			It("populates the gcp config with the ops file and vars file paths in order", func() {
+				err := command.Execute([]string{
+					"--ops-file", "some-ops-file-path",
+					"--vars-file", "some-vars-file-path",
+					"--ops-file", "some-other-ops-file-path",
+				}, storage.State{IAAS: "gcp"})
+				Expect(err).NotTo(HaveOccurred())
 
-						Expect(string(fileContents)).To(Equal("some-ops-file-contents"))
-					})
-				})
+				Expect(fakeGCPUp.ExecuteCall.Receives.GCPUpConfig.OpsFilePaths).To(Equal([]string{"some-ops-file-path", "some-other-ops-file-path"}))
+				Expect(fakeGCPUp.ExecuteCall.Receives.GCPUpConfig.VarsFilePaths).To(Equal([]string{"some-vars-file-path"}))
 			})
 		})
 
@@ -310,6 +417,127 @@ var _ = Describe("Up", func() {
 			})
 		})
 
+		Context("when the network flags are specified", func() {
+			It("stores the network in the state", func() {
+				err := command.Execute([]string{
+					"--network-cidr", "192.168.0.0/16",
+					"--bosh-subnet-cidr", "192.168.1.0/24",
+					"--internal-subnet-cidr", "192.168.16.0/20",
+					"--internal-subnet-cidr", "192.168.32.0/20",
+					"--lb-subnet-cidr", "192.168.2.0/24",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeAWSUp.ExecuteCall.Receives.State.Network).To(Equal(storage.Network{
+					CIDR:                "192.168.0.0/16",
+					BOSHSubnetCIDR:      "192.168.1.0/24",
+					InternalSubnetCIDRs: []string{"192.168.16.0/20", "192.168.32.0/20"},
+					LBSubnetCIDRs:       []string{"192.168.2.0/24"},
+				}))
+			})
+
+			It("keeps the network in the state when they are omitted", func() {
+				network := storage.Network{CIDR: "192.168.0.0/16"}
+
+				err := command.Execute([]string{}, storage.State{IAAS: "gcp", EnvID: "some-name", Network: network})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGCPUp.ExecuteCall.Receives.State.Network).To(Equal(network))
+			})
+		})
+
+		Context("when the director sizing flags are specified", func() {
+			It("stores the sizing in the state", func() {
+				err := command.Execute([]string{
+					"--director-vm-type", "n1-standard-8",
+					"--director-disk-size", "100",
+					"--director-stemcell", "https://example.com/stemcell.tgz",
+					"--director-stemcell-sha1", "316a699d44f49d69493b1545d4addd17b78b5840",
+				}, storage.State{IAAS: "gcp"})
+				Expect(err).NotTo(HaveOccurred())
+
+				boshState := fakeGCPUp.ExecuteCall.Receives.State.BOSH
+				Expect(boshState.DirectorVMType).To(Equal("n1-standard-8"))
+				Expect(boshState.DirectorDiskSizeGB).To(Equal(100))
+				Expect(boshState.DirectorStemcellURL).To(Equal("https://example.com/stemcell.tgz"))
+				Expect(boshState.DirectorStemcellSHA1).To(Equal("316a699d44f49d69493b1545d4addd17b78b5840"))
+			})
+
+			It("stores a file url for a local stemcell", func() {
+				stemcellDir, err := ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(stemcellDir)
+
+				stemcellPath := filepath.Join(stemcellDir, "stemcell.tgz")
+				err = ioutil.WriteFile(stemcellPath, []byte("some-stemcell"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = command.Execute([]string{"--director-stemcell", stemcellPath}, storage.State{IAAS: "aws"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeAWSUp.ExecuteCall.Receives.State.BOSH.DirectorStemcellURL).To(Equal("file://" + stemcellPath))
+			})
+
+			It("keeps the stored sizing when the flags are omitted", func() {
+				err := command.Execute([]string{}, storage.State{
+					IAAS: "aws",
+					BOSH: storage.BOSH{DirectorVMType: "m4.2xlarge"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeAWSUp.ExecuteCall.Receives.State.BOSH.DirectorVMType).To(Equal("m4.2xlarge"))
+			})
+		})
+
+		Context("when the external database and blobstore flags are specified", func() {
+			It("stores them in the state", func() {
+				err := command.Execute([]string{
+					"--director-external-db",
+					"--director-external-blobstore",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).NotTo(HaveOccurred())
+
+				boshState := fakeAWSUp.ExecuteCall.Receives.State.BOSH
+				Expect(boshState.ExternalDatabase).To(BeTrue())
+				Expect(boshState.ExternalBlobstore).To(BeTrue())
+			})
+
+			It("keeps them for an existing environment when the flags are omitted", func() {
+				err := command.Execute([]string{}, storage.State{
+					IAAS:  "gcp",
+					EnvID: "some-name",
+					BOSH:  storage.BOSH{ExternalDatabase: true},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGCPUp.ExecuteCall.Receives.State.BOSH.ExternalDatabase).To(BeTrue())
+			})
+		})
+
+		Context("when the user provides the recreate flag", func() {
+			It("clears the jumpbox and director fingerprints so that create-env runs", func() {
+				err := command.Execute([]string{"--recreate"}, storage.State{
+					IAAS:    "gcp",
+					Jumpbox: storage.Jumpbox{Fingerprint: "some-jumpbox-fingerprint"},
+					BOSH:    storage.BOSH{Fingerprint: "some-director-fingerprint"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGCPUp.ExecuteCall.Receives.State.Jumpbox.Fingerprint).To(BeEmpty())
+				Expect(fakeGCPUp.ExecuteCall.Receives.State.BOSH.Fingerprint).To(BeEmpty())
+			})
+
+			It("keeps the fingerprints when the flag is not specified", func() {
+				err := command.Execute([]string{}, storage.State{
+					IAAS: "gcp",
+					BOSH: storage.BOSH{Fingerprint: "some-director-fingerprint"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGCPUp.ExecuteCall.Receives.State.BOSH.Fingerprint).To(Equal("some-director-fingerprint"))
+			})
+		})
+
 		Context("failure cases", func() {
 			It("returns an error when the desired up command fails", func() {
 				fakeAWSUp.ExecuteCall.Returns.Error = errors.New("failed execution")
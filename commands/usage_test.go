@@ -41,15 +41,24 @@ Usage:
 Global Options:
   --help      [-h]       Prints usage
   --state-dir            Directory containing bbl-state.json
+  --state-backend        Remote location of bbl-state.json, e.g. s3://bucket/prefix
   --debug                Prints debugging output
+  --keep-workspaces      Keeps the temporary terraform and bosh working directories
+  --terraform-retries    Number of times to retry transient terraform failures (default: 3)
+  --terraform-plugin-dir Directory of terraform providers to use instead of downloading them
   --version              Prints version
 
 Commands:
+  adopt-resource         Imports an existing resource into the terraform state
   bosh-deployment-vars   Prints required variables for BOSH deployment
   cloud-config           Prints suggested cloud configuration for BOSH environment
   create-lbs             Attaches load balancer(s)
+  decrypt-state          Decrypts bbl-state.json
   delete-lbs             Deletes attached load balancer(s)
   destroy                Tears down BOSH director infrastructure
+  drift                  Reports infrastructure changed outside of bbl
+  encrypt-state          Encrypts bbl-state.json
+  force-unlock           Removes the lock on bbl-state.json
   jumpbox-address        Prints BOSH jumpbox address
   director-address       Prints BOSH director address
   director-username      Prints BOSH director username
@@ -60,7 +69,12 @@ Commands:
   print-env              Prints BOSH friendly environment variables
   help                   Prints usage
   lbs                    Prints attached load balancer(s)
+  migrate-state          Upgrades bbl-state.json to the current schema version
+  ops-files              Lists or removes the ops files and vars files of the director
+  plan                   Prints the changes bbl up would make without making them
+  proxy                  Serves a SOCKS5 proxy through the jumpbox
   ssh-key                Prints SSH private key
+  state                  Manages snapshots and the layout of bbl-state.json
   up                     Deploys BOSH director on an IAAS
   update-lbs             Updates load balancer(s)
   version                Prints version
@@ -80,7 +94,11 @@ Commands:
 Global Options:
   --help      [-h]       Prints usage
   --state-dir            Directory containing bbl-state.json
+  --state-backend        Remote location of bbl-state.json, e.g. s3://bucket/prefix
   --debug                Prints debugging output
+  --keep-workspaces      Keeps the temporary terraform and bosh working directories
+  --terraform-retries    Number of times to retry transient terraform failures (default: 3)
+  --terraform-plugin-dir Directory of terraform providers to use instead of downloading them
   --version              Prints version
 
 [my-command command options]
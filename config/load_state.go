@@ -12,11 +12,15 @@ import (
 )
 
 type globalFlags struct {
-	Help     bool   `short:"h" long:"help"`
-	Debug    bool   `short:"d" long:"debug"         env:"BBL_DEBUG"`
-	Version  bool   `short:"v" long:"version"`
-	StateDir string `short:"s" long:"state-dir"`
-	IAAS     string `long:"iaas"                    env:"BBL_IAAS"`
+	Help           bool   `short:"h" long:"help"`
+	Debug          bool   `short:"d" long:"debug"         env:"BBL_DEBUG"`
+	KeepWorkspaces bool   `long:"keep-workspaces"         env:"BBL_KEEP_WORKSPACES"`
+	TFRetries      int    `long:"terraform-retries"       env:"BBL_TERRAFORM_RETRIES" default:"3"`
+	TFPluginDir    string `long:"terraform-plugin-dir"    env:"BBL_TERRAFORM_PLUGIN_DIR"`
+	Version        bool   `short:"v" long:"version"`
+	StateDir       string `short:"s" long:"state-dir"`
+	StateBackend   string `long:"state-backend"           env:"BBL_STATE_BACKEND"`
+	IAAS           string `long:"iaas"                    env:"BBL_IAAS"`
 
 	AWSAccessKeyID     string `long:"aws-access-key-id"       env:"BBL_AWS_ACCESS_KEY_ID"`
 	AWSSecretAccessKey string `long:"aws-secret-access-key"   env:"BBL_AWS_SECRET_ACCESS_KEY"`
@@ -34,12 +38,16 @@ type globalFlags struct {
 }
 
 type ParsedFlags struct {
-	State         storage.State
-	RemainingArgs []string
-	Help          bool
-	Debug         bool
-	Version       bool
-	StateDir      string
+	State          storage.State
+	RemainingArgs  []string
+	Help           bool
+	Debug          bool
+	KeepWorkspaces bool
+	TFRetries      int
+	TFPluginDir    string
+	Version        bool
+	StateDir       string
+	StateBackend   string
 }
 
 func NewConfig(getState func(string) (storage.State, error)) Config {
@@ -62,15 +70,23 @@ func (c Config) Bootstrap(args []string) (ParsedFlags, error) {
 		return ParsedFlags{}, err
 	}
 
+	if globalFlags.TFRetries < 0 {
+		return ParsedFlags{}, fmt.Errorf("--terraform-retries must be 0 or more, got %d", globalFlags.TFRetries)
+	}
+
 	nonStatefulCommand := len(remainingArgs) == 0 || globalFlags.Help || globalFlags.Version
-	nonStatefulCommand = nonStatefulCommand || (remainingArgs[0] == "help" || remainingArgs[0] == "version")
+	nonStatefulCommand = nonStatefulCommand || (remainingArgs[0] == "help" || remainingArgs[0] == "version" || remainingArgs[0] == "force-unlock" || remainingArgs[0] == "migrate-state")
 	if nonStatefulCommand {
 		return ParsedFlags{
-			RemainingArgs: remainingArgs,
-			Help:          globalFlags.Help,
-			Debug:         globalFlags.Debug,
-			Version:       globalFlags.Version,
-			StateDir:      globalFlags.StateDir,
+			RemainingArgs:  remainingArgs,
+			Help:           globalFlags.Help,
+			Debug:          globalFlags.Debug,
+			KeepWorkspaces: globalFlags.KeepWorkspaces,
+			TFRetries:      globalFlags.TFRetries,
+			TFPluginDir:    globalFlags.TFPluginDir,
+			Version:        globalFlags.Version,
+			StateDir:       globalFlags.StateDir,
+			StateBackend:   globalFlags.StateBackend,
 		}, nil
 	}
 
@@ -83,7 +99,12 @@ func (c Config) Bootstrap(args []string) (ParsedFlags, error) {
 		}
 	}
 
-	state, err := c.getState(stateDir)
+	stateLocation := stateDir
+	if globalFlags.StateBackend != "" {
+		stateLocation = globalFlags.StateBackend
+	}
+
+	state, err := c.getState(stateLocation)
 	if err != nil {
 		return ParsedFlags{}, err
 	}
@@ -148,7 +169,7 @@ func (c Config) Bootstrap(args []string) (ParsedFlags, error) {
 		return ParsedFlags{}, err
 	}
 
-	return ParsedFlags{State: state, RemainingArgs: remainingArgs, Help: globalFlags.Help, Debug: globalFlags.Debug, Version: globalFlags.Version, StateDir: globalFlags.StateDir}, nil
+	return ParsedFlags{State: state, RemainingArgs: remainingArgs, Help: globalFlags.Help, Debug: globalFlags.Debug, KeepWorkspaces: globalFlags.KeepWorkspaces, TFRetries: globalFlags.TFRetries, TFPluginDir: globalFlags.TFPluginDir, Version: globalFlags.Version, StateDir: globalFlags.StateDir, StateBackend: globalFlags.StateBackend}, nil
 }
 
 func validate(state storage.State) error {
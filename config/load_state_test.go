@@ -62,12 +62,23 @@ var _ = Describe("InitializeState", func() {
 						Expect(parsedFlags.RemainingArgs).To(Equal([]string{"up", "--name", "some-env-id"}))
 					})
 
+					It("retries transient terraform failures three times by default", func() {
+						parsedFlags, err := c.Bootstrap(args)
+
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(parsedFlags.TFRetries).To(Equal(3))
+					})
+
 					Context("when configuration includes global flags", func() {
 						BeforeEach(func() {
 							args = append([]string{
 								"bbl",
 								"--help",
 								"--debug",
+								"--keep-workspaces",
+								"--terraform-retries", "5",
+								"--terraform-plugin-dir", "some-plugin-dir",
 								"--version",
 								"--state-dir", "some-state-dir",
 							}, args[1:]...)
@@ -80,6 +91,9 @@ var _ = Describe("InitializeState", func() {
 
 							Expect(parsedFlags.Help).To(BeTrue())
 							Expect(parsedFlags.Debug).To(BeTrue())
+							Expect(parsedFlags.KeepWorkspaces).To(BeTrue())
+							Expect(parsedFlags.TFRetries).To(Equal(5))
+							Expect(parsedFlags.TFPluginDir).To(Equal("some-plugin-dir"))
 							Expect(parsedFlags.Version).To(BeTrue())
 							Expect(parsedFlags.StateDir).To(Equal("some-state-dir"))
 						})
@@ -101,6 +115,9 @@ var _ = Describe("InitializeState", func() {
 					Entry("when AWS region is missing",
 						[]string{"bbl", "up", "--iaas", "aws", "--aws-access-key-id", "some-access-key-id", "--aws-secret-access-key", "some-secret-key"},
 						"AWS region must be provided"),
+					Entry("when the terraform retry count is negative",
+						[]string{"bbl", "--terraform-retries=-1", "up"},
+						"--terraform-retries must be 0 or more, got -1"),
 				)
 			})
 
@@ -150,10 +167,16 @@ var _ = Describe("InitializeState", func() {
 					Context("when configuration includes global flags", func() {
 						BeforeEach(func() {
 							os.Setenv("BBL_DEBUG", "true")
+							os.Setenv("BBL_KEEP_WORKSPACES", "true")
+							os.Setenv("BBL_TERRAFORM_RETRIES", "0")
+							os.Setenv("BBL_TERRAFORM_PLUGIN_DIR", "some-plugin-dir")
 						})
 
 						AfterEach(func() {
 							os.Unsetenv("BBL_DEBUG")
+							os.Unsetenv("BBL_KEEP_WORKSPACES")
+							os.Unsetenv("BBL_TERRAFORM_RETRIES")
+							os.Unsetenv("BBL_TERRAFORM_PLUGIN_DIR")
 						})
 
 						It("returns global flags", func() {
@@ -162,6 +185,16 @@ var _ = Describe("InitializeState", func() {
 							Expect(err).NotTo(HaveOccurred())
 
 							Expect(parsedFlags.Debug).To(BeTrue())
+							Expect(parsedFlags.KeepWorkspaces).To(BeTrue())
+							Expect(parsedFlags.TFRetries).To(Equal(0))
+							Expect(parsedFlags.TFPluginDir).To(Equal("some-plugin-dir"))
+						})
+
+						It("returns an error when the terraform retry count is negative", func() {
+							os.Setenv("BBL_TERRAFORM_RETRIES", "-2")
+
+							_, err := c.Bootstrap(args)
+							Expect(err).To(MatchError("--terraform-retries must be 0 or more, got -2"))
 						})
 					})
 				})
@@ -216,6 +249,34 @@ var _ = Describe("InitializeState", func() {
 						Expect(getStateArg).To(Equal("some-state-dir"))
 					})
 				})
+
+				Context("when a state backend is specified", func() {
+					It("loads the state from the backend instead of the state dir", func() {
+						parsedFlags, err := c.Bootstrap([]string{
+							"bbl",
+							"create-lbs",
+							"--state-dir", "some-state-dir",
+							"--state-backend", "s3://some-bucket/some-env",
+						})
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(getStateArg).To(Equal("s3://some-bucket/some-env"))
+						Expect(parsedFlags.StateBackend).To(Equal("s3://some-bucket/some-env"))
+					})
+
+					It("reads the backend from BBL_STATE_BACKEND", func() {
+						os.Setenv("BBL_STATE_BACKEND", "s3://some-bucket/some-other-env")
+						defer os.Unsetenv("BBL_STATE_BACKEND")
+
+						_, err := c.Bootstrap([]string{
+							"bbl",
+							"create-lbs",
+						})
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(getStateArg).To(Equal("s3://some-bucket/some-other-env"))
+					})
+				})
 			})
 
 			Context("when valid matching configuration is passed in", func() {
@@ -333,6 +394,14 @@ var _ = Describe("InitializeState", func() {
 						Expect(parsedFlags.RemainingArgs).To(Equal([]string{"up", "--name", "some-env-id"}))
 					})
 
+					It("retries transient terraform failures three times by default", func() {
+						parsedFlags, err := c.Bootstrap(args)
+
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(parsedFlags.TFRetries).To(Equal(3))
+					})
+
 					Context("when service account key is passed inline", func() {
 						var args []string
 
@@ -681,6 +750,14 @@ var _ = Describe("InitializeState", func() {
 						Expect(parsedFlags.RemainingArgs).To(Equal([]string{"up", "--name", "some-env-id"}))
 					})
 
+					It("retries transient terraform failures three times by default", func() {
+						parsedFlags, err := c.Bootstrap(args)
+
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(parsedFlags.TFRetries).To(Equal(3))
+					})
+
 					Context("when configuration includes global flags", func() {
 						BeforeEach(func() {
 							args = append([]string{
@@ -942,6 +1019,8 @@ var _ = Describe("InitializeState", func() {
 		Entry("when no command is used", []string{"bbl"}, false, ""),
 		Entry("when version flag is set", []string{"bbl", "--version"}, false, ""),
 		Entry("when version command is used", []string{"bbl", "version"}, false, ""),
+		Entry("when force-unlock command is used", []string{"bbl", "force-unlock"}, false, ""),
+		Entry("when migrate-state command is used", []string{"bbl", "migrate-state", "--dry-run"}, false, ""),
 		// Entry("when invalid flag is passed", []string{"bbl", "--foo", "bar"}, true, "flag provided but not defined: -foo"),
 	)
 })
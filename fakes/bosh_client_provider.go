@@ -11,6 +11,7 @@ type BOSHClientProvider struct {
 
 		Receives struct {
 			Jumpbox          storage.Jumpbox
+			Network          storage.Network
 			DirectorAddress  string
 			DirectorUsername string
 			DirectorPassword string
@@ -22,9 +23,10 @@ type BOSHClientProvider struct {
 	}
 }
 
-func (b *BOSHClientProvider) Client(jumpbox storage.Jumpbox, directorAddress, directorUsername, directorPassword, directorCACert string) (bosh.Client, error) {
+func (b *BOSHClientProvider) Client(jumpbox storage.Jumpbox, network storage.Network, directorAddress, directorUsername, directorPassword, directorCACert string) (bosh.Client, error) {
 	b.ClientCall.CallCount++
 	b.ClientCall.Receives.Jumpbox = jumpbox
+	b.ClientCall.Receives.Network = network
 	b.ClientCall.Receives.DirectorAddress = directorAddress
 	b.ClientCall.Receives.DirectorUsername = directorUsername
 	b.ClientCall.Receives.DirectorPassword = directorPassword
@@ -25,6 +25,28 @@ type BOSHManager struct {
 			Error error
 		}
 	}
+	InterpolateJumpboxCall struct {
+		CallCount int
+		Receives  struct {
+			State            storage.State
+			TerraformOutputs map[string]interface{}
+		}
+		Returns struct {
+			Manifest string
+			Error    error
+		}
+	}
+	InterpolateDirectorCall struct {
+		CallCount int
+		Receives  struct {
+			State            storage.State
+			TerraformOutputs map[string]interface{}
+		}
+		Returns struct {
+			Manifest string
+			Error    error
+		}
+	}
 	VersionCall struct {
 		CallCount int
 		Returns   struct {
@@ -59,7 +81,8 @@ type BOSHManager struct {
 			TerraformOutputs map[string]interface{}
 		}
 		Returns struct {
-			Vars string
+			Vars  string
+			Error error
 		}
 	}
 	GetJumpboxDeploymentVarsCall struct {
@@ -69,7 +92,8 @@ type BOSHManager struct {
 			TerraformOutputs map[string]interface{}
 		}
 		Returns struct {
-			Vars string
+			Vars  string
+			Error error
 		}
 	}
 }
@@ -90,6 +114,20 @@ func (b *BOSHManager) CreateDirector(state storage.State, terraformOutputs map[s
 	return state, b.CreateDirectorCall.Returns.Error
 }
 
+func (b *BOSHManager) InterpolateJumpbox(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
+	b.InterpolateJumpboxCall.CallCount++
+	b.InterpolateJumpboxCall.Receives.State = state
+	b.InterpolateJumpboxCall.Receives.TerraformOutputs = terraformOutputs
+	return b.InterpolateJumpboxCall.Returns.Manifest, b.InterpolateJumpboxCall.Returns.Error
+}
+
+func (b *BOSHManager) InterpolateDirector(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
+	b.InterpolateDirectorCall.CallCount++
+	b.InterpolateDirectorCall.Receives.State = state
+	b.InterpolateDirectorCall.Receives.TerraformOutputs = terraformOutputs
+	return b.InterpolateDirectorCall.Returns.Manifest, b.InterpolateDirectorCall.Returns.Error
+}
+
 func (b *BOSHManager) Delete(state storage.State, terraformOutputs map[string]interface{}) error {
 	b.DeleteCall.CallCount++
 	b.DeleteCall.Receives.State = state
@@ -104,18 +142,18 @@ func (b *BOSHManager) DeleteJumpbox(state storage.State, terraformOutputs map[st
 	return b.DeleteJumpboxCall.Returns.Error
 }
 
-func (b *BOSHManager) GetDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) string {
+func (b *BOSHManager) GetDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
 	b.GetDeploymentVarsCall.CallCount++
 	b.GetDeploymentVarsCall.Receives.State = state
 	b.GetDeploymentVarsCall.Receives.TerraformOutputs = terraformOutputs
-	return b.GetDeploymentVarsCall.Returns.Vars
+	return b.GetDeploymentVarsCall.Returns.Vars, b.GetDeploymentVarsCall.Returns.Error
 }
 
-func (b *BOSHManager) GetJumpboxDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) string {
+func (b *BOSHManager) GetJumpboxDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) (string, error) {
 	b.GetJumpboxDeploymentVarsCall.CallCount++
 	b.GetJumpboxDeploymentVarsCall.Receives.State = state
 	b.GetJumpboxDeploymentVarsCall.Receives.TerraformOutputs = terraformOutputs
-	return b.GetJumpboxDeploymentVarsCall.Returns.Vars
+	return b.GetJumpboxDeploymentVarsCall.Returns.Vars, b.GetJumpboxDeploymentVarsCall.Returns.Error
 }
 
 func (b *BOSHManager) Version() (string, error) {
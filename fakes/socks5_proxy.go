@@ -1,34 +1,121 @@
 package fakes
 
+import (
+	"io"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/proxy"
+	goproxy "golang.org/x/net/proxy"
+)
+
 type Socks5Proxy struct {
 	StartCall struct {
 		CallCount int
 		Receives  struct {
 			JumpboxPrivateKey  string
+			JumpboxHostKey     string
+			JumpboxExternalURL string
+			Allowlist          proxy.Allowlist
+		}
+		Returns struct {
+			Error error
+		}
+	}
+	StartOnPortCall struct {
+		CallCount int
+		Receives  struct {
+			JumpboxPrivateKey  string
+			JumpboxHostKey     string
 			JumpboxExternalURL string
+			Port               int
+			Allowlist          proxy.Allowlist
+			AuditLog           io.Writer
 		}
 		Returns struct {
 			Error error
 		}
 	}
+	KeepAliveCall struct {
+		CallCount int
+		Receives  struct {
+			Interval time.Duration
+		}
+	}
 	AddrCall struct {
 		CallCount int
 		Returns   struct {
 			Addr string
 		}
 	}
+	URLCall struct {
+		CallCount int
+		Returns   struct {
+			URL string
+		}
+	}
+	AuthCall struct {
+		CallCount int
+		Returns   struct {
+			Auth *goproxy.Auth
+		}
+	}
+	HostKeyCall struct {
+		CallCount int
+		Returns   struct {
+			HostKey string
+		}
+	}
 }
 
-func (s *Socks5Proxy) Start(jumpboxPrivateKey, jumpboxExternalURL string) error {
+func (s *Socks5Proxy) Start(jumpboxPrivateKey, jumpboxHostKey, jumpboxExternalURL string, allowlist proxy.Allowlist) error {
 	s.StartCall.CallCount++
 	s.StartCall.Receives.JumpboxPrivateKey = jumpboxPrivateKey
+	s.StartCall.Receives.JumpboxHostKey = jumpboxHostKey
 	s.StartCall.Receives.JumpboxExternalURL = jumpboxExternalURL
+	s.StartCall.Receives.Allowlist = allowlist
 
 	return s.StartCall.Returns.Error
 }
 
+func (s *Socks5Proxy) StartOnPort(jumpboxPrivateKey, jumpboxHostKey, jumpboxExternalURL string, port int, allowlist proxy.Allowlist, auditLog io.Writer) error {
+	s.StartOnPortCall.CallCount++
+	s.StartOnPortCall.Receives.JumpboxPrivateKey = jumpboxPrivateKey
+	s.StartOnPortCall.Receives.JumpboxHostKey = jumpboxHostKey
+	s.StartOnPortCall.Receives.JumpboxExternalURL = jumpboxExternalURL
+	s.StartOnPortCall.Receives.Port = port
+	s.StartOnPortCall.Receives.Allowlist = allowlist
+	s.StartOnPortCall.Receives.AuditLog = auditLog
+
+	return s.StartOnPortCall.Returns.Error
+}
+
+func (s *Socks5Proxy) KeepAlive(interval time.Duration, stop <-chan struct{}) {
+	s.KeepAliveCall.CallCount++
+	s.KeepAliveCall.Receives.Interval = interval
+
+	<-stop
+}
+
 func (s *Socks5Proxy) Addr() string {
 	s.AddrCall.CallCount++
 
 	return s.AddrCall.Returns.Addr
 }
+
+func (s *Socks5Proxy) URL() string {
+	s.URLCall.CallCount++
+
+	return s.URLCall.Returns.URL
+}
+
+func (s *Socks5Proxy) Auth() *goproxy.Auth {
+	s.AuthCall.CallCount++
+
+	return s.AuthCall.Returns.Auth
+}
+
+func (s *Socks5Proxy) HostKey() string {
+	s.HostKeyCall.CallCount++
+
+	return s.HostKeyCall.Returns.HostKey
+}
@@ -0,0 +1,26 @@
+package fakes
+
+type StateEncrypter struct {
+	EncryptCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+	DecryptCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+}
+
+func (s *StateEncrypter) Encrypt() error {
+	s.EncryptCall.CallCount++
+	return s.EncryptCall.Returns.Error
+}
+
+func (s *StateEncrypter) Decrypt() error {
+	s.DecryptCall.CallCount++
+	return s.DecryptCall.Returns.Error
+}
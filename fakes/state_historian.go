@@ -0,0 +1,33 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type StateHistorian struct {
+	HistoryCall struct {
+		CallCount int
+		Returns   struct {
+			Snapshots []storage.Snapshot
+			Error     error
+		}
+	}
+	RollbackCall struct {
+		CallCount int
+		Receives  struct {
+			ID string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+func (s *StateHistorian) History() ([]storage.Snapshot, error) {
+	s.HistoryCall.CallCount++
+	return s.HistoryCall.Returns.Snapshots, s.HistoryCall.Returns.Error
+}
+
+func (s *StateHistorian) Rollback(id string) error {
+	s.RollbackCall.CallCount++
+	s.RollbackCall.Receives.ID = id
+	return s.RollbackCall.Returns.Error
+}
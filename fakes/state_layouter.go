@@ -0,0 +1,26 @@
+package fakes
+
+type StateLayouter struct {
+	SplitCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+	JoinCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+}
+
+func (s *StateLayouter) Split() error {
+	s.SplitCall.CallCount++
+	return s.SplitCall.Returns.Error
+}
+
+func (s *StateLayouter) Join() error {
+	s.JoinCall.CallCount++
+	return s.JoinCall.Returns.Error
+}
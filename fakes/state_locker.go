@@ -0,0 +1,49 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type StateLocker struct {
+	LockCall struct {
+		CallCount int
+		Receives  struct {
+			Command string
+		}
+		Returns struct {
+			LockInfo storage.LockInfo
+			Error    error
+		}
+	}
+	UnlockCall struct {
+		CallCount int
+		Receives  struct {
+			LockInfo storage.LockInfo
+		}
+		Returns struct {
+			Error error
+		}
+	}
+	ForceUnlockCall struct {
+		CallCount int
+		Returns   struct {
+			LockInfo storage.LockInfo
+			Error    error
+		}
+	}
+}
+
+func (s *StateLocker) Lock(command string) (storage.LockInfo, error) {
+	s.LockCall.CallCount++
+	s.LockCall.Receives.Command = command
+	return s.LockCall.Returns.LockInfo, s.LockCall.Returns.Error
+}
+
+func (s *StateLocker) Unlock(lockInfo storage.LockInfo) error {
+	s.UnlockCall.CallCount++
+	s.UnlockCall.Receives.LockInfo = lockInfo
+	return s.UnlockCall.Returns.Error
+}
+
+func (s *StateLocker) ForceUnlock() (storage.LockInfo, error) {
+	s.ForceUnlockCall.CallCount++
+	return s.ForceUnlockCall.Returns.LockInfo, s.ForceUnlockCall.Returns.Error
+}
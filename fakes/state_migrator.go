@@ -0,0 +1,30 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type StateMigrator struct {
+	PlanMigrationCall struct {
+		CallCount int
+		Returns   struct {
+			MigrationPlan storage.MigrationPlan
+			Error         error
+		}
+	}
+	MigrateCall struct {
+		CallCount int
+		Returns   struct {
+			MigrationPlan storage.MigrationPlan
+			Error         error
+		}
+	}
+}
+
+func (s *StateMigrator) PlanMigration() (storage.MigrationPlan, error) {
+	s.PlanMigrationCall.CallCount++
+	return s.PlanMigrationCall.Returns.MigrationPlan, s.PlanMigrationCall.Returns.Error
+}
+
+func (s *StateMigrator) Migrate() (storage.MigrationPlan, error) {
+	s.MigrateCall.CallCount++
+	return s.MigrateCall.Returns.MigrationPlan, s.MigrateCall.Returns.Error
+}
@@ -19,6 +19,14 @@ type StateStore struct {
 			Error error
 		}
 	}
+
+	MigrateCall struct {
+		CallCount int
+		Returns   struct {
+			MigrationPlan storage.MigrationPlan
+			Error         error
+		}
+	}
 }
 
 type SetCallReceive struct {
@@ -40,3 +48,13 @@ func (s *StateStore) Set(state storage.State) error {
 
 	return s.SetCall.Returns[s.SetCall.CallCount-1].Error
 }
+
+func (s *StateStore) Get() (storage.State, error) {
+	s.GetCall.CallCount++
+	return s.GetCall.Returns.State, s.GetCall.Returns.Error
+}
+
+func (s *StateStore) Migrate() (storage.MigrationPlan, error) {
+	s.MigrateCall.CallCount++
+	return s.MigrateCall.Returns.MigrationPlan, s.MigrateCall.Returns.Error
+}
@@ -52,6 +52,7 @@ func main() {
 		}
 
 		fmt.Printf("working directory: %s\n", dir)
+		fmt.Printf("plugin cache directory: %s\n", os.Getenv("TF_PLUGIN_CACHE_DIR"))
 		fmt.Printf("terraform %s/n", removeBrackets(fmt.Sprintf("%+v", os.Args)))
 	}
 }
@@ -13,6 +13,7 @@ type TerraformCmd struct {
 			Errors []error
 		}
 		Initialized bool
+		InitArgs    []string
 		Receives    struct {
 			Stdout           io.Writer
 			WorkingDirectory string
@@ -36,6 +37,7 @@ func (t *TerraformCmd) Run(stdout io.Writer, workingDirectory string, args []str
 		}
 	case "init":
 		t.RunCall.Initialized = true
+		t.RunCall.InitArgs = args
 	default:
 		if !t.RunCall.Initialized {
 			return errors.New("must initialize terraform v0.10.* before running any other commands")
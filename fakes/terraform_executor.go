@@ -1,19 +1,16 @@
 package fakes
 
-import "github.com/cloudfoundry/bosh-bootloader/storage"
-
-type Import struct {
-	Addr string
-	ID   string
-}
+import "github.com/cloudfoundry/bosh-bootloader/terraform"
 
 type TerraformExecutor struct {
 	ApplyCall struct {
 		CallCount int
+		Stub      func() (string, error)
 		Receives  struct {
-			Inputs   map[string]string
-			Template string
-			TFState  string
+			Inputs    map[string]string
+			Template  string
+			Overrides map[string]string
+			TFState   string
 		}
 		Returns struct {
 			TFState string
@@ -23,21 +20,47 @@ type TerraformExecutor struct {
 	DestroyCall struct {
 		CallCount int
 		Receives  struct {
-			Inputs   map[string]string
-			Template string
-			TFState  string
+			Inputs    map[string]string
+			Template  string
+			Overrides map[string]string
+			TFState   string
 		}
 		Returns struct {
 			TFState string
 			Error   error
 		}
 	}
-	ImportCall struct {
+	PlanCall struct {
 		CallCount int
 		Receives  struct {
+			Inputs    map[string]string
+			Template  string
+			Overrides map[string]string
+			TFState   string
+		}
+		Returns struct {
+			Plan  string
+			Error error
+		}
+	}
+	RefreshCall struct {
+		CallCount int
+		Receives  struct {
+			Inputs    map[string]string
+			Template  string
+			Overrides map[string]string
+			TFState   string
+		}
+		Returns struct {
 			TFState string
-			Imports []Import
-			Creds   storage.AWS
+			Error   error
+		}
+	}
+	ImportCall struct {
+		CallCount int
+		Stub      func()
+		Receives  struct {
+			Input terraform.ImportInput
 		}
 		Returns struct {
 			TFState string
@@ -76,30 +99,54 @@ type TerraformExecutor struct {
 	}
 }
 
-func (t *TerraformExecutor) Apply(inputs map[string]string, template, tfState string) (string, error) {
+func (t *TerraformExecutor) Apply(inputs map[string]string, template string, overrides map[string]string, tfState string) (string, error) {
 	t.ApplyCall.CallCount++
 	t.ApplyCall.Receives.Inputs = inputs
 	t.ApplyCall.Receives.Template = template
+	t.ApplyCall.Receives.Overrides = overrides
 	t.ApplyCall.Receives.TFState = tfState
+
+	if t.ApplyCall.Stub != nil {
+		return t.ApplyCall.Stub()
+	}
+
 	return t.ApplyCall.Returns.TFState, t.ApplyCall.Returns.Error
 }
 
-func (t *TerraformExecutor) Destroy(inputs map[string]string, template, tfState string) (string, error) {
+func (t *TerraformExecutor) Destroy(inputs map[string]string, template string, overrides map[string]string, tfState string) (string, error) {
 	t.DestroyCall.CallCount++
 	t.DestroyCall.Receives.Inputs = inputs
 	t.DestroyCall.Receives.Template = template
+	t.DestroyCall.Receives.Overrides = overrides
 	t.DestroyCall.Receives.TFState = tfState
 	return t.DestroyCall.Returns.TFState, t.DestroyCall.Returns.Error
 }
 
-func (t *TerraformExecutor) Import(addr, id, tfstate string, creds storage.AWS) (string, error) {
+func (t *TerraformExecutor) Plan(inputs map[string]string, template string, overrides map[string]string, tfState string) (string, error) {
+	t.PlanCall.CallCount++
+	t.PlanCall.Receives.Inputs = inputs
+	t.PlanCall.Receives.Template = template
+	t.PlanCall.Receives.Overrides = overrides
+	t.PlanCall.Receives.TFState = tfState
+	return t.PlanCall.Returns.Plan, t.PlanCall.Returns.Error
+}
+
+func (t *TerraformExecutor) Refresh(inputs map[string]string, template string, overrides map[string]string, tfState string) (string, error) {
+	t.RefreshCall.CallCount++
+	t.RefreshCall.Receives.Inputs = inputs
+	t.RefreshCall.Receives.Template = template
+	t.RefreshCall.Receives.Overrides = overrides
+	t.RefreshCall.Receives.TFState = tfState
+	return t.RefreshCall.Returns.TFState, t.RefreshCall.Returns.Error
+}
+
+func (t *TerraformExecutor) Import(input terraform.ImportInput) (string, error) {
 	t.ImportCall.CallCount++
-	t.ImportCall.Receives.Imports = append(t.ImportCall.Receives.Imports, Import{
-		Addr: addr,
-		ID:   id,
-	})
-	t.ImportCall.Receives.TFState = tfstate
-	t.ImportCall.Receives.Creds = creds
+	t.ImportCall.Receives.Input = input
+
+	if t.ImportCall.Stub != nil {
+		t.ImportCall.Stub()
+	}
 
 	return t.ImportCall.Returns.TFState, t.ImportCall.Returns.Error
 }
@@ -2,6 +2,7 @@ package fakes
 
 import (
 	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
 )
 
 type TerraformManager struct {
@@ -25,11 +26,32 @@ type TerraformManager struct {
 			Error    error
 		}
 	}
+	PlanCall struct {
+		CallCount int
+		Receives  struct {
+			BBLState storage.State
+		}
+		Returns struct {
+			Plan  string
+			Error error
+		}
+	}
+	DriftCall struct {
+		CallCount int
+		Receives  struct {
+			BBLState storage.State
+		}
+		Returns struct {
+			Drift []terraform.ResourceDrift
+			Error error
+		}
+	}
 	ImportCall struct {
 		CallCount int
 		Receives  struct {
 			BBLState storage.State
-			Outputs  map[string]string
+			Address  string
+			ID       string
 		}
 		Returns struct {
 			BBLState storage.State
@@ -75,10 +97,25 @@ func (t *TerraformManager) Destroy(bblState storage.State) (storage.State, error
 	return t.DestroyCall.Returns.BBLState, t.DestroyCall.Returns.Error
 }
 
-func (t *TerraformManager) Import(bblState storage.State, outputs map[string]string) (storage.State, error) {
+func (t *TerraformManager) Plan(bblState storage.State) (string, error) {
+	t.PlanCall.CallCount++
+	t.PlanCall.Receives.BBLState = bblState
+
+	return t.PlanCall.Returns.Plan, t.PlanCall.Returns.Error
+}
+
+func (t *TerraformManager) Drift(bblState storage.State) ([]terraform.ResourceDrift, error) {
+	t.DriftCall.CallCount++
+	t.DriftCall.Receives.BBLState = bblState
+
+	return t.DriftCall.Returns.Drift, t.DriftCall.Returns.Error
+}
+
+func (t *TerraformManager) Import(bblState storage.State, address, id string) (storage.State, error) {
 	t.ImportCall.CallCount++
 	t.ImportCall.Receives.BBLState = bblState
-	t.ImportCall.Receives.Outputs = outputs
+	t.ImportCall.Receives.Address = address
+	t.ImportCall.Receives.ID = id
 
 	return t.ImportCall.Returns.BBLState, t.ImportCall.Returns.Error
 }
@@ -12,12 +12,16 @@ var _ = Describe("Flags", func() {
 		f         flags.Flags
 		boolVal   bool
 		stringVal string
+		intVal    int
+		sliceVal  []string
 	)
 
 	BeforeEach(func() {
 		f = flags.New("test")
 		f.Bool(&boolVal, "b", "bool", false)
 		f.String(&stringVal, "string", "")
+		f.Int(&intVal, "int", 0)
+		f.Strings(&sliceVal, "strings")
 	})
 
 	Describe("Parse", func() {
@@ -49,6 +53,28 @@ var _ = Describe("Flags", func() {
 				Expect(stringVal).To(Equal("string_value"))
 			})
 		})
+
+		Context("Int flags", func() {
+			It("can parse int fields from flags", func() {
+				err := f.Parse([]string{"--int", "64"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(intVal).To(Equal(64))
+			})
+
+			It("returns an error when the value is not a number", func() {
+				err := f.Parse([]string{"--int", "lots"})
+				Expect(err).To(MatchError(`invalid value "lots" for flag -int: parse error`))
+			})
+		})
+
+		Context("Strings flags", func() {
+			It("collects every occurrence of the flag in order", func() {
+				sliceVal = nil
+				err := f.Parse([]string{"--strings", "first", "--strings", "second"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sliceVal).To(Equal([]string{"first", "second"}))
+			})
+		})
 	})
 
 	Describe("Args", func() {
@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+// LineDiff returns a unified-style diff of two texts, with "-" marking
+// removed lines, "+" added lines and up to three unchanged lines of context
+// around each change. It returns an empty string when the texts are equal.
+func LineDiff(before, after string) string {
+	beforeLines := splitLines(before)
+	afterLines := splitLines(after)
+
+	type line struct {
+		op   byte
+		text string
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// beforeLines[i:] and afterLines[j:].
+	lcs := make([][]int, len(beforeLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(afterLines)+1)
+	}
+	for i := len(beforeLines) - 1; i >= 0; i-- {
+		for j := len(afterLines) - 1; j >= 0; j-- {
+			if beforeLines[i] == afterLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []line
+	i, j := 0, 0
+	for i < len(beforeLines) || j < len(afterLines) {
+		switch {
+		case i < len(beforeLines) && j < len(afterLines) && beforeLines[i] == afterLines[j]:
+			lines = append(lines, line{' ', beforeLines[i]})
+			i++
+			j++
+		case j < len(afterLines) && (i == len(beforeLines) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, line{'+', afterLines[j]})
+			j++
+		default:
+			lines = append(lines, line{'-', beforeLines[i]})
+			i++
+		}
+	}
+
+	keep := make([]bool, len(lines))
+	changed := false
+	for index, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		changed = true
+		for k := index - diffContextLines; k <= index+diffContextLines; k++ {
+			if k >= 0 && k < len(lines) {
+				keep[k] = true
+			}
+		}
+	}
+
+	if !changed {
+		return ""
+	}
+
+	var diff []string
+	for index, l := range lines {
+		if !keep[index] {
+			if index == 0 || keep[index-1] {
+				diff = append(diff, "...")
+			}
+			continue
+		}
+		diff = append(diff, fmt.Sprintf("%c %s", l.op, l.text))
+	}
+
+	return strings.Join(diff, "\n")
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,28 @@
+package helpers_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LineDiff", func() {
+	It("returns an empty string when nothing changed", func() {
+		Expect(helpers.LineDiff("a\nb\n", "a\nb\n")).To(Equal(""))
+	})
+
+	It("marks removed and added lines", func() {
+		diff := helpers.LineDiff("a\nb\nc", "a\nB\nc\nd")
+		Expect(diff).To(Equal("  a\n- b\n+ B\n  c\n+ d"))
+	})
+
+	It("elides unchanged lines far away from a change", func() {
+		diff := helpers.LineDiff("1\n2\n3\n4\n5\n6\n7\n8\n9", "1\n2\n3\n4\n5\n6\n7\n8\nnine")
+		Expect(diff).To(Equal("...\n  6\n  7\n  8\n- 9\n+ nine"))
+	})
+
+	It("handles empty texts", func() {
+		Expect(helpers.LineDiff("", "a")).To(Equal("+ a"))
+		Expect(helpers.LineDiff("a", "")).To(Equal("- a"))
+	})
+})
@@ -0,0 +1,239 @@
+package interpolate
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	keyBits           = 2048
+	passwordLength    = 20
+	passwordAlphabet  = "abcdefghijklmnopqrstuvwxyz0123456789"
+	certificateExpiry = 365 * 24 * time.Hour
+)
+
+// definition is an entry of the variables section of a manifest.
+type definition struct {
+	Name    string                      `yaml:"name"`
+	Type    string                      `yaml:"type"`
+	Options map[interface{}]interface{} `yaml:"options"`
+}
+
+// generate creates a value for a variable of the given type, the same way
+// the bosh CLI does for its vars store.
+func generate(name, variableType string, options map[interface{}]interface{}, r *resolver) (interface{}, error) {
+	switch variableType {
+	case "password":
+		return generatePassword()
+	case "certificate":
+		return generateCertificate(name, options, r)
+	case "ssh":
+		return generateSSHKey()
+	case "rsa":
+		return generateRSAKey()
+	}
+
+	return nil, fmt.Errorf("variable %q has unsupported type %q", name, variableType)
+}
+
+func generatePassword() (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+
+	password := make([]byte, passwordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("generate password: %s", err)
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+
+	return string(password), nil
+}
+
+func generateCertificate(name string, options map[interface{}]interface{}, r *resolver) (interface{}, error) {
+	commonName, _ := options["common_name"].(string)
+	isCA, _ := options["is_ca"].(bool)
+	caName, _ := options["ca"].(string)
+
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate certificate %s: %s", name, err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate certificate %s: %s", name, err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Country:      []string{"USA"},
+			Organization: []string{"Cloud Foundry"},
+			CommonName:   commonName,
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(certificateExpiry),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	} else {
+		template.KeyUsage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+	}
+
+	alternativeNames, _ := options["alternative_names"].([]interface{})
+	for _, alternativeName := range alternativeNames {
+		value := fmt.Sprintf("%v", alternativeName)
+		if ip := net.ParseIP(value); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, value)
+		}
+	}
+
+	extendedKeyUsages, _ := options["extended_key_usage"].([]interface{})
+	for _, usage := range extendedKeyUsages {
+		switch usage {
+		case "server_auth":
+			template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+		case "client_auth":
+			template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+		default:
+			return nil, fmt.Errorf("generate certificate %s: unsupported extended key usage %v", name, usage)
+		}
+	}
+
+	parent := template
+	var parentKey interface{} = key
+	if caName != "" {
+		parent, parentKey, err = loadCA(caName, r)
+		if err != nil {
+			return nil, fmt.Errorf("generate certificate %s: %s", name, err)
+		}
+	} else if !isCA {
+		return nil, fmt.Errorf("generate certificate %s: either is_ca or ca must be set", name)
+	}
+
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, fmt.Errorf("generate certificate %s: %s", name, err)
+	}
+
+	certificatePEM := encodePEM("CERTIFICATE", certificateDER)
+	caPEM := certificatePEM
+	if caName != "" {
+		caPEM = encodePEM("CERTIFICATE", parent.Raw)
+	}
+
+	return map[interface{}]interface{}{
+		"ca":          caPEM,
+		"certificate": certificatePEM,
+		"private_key": encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+	}, nil
+}
+
+// loadCA returns the certificate and key of the CA variable name, generating
+// the CA first if it does not exist yet.
+func loadCA(name string, r *resolver) (*x509.Certificate, interface{}, error) {
+	value, found, err := r.get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("ca %q is not defined", name)
+	}
+
+	ca, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("ca %q is not a certificate", name)
+	}
+
+	certificatePEM, _ := ca["certificate"].(string)
+	block, _ := pem.Decode([]byte(certificatePEM))
+	if block == nil {
+		return nil, nil, fmt.Errorf("ca %q does not contain a PEM encoded certificate", name)
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse ca %q: %s", name, err)
+	}
+
+	privateKeyPEM, _ := ca["private_key"].(string)
+	block, _ = pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, nil, fmt.Errorf("ca %q does not contain a PEM encoded private key", name)
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse ca %q: %s", name, err)
+	}
+
+	return certificate, key, nil
+}
+
+func generateSSHKey() (interface{}, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate ssh key: %s", err)
+	}
+
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("generate ssh key: %s", err)
+	}
+
+	return map[interface{}]interface{}{
+		"private_key":            encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		"public_key":             strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		"public_key_fingerprint": md5Fingerprint(publicKey),
+	}, nil
+}
+
+func generateRSAKey() (interface{}, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate rsa key: %s", err)
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("generate rsa key: %s", err)
+	}
+
+	return map[interface{}]interface{}{
+		"private_key": encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		"public_key":  encodePEM("PUBLIC KEY", publicKeyDER),
+	}, nil
+}
+
+func md5Fingerprint(publicKey ssh.PublicKey) string {
+	sum := md5.Sum(publicKey.Marshal())
+
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = fmt.Sprintf("%02x", b)
+	}
+
+	return strings.Join(pairs, ":")
+}
+
+func encodePEM(blockType string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
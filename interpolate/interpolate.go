@@ -0,0 +1,136 @@
+// Package interpolate applies ops files and variables to bosh manifests the
+// way `bosh interpolate` does, without shelling out to the bosh CLI.
+package interpolate
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type Input struct {
+	Manifest  string
+	OpsFiles  []string
+	VarsFiles []string
+	VarsStore string
+
+	// ExpectAllKeys fails when a placeholder cannot be resolved, like
+	// --var-errs.
+	ExpectAllKeys bool
+	// ExpectAllVarsUsed fails when a variable from the vars files is not
+	// used, like --var-errs-unused.
+	ExpectAllVarsUsed bool
+}
+
+type Output struct {
+	Manifest  string
+	VarsStore string
+}
+
+// Interpolate applies the ops files to the manifest in order, then replaces
+// its placeholders with variables from the vars files and the vars store.
+// Variables defined in the manifest that are not in the vars store yet are
+// generated and returned with the rest of the store.
+func Interpolate(input Input) (Output, error) {
+	var doc interface{}
+	err := yaml.Unmarshal([]byte(input.Manifest), &doc)
+	if err != nil {
+		return Output{}, fmt.Errorf("parse manifest: %s", err)
+	}
+
+	for _, opsFile := range input.OpsFiles {
+		ops, err := parseOps([]byte(opsFile))
+		if err != nil {
+			return Output{}, err
+		}
+
+		doc, err = applyOps(doc, ops)
+		if err != nil {
+			return Output{}, err
+		}
+	}
+
+	static := map[interface{}]interface{}{}
+	for _, varsFile := range input.VarsFiles {
+		vars := map[interface{}]interface{}{}
+		err = yaml.Unmarshal([]byte(varsFile), &vars)
+		if err != nil {
+			return Output{}, fmt.Errorf("parse vars file: %s", err)
+		}
+
+		for name, value := range vars {
+			static[name] = value
+		}
+	}
+
+	store := map[interface{}]interface{}{}
+	err = yaml.Unmarshal([]byte(input.VarsStore), &store)
+	if err != nil {
+		return Output{}, fmt.Errorf("parse vars store: %s", err)
+	}
+
+	definitions, err := variableDefinitions(doc)
+	if err != nil {
+		return Output{}, err
+	}
+
+	r := newResolver(static, store, definitions)
+
+	doc, err = r.interpolate(doc)
+	if err != nil {
+		return Output{}, err
+	}
+
+	for _, d := range definitions {
+		_, _, err = r.get(d.Name)
+		if err != nil {
+			return Output{}, err
+		}
+	}
+
+	if missing := r.missingVariables(); input.ExpectAllKeys && len(missing) > 0 {
+		return Output{}, fmt.Errorf("Expected to find variables:\n  - %s", strings.Join(missing, "\n  - "))
+	}
+
+	if unused := r.unusedVariables(); input.ExpectAllVarsUsed && len(unused) > 0 {
+		return Output{}, fmt.Errorf("Expected to use variables:\n  - %s", strings.Join(unused, "\n  - "))
+	}
+
+	manifest, err := yaml.Marshal(doc)
+	if err != nil {
+		return Output{}, fmt.Errorf("marshal manifest: %s", err) //not tested
+	}
+
+	varsStore, err := yaml.Marshal(store)
+	if err != nil {
+		return Output{}, fmt.Errorf("marshal vars store: %s", err) //not tested
+	}
+
+	return Output{
+		Manifest:  string(manifest),
+		VarsStore: string(varsStore),
+	}, nil
+}
+
+// variableDefinitions reads the variables section of the manifest after the
+// ops files have been applied.
+func variableDefinitions(doc interface{}) ([]definition, error) {
+	m, ok := doc.(map[interface{}]interface{})
+	if !ok || m["variables"] == nil {
+		return nil, nil
+	}
+
+	contents, err := yaml.Marshal(m["variables"])
+	if err != nil {
+		return nil, fmt.Errorf("marshal variables: %s", err) //not tested
+	}
+
+	var definitions []definition
+	err = yaml.Unmarshal(contents, &definitions)
+	if err != nil {
+		return nil, fmt.Errorf("parse variables: %s", err)
+	}
+
+	return definitions, nil
+}
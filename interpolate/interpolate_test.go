@@ -0,0 +1,264 @@
+package interpolate_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/cloudfoundry/bosh-bootloader/interpolate"
+	"github.com/pivotal-cf-experimental/gomegamatchers"
+	yaml "gopkg.in/yaml.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Interpolate", func() {
+	Describe("ops files", func() {
+		const manifest = `
+name: some-name
+instance_groups:
+- name: bosh
+  jobs:
+  - name: nats
+  - name: director
+  properties:
+    tags: [a, b]
+`
+
+		DescribeTable("applying operations",
+			func(ops, expectedManifest string) {
+				output, err := interpolate.Interpolate(interpolate.Input{
+					Manifest: manifest,
+					OpsFiles: []string{ops},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output.Manifest).To(gomegamatchers.MatchYAML(expectedManifest))
+			},
+			Entry("replaces a map key",
+				"- type: replace\n  path: /name\n  value: some-other-name",
+				"name: some-other-name\ninstance_groups: [{name: bosh, jobs: [{name: nats}, {name: director}], properties: {tags: [a, b]}}]",
+			),
+			Entry("creates missing optional keys",
+				"- type: replace\n  path: /instance_groups/name=bosh/properties/director?/ssl/cert\n  value: some-cert",
+				"name: some-name\ninstance_groups: [{name: bosh, jobs: [{name: nats}, {name: director}], properties: {tags: [a, b], director: {ssl: {cert: some-cert}}}}]",
+			),
+			Entry("appends to an array",
+				"- type: replace\n  path: /instance_groups/0/jobs/-\n  value: {name: uaa}",
+				"name: some-name\ninstance_groups: [{name: bosh, jobs: [{name: nats}, {name: director}, {name: uaa}], properties: {tags: [a, b]}}]",
+			),
+			Entry("replaces an array item by name",
+				"- type: replace\n  path: /instance_groups/name=bosh/jobs/name=nats\n  value: {name: nats, release: bosh}",
+				"name: some-name\ninstance_groups: [{name: bosh, jobs: [{name: nats, release: bosh}, {name: director}], properties: {tags: [a, b]}}]",
+			),
+			Entry("appends a missing optional array item",
+				"- type: replace\n  path: /instance_groups/name=bosh/jobs/name=uaa?/release\n  value: uaa",
+				"name: some-name\ninstance_groups: [{name: bosh, jobs: [{name: nats}, {name: director}, {name: uaa, release: uaa}], properties: {tags: [a, b]}}]",
+			),
+			Entry("removes an array item",
+				"- type: remove\n  path: /instance_groups/name=bosh/jobs/name=nats",
+				"name: some-name\ninstance_groups: [{name: bosh, jobs: [{name: director}], properties: {tags: [a, b]}}]",
+			),
+			Entry("removes a map key",
+				"- type: remove\n  path: /instance_groups/0/properties",
+				"name: some-name\ninstance_groups: [{name: bosh, jobs: [{name: nats}, {name: director}]}]",
+			),
+			Entry("ignores a missing optional key on remove",
+				"- type: remove\n  path: /missing?",
+				manifest,
+			),
+			Entry("unescapes slashes in keys",
+				"- type: replace\n  path: /some~1key?\n  value: some-value",
+				manifest+"some/key: some-value",
+			),
+		)
+
+		DescribeTable("failing operations",
+			func(ops, expectedError string) {
+				_, err := interpolate.Interpolate(interpolate.Input{
+					Manifest: manifest,
+					OpsFiles: []string{ops},
+				})
+				Expect(err).To(MatchError(expectedError))
+			},
+			Entry("missing map key",
+				"- type: replace\n  path: /missing/key\n  value: 1",
+				`replace /missing/key: expected to find map key "missing"`,
+			),
+			Entry("missing array item",
+				"- type: replace\n  path: /instance_groups/name=uaa/jobs\n  value: []",
+				"replace /instance_groups/name=uaa/jobs: expected to find an array item with name=uaa",
+			),
+			Entry("index out of range",
+				"- type: remove\n  path: /instance_groups/3",
+				"remove /instance_groups/3: expected to find array index 3 but found 1 items",
+			),
+			Entry("unsupported operation type",
+				"- type: test\n  path: /name",
+				`unsupported operation type "test" for path "/name"`,
+			),
+			Entry("relative path",
+				"- type: replace\n  path: name\n  value: 1",
+				`expected path "name" to start with /`,
+			),
+		)
+	})
+
+	Describe("variables", func() {
+		It("replaces placeholders with variables from the vars files", func() {
+			output, err := interpolate.Interpolate(interpolate.Input{
+				Manifest:  "ip: ((internal_ip))\nurl: https://((internal_ip)):((port))\ntags: ((tags))\nnested: ((creds.password))",
+				VarsFiles: []string{"internal_ip: 10.0.0.6\nport: 25555\ntags: [a, b]\ncreds: {password: some-password}"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output.Manifest).To(gomegamatchers.MatchYAML("ip: 10.0.0.6\nurl: https://10.0.0.6:25555\ntags: [a, b]\nnested: some-password"))
+		})
+
+		It("prefers the vars files over the vars store", func() {
+			output, err := interpolate.Interpolate(interpolate.Input{
+				Manifest:  "password: ((password))",
+				VarsFiles: []string{"password: from-vars-file"},
+				VarsStore: "password: from-vars-store",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output.Manifest).To(gomegamatchers.MatchYAML("password: from-vars-file"))
+		})
+
+		It("leaves missing variables alone unless all keys are expected", func() {
+			input := interpolate.Input{Manifest: "a: ((b))\nc: ((d))"}
+
+			output, err := interpolate.Interpolate(input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Manifest).To(gomegamatchers.MatchYAML("a: ((b))\nc: ((d))"))
+
+			input.ExpectAllKeys = true
+			_, err = interpolate.Interpolate(input)
+			Expect(err).To(MatchError("Expected to find variables:\n  - b\n  - d"))
+		})
+
+		It("fails on unused variables when all variables are expected to be used", func() {
+			_, err := interpolate.Interpolate(interpolate.Input{
+				Manifest:          "a: ((b))",
+				VarsFiles:         []string{"b: 1\nc: 2"},
+				ExpectAllVarsUsed: true,
+			})
+			Expect(err).To(MatchError("Expected to use variables:\n  - c"))
+		})
+
+		It("fails when a map is interpolated within a string", func() {
+			_, err := interpolate.Interpolate(interpolate.Input{
+				Manifest:  "a: prefix-((b))",
+				VarsFiles: []string{"b: {c: d}"},
+			})
+			Expect(err).To(MatchError(`variable "b" is a map[interface {}]interface {}, only strings and numbers can be interpolated within a string`))
+		})
+	})
+
+	Describe("generated variables", func() {
+		var (
+			output interpolate.Output
+			store  map[string]map[string]string
+		)
+
+		BeforeEach(func() {
+			var err error
+			output, err = interpolate.Interpolate(interpolate.Input{
+				Manifest: `
+password: ((admin_password))
+certificate: ((director_ssl.certificate))
+ssh_key: ((jumpbox_ssh.public_key))
+variables:
+- name: admin_password
+  type: password
+- name: director_ssl
+  type: certificate
+  options:
+    ca: default_ca
+    common_name: ((internal_ip))
+    alternative_names: [((internal_ip)), some-host]
+    extended_key_usage: [server_auth]
+- name: default_ca
+  type: certificate
+  options:
+    is_ca: true
+    common_name: ca
+- name: jumpbox_ssh
+  type: ssh
+- name: jwt_signing_key
+  type: rsa
+`,
+				VarsFiles: []string{"internal_ip: 10.0.0.6"},
+				VarsStore: "admin_password: some-admin-password",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var rawStore map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(output.VarsStore), &rawStore)).To(Succeed())
+
+			store = map[string]map[string]string{}
+			for name, value := range rawStore {
+				if m, ok := value.(map[interface{}]interface{}); ok {
+					store[name] = map[string]string{}
+					for k, v := range m {
+						store[name][k.(string)] = v.(string)
+					}
+				}
+			}
+		})
+
+		It("keeps variables that are already in the vars store", func() {
+			Expect(output.VarsStore).To(ContainSubstring("admin_password: some-admin-password"))
+			Expect(output.Manifest).To(ContainSubstring("password: some-admin-password"))
+		})
+
+		It("generates certificates signed by their ca", func() {
+			Expect(store).To(HaveKey("default_ca"))
+			Expect(store).To(HaveKey("director_ssl"))
+
+			ca := parseCertificate(store["default_ca"]["certificate"])
+			Expect(ca.IsCA).To(BeTrue())
+			Expect(ca.Subject.CommonName).To(Equal("ca"))
+
+			certificate := parseCertificate(store["director_ssl"]["certificate"])
+			Expect(certificate.Subject.CommonName).To(Equal("10.0.0.6"))
+			Expect(certificate.DNSNames).To(Equal([]string{"some-host"}))
+			Expect(certificate.IPAddresses[0].String()).To(Equal("10.0.0.6"))
+			Expect(certificate.ExtKeyUsage).To(Equal([]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}))
+			Expect(certificate.CheckSignatureFrom(ca)).To(Succeed())
+
+			Expect(store["director_ssl"]["ca"]).To(Equal(store["default_ca"]["certificate"]))
+			Expect(store["director_ssl"]["private_key"]).To(ContainSubstring("BEGIN RSA PRIVATE KEY"))
+		})
+
+		It("generates ssh keys", func() {
+			Expect(store["jumpbox_ssh"]["private_key"]).To(ContainSubstring("BEGIN RSA PRIVATE KEY"))
+			Expect(store["jumpbox_ssh"]["public_key"]).To(HavePrefix("ssh-rsa "))
+			Expect(store["jumpbox_ssh"]["public_key_fingerprint"]).To(MatchRegexp(`^([0-9a-f]{2}:){15}[0-9a-f]{2}$`))
+			Expect(output.Manifest).To(ContainSubstring(store["jumpbox_ssh"]["public_key"]))
+		})
+
+		It("generates rsa keys that are defined but not used", func() {
+			Expect(store["jwt_signing_key"]["private_key"]).To(ContainSubstring("BEGIN RSA PRIVATE KEY"))
+			Expect(store["jwt_signing_key"]["public_key"]).To(ContainSubstring("BEGIN PUBLIC KEY"))
+		})
+
+		It("fails for unsupported variable types", func() {
+			_, err := interpolate.Interpolate(interpolate.Input{
+				Manifest: "variables:\n- name: some-value\n  type: some-type",
+			})
+			Expect(err).To(MatchError(`variable "some-value" has unsupported type "some-type"`))
+		})
+	})
+})
+
+func parseCertificate(contents string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(contents))
+	Expect(block).NotTo(BeNil())
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	Expect(err).NotTo(HaveOccurred())
+
+	return certificate
+}
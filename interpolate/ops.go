@@ -0,0 +1,343 @@
+package interpolate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// op is one entry of a bosh ops file. Only the replace and remove types are
+// supported, which is all that bosh-deployment, jumpbox-deployment and the
+// cloud config templates use.
+type op struct {
+	Type  string      `yaml:"type"`
+	Path  string      `yaml:"path"`
+	Value interface{} `yaml:"value"`
+}
+
+type token struct {
+	key      string
+	index    int
+	matchKey string
+	matchVal string
+	optional bool
+	kind     tokenKind
+}
+
+type tokenKind int
+
+const (
+	keyToken tokenKind = iota
+	indexToken
+	matchToken
+	appendToken
+)
+
+func parseOps(contents []byte) ([]op, error) {
+	var ops []op
+	err := yaml.Unmarshal(contents, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("parse ops file: %s", err)
+	}
+
+	for _, o := range ops {
+		if o.Type != "replace" && o.Type != "remove" {
+			return nil, fmt.Errorf("unsupported operation type %q for path %q", o.Type, o.Path)
+		}
+	}
+
+	return ops, nil
+}
+
+// parsePath splits a path like /instance_groups/name=bosh/jobs/-/name? into
+// tokens. Once a token is marked optional with a trailing "?", every token
+// after it is optional too, so that missing parents are created.
+func parsePath(path string) ([]token, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("expected path %q to start with /", path)
+	}
+
+	if path == "/" {
+		return []token{}, nil
+	}
+
+	var tokens []token
+	optional := false
+	for _, part := range strings.Split(path[1:], "/") {
+		part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+
+		if strings.HasSuffix(part, "?") {
+			optional = true
+			part = strings.TrimSuffix(part, "?")
+		}
+
+		t := token{optional: optional}
+		switch {
+		case part == "-":
+			t.kind = appendToken
+		case strings.Contains(part, "="):
+			pieces := strings.SplitN(part, "=", 2)
+			t.kind = matchToken
+			t.matchKey = pieces[0]
+			t.matchVal = pieces[1]
+		default:
+			index, err := strconv.Atoi(part)
+			if err == nil {
+				t.kind = indexToken
+				t.index = index
+			} else {
+				t.kind = keyToken
+				t.key = part
+			}
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	return tokens, nil
+}
+
+func applyOps(doc interface{}, ops []op) (interface{}, error) {
+	for _, o := range ops {
+		tokens, err := parsePath(o.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch o.Type {
+		case "replace":
+			doc, err = replace(doc, tokens, o.Value)
+		case "remove":
+			if len(tokens) == 0 {
+				return nil, errors.New("cannot remove the root of the document")
+			}
+			doc, err = remove(doc, tokens)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %s", o.Type, o.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// replace returns node with the value at tokens set to value.
+func replace(node interface{}, tokens []token, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	t, rest := tokens[0], tokens[1:]
+	if node == nil && t.optional {
+		node = emptyContainer(t)
+	}
+
+	switch t.kind {
+	case keyToken:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a map at key %q, found %T", t.key, node)
+		}
+
+		child, found := m[t.key]
+		if !found {
+			if !t.optional {
+				return nil, fmt.Errorf("expected to find map key %q", t.key)
+			}
+			if len(rest) > 0 {
+				child = emptyContainer(rest[0])
+			}
+		}
+
+		newChild, err := replace(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		m[t.key] = newChild
+		return m, nil
+
+	case indexToken:
+		items, ok := node.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array at index %d, found %T", t.index, node)
+		}
+
+		index := t.index
+		if index < 0 {
+			index += len(items)
+		}
+		if index < 0 || index >= len(items) {
+			return nil, fmt.Errorf("expected to find array index %d but found %d items", t.index, len(items))
+		}
+
+		newChild, err := replace(items[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		items[index] = newChild
+		return items, nil
+
+	case appendToken:
+		items, ok := node.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array to append to, found %T", node)
+		}
+
+		var child interface{}
+		if len(rest) > 0 {
+			child = emptyContainer(rest[0])
+		}
+
+		newChild, err := replace(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		return append(items, newChild), nil
+
+	case matchToken:
+		items, ok := node.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array at %s=%s, found %T", t.matchKey, t.matchVal, node)
+		}
+
+		index, err := findMatch(items, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if index == -1 {
+			if !t.optional {
+				return nil, fmt.Errorf("expected to find an array item with %s=%s", t.matchKey, t.matchVal)
+			}
+
+			if len(rest) == 0 {
+				return append(items, value), nil
+			}
+
+			newChild, err := replace(map[interface{}]interface{}{t.matchKey: t.matchVal}, rest, value)
+			if err != nil {
+				return nil, err
+			}
+			return append(items, newChild), nil
+		}
+
+		newChild, err := replace(items[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		items[index] = newChild
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("unknown path token %+v", t) //not tested
+}
+
+// remove returns node without the value at tokens. Removing an optional key
+// or item that does not exist is not an error.
+func remove(node interface{}, tokens []token) (interface{}, error) {
+	t, rest := tokens[0], tokens[1:]
+
+	switch t.kind {
+	case keyToken:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			if node == nil && t.optional {
+				return node, nil
+			}
+			return nil, fmt.Errorf("expected a map at key %q, found %T", t.key, node)
+		}
+
+		child, found := m[t.key]
+		if !found {
+			if t.optional {
+				return m, nil
+			}
+			return nil, fmt.Errorf("expected to find map key %q", t.key)
+		}
+
+		if len(rest) == 0 {
+			delete(m, t.key)
+			return m, nil
+		}
+
+		newChild, err := remove(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		m[t.key] = newChild
+		return m, nil
+
+	case indexToken, matchToken:
+		items, ok := node.([]interface{})
+		if !ok {
+			if node == nil && t.optional {
+				return node, nil
+			}
+			return nil, fmt.Errorf("expected an array, found %T", node)
+		}
+
+		index := t.index
+		if t.kind == matchToken {
+			var err error
+			index, err = findMatch(items, t)
+			if err != nil {
+				return nil, err
+			}
+			if index == -1 {
+				if t.optional {
+					return items, nil
+				}
+				return nil, fmt.Errorf("expected to find an array item with %s=%s", t.matchKey, t.matchVal)
+			}
+		} else if index < 0 {
+			index += len(items)
+		}
+
+		if index < 0 || index >= len(items) {
+			return nil, fmt.Errorf("expected to find array index %d but found %d items", t.index, len(items))
+		}
+
+		if len(rest) == 0 {
+			return append(items[:index], items[index+1:]...), nil
+		}
+
+		newChild, err := remove(items[index], rest)
+		if err != nil {
+			return nil, err
+		}
+		items[index] = newChild
+		return items, nil
+	}
+
+	return nil, errors.New("cannot remove the end of an array")
+}
+
+func findMatch(items []interface{}, t token) (int, error) {
+	found := -1
+	for i, item := range items {
+		m, ok := item.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		if v, ok := m[t.matchKey]; ok && fmt.Sprintf("%v", v) == t.matchVal {
+			if found != -1 {
+				return -1, fmt.Errorf("expected to find exactly one array item with %s=%s but found more", t.matchKey, t.matchVal)
+			}
+			found = i
+		}
+	}
+
+	return found, nil
+}
+
+// emptyContainer returns the value to create for a missing parent of t.
+func emptyContainer(t token) interface{} {
+	if t.kind == keyToken {
+		return map[interface{}]interface{}{}
+	}
+	return []interface{}{}
+}
@@ -0,0 +1,208 @@
+package interpolate
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholderRegexp = regexp.MustCompile(`\(\((!?[-/\.\w\pL]+)\)\)`)
+
+// resolver looks variables up in the vars files first and the vars store
+// second. Variables that are in neither but have a definition in the
+// manifest are generated and added to the store.
+type resolver struct {
+	static      map[interface{}]interface{}
+	store       map[interface{}]interface{}
+	definitions map[string]definition
+	generating  map[string]bool
+
+	used    map[string]bool
+	missing map[string]bool
+}
+
+func newResolver(static, store map[interface{}]interface{}, definitions []definition) *resolver {
+	r := &resolver{
+		static:      static,
+		store:       store,
+		definitions: map[string]definition{},
+		generating:  map[string]bool{},
+		used:        map[string]bool{},
+		missing:     map[string]bool{},
+	}
+
+	for _, d := range definitions {
+		r.definitions[d.Name] = d
+	}
+
+	return r
+}
+
+// get returns the value of the variable name, generating it if needed.
+func (r *resolver) get(name string) (interface{}, bool, error) {
+	if value, ok := r.static[name]; ok {
+		r.used[name] = true
+		return value, true, nil
+	}
+
+	if value, ok := r.store[name]; ok {
+		return value, true, nil
+	}
+
+	d, ok := r.definitions[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if r.generating[name] {
+		return nil, false, fmt.Errorf("variable %q refers to itself", name)
+	}
+	r.generating[name] = true
+	defer delete(r.generating, name)
+
+	// Options such as the common name of a certificate can be placeholders.
+	_, err := r.interpolate(d.Options)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err := generate(d.Name, d.Type, d.Options, r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.store[name] = value
+	return value, true, nil
+}
+
+// interpolate replaces the placeholders in node. A string that is a single
+// placeholder is replaced by the value of the variable, whatever its type.
+// Placeholders inside longer strings must refer to strings or numbers.
+// Placeholders that cannot be resolved are left as they are and recorded
+// as missing.
+func (r *resolver) interpolate(node interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range n {
+			newValue, err := r.interpolate(value)
+			if err != nil {
+				return nil, err
+			}
+			n[key] = newValue
+		}
+		return n, nil
+
+	case []interface{}:
+		for i, value := range n {
+			newValue, err := r.interpolate(value)
+			if err != nil {
+				return nil, err
+			}
+			n[i] = newValue
+		}
+		return n, nil
+
+	case string:
+		return r.interpolateString(n)
+	}
+
+	return node, nil
+}
+
+func (r *resolver) interpolateString(s string) (interface{}, error) {
+	matches := placeholderRegexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		value, found, err := r.lookup(s[matches[0][2]:matches[0][3]])
+		if err != nil || !found {
+			return s, err
+		}
+		return value, nil
+	}
+
+	var result bytes.Buffer
+	last := 0
+	for _, match := range matches {
+		result.WriteString(s[last:match[0]])
+		last = match[1]
+
+		name := s[match[2]:match[3]]
+		value, found, err := r.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			result.WriteString(s[match[0]:match[1]])
+			continue
+		}
+
+		switch value.(type) {
+		case string, int, int64, uint64, float64:
+			fmt.Fprintf(&result, "%v", value)
+		default:
+			return nil, fmt.Errorf("variable %q is a %T, only strings and numbers can be interpolated within a string", name, value)
+		}
+	}
+	result.WriteString(s[last:])
+
+	return result.String(), nil
+}
+
+// lookup resolves a placeholder name like director_ssl.certificate, where
+// everything after the first dot indexes into the value of the variable.
+func (r *resolver) lookup(placeholder string) (interface{}, bool, error) {
+	parts := strings.Split(strings.TrimPrefix(placeholder, "!"), ".")
+
+	value, found, err := r.get(parts[0])
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		r.missing[parts[0]] = true
+		return nil, false, nil
+	}
+
+	for _, key := range parts[1:] {
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("expected variable %q to be a map to look up %q", parts[0], key)
+		}
+
+		value, ok = m[key]
+		if !ok {
+			return nil, false, fmt.Errorf("expected to find key %q in variable %q", key, parts[0])
+		}
+	}
+
+	return value, true, nil
+}
+
+func (r *resolver) missingVariables() []string {
+	return sortedKeys(r.missing)
+}
+
+func (r *resolver) unusedVariables() []string {
+	unused := map[string]bool{}
+	for name := range r.static {
+		key := fmt.Sprintf("%v", name)
+		if !r.used[key] {
+			unused[key] = true
+		}
+	}
+	return sortedKeys(unused)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+)
+
+// DirectorPorts are the ports of the jumpbox, the director and the VMs it
+// deploys that the BOSH and CredHub CLIs connect to: SSH, the agent, UAA,
+// CredHub, the blobstore and the director API.
+var DirectorPorts = []int{22, 6868, 8443, 8844, 25250, 25555}
+
+// Allowlist confines the destinations a proxy forwards connections to. A
+// destination must be in one of the networks and use one of the ports.
+type Allowlist struct {
+	Networks []*net.IPNet
+	Ports    []int
+}
+
+func NewAllowlist(cidrs []string, ports []int) (Allowlist, error) {
+	allowlist := Allowlist{Ports: ports}
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return Allowlist{}, fmt.Errorf("allowlist: %s", err)
+		}
+		allowlist.Networks = append(allowlist.Networks, network)
+	}
+
+	return allowlist, nil
+}
+
+func (a Allowlist) Allows(ip net.IP, port int) bool {
+	return a.allowsIP(ip) && a.allowsPort(port)
+}
+
+func (a Allowlist) allowsIP(ip net.IP) bool {
+	for _, network := range a.Networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a Allowlist) allowsPort(port int) bool {
+	for _, allowed := range a.Ports {
+		if allowed == port {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package proxy_test
+
+import (
+	"net"
+
+	"github.com/cloudfoundry/bosh-bootloader/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Allowlist", func() {
+	var allowlist proxy.Allowlist
+
+	BeforeEach(func() {
+		var err error
+		allowlist, err = proxy.NewAllowlist([]string{"10.0.0.0/16", "192.168.1.0/24"}, proxy.DirectorPorts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("Allows",
+		func(ip string, port int, allowed bool) {
+			Expect(allowlist.Allows(net.ParseIP(ip), port)).To(Equal(allowed))
+		},
+		Entry("the director api", "10.0.0.6", 25555, true),
+		Entry("the agent of the director", "10.0.0.6", 6868, true),
+		Entry("uaa", "10.0.0.6", 8443, true),
+		Entry("credhub", "10.0.0.6", 8844, true),
+		Entry("ssh to a deployed vm", "10.0.16.4", 22, true),
+		Entry("a vm in another network", "192.168.1.10", 25555, true),
+		Entry("another port of the director", "10.0.0.6", 5432, false),
+		Entry("an address outside of the networks", "8.8.8.8", 22, false),
+		Entry("the loopback address", "127.0.0.1", 25555, false),
+	)
+
+	It("allows nothing when it is empty", func() {
+		Expect(proxy.Allowlist{}.Allows(net.ParseIP("10.0.0.6"), 25555)).To(BeFalse())
+	})
+
+	It("returns an error when a cidr cannot be parsed", func() {
+		_, err := proxy.NewAllowlist([]string{"banana"}, proxy.DirectorPorts)
+		Expect(err).To(MatchError("allowlist: invalid CIDR address: banana"))
+	})
+})
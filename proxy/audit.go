@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	socks5 "github.com/armon/go-socks5"
+
+	"golang.org/x/net/context"
+)
+
+type requestKey struct{}
+
+// audit records every connection a client asks the proxy for, one line per
+// connection. A nil audit records nothing.
+type audit struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func newAudit(writer io.Writer) *audit {
+	if writer == nil {
+		return nil
+	}
+
+	return &audit{writer: writer}
+}
+
+func (a *audit) record(format string, args ...interface{}) {
+	if a == nil {
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	fmt.Fprintf(a.writer, "%s %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+func (a *audit) recordRequest(action string, req *socks5.Request, reason string) {
+	a.record("%s %s -> %s: %s", action, req.RemoteAddr.Address(), req.DestAddr, reason)
+}
+
+// recordDial records the outcome of a connection that the rules have let
+// through, which they pass on in ctx.
+func (a *audit) recordDial(ctx context.Context, err error) {
+	req, ok := ctx.Value(requestKey{}).(*socks5.Request)
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		a.recordRequest("fail", req, err.Error())
+		return
+	}
+
+	a.record("allow %s -> %s", req.RemoteAddr.Address(), req.DestAddr)
+}
+
+type allowlistRules struct {
+	allowlist Allowlist
+	audit     *audit
+}
+
+func (r allowlistRules) Allow(ctx context.Context, req *socks5.Request) (context.Context, bool) {
+	if req.Command != socks5.ConnectCommand {
+		r.audit.recordRequest("deny", req, "only connect is supported")
+		return ctx, false
+	}
+
+	if !r.allowlist.Allows(req.DestAddr.IP, req.DestAddr.Port) {
+		r.audit.recordRequest("deny", req, "not in the allowlist")
+		return ctx, false
+	}
+
+	return context.WithValue(ctx, requestKey{}, req), true
+}
+
+// credentials accepts the user name and the password of the session.
+type credentials struct {
+	password string
+	audit    *audit
+}
+
+func (c credentials) Valid(user, password string) bool {
+	if user == username && subtle.ConstantTimeCompare([]byte(password), []byte(c.password)) == 1 {
+		return true
+	}
+
+	c.audit.record("deny authentication as %q", user)
+	return false
+}
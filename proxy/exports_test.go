@@ -9,3 +9,7 @@ func SetNetListen(f func(net, laddr string) (net.Listener, error)) {
 func ResetNetListen() {
 	netListen = net.Listen
 }
+
+func (s *Socks5Proxy) CloseConnection() {
+	s.sshClient().Close()
+}
@@ -71,55 +71,62 @@ func startSSHServer(httpServerURL string) string {
 	}
 
 	go func() {
-		nConn, err := listener.Accept()
-		if err != nil {
-			log.Fatal("failed to accept incoming connection: ", err)
-		}
+		for {
+			nConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
 
-		_, chans, reqs, err := ssh.NewServerConn(nConn, config)
-		if err != nil {
-			log.Fatal("failed to handshake: ", err)
+			go serveSSHConn(nConn, config, httpServerURL)
 		}
-		go ssh.DiscardRequests(reqs)
+	}()
 
-		for newChannel := range chans {
-			if newChannel.ChannelType() != "direct-tcpip" {
-				newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
-				continue
-			}
-			channel, _, err := newChannel.Accept()
-			if err != nil {
-				log.Fatalf("Could not accept channel: %v", err)
-			}
-			defer channel.Close()
+	return listener.Addr().String()
+}
 
-			data, err := bufio.NewReader(channel).ReadString('\n')
-			if err != nil {
-				log.Fatalf("Can't read data from channel: %v", err)
-			}
+func serveSSHConn(nConn net.Conn, config *ssh.ServerConfig, httpServerURL string) {
+	_, chans, reqs, err := ssh.NewServerConn(nConn, config)
+	if err != nil {
+		// the client rejected the host key
+		return
+	}
+	go ssh.DiscardRequests(reqs)
 
-			httpConn, err := net.Dial("tcp", httpServerURL)
-			if err != nil {
-				log.Fatalf("Could not open connection to http server: %v", err)
-			}
-			defer httpConn.Close()
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, _, err := newChannel.Accept()
+		if err != nil {
+			log.Fatalf("Could not accept channel: %v", err)
+		}
+		defer channel.Close()
 
-			_, err = httpConn.Write([]byte(data + "\r\n\r\n"))
-			if err != nil {
-				log.Fatalf("Could not write to http server: %v", err)
-			}
+		data, err := bufio.NewReader(channel).ReadString('\n')
+		if err != nil {
+			log.Fatalf("Can't read data from channel: %v", err)
+		}
 
-			data, err = bufio.NewReader(httpConn).ReadString('\n')
-			if err != nil {
-				log.Fatalf("Can't read data from http conn: %v", err)
-			}
+		httpConn, err := net.Dial("tcp", httpServerURL)
+		if err != nil {
+			log.Fatalf("Could not open connection to http server: %v", err)
+		}
+		defer httpConn.Close()
 
-			_, err = channel.Write([]byte(data))
-			if err != nil {
-				log.Fatalf("Can't write data to channel: %v", err)
-			}
+		_, err = httpConn.Write([]byte(data + "\r\n\r\n"))
+		if err != nil {
+			log.Fatalf("Could not write to http server: %v", err)
 		}
-	}()
 
-	return listener.Addr().String()
+		data, err = bufio.NewReader(httpConn).ReadString('\n')
+		if err != nil {
+			log.Fatalf("Can't read data from http conn: %v", err)
+		}
+
+		_, err = channel.Write([]byte(data))
+		if err != nil {
+			log.Fatalf("Can't write data to channel: %v", err)
+		}
+	}
 }
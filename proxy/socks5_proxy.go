@@ -1,14 +1,32 @@
 package proxy
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"net"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	socks5 "github.com/armon/go-socks5"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/net/context"
+	goproxy "golang.org/x/net/proxy"
+)
+
+const (
+	keepAliveRequest = "keepalive@openssh.com"
+
+	username = "bbl"
+
+	defaultPortBase  = 20000
+	defaultPortRange = 10000
 )
 
 var netListen = net.Listen
@@ -18,6 +36,13 @@ type Socks5Proxy struct {
 	hostKeyGetter hostKeyGetter
 	port          int
 	started       bool
+	hostKey       ssh.PublicKey
+	password      string
+
+	mutex        sync.Mutex
+	url          string
+	clientConfig *ssh.ClientConfig
+	client       *ssh.Client
 }
 
 type logger interface {
@@ -37,64 +62,299 @@ func NewSocks5Proxy(logger logger, hostKeyGetter hostKeyGetter, port int) *Socks
 	}
 }
 
-func (s *Socks5Proxy) Start(key, url string) error {
+// Start connects to the jumpbox and serves a socks5 proxy through it. The
+// jumpbox must present hostKey, in authorized_keys format. Without a hostKey
+// the key the jumpbox presents is trusted, which is only meant for a jumpbox
+// that create-env has just deployed and for states that have no key yet.
+// Only connections to destinations on the allowlist are forwarded.
+func (s *Socks5Proxy) Start(key, hostKey, url string, allowlist Allowlist) error {
 	if s.started {
 		return nil
 	}
 
-	signer, err := ssh.ParsePrivateKey([]byte(key))
+	err := s.connect(key, hostKey, url)
 	if err != nil {
 		return err
 	}
 
-	hostKey, err := s.hostKeyGetter.Get(key, url)
+	server, err := s.newServer(allowlistRules{allowlist: allowlist}, nil)
 	if err != nil {
+		return err //not tested
+	}
+
+	if s.port == 0 {
+		s.port, err = openPort()
+		if err != nil {
+			return err
+		}
+	}
+	go func() {
+		err = server.ListenAndServe("tcp", fmt.Sprintf("127.0.0.1:%d", s.port))
+		if err != nil {
+			s.logger.Println(fmt.Sprintf("err: failed to start socks5 proxy: %s", err.Error()))
+		}
+	}()
+
+	s.started = true
+	return nil
+}
+
+// StartOnPort is Start for a proxy that outlives a single bbl command. It
+// listens on port before it returns, so that a port that is taken is an
+// error rather than a log line, and connections that fail because the jumpbox
+// has gone away reconnect to it. Only connections to destinations on the
+// allowlist are forwarded, and every connection is recorded in auditLog.
+func (s *Socks5Proxy) StartOnPort(key, hostKey, url string, port int, allowlist Allowlist, auditLog io.Writer) error {
+	if s.started {
+		return nil
+	}
+
+	err := s.connect(key, hostKey, url)
+	if err != nil {
+		return err
+	}
+
+	audit := newAudit(auditLog)
+	server, err := s.newServer(allowlistRules{allowlist: allowlist, audit: audit}, audit)
+	if err != nil {
+		// not tested
+		s.client.Close()
 		return err
 	}
 
+	listener, err := netListen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		s.client.Close()
+		return err
+	}
+
+	_, listenPort, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		return err //not tested
+	}
+
+	s.port, err = strconv.Atoi(listenPort)
+	if err != nil {
+		return err //not tested
+	}
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil {
+			s.logger.Println(fmt.Sprintf("err: socks5 proxy stopped: %s", err.Error()))
+		}
+	}()
+
+	s.started = true
+	return nil
+}
+
+// KeepAlive checks the connection to the jumpbox every interval until stop is
+// closed, and reconnects when the jumpbox does not answer.
+func (s *Socks5Proxy) KeepAlive(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			client := s.sshClient()
+			if isAlive(client, interval) {
+				continue
+			}
+
+			s.logger.Println("lost the connection to the jumpbox, reconnecting")
+			_, err := s.reconnect(client)
+			if err != nil {
+				s.logger.Println(fmt.Sprintf("err: failed to reconnect to the jumpbox: %s", err.Error()))
+			}
+		}
+	}
+}
+
+func (s *Socks5Proxy) Addr() string {
+	return fmt.Sprintf("127.0.0.1:%d", s.port)
+}
+
+// URL returns the address of the proxy with the credentials of this session,
+// in the format BOSH_ALL_PROXY expects.
+func (s *Socks5Proxy) URL() string {
+	return fmt.Sprintf("socks5://%s:%s@%s", username, s.password, s.Addr())
+}
+
+// Auth returns the credentials of this session. They are generated when the
+// proxy starts, and a client has to present them to use the proxy.
+func (s *Socks5Proxy) Auth() *goproxy.Auth {
+	return &goproxy.Auth{
+		User:     username,
+		Password: s.password,
+	}
+}
+
+// HostKey returns the host key of the jumpbox the proxy is connected to, in
+// authorized_keys format.
+func (s *Socks5Proxy) HostKey() string {
+	if s.hostKey == nil {
+		return ""
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(s.hostKey)))
+}
+
+// DefaultPort is the port bbl proxy listens on for the environment when no
+// --port is given. It is derived from the env id, so that the proxy of an
+// environment keeps its port and proxies for several environments can run at
+// once.
+func DefaultPort(envID string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(envID))
+
+	return defaultPortBase + int(hash.Sum32()%defaultPortRange)
+}
+
+func (s *Socks5Proxy) connect(key, hostKey, url string) error {
+	signer, err := ssh.ParsePrivateKey([]byte(key))
+	if err != nil {
+		return err
+	}
+
+	var pinnedKey ssh.PublicKey
+	if hostKey == "" {
+		pinnedKey, err = s.hostKeyGetter.Get(key, url)
+		if err != nil {
+			return err
+		}
+	} else {
+		pinnedKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(hostKey))
+		if err != nil {
+			return fmt.Errorf("parse jumpbox host key: %s", err)
+		}
+	}
+
 	clientConfig := &ssh.ClientConfig{
 		User: "jumpbox",
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
-		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		HostKeyCallback: verifyHostKey(pinnedKey),
 	}
 
-	serverConn, err := ssh.Dial("tcp", url, clientConfig)
+	client, err := ssh.Dial("tcp", url, clientConfig)
 	if err != nil {
 		return err
 	}
 
-	conf := &socks5.Config{
+	s.mutex.Lock()
+	s.url = url
+	s.clientConfig = clientConfig
+	s.client = client
+	s.hostKey = pinnedKey
+	s.mutex.Unlock()
+
+	if s.password == "" {
+		s.password, err = generatePassword()
+		if err != nil {
+			return err //not tested
+		}
+	}
+
+	return nil
+}
+
+func (s *Socks5Proxy) newServer(rules socks5.RuleSet, audit *audit) (*socks5.Server, error) {
+	return socks5.New(&socks5.Config{
+		Credentials: credentials{password: s.password, audit: audit},
+		Rules:       rules,
 		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return serverConn.Dial(network, addr)
+			conn, err := s.dial(ctx, network, addr)
+			audit.recordDial(ctx, err)
+			return conn, err
 		},
+	})
+}
+
+func (s *Socks5Proxy) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client := s.sshClient()
+
+	conn, err := client.Dial(network, addr)
+	if err == nil || isAlive(client, 10*time.Second) {
+		return conn, err
 	}
-	server, err := socks5.New(conf)
+
+	client, err = s.reconnect(client)
 	if err != nil {
-		// not tested
-		return err
+		return nil, err
 	}
 
-	if s.port == 0 {
-		s.port, err = openPort()
-		if err != nil {
-			return err
-		}
+	return client.Dial(network, addr)
+}
+
+func (s *Socks5Proxy) sshClient() *ssh.Client {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.client
+}
+
+// reconnect replaces broken with a new connection to the jumpbox, unless
+// another connection has replaced it already.
+func (s *Socks5Proxy) reconnect(broken *ssh.Client) (*ssh.Client, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.client != broken {
+		return s.client, nil
+	}
+
+	broken.Close()
+
+	client, err := ssh.Dial("tcp", s.url, s.clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	s.client = client
+	s.logger.Println("reconnected to the jumpbox")
+
+	return client, nil
+}
+
+func generatePassword() (string, error) {
+	password := make([]byte, 16)
+	_, err := rand.Read(password)
+	if err != nil {
+		return "", err //not tested
 	}
+
+	return hex.EncodeToString(password), nil
+}
+
+func isAlive(client *ssh.Client, timeout time.Duration) bool {
+	result := make(chan error, 1)
 	go func() {
-		err = server.ListenAndServe("tcp", fmt.Sprintf("127.0.0.1:%d", s.port))
-		if err != nil {
-			s.logger.Println(fmt.Sprintf("err: failed to start socks5 proxy: %s", err.Error()))
-		}
+		_, _, err := client.SendRequest(keepAliveRequest, true, nil)
+		result <- err
 	}()
 
-	s.started = true
-	return nil
+	select {
+	case err := <-result:
+		return err == nil
+	case <-time.After(timeout):
+		return false
+	}
 }
 
-func (s *Socks5Proxy) Addr() string {
-	return fmt.Sprintf("127.0.0.1:%d", s.port)
+func verifyHostKey(pinnedKey ssh.PublicKey) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if bytes.Equal(key.Marshal(), pinnedKey.Marshal()) {
+			return nil
+		}
+
+		return fmt.Errorf("jumpbox host key mismatch: %s presented the %s key %s, but the state has the %s key %s. "+
+			"If the jumpbox has been recreated, run \"bbl up --recreate\" to record its new host key",
+			hostname, key.Type(), ssh.FingerprintSHA256(key), pinnedKey.Type(), ssh.FingerprintSHA256(pinnedKey))
+	}
 }
 
 func openPort() (int, error) {
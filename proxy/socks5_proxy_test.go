@@ -2,10 +2,14 @@ package proxy_test
 
 import (
 	"bufio"
+	"crypto/rand"
+	"crypto/rsa"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
 	"time"
 
@@ -17,6 +21,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("Socks5Proxy", func() {
@@ -29,6 +34,7 @@ var _ = Describe("Socks5Proxy", func() {
 			sshServerURL       string
 			httpServerHostPort string
 			httpServer         *httptest.Server
+			allowlist          proxy.Allowlist
 		)
 
 		BeforeEach(func() {
@@ -45,6 +51,9 @@ var _ = Describe("Socks5Proxy", func() {
 			hostKeyGetter = &fakes.HostKeyGetter{}
 			hostKeyGetter.GetCall.Returns.HostKey = signer.PublicKey()
 
+			allowlist, err = proxy.NewAllowlist([]string{"127.0.0.0/8"}, []int{httpServer.Listener.Addr().(*net.TCPAddr).Port})
+			Expect(err).NotTo(HaveOccurred())
+
 			logger = &fakes.Logger{}
 			socks5Proxy = proxy.NewSocks5Proxy(logger, hostKeyGetter, 0)
 		})
@@ -54,14 +63,14 @@ var _ = Describe("Socks5Proxy", func() {
 		})
 
 		It("starts a proxy to the jumpbox", func() {
-			err := socks5Proxy.Start(sshPrivateKey, sshServerURL)
+			err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Wait for socks5 proxy to start
 			time.Sleep(1 * time.Second)
 
 			socks5Addr := socks5Proxy.Addr()
-			socks5Client, err := goproxy.SOCKS5("tcp", socks5Addr, nil, goproxy.Direct)
+			socks5Client, err := goproxy.SOCKS5("tcp", socks5Addr, socks5Proxy.Auth(), goproxy.Direct)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(hostKeyGetter.GetCall.CallCount).To(Equal(1))
@@ -79,19 +88,104 @@ var _ = Describe("Socks5Proxy", func() {
 			Expect(status).To(Equal("HTTP/1.0 200 OK\r\n"))
 		})
 
+		It("requires the credentials of the session", func() {
+			err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() error {
+				conn, err := net.Dial("tcp", socks5Proxy.Addr())
+				if err == nil {
+					conn.Close()
+				}
+				return err
+			}).Should(Succeed())
+
+			socks5Client, err := goproxy.SOCKS5("tcp", socks5Proxy.Addr(), nil, goproxy.Direct)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = socks5Client.Dial("tcp", httpServerHostPort)
+			Expect(err).To(HaveOccurred())
+
+			auth := socks5Proxy.Auth()
+			Expect(auth.User).To(Equal("bbl"))
+			Expect(auth.Password).To(MatchRegexp(`^[0-9a-f]{32}$`))
+			Expect(socks5Proxy.URL()).To(Equal(fmt.Sprintf("socks5://bbl:%s@%s", auth.Password, socks5Proxy.Addr())))
+
+			other := proxy.NewSocks5Proxy(logger, hostKeyGetter, 0)
+			err = other.Start(sshPrivateKey, "", sshServerURL, allowlist)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other.Auth().Password).NotTo(Equal(auth.Password))
+		})
+
+		It("refuses destinations that are not on the allowlist", func() {
+			err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() error {
+				conn, err := net.Dial("tcp", socks5Proxy.Addr())
+				if err == nil {
+					conn.Close()
+				}
+				return err
+			}).Should(Succeed())
+
+			socks5Client, err := goproxy.SOCKS5("tcp", socks5Proxy.Addr(), socks5Proxy.Auth(), goproxy.Direct)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = socks5Client.Dial("tcp", "8.8.8.8:443")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("records the host key of the jumpbox", func() {
+			err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(socks5Proxy.HostKey()).To(Equal(authorizedKey(hostKeyGetter.GetCall.Returns.HostKey)))
+		})
+
+		Context("when the host key of the jumpbox is pinned", func() {
+			It("verifies it instead of trusting the key the jumpbox presents", func() {
+				pinnedKey := authorizedKey(hostKeyGetter.GetCall.Returns.HostKey)
+
+				err := socks5Proxy.Start(sshPrivateKey, pinnedKey, sshServerURL, allowlist)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(hostKeyGetter.GetCall.CallCount).To(Equal(0))
+				Expect(socks5Proxy.HostKey()).To(Equal(pinnedKey))
+			})
+
+			It("returns an error when the jumpbox presents another key", func() {
+				otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+				Expect(err).NotTo(HaveOccurred())
+
+				otherPublicKey, err := ssh.NewPublicKey(&otherKey.PublicKey)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = socks5Proxy.Start(sshPrivateKey, authorizedKey(otherPublicKey), sshServerURL, allowlist)
+				Expect(err).To(MatchError(ContainSubstring("jumpbox host key mismatch: %s presented the ssh-rsa key %s, but the state has the ssh-rsa key %s.",
+					sshServerURL, ssh.FingerprintSHA256(hostKeyGetter.GetCall.Returns.HostKey), ssh.FingerprintSHA256(otherPublicKey))))
+				Expect(err).To(MatchError(ContainSubstring(`run "bbl up --recreate" to record its new host key`)))
+			})
+
+			It("returns an error when the pinned key cannot be parsed", func() {
+				err := socks5Proxy.Start(sshPrivateKey, "some-bad-host-key", sshServerURL, allowlist)
+				Expect(err).To(MatchError("parse jumpbox host key: ssh: no key found"))
+			})
+		})
+
 		Context("when starting the proxy a second time", func() {
 			It("no-ops on the second run", func() {
-				err := socks5Proxy.Start(sshPrivateKey, sshServerURL)
+				err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
 				Expect(err).NotTo(HaveOccurred())
 
 				// Wait for socks5 proxy to start
 				time.Sleep(1 * time.Second)
 
-				err = socks5Proxy.Start(sshPrivateKey, sshServerURL)
+				err = socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
 				Expect(err).NotTo(HaveOccurred())
 
 				socks5Addr := socks5Proxy.Addr()
-				socks5Client, err := goproxy.SOCKS5("tcp", socks5Addr, nil, goproxy.Direct)
+				socks5Client, err := goproxy.SOCKS5("tcp", socks5Addr, socks5Proxy.Auth(), goproxy.Direct)
 				Expect(err).NotTo(HaveOccurred())
 
 				conn, err := socks5Client.Dial("tcp", httpServerHostPort)
@@ -108,18 +202,18 @@ var _ = Describe("Socks5Proxy", func() {
 
 		Context("failure cases", func() {
 			It("returns an error when it cannot parse the private key", func() {
-				err := socks5Proxy.Start("some-bad-private-key", sshServerURL)
+				err := socks5Proxy.Start("some-bad-private-key", "", sshServerURL, allowlist)
 				Expect(err).To(MatchError("ssh: no key found"))
 			})
 
 			It("returns an error when it cannot get the host key", func() {
 				hostKeyGetter.GetCall.Returns.Error = errors.New("failed to get host key")
-				err := socks5Proxy.Start(sshPrivateKey, sshServerURL)
+				err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
 				Expect(err).To(MatchError("failed to get host key"))
 			})
 
 			It("returns an error when it cannot dial the jumpbox url", func() {
-				err := socks5Proxy.Start(sshPrivateKey, "some-bad-url")
+				err := socks5Proxy.Start(sshPrivateKey, "", "some-bad-url", allowlist)
 				Expect(err).To(MatchError("dial tcp: address some-bad-url: missing port in address"))
 			})
 
@@ -141,7 +235,7 @@ var _ = Describe("Socks5Proxy", func() {
 				})
 
 				It("logs a helpful error message", func() {
-					err := socks5Proxy.Start(sshPrivateKey, sshServerURL)
+					err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
 					Expect(err).NotTo(HaveOccurred())
 					Eventually(func() []string {
 						return logger.PrintlnMessages()
@@ -154,12 +248,181 @@ var _ = Describe("Socks5Proxy", func() {
 					return nil, errors.New("failed to listen")
 				})
 
-				err := socks5Proxy.Start(sshPrivateKey, sshServerURL)
+				err := socks5Proxy.Start(sshPrivateKey, "", sshServerURL, allowlist)
 				Expect(err).To(MatchError("failed to listen"))
 			})
 		})
 	})
 
+	Describe("StartOnPort", func() {
+		var (
+			socks5Proxy   *proxy.Socks5Proxy
+			hostKeyGetter *fakes.HostKeyGetter
+			logger        *fakes.Logger
+			pinnedKey     string
+			allowlist     proxy.Allowlist
+			auditLog      *gbytes.Buffer
+
+			sshServerURL       string
+			httpServerHostPort string
+		)
+
+		BeforeEach(func() {
+			httpServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			}))
+			httpServerHostPort = strings.Split(httpServer.URL, "http://")[1]
+
+			sshServerURL = startSSHServer(httpServerHostPort)
+
+			signer, err := ssh.ParsePrivateKey([]byte(sshPrivateKey))
+			Expect(err).NotTo(HaveOccurred())
+			pinnedKey = authorizedKey(signer.PublicKey())
+
+			allowlist, err = proxy.NewAllowlist([]string{"127.0.0.0/8"}, []int{httpServer.Listener.Addr().(*net.TCPAddr).Port})
+			Expect(err).NotTo(HaveOccurred())
+			auditLog = gbytes.NewBuffer()
+
+			hostKeyGetter = &fakes.HostKeyGetter{}
+			logger = &fakes.Logger{}
+			socks5Proxy = proxy.NewSocks5Proxy(logger, hostKeyGetter, 0)
+		})
+
+		AfterEach(func() {
+			proxy.ResetNetListen()
+		})
+
+		It("serves a proxy to the jumpbox on the port once it returns", func() {
+			port, err := freePort()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = socks5Proxy.StartOnPort(sshPrivateKey, pinnedKey, sshServerURL, port, allowlist, auditLog)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(socks5Proxy.Addr()).To(Equal(fmt.Sprintf("127.0.0.1:%d", port)))
+			Expect(getThroughProxy(socks5Proxy, httpServerHostPort)).To(Equal("HTTP/1.0 200 OK\r\n"))
+			Expect(hostKeyGetter.GetCall.CallCount).To(Equal(0))
+		})
+
+		It("reconnects to the jumpbox when the connection has dropped", func() {
+			err := socks5Proxy.StartOnPort(sshPrivateKey, pinnedKey, sshServerURL, 0, allowlist, auditLog)
+			Expect(err).NotTo(HaveOccurred())
+
+			socks5Proxy.CloseConnection()
+
+			Expect(getThroughProxy(socks5Proxy, httpServerHostPort)).To(Equal("HTTP/1.0 200 OK\r\n"))
+			Expect(logger.PrintlnMessages()).To(ContainElement("reconnected to the jumpbox"))
+		})
+
+		It("records every connection in the audit log", func() {
+			err := socks5Proxy.StartOnPort(sshPrivateKey, pinnedKey, sshServerURL, 0, allowlist, auditLog)
+			Expect(err).NotTo(HaveOccurred())
+
+			getThroughProxy(socks5Proxy, httpServerHostPort)
+
+			Expect(string(auditLog.Contents())).To(MatchRegexp(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ allow 127\.0\.0\.1:\d+ -> %s\n$`, regexp.QuoteMeta(httpServerHostPort)))
+		})
+
+		It("refuses destinations that are not on the allowlist", func() {
+			err := socks5Proxy.StartOnPort(sshPrivateKey, pinnedKey, sshServerURL, 0, allowlist, auditLog)
+			Expect(err).NotTo(HaveOccurred())
+
+			socks5Client, err := goproxy.SOCKS5("tcp", socks5Proxy.Addr(), socks5Proxy.Auth(), goproxy.Direct)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = socks5Client.Dial("tcp", "8.8.8.8:443")
+			Expect(err).To(HaveOccurred())
+
+			Expect(string(auditLog.Contents())).To(MatchRegexp(`deny 127\.0\.0\.1:\d+ -> 8\.8\.8\.8:443: not in the allowlist\n$`))
+		})
+
+		It("refuses clients without the credentials of the session", func() {
+			err := socks5Proxy.StartOnPort(sshPrivateKey, pinnedKey, sshServerURL, 0, allowlist, auditLog)
+			Expect(err).NotTo(HaveOccurred())
+
+			socks5Client, err := goproxy.SOCKS5("tcp", socks5Proxy.Addr(), &goproxy.Auth{User: "bbl", Password: "some-wrong-password"}, goproxy.Direct)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = socks5Client.Dial("tcp", httpServerHostPort)
+			Expect(err).To(HaveOccurred())
+
+			socks5Client, err = goproxy.SOCKS5("tcp", socks5Proxy.Addr(), nil, goproxy.Direct)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = socks5Client.Dial("tcp", httpServerHostPort)
+			Expect(err).To(HaveOccurred())
+
+			Expect(string(auditLog.Contents())).To(ContainSubstring(`deny authentication as "bbl"`))
+			Expect(string(auditLog.Contents())).NotTo(ContainSubstring("allow"))
+		})
+
+		It("returns an error when the port is taken", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			port := listener.Addr().(*net.TCPAddr).Port
+
+			err = socks5Proxy.StartOnPort(sshPrivateKey, pinnedKey, sshServerURL, port, allowlist, auditLog)
+			Expect(err).To(MatchError(fmt.Sprintf("listen tcp 127.0.0.1:%d: bind: address already in use", port)))
+		})
+
+		It("returns an error when it cannot connect to the jumpbox", func() {
+			err := socks5Proxy.StartOnPort(sshPrivateKey, pinnedKey, "some-bad-url", 0, allowlist, auditLog)
+			Expect(err).To(MatchError("dial tcp: address some-bad-url: missing port in address"))
+		})
+	})
+
+	Describe("KeepAlive", func() {
+		It("reconnects to the jumpbox when it stops answering", func() {
+			httpServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			}))
+			httpServerHostPort := strings.Split(httpServer.URL, "http://")[1]
+			sshServerURL := startSSHServer(httpServerHostPort)
+
+			signer, err := ssh.ParsePrivateKey([]byte(sshPrivateKey))
+			Expect(err).NotTo(HaveOccurred())
+
+			logger := &fakes.Logger{}
+			socks5Proxy := proxy.NewSocks5Proxy(logger, &fakes.HostKeyGetter{}, 0)
+
+			allowlist, err := proxy.NewAllowlist([]string{"127.0.0.0/8"}, []int{httpServer.Listener.Addr().(*net.TCPAddr).Port})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = socks5Proxy.StartOnPort(sshPrivateKey, authorizedKey(signer.PublicKey()), sshServerURL, 0, allowlist, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			stop := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				socks5Proxy.KeepAlive(10*time.Millisecond, stop)
+				close(stopped)
+			}()
+
+			socks5Proxy.CloseConnection()
+
+			Eventually(logger.PrintlnMessages, "5s").Should(ContainElement("reconnected to the jumpbox"))
+			Expect(logger.PrintlnMessages()).To(ContainElement("lost the connection to the jumpbox, reconnecting"))
+
+			close(stop)
+			Eventually(stopped).Should(BeClosed())
+
+			Expect(getThroughProxy(socks5Proxy, httpServerHostPort)).To(Equal("HTTP/1.0 200 OK\r\n"))
+		})
+	})
+
+	Describe("DefaultPort", func() {
+		It("derives a stable port from the env id", func() {
+			port := proxy.DefaultPort("some-env-id")
+			Expect(port).To(Equal(proxy.DefaultPort("some-env-id")))
+			Expect(port).To(BeNumerically(">=", 20000))
+			Expect(port).To(BeNumerically("<", 30000))
+
+			Expect(proxy.DefaultPort("some-other-env-id")).NotTo(Equal(port))
+		})
+	})
+
 	Describe("Addr", func() {
 		var (
 			socks5Proxy   *proxy.Socks5Proxy
@@ -177,3 +440,34 @@ var _ = Describe("Socks5Proxy", func() {
 		})
 	})
 })
+
+func getThroughProxy(socks5Proxy *proxy.Socks5Proxy, target string) string {
+	socks5Client, err := goproxy.SOCKS5("tcp", socks5Proxy.Addr(), socks5Proxy.Auth(), goproxy.Direct)
+	Expect(err).NotTo(HaveOccurred())
+
+	conn, err := socks5Client.Dial("tcp", target)
+	Expect(err).NotTo(HaveOccurred())
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.0\r\n\r\n"))
+	Expect(err).NotTo(HaveOccurred())
+
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	Expect(err).NotTo(HaveOccurred())
+
+	return status
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func authorizedKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
@@ -147,9 +147,9 @@ func (m Migrator) Migrate(state storage.State) (storage.State, error) {
 		var err error
 		state.TFState, err = m.terraform.Import(terraform.ImportInput{
 			TerraformAddr: addr,
-			AWSResourceID: value,
+			ResourceID:    value,
 			TFState:       state.TFState,
-			Creds:         state.AWS,
+			Template:      importTemplate(addr, state.AWS),
 		})
 		if err != nil {
 			return storage.State{}, err
@@ -177,3 +177,22 @@ func (m Migrator) Migrate(state storage.State) (storage.State, error) {
 
 	return state, nil
 }
+
+// importTemplate declares just the resource being imported, since the
+// terraform template for the migrated stack has not been applied yet.
+func importTemplate(addr string, creds storage.AWS) string {
+	resourceType := strings.Split(addr, ".")[0]
+	resourceName := strings.Split(addr, ".")[1]
+	resourceName = strings.Split(resourceName, "[")[0]
+
+	return fmt.Sprintf(`
+provider "aws" {
+	version    = "~> 1.0"
+	region     = %q
+	access_key = %q
+	secret_key = %q
+}
+
+resource %q %q {
+}`, creds.Region, creds.AccessKeyID, creds.SecretAccessKey, resourceType, resourceName)
+}
@@ -13,6 +13,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gstruct"
+	"github.com/onsi/gomega/types"
 )
 
 var _ = Describe("Migrate", func() {
@@ -147,11 +148,33 @@ var _ = Describe("Migrate", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(tf.ImportArgsForCall(0).TFState).To(Equal(""))
-		Expect(tf.ImportArgsForCall(0).Creds).To(Equal(incomingState.AWS))
+		Expect(tf.ImportArgsForCall(0).Template).To(ContainSubstring(`
+provider "aws" {
+	version    = "~> 1.0"
+	region     = "some-region"
+	access_key = "some-access-key-id"
+	secret_key = "some-secret-access-key"
+}`))
 
 		Expect(tf.ImportArgsForCall(1).TFState).To(Equal("some-tfstate"))
 	})
 
+	It("declares the imported resource in the import template", func() {
+		_, err := migrator.Migrate(incomingState)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, importCall := range tf.Invocations()["Import"] {
+			input := importCall[0].(terraform.ImportInput)
+			if input.TerraformAddr == "aws_subnet.internal_subnets[0]" {
+				Expect(input.Template).To(HaveSuffix(`
+resource "aws_subnet" "internal_subnets" {
+}`))
+				return
+			}
+		}
+		Fail("aws_subnet.internal_subnets[0] was not imported")
+	})
+
 	It("maps each resource to terraform", func() {
 		_, err := migrator.Migrate(incomingState)
 		Expect(err).NotTo(HaveOccurred())
@@ -162,61 +185,61 @@ var _ = Describe("Migrate", func() {
 		}
 
 		Expect(tf.ImportCallCount()).To(Equal(27))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_vpc.vpc", AWSResourceID: "some-vpc"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_internet_gateway.ig", AWSResourceID: "some-vpc-gateway-internet-gateway"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_eip.nat_eip", AWSResourceID: "some-nat-eip"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_instance.nat", AWSResourceID: "some-nat-instance"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.nat_security_group", AWSResourceID: "some-nat-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_eip.bosh_eip", AWSResourceID: "some-bosh-eip"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.bosh_security_group", AWSResourceID: "some-bosh-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_subnet.bosh_subnet", AWSResourceID: "some-bosh-subnet"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_route_table.bosh_route_table", AWSResourceID: "some-bosh-route-table"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.internal_security_group", AWSResourceID: "some-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_route_table.internal_route_table", AWSResourceID: "some-internal-route-table"}))
+		Expect(importInputs).To(ContainElement(importOf("aws_vpc.vpc", "some-vpc")))
+		Expect(importInputs).To(ContainElement(importOf("aws_internet_gateway.ig", "some-vpc-gateway-internet-gateway")))
+		Expect(importInputs).To(ContainElement(importOf("aws_eip.nat_eip", "some-nat-eip")))
+		Expect(importInputs).To(ContainElement(importOf("aws_instance.nat", "some-nat-instance")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.nat_security_group", "some-nat-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_eip.bosh_eip", "some-bosh-eip")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.bosh_security_group", "some-bosh-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_subnet.bosh_subnet", "some-bosh-subnet")))
+		Expect(importInputs).To(ContainElement(importOf("aws_route_table.bosh_route_table", "some-bosh-route-table")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.internal_security_group", "some-internal-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_route_table.internal_route_table", "some-internal-route-table")))
 		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
 			gstruct.Fields{
 				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-1"),
+				"ResourceID":    Equal("some-internal-subnet-1"),
 			},
 		)))
 		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
 			gstruct.Fields{
 				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-2"),
+				"ResourceID":    Equal("some-internal-subnet-2"),
 			},
 		)))
 		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
 			gstruct.Fields{
 				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-3"),
+				"ResourceID":    Equal("some-internal-subnet-3"),
 			},
 		)))
 		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
 			gstruct.Fields{
 				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-4"),
+				"ResourceID":    Equal("some-internal-subnet-4"),
 			},
 		)))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_router_lb_internal_security_group", AWSResourceID: "some-cf-router-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_router_lb_security_group", AWSResourceID: "some-cf-router-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_elb.cf_router_lb", AWSResourceID: "some-cf-router-load-balancer"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_ssh_lb_internal_security_group", AWSResourceID: "some-cf-ssh-proxy-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_ssh_lb_security_group", AWSResourceID: "some-cf-ssh-proxy-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_elb.cf_ssh_lb", AWSResourceID: "some-cf-ssh-proxy-load-balancer"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.concourse_lb_internal_security_group", AWSResourceID: "some-concourse-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.concourse_lb_security_group", AWSResourceID: "some-concourse-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_elb.concourse_lb", AWSResourceID: "some-concourse-load-balancer"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_route_table.lb_route_table", AWSResourceID: "some-lb-route-table"}))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.cf_router_lb_internal_security_group", "some-cf-router-internal-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.cf_router_lb_security_group", "some-cf-router-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_elb.cf_router_lb", "some-cf-router-load-balancer")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.cf_ssh_lb_internal_security_group", "some-cf-ssh-proxy-internal-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.cf_ssh_lb_security_group", "some-cf-ssh-proxy-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_elb.cf_ssh_lb", "some-cf-ssh-proxy-load-balancer")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.concourse_lb_internal_security_group", "some-concourse-internal-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_security_group.concourse_lb_security_group", "some-concourse-security-group")))
+		Expect(importInputs).To(ContainElement(importOf("aws_elb.concourse_lb", "some-concourse-load-balancer")))
+		Expect(importInputs).To(ContainElement(importOf("aws_route_table.lb_route_table", "some-lb-route-table")))
 		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
 			gstruct.Fields{
 				"TerraformAddr": MatchRegexp(`aws_subnet.lb_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-lb-subnet-1"),
+				"ResourceID":    Equal("some-lb-subnet-1"),
 			},
 		)))
 		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
 			gstruct.Fields{
 				"TerraformAddr": MatchRegexp(`aws_subnet.lb_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-lb-subnet-2"),
+				"ResourceID":    Equal("some-lb-subnet-2"),
 			},
 		)))
 	})
@@ -273,10 +296,7 @@ var _ = Describe("Migrate", func() {
 			Expect(certificateARN).To(Equal("some-dumb-arn"))
 			Expect(returnedState.LB.Type).To(Equal(lbType))
 
-			Expect(importInputs).To(ContainElement(terraform.ImportInput{
-				TerraformAddr: "aws_iam_server_certificate.lb_cert",
-				AWSResourceID: "some-certificate-name",
-			}))
+			Expect(importInputs).To(ContainElement(importOf("aws_iam_server_certificate.lb_cert", "some-certificate-name")))
 		})
 	})
 
@@ -346,3 +366,10 @@ var _ = Describe("Migrate", func() {
 		})
 	})
 })
+
+func importOf(addr, id string) types.GomegaMatcher {
+	return gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"TerraformAddr": Equal(addr),
+		"ResourceID":    Equal(id),
+	})
+}
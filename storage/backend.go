@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+var (
+	NotFound      = errors.New("not found")
+	AlreadyExists = errors.New("already exists")
+)
+
+// Backend persists the named files that make up a bbl environment's state.
+// Read returns NotFound when the file does not exist, and Create returns
+// AlreadyExists instead of replacing an existing file.
+type Backend interface {
+	Read(name string) ([]byte, error)
+	Write(name string, contents []byte, mode os.FileMode) error
+	Create(name string, contents []byte, mode os.FileMode) error
+	Delete(name string) error
+	Location() string
+}
+
+// NewBackend returns the backend for a --state-backend URL. A plain path or
+// a file:// URL selects the filesystem backend, and an s3:// URL selects an
+// S3-compatible object store.
+func NewBackend(location string) (Backend, error) {
+	backendURL, err := url.Parse(location)
+	if err != nil || len(backendURL.Scheme) <= 1 {
+		return NewFileBackend(location), nil
+	}
+
+	switch backendURL.Scheme {
+	case "file":
+		return NewFileBackend(backendURL.Host + backendURL.Path), nil
+	case "s3":
+		backend, err := NewObjectStoreBackendFromURL(backendURL)
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("unsupported state backend %q, use a directory, file:// or s3:// URL", location)
+	}
+}
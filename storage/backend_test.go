@@ -0,0 +1,212 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backend", func() {
+	Describe("NewBackend", func() {
+		It("returns a file backend for a directory", func() {
+			backend, err := storage.NewBackend("/some/state/dir")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backend).To(Equal(storage.NewFileBackend("/some/state/dir")))
+		})
+
+		It("returns a file backend for a file:// URL", func() {
+			backend, err := storage.NewBackend("file:///some/state/dir")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backend).To(Equal(storage.NewFileBackend("/some/state/dir")))
+		})
+
+		It("returns an object store backend for an s3:// URL", func() {
+			backend, err := storage.NewBackend("s3://some-bucket/some/prefix?region=some-region")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backend).To(BeAssignableToTypeOf(storage.ObjectStoreBackend{}))
+			Expect(backend.Location()).To(Equal("s3://some-bucket/some/prefix"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error for an unsupported scheme", func() {
+				_, err := storage.NewBackend("ftp://some-host/some-dir")
+				Expect(err).To(MatchError(`unsupported state backend "ftp://some-host/some-dir", use a directory, file:// or s3:// URL`))
+			})
+
+			It("returns an error when the s3:// URL has no bucket", func() {
+				_, err := storage.NewBackend("s3:///some-prefix")
+				Expect(err).To(MatchError("state backend URL must name a bucket, e.g. s3://some-bucket/some-env"))
+			})
+		})
+	})
+
+	Describe("FileBackend", func() {
+		var (
+			backend storage.FileBackend
+			tempDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			backend = storage.NewFileBackend(tempDir)
+		})
+
+		It("writes, reads and deletes files with the requested mode", func() {
+			err := backend.Write("some-dir/some-file", []byte("some-contents"), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := os.Stat(filepath.Join(tempDir, "some-dir", "some-file"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			contents, err := backend.Read("some-dir/some-file")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("some-contents"))
+
+			err = backend.Delete("some-dir/some-file")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = backend.Read("some-dir/some-file")
+			Expect(err).To(Equal(storage.NotFound))
+		})
+
+		It("creates a file only when it does not exist yet", func() {
+			err := backend.Create("some-file", []byte("some-contents"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = backend.Create("some-file", []byte("some-other-contents"), 0644)
+			Expect(err).To(Equal(storage.AlreadyExists))
+
+			contents, err := backend.Read("some-file")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("some-contents"))
+		})
+
+		It("ignores deleting a file that does not exist", func() {
+			err := backend.Delete("some-missing-file")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the directory as its location", func() {
+			Expect(backend.Location()).To(Equal(tempDir))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the directory does not exist", func() {
+				backend = storage.NewFileBackend("some-missing-dir")
+
+				_, err := backend.Read("some-file")
+				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+
+				err = backend.Write("some-file", []byte("some-contents"), 0644)
+				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+			})
+		})
+	})
+
+	Describe("ObjectStoreBackend", func() {
+		var (
+			objectStore *testhelpers.FakeObjectStore
+			backend     storage.Backend
+		)
+
+		BeforeEach(func() {
+			os.Setenv("AWS_ACCESS_KEY_ID", "some-access-key-id")
+			os.Setenv("AWS_SECRET_ACCESS_KEY", "some-secret-access-key")
+
+			objectStore = testhelpers.NewFakeObjectStore()
+
+			var err error
+			backend, err = storage.NewBackend("s3://some-bucket/some-env?endpoint=" + objectStore.URL())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			objectStore.Close()
+			os.Unsetenv("AWS_ACCESS_KEY_ID")
+			os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+		})
+
+		It("writes, reads and deletes objects under the prefix", func() {
+			err := backend.Write("bbl-state.json", []byte("some-contents"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			object, ok := objectStore.Object("some-bucket/some-env/bbl-state.json")
+			Expect(ok).To(BeTrue())
+			Expect(string(object)).To(Equal("some-contents"))
+
+			contents, err := backend.Read("bbl-state.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("some-contents"))
+
+			err = backend.Delete("bbl-state.json")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = backend.Read("bbl-state.json")
+			Expect(err).To(Equal(storage.NotFound))
+		})
+
+		It("creates an object only when it does not exist yet", func() {
+			err := backend.Create("bbl-state.lock", []byte("some-contents"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = backend.Create("bbl-state.lock", []byte("some-other-contents"), 0644)
+			Expect(err).To(Equal(storage.AlreadyExists))
+
+			object, _ := objectStore.Object("some-bucket/some-env/bbl-state.lock")
+			Expect(string(object)).To(Equal("some-contents"))
+		})
+
+		It("round trips state through a store", func() {
+			store := storage.NewStore(backend)
+
+			err := store.Set(storage.State{IAAS: "gcp", EnvID: "some-env-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			state, err := storage.GetStateFromBackend(backend)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.EnvID).To(Equal("some-env-id"))
+			Expect(state.Version).To(Equal(storage.STATE_VERSION))
+		})
+
+		It("returns an empty state when the environment does not exist yet", func() {
+			state, err := storage.GetStateFromBackend(backend)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(storage.State{}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the object store fails", func() {
+				objectStore.FailRequests()
+
+				_, err := backend.Read("bbl-state.json")
+				Expect(err).To(MatchError(ContainSubstring("failed to read bbl-state.json in s3://some-bucket/some-env: 500 Internal Server Error")))
+
+				err = backend.Write("bbl-state.json", []byte("some-contents"), 0644)
+				Expect(err).To(MatchError(ContainSubstring("failed to write bbl-state.json in s3://some-bucket/some-env")))
+
+				err = backend.Delete("bbl-state.json")
+				Expect(err).To(MatchError(ContainSubstring("failed to delete bbl-state.json in s3://some-bucket/some-env")))
+			})
+
+			It("returns an error when no credentials are available", func() {
+				os.Unsetenv("AWS_ACCESS_KEY_ID")
+				os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+				os.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/some/missing/credentials")
+				defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+
+				_, err := backend.Read("bbl-state.json")
+				Expect(err).To(MatchError(ContainSubstring("sign request for s3://some-bucket/some-env")))
+			})
+		})
+	})
+})
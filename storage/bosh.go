@@ -14,7 +14,26 @@ type BOSH struct {
 	Variables              string                 `json:"variables"`
 	State                  map[string]interface{} `json:"state"`
 	Manifest               string                 `json:"manifest"`
-	UserOpsFile            string                 `json:"userOpsFile"`
+	UserOpsFiles           []NamedFile            `json:"userOpsFiles,omitempty"`
+	UserVarsFiles          []NamedFile            `json:"userVarsFiles,omitempty"`
+	DirectorVMType         string                 `json:"directorVMType,omitempty"`
+	DirectorDiskSizeGB     int                    `json:"directorDiskSizeGB,omitempty"`
+	DirectorStemcellURL    string                 `json:"directorStemcellURL,omitempty"`
+	DirectorStemcellSHA1   string                 `json:"directorStemcellSHA1,omitempty"`
+	ExternalDatabase       bool                   `json:"externalDatabase,omitempty"`
+	ExternalBlobstore      bool                   `json:"externalBlobstore,omitempty"`
+	Fingerprint            string                 `json:"fingerprint,omitempty"`
+}
+
+// legacyUserOpsFileName names the single user ops file that states before
+// version 10 could hold.
+const legacyUserOpsFileName = "user-ops-file.yml"
+
+// NamedFile is an ops file or vars file provided by the user, kept under the
+// base name of the path it was read from.
+type NamedFile struct {
+	Name     string `json:"name"`
+	Contents string `json:"contents"`
 }
 
 func (b BOSH) IsEmpty() bool {
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	EncryptedStateFormat = "bbl-encrypted-state-v1"
+
+	StateKeyEnvVar     = "BBL_STATE_KEY"
+	StateKeyFileEnvVar = "BBL_STATE_KEY_FILE"
+
+	OS_OWNER_READ_WRITE_MODE = os.FileMode(0600)
+
+	keyDerivationIterations = 100000
+	keyLength               = 32
+	saltLength              = 16
+)
+
+var (
+	randReader = rand.Reader
+	getenv     = os.Getenv
+
+	StateKeyMissing = fmt.Errorf("bbl-state.json is encrypted, set %s or %s to decrypt it", StateKeyEnvVar, StateKeyFileEnvVar)
+)
+
+type encryptedState struct {
+	Format     string `json:"format"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type Cipher struct {
+	secret []byte
+}
+
+func NewCipher(secret []byte) Cipher {
+	return Cipher{secret: secret}
+}
+
+// LoadStateKey returns the secret used to encrypt bbl-state.json. The
+// BBL_STATE_KEY passphrase takes precedence over the contents of the file
+// named by BBL_STATE_KEY_FILE. A nil key means no key was configured.
+func LoadStateKey() ([]byte, error) {
+	if passphrase := getenv(StateKeyEnvVar); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	keyFile := getenv(StateKeyFileEnvVar)
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read state key file: %s", err)
+	}
+
+	key = bytes.TrimSpace(key)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("state key file %q is empty", keyFile)
+	}
+
+	return key, nil
+}
+
+func IsEncrypted(contents []byte) bool {
+	var envelope encryptedState
+	err := json.Unmarshal(contents, &envelope)
+	if err != nil {
+		return false
+	}
+
+	return envelope.Format == EncryptedStateFormat
+}
+
+func (c Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	_, err := io.ReadFull(randReader, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := c.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(randReader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalIndent(encryptedState{
+		Format:     EncryptedStateFormat,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(EncryptedStateFormat)),
+	}, "", "\t")
+}
+
+func (c Cipher) Decrypt(contents []byte) ([]byte, error) {
+	var envelope encryptedState
+	err := json.Unmarshal(contents, &envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	if envelope.Format != EncryptedStateFormat {
+		return nil, fmt.Errorf("unsupported encrypted state format %q", envelope.Format)
+	}
+
+	aead, err := c.aead(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(envelope.Nonce) != aead.NonceSize() {
+		return nil, errors.New("encrypted state has an invalid nonce")
+	}
+
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, []byte(EncryptedStateFormat))
+	if err != nil {
+		return nil, errors.New("failed to decrypt bbl-state.json, the state key is incorrect or the file has been modified")
+	}
+
+	return plaintext, nil
+}
+
+func (c Cipher) aead(salt []byte) (cipher.AEAD, error) {
+	if len(c.secret) == 0 {
+		return nil, errors.New("state key must not be empty")
+	}
+
+	block, err := aes.NewCipher(deriveKey(c.secret, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// deriveKey is PBKDF2 with HMAC-SHA256 producing a single block, which is
+// all AES-256 needs.
+func deriveKey(secret, salt []byte) []byte {
+	prf := hmac.New(sha256.New, secret)
+
+	blockIndex := make([]byte, 4)
+	binary.BigEndian.PutUint32(blockIndex, 1)
+
+	prf.Write(salt)
+	prf.Write(blockIndex)
+	u := prf.Sum(nil)
+
+	key := make([]byte, len(u))
+	copy(key, u)
+
+	for i := 1; i < keyDerivationIterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+
+	return key[:keyLength]
+}
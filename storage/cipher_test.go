@@ -0,0 +1,142 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cipher", func() {
+	var cipher storage.Cipher
+
+	BeforeEach(func() {
+		cipher = storage.NewCipher([]byte("some-passphrase"))
+	})
+
+	Describe("Encrypt", func() {
+		It("produces an envelope that round trips through Decrypt", func() {
+			encrypted, err := cipher.Encrypt([]byte(`{"version": 9}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(encrypted)).NotTo(ContainSubstring("version"))
+			Expect(storage.IsEncrypted(encrypted)).To(BeTrue())
+
+			decrypted, err := cipher.Decrypt(encrypted)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decrypted).To(MatchJSON(`{"version": 9}`))
+		})
+
+		It("uses a fresh salt and nonce every time", func() {
+			first, err := cipher.Encrypt([]byte("some-plaintext"))
+			Expect(err).NotTo(HaveOccurred())
+
+			second, err := cipher.Encrypt([]byte("some-plaintext"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(first).NotTo(Equal(second))
+		})
+
+		Context("when the secret is empty", func() {
+			It("returns an error", func() {
+				_, err := storage.NewCipher(nil).Encrypt([]byte("some-plaintext"))
+				Expect(err).To(MatchError("state key must not be empty"))
+			})
+		})
+	})
+
+	Describe("Decrypt", func() {
+		Context("failure cases", func() {
+			It("returns an error when the passphrase is wrong", func() {
+				encrypted, err := cipher.Encrypt([]byte("some-plaintext"))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = storage.NewCipher([]byte("some-other-passphrase")).Decrypt(encrypted)
+				Expect(err).To(MatchError("failed to decrypt bbl-state.json, the state key is incorrect or the file has been modified"))
+			})
+
+			It("returns an error when the format is unknown", func() {
+				_, err := cipher.Decrypt([]byte(`{"format": "some-format"}`))
+				Expect(err).To(MatchError(`unsupported encrypted state format "some-format"`))
+			})
+
+			It("returns an error when the contents are not json", func() {
+				_, err := cipher.Decrypt([]byte("%%%"))
+				Expect(err).To(MatchError(ContainSubstring("invalid character")))
+			})
+		})
+	})
+
+	Describe("IsEncrypted", func() {
+		It("returns false for a plaintext state file", func() {
+			Expect(storage.IsEncrypted([]byte(`{"version": 9}`))).To(BeFalse())
+		})
+
+		It("returns false for non-json contents", func() {
+			Expect(storage.IsEncrypted([]byte("%%%"))).To(BeFalse())
+		})
+	})
+
+	Describe("LoadStateKey", func() {
+		AfterEach(func() {
+			os.Unsetenv("BBL_STATE_KEY")
+			os.Unsetenv("BBL_STATE_KEY_FILE")
+		})
+
+		It("returns nil when no key is configured", func() {
+			key, err := storage.LoadStateKey()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(BeNil())
+		})
+
+		It("returns the passphrase from BBL_STATE_KEY", func() {
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+			key, err := storage.LoadStateKey()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(key)).To(Equal("some-passphrase"))
+		})
+
+		It("returns the trimmed contents of the BBL_STATE_KEY_FILE", func() {
+			tempDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			keyFile := filepath.Join(tempDir, "state.key")
+			err = ioutil.WriteFile(keyFile, []byte("some-key-file-contents\n"), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			os.Setenv("BBL_STATE_KEY_FILE", keyFile)
+
+			key, err := storage.LoadStateKey()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(key)).To(Equal("some-key-file-contents"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the key file cannot be read", func() {
+				os.Setenv("BBL_STATE_KEY_FILE", "/some/missing/key/file")
+
+				_, err := storage.LoadStateKey()
+				Expect(err).To(MatchError(ContainSubstring("read state key file")))
+			})
+
+			It("returns an error when the key file is empty", func() {
+				tempDir, err := ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				keyFile := filepath.Join(tempDir, "state.key")
+				err = ioutil.WriteFile(keyFile, []byte("\n"), 0600)
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Setenv("BBL_STATE_KEY_FILE", keyFile)
+
+				_, err = storage.LoadStateKey()
+				Expect(err).To(MatchError(ContainSubstring("is empty")))
+			})
+		})
+	})
+})
@@ -1,6 +1,10 @@
 package storage
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
 
 func SetMarshalIndent(f func(state interface{}, prefix, indent string) ([]byte, error)) {
 	marshalIndent = f
@@ -9,3 +13,27 @@ func SetMarshalIndent(f func(state interface{}, prefix, indent string) ([]byte,
 func ResetMarshalIndent() {
 	marshalIndent = json.MarshalIndent
 }
+
+func SetHostname(f func() (string, error)) {
+	hostname = f
+}
+
+func ResetHostname() {
+	hostname = os.Hostname
+}
+
+func SetNow(f func() time.Time) {
+	now = f
+}
+
+func ResetNow() {
+	now = time.Now
+}
+
+func SetProcessExists(f func(int) bool) {
+	processExists = f
+}
+
+func ResetProcessExists() {
+	processExists = processIsRunning
+}
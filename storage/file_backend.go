@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type FileBackend struct {
+	dir string
+}
+
+func NewFileBackend(dir string) FileBackend {
+	return FileBackend{dir: dir}
+}
+
+func (f FileBackend) Read(name string) ([]byte, error) {
+	_, err := os.Stat(f.root())
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(f.dir, name))
+	if os.IsNotExist(err) {
+		return nil, NotFound
+	}
+
+	return contents, err
+}
+
+func (f FileBackend) Write(name string, contents []byte, mode os.FileMode) error {
+	path := filepath.Join(f.dir, name)
+
+	_, err := os.Stat(f.root())
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path, contents, mode)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(path, mode)
+}
+
+func (f FileBackend) Create(name string, contents []byte, mode os.FileMode) error {
+	path := filepath.Join(f.dir, name)
+
+	_, err := os.Stat(f.root())
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if os.IsExist(err) {
+		return AlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(contents)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (f FileBackend) Delete(name string) error {
+	_, err := os.Stat(f.root())
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(f.dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (f FileBackend) Location() string {
+	return f.dir
+}
+
+func (f FileBackend) root() string {
+	if f.dir == "" {
+		return "."
+	}
+	return f.dir
+}
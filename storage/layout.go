@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SplitLayout keeps the terraform state, the create-env states, the vars
+// stores and the manifests in their own files next to a small bbl-state.json
+// index, so that the bosh and terraform CLIs can be pointed at them directly.
+const SplitLayout = "split"
+
+var SplitLayoutEncrypted = errors.New("the split state layout cannot be used with an encrypted bbl-state.json, run bbl decrypt-state first")
+
+type component struct {
+	name    string
+	path    string
+	extract func(State) ([]byte, error)
+	inject  func(*State, []byte) error
+}
+
+var components = []component{
+	{
+		name:    "terraform-state",
+		path:    "terraform/terraform.tfstate",
+		extract: func(s State) ([]byte, error) { return []byte(s.TFState), nil },
+		inject:  func(s *State, c []byte) error { s.TFState = string(c); return nil },
+	},
+	{
+		name:    "director-state",
+		path:    "create-env/director-state.json",
+		extract: func(s State) ([]byte, error) { return marshalComponent(s.BOSH.State) },
+		inject:  func(s *State, c []byte) error { return unmarshalComponent(c, &s.BOSH.State) },
+	},
+	{
+		name:    "jumpbox-state",
+		path:    "create-env/jumpbox-state.json",
+		extract: func(s State) ([]byte, error) { return marshalComponent(s.Jumpbox.State) },
+		inject:  func(s *State, c []byte) error { return unmarshalComponent(c, &s.Jumpbox.State) },
+	},
+	{
+		name:    "director-vars-store",
+		path:    "vars/director-vars-store.yml",
+		extract: func(s State) ([]byte, error) { return []byte(s.BOSH.Variables), nil },
+		inject:  func(s *State, c []byte) error { s.BOSH.Variables = string(c); return nil },
+	},
+	{
+		name:    "jumpbox-vars-store",
+		path:    "vars/jumpbox-vars-store.yml",
+		extract: func(s State) ([]byte, error) { return []byte(s.Jumpbox.Variables), nil },
+		inject:  func(s *State, c []byte) error { s.Jumpbox.Variables = string(c); return nil },
+	},
+	{
+		name:    "director-manifest",
+		path:    "manifests/director.yml",
+		extract: func(s State) ([]byte, error) { return []byte(s.BOSH.Manifest), nil },
+		inject:  func(s *State, c []byte) error { s.BOSH.Manifest = string(c); return nil },
+	},
+	{
+		name:    "jumpbox-manifest",
+		path:    "manifests/jumpbox.yml",
+		extract: func(s State) ([]byte, error) { return []byte(s.Jumpbox.Manifest), nil },
+		inject:  func(s *State, c []byte) error { s.Jumpbox.Manifest = string(c); return nil },
+	},
+	{
+		// The user ops files are kept in the index since version 10. A split
+		// state written before that still has its single ops file here; it is
+		// read into the list and the file is removed on the next write.
+		name:    "user-ops-file",
+		path:    "manifests/user-ops-file.yml",
+		extract: func(State) ([]byte, error) { return nil, nil },
+		inject:  injectLegacyUserOpsFile,
+	},
+}
+
+// Split switches the state to the split layout.
+func (s Store) Split() error {
+	return s.setLayout(SplitLayout)
+}
+
+// Join switches the state back to a single bbl-state.json.
+func (s Store) Join() error {
+	err := s.setLayout("")
+	if err != nil {
+		return err
+	}
+
+	return s.deleteComponents(nil)
+}
+
+func (s Store) setLayout(layout string) error {
+	_, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	state, err := s.Get()
+	if err != nil {
+		return err
+	}
+
+	if state.Layout == layout {
+		return nil
+	}
+
+	state.Layout = layout
+	return s.Set(state)
+}
+
+// writeSplit writes every non-empty component to its own file, and
+// bbl-state.json as an index pointing at them.
+func (s Store) writeSplit(state State) error {
+	encrypted, err := s.shouldEncrypt()
+	if err != nil {
+		return err
+	}
+
+	if encrypted {
+		return SplitLayoutEncrypted
+	}
+
+	index := state
+	index.Components = map[string]string{}
+
+	for _, c := range components {
+		contents, err := c.extract(state)
+		if err != nil {
+			return err
+		}
+
+		if len(contents) == 0 {
+			err = s.backend.Delete(c.path)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = s.backend.Write(c.path, contents, OS_READ_WRITE_MODE)
+		if err != nil {
+			return err
+		}
+
+		index.Components[c.name] = c.path
+		err = c.inject(&index, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	indexJSON, err := marshalIndent(index, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return s.write(indexJSON, OS_READ_WRITE_MODE)
+}
+
+// deleteComponents removes the component files of a split state except those
+// listed in keep.
+func (s Store) deleteComponents(keep map[string]string) error {
+	for _, c := range components {
+		if _, ok := keep[c.name]; ok {
+			continue
+		}
+
+		err := s.backend.Delete(c.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readComponents(backend Backend, state State) (State, error) {
+	for name, path := range state.Components {
+		c, ok := findComponent(name)
+		if !ok {
+			return State{}, fmt.Errorf("%s refers to unknown component %q", StateFileName, name)
+		}
+
+		contents, err := backend.Read(path)
+		if err == NotFound {
+			return State{}, fmt.Errorf("%s is missing, it is referenced by %s", path, StateFileName)
+		}
+		if err != nil {
+			return State{}, err
+		}
+
+		err = c.inject(&state, contents)
+		if err != nil {
+			return State{}, fmt.Errorf("read %s: %s", path, err)
+		}
+	}
+
+	state.Components = nil
+	return state, nil
+}
+
+func findComponent(name string) (component, bool) {
+	for _, c := range components {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return component{}, false
+}
+
+func injectLegacyUserOpsFile(s *State, c []byte) error {
+	if len(c) > 0 {
+		s.BOSH.UserOpsFiles = append(s.BOSH.UserOpsFiles, NamedFile{Name: legacyUserOpsFileName, Contents: string(c)})
+	}
+	return nil
+}
+
+func marshalComponent(value map[string]interface{}) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return marshalIndent(value, "", "\t")
+}
+
+func unmarshalComponent(contents []byte, value *map[string]interface{}) error {
+	*value = nil
+	if len(contents) == 0 {
+		return nil
+	}
+	return json.Unmarshal(contents, value)
+}
@@ -0,0 +1,236 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Layout", func() {
+	var (
+		store   storage.Store
+		tempDir string
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		store = storage.NewStore(storage.NewFileBackend(tempDir))
+
+		state = storage.State{
+			Version: storage.STATE_VERSION,
+			IAAS:    "gcp",
+			EnvID:   "some-env-id",
+			TFState: "some-tf-state",
+			BOSH: storage.BOSH{
+				DirectorName: "some-director",
+				Variables:    "some-director-vars",
+				Manifest:     "some-director-manifest",
+				State:        map[string]interface{}{"some-key": "some-value"},
+			},
+			Jumpbox: storage.Jumpbox{
+				Variables: "some-jumpbox-vars",
+				Manifest:  "some-jumpbox-manifest",
+			},
+		}
+
+		err = store.Set(state)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("BBL_STATE_KEY")
+	})
+
+	readFile := func(name string) string {
+		contents, err := ioutil.ReadFile(filepath.Join(tempDir, name))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Describe("Split", func() {
+		It("writes the components to their own files and keeps an index in bbl-state.json", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readFile("terraform/terraform.tfstate")).To(Equal("some-tf-state"))
+			Expect(readFile("vars/director-vars-store.yml")).To(Equal("some-director-vars"))
+			Expect(readFile("vars/jumpbox-vars-store.yml")).To(Equal("some-jumpbox-vars"))
+			Expect(readFile("manifests/director.yml")).To(Equal("some-director-manifest"))
+			Expect(readFile("manifests/jumpbox.yml")).To(Equal("some-jumpbox-manifest"))
+			Expect(readFile("create-env/director-state.json")).To(MatchJSON(`{"some-key": "some-value"}`))
+
+			_, err = os.Stat(filepath.Join(tempDir, "create-env", "jumpbox-state.json"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			var index storage.State
+			err = json.Unmarshal([]byte(readFile("bbl-state.json")), &index)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index.Layout).To(Equal("split"))
+			Expect(index.TFState).To(BeEmpty())
+			Expect(index.BOSH.Variables).To(BeEmpty())
+			Expect(index.BOSH.State).To(BeNil())
+			Expect(index.BOSH.DirectorName).To(Equal("some-director"))
+			Expect(index.Components).To(Equal(map[string]string{
+				"terraform-state":     "terraform/terraform.tfstate",
+				"director-state":      "create-env/director-state.json",
+				"director-vars-store": "vars/director-vars-store.yml",
+				"jumpbox-vars-store":  "vars/jumpbox-vars-store.yml",
+				"director-manifest":   "manifests/director.yml",
+				"jumpbox-manifest":    "manifests/jumpbox.yml",
+			}))
+		})
+
+		It("reads the state back from the components", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(tempDir, "terraform", "terraform.tfstate"), []byte("some-edited-tf-state"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			loadedState, err := store.Get()
+			Expect(err).NotTo(HaveOccurred())
+
+			state.Layout = "split"
+			state.TFState = "some-edited-tf-state"
+			Expect(loadedState).To(Equal(state))
+		})
+
+		It("keeps the layout when the state is written again", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			state.Layout = "split"
+			state.TFState = ""
+			err = store.Set(state)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(filepath.Join(tempDir, "terraform", "terraform.tfstate"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			Expect(readFile("vars/director-vars-store.yml")).To(Equal("some-director-vars"))
+		})
+
+		It("reads the user ops file of a split state written before version 10 into the list", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			var index map[string]interface{}
+			err = json.Unmarshal([]byte(readFile("bbl-state.json")), &index)
+			Expect(err).NotTo(HaveOccurred())
+			index["components"].(map[string]interface{})["user-ops-file"] = "manifests/user-ops-file.yml"
+
+			indexJSON, err := json.Marshal(index)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), indexJSON, 0644)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(tempDir, "manifests", "user-ops-file.yml"), []byte("some-ops-file"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			loadedState, err := store.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loadedState.BOSH.UserOpsFiles).To(Equal([]storage.NamedFile{
+				{Name: "user-ops-file.yml", Contents: "some-ops-file"},
+			}))
+
+			err = store.Set(loadedState)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(filepath.Join(tempDir, "manifests", "user-ops-file.yml"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			loadedState, err = store.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loadedState.BOSH.UserOpsFiles).To(HaveLen(1))
+		})
+
+		It("removes the components when the state is deleted", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(filepath.Join(tempDir, "vars", "director-vars-store.yml"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when bbl-state.json is encrypted", func() {
+				os.Setenv("BBL_STATE_KEY", "some-passphrase")
+				err := store.Encrypt()
+				Expect(err).NotTo(HaveOccurred())
+
+				err = store.Split()
+				Expect(err).To(Equal(storage.SplitLayoutEncrypted))
+			})
+
+			It("returns an error when a component is missing", func() {
+				err := store.Split()
+				Expect(err).NotTo(HaveOccurred())
+
+				err = os.Remove(filepath.Join(tempDir, "manifests", "director.yml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = store.Get()
+				Expect(err).To(MatchError("manifests/director.yml is missing, it is referenced by bbl-state.json"))
+			})
+
+			It("does not encrypt a split state", func() {
+				err := store.Split()
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Setenv("BBL_STATE_KEY", "some-passphrase")
+				err = store.Encrypt()
+				Expect(err).To(Equal(storage.SplitLayoutEncrypted))
+			})
+		})
+	})
+
+	Describe("Join", func() {
+		It("moves the components back into bbl-state.json", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Join()
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(filepath.Join(tempDir, "terraform", "terraform.tfstate"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			var joined storage.State
+			err = json.Unmarshal([]byte(readFile("bbl-state.json")), &joined)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(joined).To(Equal(state))
+		})
+	})
+
+	Describe("Rollback", func() {
+		It("restores the components of a split state", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshots, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			splitSnapshot := snapshots[len(snapshots)-1].ID
+
+			state.Layout = "split"
+			state.TFState = "some-broken-tf-state"
+			err = store.Set(state)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Rollback(splitSnapshot)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readFile("terraform/terraform.tfstate")).To(Equal("some-tf-state"))
+		})
+	})
+})
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+const (
+	LockFileName = "bbl-state.lock"
+
+	// StaleLockAge is how long a lock is honoured when bbl cannot tell
+	// whether its holder is still running, e.g. a lock taken on another host.
+	StaleLockAge = 24 * time.Hour
+)
+
+var (
+	hostname      = os.Hostname
+	getpid        = os.Getpid
+	currentUser   = user.Current
+	now           = time.Now
+	processExists = processIsRunning
+)
+
+type LockInfo struct {
+	Host      string    `json:"host"`
+	User      string    `json:"user"`
+	PID       int       `json:"pid"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+func (l LockInfo) String() string {
+	return fmt.Sprintf("`bbl %s` run by %s on %s (pid %d) since %s", l.Command, l.User, l.Host, l.PID, l.StartedAt.Format(time.RFC3339))
+}
+
+type StateLockedError struct {
+	Holder LockInfo
+}
+
+func (e StateLockedError) Error() string {
+	return fmt.Sprintf("bbl-state.json is locked by %s. Wait for it to finish, or run `bbl force-unlock` if it is no longer running.", e.Holder)
+}
+
+// Locker guards the state against concurrent mutating bbl commands with an
+// advisory lock file kept next to bbl-state.json.
+type Locker struct {
+	backend Backend
+	logger  logger
+}
+
+func NewLocker(backend Backend, logger logger) Locker {
+	return Locker{
+		backend: backend,
+		logger:  logger,
+	}
+}
+
+// Lock takes the lock for command and returns the lock that was written,
+// which must be handed back to Unlock. A stale lock is broken with a warning.
+func (l Locker) Lock(command string) (LockInfo, error) {
+	info, err := newLockInfo(command)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	contents, err := json.Marshal(info)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	err = l.backend.Create(LockFileName, contents, OS_READ_WRITE_MODE)
+	if err != AlreadyExists {
+		return info, err
+	}
+
+	holder, err := l.holder()
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	if !holder.stale() {
+		return LockInfo{}, StateLockedError{Holder: holder}
+	}
+
+	l.logger.Println(fmt.Sprintf("breaking stale lock held by %s", holder))
+
+	err = l.breakStaleLock(holder, contents)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	return info, nil
+}
+
+// breakStaleLock replaces the stale lock of holder with contents. Only one
+// bbl can create the break marker of a given stale lock, and it only
+// overwrites the lock if it is still the stale one, so of several bbls
+// breaking the same lock at once exactly one ends up holding it.
+func (l Locker) breakStaleLock(holder LockInfo, contents []byte) error {
+	staleContents, err := json.Marshal(holder)
+	if err != nil {
+		return err //not tested
+	}
+
+	marker := breakMarkerName(staleContents)
+	err = l.backend.Create(marker, nil, OS_READ_WRITE_MODE)
+	if err == AlreadyExists {
+		return StateLockedError{Holder: holder}
+	}
+	if err != nil {
+		return err
+	}
+	defer l.backend.Delete(marker)
+
+	current, err := l.holder()
+	if err == NotFound {
+		return l.create(contents)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !current.sameAs(holder) {
+		return StateLockedError{Holder: current}
+	}
+
+	return l.backend.Write(LockFileName, contents, OS_READ_WRITE_MODE)
+}
+
+func (l Locker) create(contents []byte) error {
+	err := l.backend.Create(LockFileName, contents, OS_READ_WRITE_MODE)
+	if err == AlreadyExists {
+		holder, err := l.holder()
+		if err != nil {
+			return err
+		}
+		return StateLockedError{Holder: holder}
+	}
+
+	return err
+}
+
+// breakMarkerName is derived from the stale lock so that a marker left
+// behind by a bbl that died while breaking a lock only ever blocks that
+// one lock.
+func breakMarkerName(staleContents []byte) string {
+	sum := sha256.Sum256(staleContents)
+	return fmt.Sprintf("%s.break-%x", LockFileName, sum[:8])
+}
+
+// Unlock releases a lock taken by Lock. It leaves the lock alone if it was
+// force-unlocked and taken by someone else in the meantime.
+func (l Locker) Unlock(held LockInfo) error {
+	holder, err := l.holder()
+	if err == NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !holder.sameAs(held) {
+		return nil
+	}
+
+	return l.backend.Delete(LockFileName)
+}
+
+// ForceUnlock removes the lock whoever holds it, and returns the holder.
+func (l Locker) ForceUnlock() (LockInfo, error) {
+	holder, err := l.holder()
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	err = l.backend.Delete(LockFileName)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	// A bbl that died while breaking the lock leaves its marker behind.
+	staleContents, err := json.Marshal(holder)
+	if err != nil {
+		return LockInfo{}, err //not tested
+	}
+
+	err = l.backend.Delete(breakMarkerName(staleContents))
+	if err != nil {
+		return LockInfo{}, err //not tested
+	}
+
+	return holder, nil
+}
+
+func (l Locker) holder() (LockInfo, error) {
+	contents, err := l.backend.Read(LockFileName)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	var holder LockInfo
+	err = json.Unmarshal(contents, &holder)
+	if err != nil {
+		return LockInfo{}, fmt.Errorf("read %s: %s", LockFileName, err)
+	}
+
+	return holder, nil
+}
+
+func (l LockInfo) sameAs(other LockInfo) bool {
+	return l.StartedAt.Equal(other.StartedAt) && l.PID == other.PID && l.Host == other.Host && l.Command == other.Command
+}
+
+func (l LockInfo) stale() bool {
+	if now().Sub(l.StartedAt) > StaleLockAge {
+		return true
+	}
+
+	host, err := hostname()
+	if err != nil || host != l.Host {
+		return false
+	}
+
+	return !processExists(l.PID)
+}
+
+func newLockInfo(command string) (LockInfo, error) {
+	host, err := hostname()
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	username := "unknown"
+	if u, err := currentUser(); err == nil {
+		username = u.Username
+	}
+
+	return LockInfo{
+		Host:      host,
+		User:      username,
+		PID:       getpid(),
+		Command:   command,
+		StartedAt: now().UTC(),
+	}, nil
+}
+
+func processIsRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// FindProcess only succeeds for live processes on windows, and signal 0
+	// is not supported there.
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
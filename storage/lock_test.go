@@ -0,0 +1,287 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Locker", func() {
+	var (
+		locker   storage.Locker
+		logger   *fakes.Logger
+		tempDir  string
+		lockFile string
+		startAt  time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		lockFile = filepath.Join(tempDir, "bbl-state.lock")
+
+		startAt = time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+		storage.SetNow(func() time.Time { return startAt })
+		storage.SetHostname(func() (string, error) { return "some-host", nil })
+		storage.SetProcessExists(func(int) bool { return true })
+
+		logger = &fakes.Logger{}
+		locker = storage.NewLocker(storage.NewFileBackend(tempDir), logger)
+	})
+
+	AfterEach(func() {
+		storage.ResetNow()
+		storage.ResetHostname()
+		storage.ResetProcessExists()
+	})
+
+	var writeLock = func(info storage.LockInfo) {
+		contents, err := json.Marshal(info)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(lockFile, contents, 0644)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Describe("Lock", func() {
+		It("records the holder of the lock", func() {
+			info, err := locker.Lock("up")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(info.Host).To(Equal("some-host"))
+			Expect(info.PID).To(Equal(os.Getpid()))
+			Expect(info.User).NotTo(BeEmpty())
+			Expect(info.Command).To(Equal("up"))
+			Expect(info.StartedAt).To(Equal(startAt))
+
+			contents, err := ioutil.ReadFile(lockFile)
+			Expect(err).NotTo(HaveOccurred())
+
+			var written storage.LockInfo
+			err = json.Unmarshal(contents, &written)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written).To(Equal(info))
+		})
+
+		It("refuses to take a lock that is already held", func() {
+			holder, err := locker.Lock("up")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = locker.Lock("create-lbs")
+			Expect(err).To(Equal(storage.StateLockedError{Holder: holder}))
+			Expect(err.Error()).To(ContainSubstring("bbl-state.json is locked by `bbl up` run by"))
+			Expect(err.Error()).To(ContainSubstring("on some-host (pid %d) since 2017-06-01T12:00:00Z", os.Getpid()))
+			Expect(err.Error()).To(ContainSubstring("run `bbl force-unlock`"))
+		})
+
+		Context("when the lock is stale", func() {
+			It("breaks a lock whose process is no longer running on this host", func() {
+				writeLock(storage.LockInfo{Host: "some-host", User: "some-user", PID: 4321, Command: "destroy", StartedAt: startAt})
+				storage.SetProcessExists(func(pid int) bool {
+					Expect(pid).To(Equal(4321))
+					return false
+				})
+
+				info, err := locker.Lock("up")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Command).To(Equal("up"))
+
+				Expect(logger.PrintlnCall.Receives.Message).To(Equal("breaking stale lock held by `bbl destroy` run by some-user on some-host (pid 4321) since 2017-06-01T12:00:00Z"))
+			})
+
+			It("breaks a lock that is older than the stale lock age", func() {
+				writeLock(storage.LockInfo{Host: "some-other-host", PID: 4321, Command: "destroy", StartedAt: startAt.Add(-25 * time.Hour)})
+
+				_, err := locker.Lock("up")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("lets exactly one of two lockers competing for it take it over", func() {
+				storage.SetProcessExists(func(pid int) bool { return pid != 4321 })
+
+				for i := 0; i < 50; i++ {
+					writeLock(storage.LockInfo{Host: "some-host", PID: 4321, Command: "destroy", StartedAt: startAt})
+
+					commands := []string{"up", "create-lbs"}
+					infos := make([]storage.LockInfo, len(commands))
+					errs := make([]error, len(commands))
+
+					var wg sync.WaitGroup
+					for j, command := range commands {
+						wg.Add(1)
+						go func(j int, command string) {
+							defer GinkgoRecover()
+							defer wg.Done()
+							competitor := storage.NewLocker(storage.NewFileBackend(tempDir), &fakes.Logger{})
+							infos[j], errs[j] = competitor.Lock(command)
+						}(j, command)
+					}
+					wg.Wait()
+
+					winner, loser := 0, 1
+					if errs[0] != nil {
+						winner, loser = 1, 0
+					}
+					Expect(errs[winner]).NotTo(HaveOccurred())
+					Expect(errs[loser]).To(Equal(storage.StateLockedError{Holder: infos[winner]}))
+
+					contents, err := ioutil.ReadFile(lockFile)
+					Expect(err).NotTo(HaveOccurred())
+
+					var written storage.LockInfo
+					err = json.Unmarshal(contents, &written)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(written).To(Equal(infos[winner]))
+
+					markers, err := filepath.Glob(lockFile + ".break-*")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(markers).To(BeEmpty())
+				}
+			})
+
+			It("leaves the lock alone when it is replaced while being broken", func() {
+				writeLock(storage.LockInfo{Host: "some-host", PID: 4321, Command: "destroy", StartedAt: startAt})
+				storage.SetProcessExists(func(pid int) bool { return pid != 4321 })
+
+				newHolder := storage.LockInfo{Host: "some-other-host", PID: 1234, Command: "up", StartedAt: startAt}
+				backend := racingBackend{
+					Backend: storage.NewFileBackend(tempDir),
+					beforeBreaking: func(string) {
+						writeLock(newHolder)
+					},
+				}
+
+				_, err := storage.NewLocker(backend, logger).Lock("create-lbs")
+				Expect(err).To(Equal(storage.StateLockedError{Holder: newHolder}))
+
+				contents, err := ioutil.ReadFile(lockFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(ContainSubstring(`"host":"some-other-host"`))
+			})
+
+			It("does not break a lock another bbl is already breaking", func() {
+				stale := storage.LockInfo{Host: "some-host", PID: 4321, Command: "destroy", StartedAt: startAt}
+				writeLock(stale)
+				storage.SetProcessExists(func(pid int) bool { return pid != 4321 })
+
+				backend := racingBackend{
+					Backend: storage.NewFileBackend(tempDir),
+					beforeBreaking: func(marker string) {
+						err := ioutil.WriteFile(filepath.Join(tempDir, marker), nil, 0644)
+						Expect(err).NotTo(HaveOccurred())
+					},
+				}
+
+				_, err := storage.NewLocker(backend, logger).Lock("create-lbs")
+				Expect(err).To(Equal(storage.StateLockedError{Holder: stale}))
+
+				contents, err := ioutil.ReadFile(lockFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(ContainSubstring(`"command":"destroy"`))
+			})
+
+			It("does not break a recent lock held on another host", func() {
+				writeLock(storage.LockInfo{Host: "some-other-host", PID: 4321, Command: "destroy", StartedAt: startAt.Add(-time.Hour)})
+				storage.SetProcessExists(func(int) bool { return false })
+
+				_, err := locker.Lock("up")
+				Expect(err).To(BeAssignableToTypeOf(storage.StateLockedError{}))
+			})
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the hostname cannot be determined", func() {
+				storage.SetHostname(func() (string, error) { return "", errors.New("failed to get hostname") })
+
+				_, err := locker.Lock("up")
+				Expect(err).To(MatchError("failed to get hostname"))
+			})
+
+			It("returns an error when the existing lock cannot be parsed", func() {
+				err := ioutil.WriteFile(lockFile, []byte("%%%"), 0644)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = locker.Lock("up")
+				Expect(err).To(MatchError(ContainSubstring("read bbl-state.lock: invalid character")))
+			})
+		})
+	})
+
+	Describe("Unlock", func() {
+		It("removes the lock it holds", func() {
+			info, err := locker.Lock("up")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = locker.Unlock(info)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(lockFile)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("leaves a lock taken by someone else alone", func() {
+			info, err := locker.Lock("up")
+			Expect(err).NotTo(HaveOccurred())
+
+			writeLock(storage.LockInfo{Host: "some-other-host", PID: 4321, Command: "destroy", StartedAt: startAt})
+
+			err = locker.Unlock(info)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(lockFile)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does nothing when the lock is already gone", func() {
+			err := locker.Unlock(storage.LockInfo{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ForceUnlock", func() {
+		It("removes the lock and returns its holder", func() {
+			holder := storage.LockInfo{Host: "some-other-host", User: "some-user", PID: 4321, Command: "destroy", StartedAt: startAt}
+			writeLock(holder)
+
+			info, err := locker.ForceUnlock()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info).To(Equal(holder))
+
+			_, err = os.Stat(lockFile)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("returns NotFound when there is no lock", func() {
+			_, err := locker.ForceUnlock()
+			Expect(err).To(Equal(storage.NotFound))
+		})
+	})
+})
+
+// racingBackend runs beforeBreaking right before the locker tries to create
+// the break marker of a stale lock, to play another bbl that gets there
+// first.
+type racingBackend struct {
+	storage.Backend
+	beforeBreaking func(marker string)
+}
+
+func (r racingBackend) Create(name string, contents []byte, mode os.FileMode) error {
+	if strings.Contains(name, ".break-") {
+		r.beforeBreaking(name)
+	}
+
+	return r.Backend.Create(name, contents, mode)
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Migration upgrades the raw contents of bbl-state.json from schema version
+// From to From+1. Migrations only transform the state file; moving an
+// environment off CloudFormation also changes infrastructure and stays in
+// stack.Migrator.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(state map[string]interface{}) error
+}
+
+// Migrations must hold exactly one entry for every version from the oldest
+// supported one up to STATE_VERSION-1, in order.
+var Migrations = []Migration{
+	{From: 3, Description: "version 4 only added fields", Apply: noLayoutChanges},
+	{From: 4, Description: "version 5 only added fields", Apply: noLayoutChanges},
+	{From: 5, Description: "version 6 only added fields", Apply: noLayoutChanges},
+	{From: 6, Description: "version 7 only added fields", Apply: noLayoutChanges},
+	{From: 7, Description: "version 8 only added fields", Apply: noLayoutChanges},
+	{From: 8, Description: "version 9 only added fields", Apply: noLayoutChanges},
+	{From: 9, Description: "move bosh.userOpsFile into the bosh.userOpsFiles list", Apply: moveUserOpsFile},
+}
+
+type MigrationRecord struct {
+	From        int       `json:"from"`
+	To          int       `json:"to"`
+	Description string    `json:"description"`
+	AppliedAt   time.Time `json:"appliedAt"`
+}
+
+type MigrationPlan struct {
+	FromVersion int
+	Before      []byte
+	After       []byte
+	Applied     []MigrationRecord
+	BackupFile  string
+}
+
+func (m MigrationPlan) Empty() bool {
+	return len(m.Applied) == 0
+}
+
+// PlanMigration works out the migrations bbl-state.json needs without
+// writing anything.
+func (s Store) PlanMigration() (MigrationPlan, error) {
+	contents, err := s.read()
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	if IsEncrypted(contents) {
+		contents, err = decryptState(contents)
+		if err != nil {
+			return MigrationPlan{}, err
+		}
+	}
+
+	return planMigration(contents)
+}
+
+// Migrate applies the pending migrations to bbl-state.json, keeping the
+// pre-migration file as a backup. There is nothing to migrate when there is
+// no bbl-state.json yet.
+func (s Store) Migrate() (MigrationPlan, error) {
+	contents, err := s.backend.Read(StateFileName)
+	if err == NotFound {
+		return MigrationPlan{}, nil
+	}
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	plaintext := contents
+	if IsEncrypted(contents) {
+		plaintext, err = decryptState(contents)
+		if err != nil {
+			return MigrationPlan{}, err
+		}
+	}
+
+	plan, err := planMigration(plaintext)
+	if err != nil || plan.Empty() {
+		return plan, err
+	}
+
+	mode := OS_READ_WRITE_MODE
+	migrated := plan.After
+	if IsEncrypted(contents) {
+		mode = OS_OWNER_READ_WRITE_MODE
+
+		key, err := LoadStateKey()
+		if err != nil {
+			return MigrationPlan{}, err
+		}
+
+		migrated, err = NewCipher(key).Encrypt(plan.After)
+		if err != nil {
+			return MigrationPlan{}, err
+		}
+	}
+
+	err = s.backend.Write(plan.BackupFile, contents, mode)
+	if err != nil {
+		return MigrationPlan{}, fmt.Errorf("back up %s: %s", StateFileName, err)
+	}
+
+	err = s.backend.Write(StateFileName, migrated, mode)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	return plan, nil
+}
+
+func planMigration(plaintext []byte) (MigrationPlan, error) {
+	var state map[string]interface{}
+	err := json.Unmarshal(plaintext, &state)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	version, _ := state["version"].(float64)
+	plan := MigrationPlan{
+		FromVersion: int(version),
+		Before:      plaintext,
+		After:       plaintext,
+		BackupFile:  fmt.Sprintf("%s.v%d.backup", StateFileName, int(version)),
+	}
+
+	if len(state) == 0 || plan.FromVersion < Migrations[0].From || plan.FromVersion >= STATE_VERSION {
+		return plan, nil
+	}
+
+	records, _ := state["migrations"].([]interface{})
+
+	for current := plan.FromVersion; current < STATE_VERSION; current++ {
+		migration, ok := findMigration(current)
+		if !ok {
+			return MigrationPlan{}, fmt.Errorf("no migration from state version %d", current)
+		}
+
+		err = migration.Apply(state)
+		if err != nil {
+			return MigrationPlan{}, fmt.Errorf("migrate state from version %d to %d: %s", current, current+1, err)
+		}
+
+		record := MigrationRecord{
+			From:        current,
+			To:          current + 1,
+			Description: migration.Description,
+			AppliedAt:   now().UTC(),
+		}
+		plan.Applied = append(plan.Applied, record)
+		records = append(records, record)
+		state["version"] = current + 1
+	}
+
+	state["migrations"] = records
+
+	// Round trip through State so the migrated file is laid out the same way
+	// Store.Set writes it.
+	migratedJSON, err := json.Marshal(state)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	var migratedState State
+	err = json.Unmarshal(migratedJSON, &migratedState)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	plan.After, err = marshalIndent(migratedState, "", "\t")
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	return plan, nil
+}
+
+func findMigration(from int) (Migration, bool) {
+	for _, migration := range Migrations {
+		if migration.From == from {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func noLayoutChanges(map[string]interface{}) error {
+	return nil
+}
+
+// moveUserOpsFile replaces the single user ops file of version 9 with the
+// ordered list of user ops files.
+func moveUserOpsFile(state map[string]interface{}) error {
+	bosh, ok := state["bosh"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	opsFile, _ := bosh["userOpsFile"].(string)
+	delete(bosh, "userOpsFile")
+
+	if opsFile != "" {
+		bosh["userOpsFiles"] = []interface{}{
+			map[string]interface{}{"name": legacyUserOpsFileName, "contents": opsFile},
+		}
+	}
+
+	return nil
+}
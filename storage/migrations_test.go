@@ -0,0 +1,222 @@
+package storage_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migrations", func() {
+	var (
+		store      storage.Store
+		tempDir    string
+		stateFile  string
+		migrations []storage.Migration
+		appliedAt  time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		stateFile = filepath.Join(tempDir, "bbl-state.json")
+
+		appliedAt = time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+		storage.SetNow(func() time.Time { return appliedAt })
+
+		migrations = storage.Migrations
+
+		store = storage.NewStore(storage.NewFileBackend(tempDir))
+
+		err = ioutil.WriteFile(stateFile, []byte(`{
+			"version": 7,
+			"iaas": "gcp",
+			"envId": "some-old-env-id-key"
+		}`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		storage.Migrations = migrations
+		storage.ResetNow()
+	})
+
+	It("has a migration for every version from 3 up to the current one", func() {
+		for i, migration := range storage.Migrations {
+			Expect(migration.From).To(Equal(3 + i))
+		}
+		Expect(storage.Migrations[len(storage.Migrations)-1].From).To(Equal(storage.STATE_VERSION - 1))
+	})
+
+	Context("when the state was written by an older version", func() {
+		BeforeEach(func() {
+			storage.Migrations = []storage.Migration{
+				{From: 7, Description: "rename envId to envID", Apply: func(state map[string]interface{}) error {
+					state["envID"] = state["envId"]
+					delete(state, "envId")
+					return nil
+				}},
+				{From: 8, Description: "nothing to do", Apply: func(map[string]interface{}) error { return nil }},
+				{From: 9, Description: "nothing to do either", Apply: func(map[string]interface{}) error { return nil }},
+			}
+		})
+
+		It("migrates the state in memory when it is loaded and records the migrations", func() {
+			state, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(state.Version).To(Equal(10))
+			Expect(state.EnvID).To(Equal("some-old-env-id-key"))
+			Expect(state.Migrations).To(Equal([]storage.MigrationRecord{
+				{From: 7, To: 8, Description: "rename envId to envID", AppliedAt: appliedAt},
+				{From: 8, To: 9, Description: "nothing to do", AppliedAt: appliedAt},
+				{From: 9, To: 10, Description: "nothing to do either", AppliedAt: appliedAt},
+			}))
+
+			contents, err := ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring(`"version": 7`))
+
+			_, err = os.Stat(filepath.Join(tempDir, "bbl-state.json.v7.backup"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("keeps an encrypted state encrypted", func() {
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+			defer os.Unsetenv("BBL_STATE_KEY")
+
+			err := store.Encrypt()
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = store.Migrate()
+			Expect(err).NotTo(HaveOccurred())
+
+			state, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.Version).To(Equal(10))
+
+			contents, err := ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeTrue())
+
+			backup, err := ioutil.ReadFile(filepath.Join(tempDir, "bbl-state.json.v7.backup"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(backup)).To(BeTrue())
+		})
+
+		Describe("PlanMigration", func() {
+			It("returns the migrated state without writing it", func() {
+				plan, err := store.PlanMigration()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(plan.FromVersion).To(Equal(7))
+				Expect(plan.Applied).To(HaveLen(3))
+				Expect(string(plan.After)).To(ContainSubstring(`"version": 10`))
+				Expect(string(plan.After)).To(ContainSubstring(`"envID": "some-old-env-id-key"`))
+
+				contents, err := ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(ContainSubstring(`"version": 7`))
+
+				_, err = os.Stat(filepath.Join(tempDir, "bbl-state.json.v7.backup"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Describe("Migrate", func() {
+			It("writes the migrated state and keeps the previous file as a backup", func() {
+				plan, err := store.Migrate()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plan.FromVersion).To(Equal(7))
+				Expect(plan.BackupFile).To(Equal("bbl-state.json.v7.backup"))
+
+				persisted, err := store.Get()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(persisted.Version).To(Equal(10))
+				Expect(persisted.EnvID).To(Equal("some-old-env-id-key"))
+
+				contents, err := ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(ContainSubstring(`"version": 10`))
+
+				backup, err := ioutil.ReadFile(filepath.Join(tempDir, "bbl-state.json.v7.backup"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(backup)).To(ContainSubstring(`"version": 7`))
+
+				plan, err = store.PlanMigration()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plan.Empty()).To(BeTrue())
+			})
+
+			It("has nothing to migrate when there is no state file", func() {
+				err := os.Remove(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+
+				plan, err := store.Migrate()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plan.Empty()).To(BeTrue())
+			})
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when a migration is missing", func() {
+				storage.Migrations = storage.Migrations[:1]
+
+				_, err := storage.GetState(tempDir)
+				Expect(err).To(MatchError("no migration from state version 8"))
+			})
+
+			It("returns an error when a migration fails", func() {
+				storage.Migrations[0].Apply = func(map[string]interface{}) error {
+					return errors.New("failed to migrate")
+				}
+
+				_, err := storage.GetState(tempDir)
+				Expect(err).To(MatchError("migrate state from version 7 to 8: failed to migrate"))
+
+				contents, err := ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(ContainSubstring(`"version": 7`))
+			})
+		})
+	})
+
+	Describe("from version 9", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(stateFile, []byte(`{
+				"version": 9,
+				"iaas": "gcp",
+				"bosh": {
+					"directorName": "some-director-name",
+					"userOpsFile": "some-ops-file"
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("moves the user ops file into the list of user ops files", func() {
+			_, err := store.Migrate()
+			Expect(err).NotTo(HaveOccurred())
+
+			state, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(state.Version).To(Equal(10))
+			Expect(state.BOSH.DirectorName).To(Equal("some-director-name"))
+			Expect(state.BOSH.UserOpsFiles).To(Equal([]storage.NamedFile{
+				{Name: "user-ops-file.yml", Contents: "some-ops-file"},
+			}))
+
+			contents, err := ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).NotTo(ContainSubstring("userOpsFile\""))
+		})
+	})
+})
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const defaultObjectStoreRegion = "us-east-1"
+
+type ObjectStoreConfig struct {
+	Endpoint    string
+	Bucket      string
+	Prefix      string
+	Region      string
+	Credentials *credentials.Credentials
+}
+
+// ObjectStoreBackend keeps state files as objects in an S3-compatible
+// bucket, addressed path-style so that non-AWS endpoints work too.
+type ObjectStoreBackend struct {
+	endpoint string
+	bucket   string
+	prefix   string
+	region   string
+	signer   *v4.Signer
+	client   *http.Client
+}
+
+func NewObjectStoreBackend(config ObjectStoreConfig) ObjectStoreBackend {
+	region := config.Region
+	if region == "" {
+		region = defaultObjectStoreRegion
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	return ObjectStoreBackend{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		bucket:   config.Bucket,
+		prefix:   strings.Trim(config.Prefix, "/"),
+		region:   region,
+		signer: v4.NewSigner(config.Credentials, func(s *v4.Signer) {
+			s.DisableURIPathEscaping = true
+		}),
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// NewObjectStoreBackendFromURL builds a backend from a URL of the form
+// s3://bucket/prefix?region=us-west-2&endpoint=https://minio.example.com.
+// Credentials come from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, falling
+// back to the shared AWS credentials file.
+func NewObjectStoreBackendFromURL(backendURL *url.URL) (ObjectStoreBackend, error) {
+	if backendURL.Host == "" {
+		return ObjectStoreBackend{}, errors.New("state backend URL must name a bucket, e.g. s3://some-bucket/some-env")
+	}
+
+	query := backendURL.Query()
+
+	return NewObjectStoreBackend(ObjectStoreConfig{
+		Endpoint: query.Get("endpoint"),
+		Bucket:   backendURL.Host,
+		Prefix:   backendURL.Path,
+		Region:   query.Get("region"),
+		Credentials: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvProvider{},
+			&credentials.SharedCredentialsProvider{},
+		}),
+	}), nil
+}
+
+func (o ObjectStoreBackend) Read(name string) ([]byte, error) {
+	response, err := o.do("GET", name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, NotFound
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, o.responseError("read", name, response)
+	}
+
+	return ioutil.ReadAll(response.Body)
+}
+
+// Write uploads the contents. The mode has no meaning for an object store,
+// access is governed by the bucket's policy instead.
+func (o ObjectStoreBackend) Write(name string, contents []byte, mode os.FileMode) error {
+	response, err := o.do("PUT", name, contents, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return o.responseError("write", name, response)
+	}
+
+	return nil
+}
+
+// Create uses a conditional PUT so that only one writer can create the
+// object.
+func (o ObjectStoreBackend) Create(name string, contents []byte, mode os.FileMode) error {
+	response, err := o.do("PUT", name, contents, http.Header{"If-None-Match": []string{"*"}})
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return AlreadyExists
+	default:
+		return o.responseError("create", name, response)
+	}
+}
+
+func (o ObjectStoreBackend) Delete(name string) error {
+	response, err := o.do("DELETE", name, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return o.responseError("delete", name, response)
+	}
+}
+
+func (o ObjectStoreBackend) Location() string {
+	return fmt.Sprintf("s3://%s", path.Join(o.bucket, o.prefix))
+}
+
+func (o ObjectStoreBackend) key(name string) string {
+	return path.Join(o.prefix, name)
+}
+
+func (o ObjectStoreBackend) do(method, name string, body []byte, header http.Header) (*http.Response, error) {
+	request, err := http.NewRequest(method, fmt.Sprintf("%s/%s/%s", o.endpoint, o.bucket, o.key(name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.ContentLength = int64(len(body))
+
+	_, err = o.signer.Sign(request, bytes.NewReader(body), "s3", o.region, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("sign request for %s: %s", o.Location(), err)
+	}
+
+	return o.client.Do(request)
+}
+
+func (o ObjectStoreBackend) responseError(action, name string, response *http.Response) error {
+	message, _ := ioutil.ReadAll(response.Body)
+	return fmt.Errorf("failed to %s %s in %s: %s %s", action, name, o.Location(), response.Status, strings.TrimSpace(string(message)))
+}
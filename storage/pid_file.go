@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PIDFile records the pid of a long-running bbl command, so that a second
+// one for the same environment fails instead of fighting over its port.
+type PIDFile struct {
+	path string
+}
+
+func NewPIDFile(path string) PIDFile {
+	return PIDFile{
+		path: path,
+	}
+}
+
+// Acquire writes the pid of this process to the file. A file left behind by
+// a process that is no longer running is taken over.
+func (p PIDFile) Acquire() error {
+	contents, err := ioutil.ReadFile(p.path)
+	if err == nil {
+		pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+		if err == nil && pid != getpid() && processExists(pid) {
+			return fmt.Errorf("bbl proxy is already running with pid %d, see %s", pid, p.path)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("read pid file: %s", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(p.path), 0700)
+	if err != nil {
+		return fmt.Errorf("create pid file: %s", err) //not tested
+	}
+
+	err = ioutil.WriteFile(p.path, []byte(fmt.Sprintf("%d\n", getpid())), 0600)
+	if err != nil {
+		return fmt.Errorf("create pid file: %s", err) //not tested
+	}
+
+	return nil
+}
+
+// Release removes the file if this process still holds it.
+func (p PIDFile) Release() error {
+	contents, err := ioutil.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read pid file: %s", err) //not tested
+	}
+
+	if strings.TrimSpace(string(contents)) != strconv.Itoa(getpid()) {
+		return nil
+	}
+
+	err = os.Remove(p.path)
+	if err != nil {
+		return fmt.Errorf("remove pid file: %s", err) //not tested
+	}
+
+	return nil
+}
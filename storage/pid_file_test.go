@@ -0,0 +1,116 @@
+package storage_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PIDFile", func() {
+	var (
+		pidFile storage.PIDFile
+		tempDir string
+		path    string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tempDir, "proxy", "some-env-id.pid")
+
+		storage.SetProcessExists(func(int) bool { return true })
+
+		pidFile = storage.NewPIDFile(path)
+	})
+
+	AfterEach(func() {
+		storage.ResetProcessExists()
+		os.RemoveAll(tempDir)
+	})
+
+	Describe("Acquire", func() {
+		It("writes the pid of this process", func() {
+			err := pidFile.Acquire()
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(fmt.Sprintf("%d\n", os.Getpid())))
+
+			info, err := os.Stat(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("returns an error when another running process holds the file", func() {
+			err := os.MkdirAll(filepath.Dir(path), 0700)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(path, []byte("12345\n"), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = pidFile.Acquire()
+			Expect(err).To(MatchError(fmt.Sprintf("bbl proxy is already running with pid 12345, see %s", path)))
+		})
+
+		It("takes over a file left behind by a process that is no longer running", func() {
+			storage.SetProcessExists(func(int) bool { return false })
+
+			err := os.MkdirAll(filepath.Dir(path), 0700)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(path, []byte("12345\n"), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = pidFile.Acquire()
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(fmt.Sprintf("%d\n", os.Getpid())))
+		})
+
+		It("returns an error when the pid file cannot be read", func() {
+			err := ioutil.WriteFile(filepath.Join(tempDir, "proxy"), []byte{}, 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = pidFile.Acquire()
+			Expect(err).To(MatchError(ContainSubstring("read pid file: ")))
+		})
+	})
+
+	Describe("Release", func() {
+		It("removes the file", func() {
+			err := pidFile.Acquire()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = pidFile.Release()
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(path)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("leaves a file held by another process alone", func() {
+			err := os.MkdirAll(filepath.Dir(path), 0700)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(path, []byte("12345\n"), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = pidFile.Release()
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(path)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does nothing when there is no file", func() {
+			Expect(pidFile.Release()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	SnapshotsDir = "snapshots"
+	MaxSnapshots = 20
+
+	snapshotIndexFileName = "index.json"
+	snapshotIDFormat      = "20060102T150405Z"
+)
+
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Command   string    `json:"command"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// History returns the snapshots of bbl-state.json, oldest first.
+func (s Store) History() ([]Snapshot, error) {
+	contents, err := s.backend.Read(path.Join(SnapshotsDir, snapshotIndexFileName))
+	if err == NotFound {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	err = json.Unmarshal(contents, &snapshots)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot index: %s", err)
+	}
+
+	return snapshots, nil
+}
+
+// Rollback replaces bbl-state.json with the snapshot with the given id. The
+// restored state is recorded as a new snapshot so it can be undone too.
+func (s Store) Rollback(id string) error {
+	snapshots, err := s.History()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, snapshot := range snapshots {
+		if snapshot.ID == id {
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("snapshot %q not found, run `bbl state history` to list snapshots", id)
+	}
+
+	contents, err := s.backend.Read(snapshotFileName(id))
+	if err != nil {
+		return fmt.Errorf("read snapshot %q: %s", id, err)
+	}
+
+	mode := OS_READ_WRITE_MODE
+	if IsEncrypted(contents) {
+		mode = OS_OWNER_READ_WRITE_MODE
+	}
+
+	err = s.restore(contents, mode)
+	if err != nil {
+		return err
+	}
+
+	return s.snapshot(contents, mode, fmt.Sprintf("state rollback %s", id))
+}
+
+// restore writes the contents of a snapshot back. Snapshots always hold the
+// whole state, so a split state is written out to its components again.
+func (s Store) restore(contents []byte, mode os.FileMode) error {
+	if !IsEncrypted(contents) {
+		var state State
+		err := json.Unmarshal(contents, &state)
+		if err != nil {
+			return err
+		}
+
+		if state.Layout == SplitLayout {
+			return s.writeSplit(state)
+		}
+	}
+
+	err := s.write(contents, mode)
+	if err != nil {
+		return err
+	}
+
+	return s.deleteComponents(nil)
+}
+
+func (s Store) snapshot(contents []byte, mode os.FileMode, command string) error {
+	snapshots, err := s.History()
+	if err != nil {
+		return err
+	}
+
+	snapshot := Snapshot{
+		ID:        snapshotID(now().UTC(), snapshots),
+		Command:   command,
+		CreatedAt: now().UTC(),
+	}
+
+	err = s.backend.Write(snapshotFileName(snapshot.ID), contents, mode)
+	if err != nil {
+		return err
+	}
+
+	snapshots = append(snapshots, snapshot)
+	for len(snapshots) > MaxSnapshots {
+		err = s.backend.Delete(snapshotFileName(snapshots[0].ID))
+		if err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+
+	return s.writeSnapshotIndex(snapshots)
+}
+
+func (s Store) clearHistory() error {
+	snapshots, err := s.History()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		err = s.backend.Delete(snapshotFileName(snapshot.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.backend.Delete(path.Join(SnapshotsDir, snapshotIndexFileName))
+}
+
+func (s Store) writeSnapshotIndex(snapshots []Snapshot) error {
+	contents, err := marshalIndent(snapshots, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return s.backend.Write(path.Join(SnapshotsDir, snapshotIndexFileName), contents, OS_READ_WRITE_MODE)
+}
+
+// snapshotID is the creation time, with a counter appended when several
+// snapshots are taken within the same second.
+func snapshotID(createdAt time.Time, snapshots []Snapshot) string {
+	base := createdAt.Format(snapshotIDFormat)
+
+	taken := map[string]bool{}
+	for _, snapshot := range snapshots {
+		taken[snapshot.ID] = true
+	}
+
+	id := base
+	for i := 1; taken[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	return id
+}
+
+func snapshotFileName(id string) string {
+	return path.Join(SnapshotsDir, fmt.Sprintf("%s.json", id))
+}
@@ -0,0 +1,172 @@
+package storage_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshots", func() {
+	var (
+		store   storage.Store
+		tempDir string
+		clock   time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		clock = time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+		storage.SetNow(func() time.Time { return clock })
+
+		store = storage.NewStore(storage.NewFileBackend(tempDir)).ForCommand("up")
+	})
+
+	AfterEach(func() {
+		storage.ResetNow()
+		os.Unsetenv("BBL_STATE_KEY")
+	})
+
+	Describe("History", func() {
+		It("records a snapshot every time the state is written", func() {
+			err := store.Set(storage.State{IAAS: "gcp", EnvID: "some-env-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			clock = clock.Add(time.Minute)
+			err = store.ForCommand("create-lbs").Set(storage.State{IAAS: "gcp", EnvID: "some-env-id", TFState: "some-tf-state"})
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshots, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).To(Equal([]storage.Snapshot{
+				{ID: "20170601T120000Z", Command: "up", CreatedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)},
+				{ID: "20170601T120100Z", Command: "create-lbs", CreatedAt: time.Date(2017, time.June, 1, 12, 1, 0, 0, time.UTC)},
+			}))
+
+			contents, err := ioutil.ReadFile(filepath.Join(tempDir, "snapshots", "20170601T120100Z.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("some-tf-state"))
+		})
+
+		It("gives snapshots taken within the same second distinct ids", func() {
+			for i := 0; i < 3; i++ {
+				err := store.Set(storage.State{IAAS: "gcp", EnvID: fmt.Sprintf("some-env-id-%d", i)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			snapshots, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).To(HaveLen(3))
+			Expect(snapshots[0].ID).To(Equal("20170601T120000Z"))
+			Expect(snapshots[1].ID).To(Equal("20170601T120000Z-1"))
+			Expect(snapshots[2].ID).To(Equal("20170601T120000Z-2"))
+		})
+
+		It("keeps only the most recent snapshots", func() {
+			for i := 0; i < storage.MaxSnapshots+2; i++ {
+				clock = clock.Add(time.Second)
+				err := store.Set(storage.State{IAAS: "gcp", EnvID: fmt.Sprintf("some-env-id-%d", i)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			snapshots, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).To(HaveLen(storage.MaxSnapshots))
+			Expect(snapshots[0].ID).To(Equal("20170601T120003Z"))
+
+			_, err = os.Stat(filepath.Join(tempDir, "snapshots", "20170601T120001Z.json"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("clears the history when the state is deleted", func() {
+			err := store.Set(storage.State{IAAS: "gcp", EnvID: "some-env-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshots, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).To(BeEmpty())
+
+			_, err = os.Stat(filepath.Join(tempDir, "snapshots", "20170601T120000Z.json"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("discards plaintext snapshots when the state is encrypted", func() {
+			err := store.Set(storage.State{IAAS: "gcp", EnvID: "some-env-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+			clock = clock.Add(time.Minute)
+
+			err = store.ForCommand("encrypt-state").Encrypt()
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshots, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).To(HaveLen(1))
+			Expect(snapshots[0].Command).To(Equal("encrypt-state"))
+
+			contents, err := ioutil.ReadFile(filepath.Join(tempDir, "snapshots", "20170601T120100Z.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeTrue())
+
+			_, err = os.Stat(filepath.Join(tempDir, "snapshots", "20170601T120000Z.json"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the index cannot be parsed", func() {
+				err := os.MkdirAll(filepath.Join(tempDir, "snapshots"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = ioutil.WriteFile(filepath.Join(tempDir, "snapshots", "index.json"), []byte("%%%"), 0644)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = store.History()
+				Expect(err).To(MatchError(ContainSubstring("read snapshot index: invalid character")))
+			})
+		})
+	})
+
+	Describe("Rollback", func() {
+		It("restores the state from a snapshot and records the rollback", func() {
+			err := store.Set(storage.State{IAAS: "gcp", EnvID: "some-env-id", TFState: "some-good-tf-state"})
+			Expect(err).NotTo(HaveOccurred())
+
+			clock = clock.Add(time.Minute)
+			err = store.Set(storage.State{IAAS: "gcp", EnvID: "some-env-id", TFState: "some-broken-tf-state"})
+			Expect(err).NotTo(HaveOccurred())
+
+			clock = clock.Add(time.Minute)
+			err = store.Rollback("20170601T120000Z")
+			Expect(err).NotTo(HaveOccurred())
+
+			state, err := store.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.TFState).To(Equal("some-good-tf-state"))
+
+			snapshots, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).To(HaveLen(3))
+			Expect(snapshots[2].Command).To(Equal("state rollback 20170601T120000Z"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the snapshot does not exist", func() {
+				err := store.Rollback("some-missing-id")
+				Expect(err).To(MatchError("snapshot \"some-missing-id\" not found, run `bbl state history` to list snapshots"))
+			})
+		})
+	})
+})
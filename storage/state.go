@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -15,7 +14,7 @@ var (
 )
 
 const (
-	STATE_VERSION = 9
+	STATE_VERSION = 10
 
 	OS_READ_WRITE_MODE = os.FileMode(0644)
 	StateFileName      = "bbl-state.json"
@@ -61,12 +60,23 @@ type LB struct {
 	Domain string `json:"domain,omitempty"`
 }
 
+// Network holds the address ranges chosen with bbl up. Empty fields use the
+// ranges bbl has always used, which are derived from 10.0.0.0/16.
+type Network struct {
+	CIDR                string   `json:"cidr,omitempty"`
+	BOSHSubnetCIDR      string   `json:"boshSubnetCIDR,omitempty"`
+	InternalSubnetCIDRs []string `json:"internalSubnetCIDRs,omitempty"`
+	LBSubnetCIDRs       []string `json:"lbSubnetCIDRs,omitempty"`
+}
+
 type Jumpbox struct {
-	Enabled   bool                   `json:"enabled"`
-	URL       string                 `json:"url"`
-	Variables string                 `json:"variables"`
-	Manifest  string                 `json:"manifest"`
-	State     map[string]interface{} `json:"state"`
+	Enabled     bool                   `json:"enabled"`
+	URL         string                 `json:"url"`
+	Variables   string                 `json:"variables"`
+	Manifest    string                 `json:"manifest"`
+	State       map[string]interface{} `json:"state"`
+	Fingerprint string                 `json:"fingerprint,omitempty"`
+	HostKey     string                 `json:"hostKey,omitempty"`
 }
 
 type State struct {
@@ -84,34 +94,55 @@ type State struct {
 	EnvID                      string  `json:"envID"`
 	TFState                    string  `json:"tfState"`
 	LB                         LB      `json:"lb"`
+	Network                    Network `json:"network"`
 	LatestTFOutput             string  `json:"latestTFOutput"`
+
+	TerraformOverrides map[string]string `json:"terraformOverrides,omitempty"`
+
+	Migrations []MigrationRecord `json:"migrations,omitempty"`
+
+	Layout     string            `json:"layout,omitempty"`
+	Components map[string]string `json:"components,omitempty"`
 }
 
 type Store struct {
-	version   int
-	stateFile string
+	version int
+	backend Backend
+	command string
 }
 
-func NewStore(dir string) Store {
+func NewStore(backend Backend) Store {
 	return Store{
-		version:   STATE_VERSION,
-		stateFile: filepath.Join(dir, StateFileName),
+		version: STATE_VERSION,
+		backend: backend,
 	}
 }
 
-func (s Store) Set(state State) error {
-	_, err := os.Stat(filepath.Dir(s.stateFile))
-	if err != nil {
-		return err
-	}
+// ForCommand returns a store that records command as the producer of the
+// snapshots it takes.
+func (s Store) ForCommand(command string) Store {
+	s.command = command
+	return s
+}
+
+// Get reads the state as it is currently persisted in the backend.
+func (s Store) Get() (State, error) {
+	return GetStateFromBackend(s.backend)
+}
 
+func (s Store) Set(state State) error {
 	if reflect.DeepEqual(state, State{}) {
-		err := os.Remove(s.stateFile)
-		if err != nil && !os.IsNotExist(err) {
+		err := s.deleteComponents(nil)
+		if err != nil {
+			return err
+		}
+
+		err = s.backend.Delete(StateFileName)
+		if err != nil {
 			return err
 		}
 
-		return nil
+		return s.clearHistory()
 	}
 
 	state.Version = s.version
@@ -127,37 +158,196 @@ func (s Store) Set(state State) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(s.stateFile, jsonData, os.FileMode(0644))
+
+	if state.Layout == SplitLayout {
+		err = s.writeSplit(state)
+		if err != nil {
+			return err
+		}
+
+		return s.snapshot(jsonData, OS_READ_WRITE_MODE, s.command)
+	}
+
+	encrypt, err := s.shouldEncrypt()
+	if err != nil {
+		return err
+	}
+
+	if !encrypt {
+		return s.save(jsonData, OS_READ_WRITE_MODE)
+	}
+
+	key, err := LoadStateKey()
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return StateKeyMissing
+	}
+
+	jsonData, err = NewCipher(key).Encrypt(jsonData)
+	if err != nil {
+		return err
+	}
+
+	return s.save(jsonData, OS_OWNER_READ_WRITE_MODE)
+}
+
+// Encrypt rewrites a plaintext bbl-state.json in the encrypted format using
+// the configured state key. Later calls to Set keep the file encrypted. The
+// plaintext snapshots are discarded so no unencrypted copy is left behind.
+func (s Store) Encrypt() error {
+	contents, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if IsEncrypted(contents) {
+		return errors.New("bbl-state.json is already encrypted")
+	}
+
+	state, err := s.Get()
+	if err != nil {
+		return err
+	}
+
+	if state.Layout == SplitLayout {
+		return SplitLayoutEncrypted
+	}
+
+	key, err := LoadStateKey()
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return fmt.Errorf("set %s or %s to encrypt bbl-state.json", StateKeyEnvVar, StateKeyFileEnvVar)
+	}
+
+	encryptedContents, err := NewCipher(key).Encrypt(contents)
+	if err != nil {
+		return err
+	}
+
+	err = s.clearHistory()
+	if err != nil {
+		return err
+	}
+
+	return s.save(encryptedContents, OS_OWNER_READ_WRITE_MODE)
+}
+
+// Decrypt rewrites an encrypted bbl-state.json as plaintext, starting a new
+// snapshot history.
+func (s Store) Decrypt() error {
+	contents, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if !IsEncrypted(contents) {
+		return errors.New("bbl-state.json is not encrypted")
+	}
+
+	plaintext, err := decryptState(contents)
+	if err != nil {
+		return err
+	}
+
+	err = s.clearHistory()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return s.save(plaintext, OS_READ_WRITE_MODE)
+}
+
+// shouldEncrypt keeps an existing state file in its current format. A new
+// state file is encrypted whenever a state key is configured.
+func (s Store) shouldEncrypt() (bool, error) {
+	contents, err := s.backend.Read(StateFileName)
+	switch {
+	case err == NotFound:
+		key, err := LoadStateKey()
+		return key != nil, err
+	case err != nil:
+		return false, err
+	}
+
+	return IsEncrypted(contents), nil
+}
+
+func (s Store) read() ([]byte, error) {
+	contents, err := s.backend.Read(StateFileName)
+	if err == NotFound {
+		return nil, fmt.Errorf("%s not found in %q", StateFileName, s.backend.Location())
+	}
+
+	return contents, err
+}
+
+// save writes bbl-state.json and records it as a snapshot.
+func (s Store) save(contents []byte, mode os.FileMode) error {
+	err := s.write(contents, mode)
+	if err != nil {
+		return err
+	}
+
+	return s.snapshot(contents, mode, s.command)
+}
+
+func (s Store) write(contents []byte, mode os.FileMode) error {
+	return s.backend.Write(StateFileName, contents, mode)
+}
+
+func decryptState(contents []byte) ([]byte, error) {
+	key, err := LoadStateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, StateKeyMissing
+	}
+
+	return NewCipher(key).Decrypt(contents)
 }
 
 func (g GCP) Empty() bool {
 	return g.ServiceAccountKey == "" && g.ProjectID == "" && g.Region == "" && g.Zone == ""
 }
 
-var GetStateLogger logger
+// GetState loads the state from a state directory or a --state-backend URL.
+func GetState(location string) (State, error) {
+	backend, err := NewBackend(location)
+	if err != nil {
+		return State{}, err
+	}
+
+	return GetStateFromBackend(backend)
+}
 
-func GetState(dir string) (State, error) {
+func GetStateFromBackend(backend Backend) (State, error) {
 	state := State{}
 
-	_, err := os.Stat(dir)
+	contents, err := backend.Read(StateFileName)
+	if err == NotFound {
+		return state, nil
+	}
 	if err != nil {
 		return state, err
 	}
 
-	file, err := os.Open(filepath.Join(dir, StateFileName))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return state, nil
+	plaintext := contents
+	if IsEncrypted(contents) {
+		plaintext, err = decryptState(contents)
+		if err != nil {
+			return state, err
 		}
-		return state, err
 	}
 
-	err = json.NewDecoder(file).Decode(&state)
+	err = json.Unmarshal(plaintext, &state)
 	if err != nil {
 		return state, err
 	}
@@ -177,6 +367,26 @@ func GetState(dir string) (State, error) {
 		return state, fmt.Errorf("Existing bbl environment was created with a newer version of bbl. Please upgrade to a version of bbl compatible with schema version %d.\n", state.Version)
 	}
 
+	// Older states are only migrated in memory here, so that commands which
+	// merely read the state leave it alone. Store.Migrate persists the
+	// migration for migrate-state and for the commands holding the lock.
+	if state.Version < STATE_VERSION {
+		plan, err := planMigration(plaintext)
+		if err != nil {
+			return state, err
+		}
+
+		state = State{}
+		err = json.Unmarshal(plan.After, &state)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	if state.Layout == SplitLayout {
+		return readComponents(backend, state)
+	}
+
 	return state, nil
 }
 
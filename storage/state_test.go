@@ -6,7 +6,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/cloudfoundry/bosh-bootloader/fakes"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 
 	. "github.com/onsi/ginkgo"
@@ -23,7 +22,7 @@ var _ = Describe("Store", func() {
 		var err error
 		tempDir, err = ioutil.TempDir("", "")
 
-		store = storage.NewStore(tempDir)
+		store = storage.NewStore(storage.NewFileBackend(tempDir))
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -86,9 +85,11 @@ var _ = Describe("Store", func() {
 						State: map[string]interface{}{
 							"key": "value",
 						},
-						Variables:   "some-vars",
-						Manifest:    "name: bosh",
-						UserOpsFile: "some-ops-file",
+						Variables: "some-vars",
+						Manifest:  "name: bosh",
+						UserOpsFiles: []storage.NamedFile{
+							{Name: "some-ops-file.yml", Contents: "some-ops-file"},
+						},
 						Credentials: map[string]string{
 							"mbusUsername":              "some-mbus-username",
 							"natsUsername":              "some-nats-username",
@@ -120,7 +121,7 @@ var _ = Describe("Store", func() {
 				data, err := ioutil.ReadFile(filepath.Join(tempDir, "bbl-state.json"))
 				Expect(err).NotTo(HaveOccurred())
 				Expect(data).To(MatchJSON(`{
-				"version": 9,
+				"version": 10,
 				"iaas": "aws",
 				"noDirector": false,
 				"migratedFromCloudFormation": false,
@@ -151,6 +152,7 @@ var _ = Describe("Store", func() {
 					"chain": "some-chain",
 					"domain": "some-domain"
 				},
+				"network": {},
 				"jumpbox":{
 					"enabled": true,
 					"url": "some-jumpbox-url",
@@ -186,7 +188,9 @@ var _ = Describe("Store", func() {
 					},
 					"variables":   "some-vars",
 					"manifest": "name: bosh",
-					"userOpsFile": "some-ops-file",
+					"userOpsFiles": [
+						{"name": "some-ops-file.yml", "contents": "some-ops-file"}
+					],
 					"state": {
 						"key": "value"
 					}
@@ -256,9 +260,11 @@ var _ = Describe("Store", func() {
 						State: map[string]interface{}{
 							"key": "value",
 						},
-						Variables:   "some-vars",
-						Manifest:    "name: bosh",
-						UserOpsFile: "some-ops-file",
+						Variables: "some-vars",
+						Manifest:  "name: bosh",
+						UserOpsFiles: []storage.NamedFile{
+							{Name: "some-ops-file.yml", Contents: "some-ops-file"},
+						},
 						Credentials: map[string]string{
 							"mbusUsername":              "some-mbus-username",
 							"natsUsername":              "some-nats-username",
@@ -290,7 +296,7 @@ var _ = Describe("Store", func() {
 				data, err := ioutil.ReadFile(filepath.Join(tempDir, "bbl-state.json"))
 				Expect(err).NotTo(HaveOccurred())
 				Expect(data).To(MatchJSON(`{
-					"version": 9,
+					"version": 10,
 					"iaas": "aws",
 					"noDirector": false,
 					"migratedFromCloudFormation": false,
@@ -324,6 +330,7 @@ var _ = Describe("Store", func() {
 						"chain": "some-chain",
 						"domain": "some-domain"
 					},
+					"network": {},
 					"jumpbox":{
 						"enabled": false,
 						"url": "",
@@ -357,7 +364,9 @@ var _ = Describe("Store", func() {
 						},
 						"variables":   "some-vars",
 						"manifest": "name: bosh",
-						"userOpsFile": "some-ops-file",
+						"userOpsFiles": [
+						{"name": "some-ops-file.yml", "contents": "some-ops-file"}
+					],
 						"state": {
 							"key": "value"
 						}
@@ -426,7 +435,7 @@ var _ = Describe("Store", func() {
 			})
 
 			It("fails when the directory does not exist", func() {
-				store = storage.NewStore("non-valid-dir")
+				store = storage.NewStore(storage.NewFileBackend("non-valid-dir"))
 				err := store.Set(storage.State{})
 				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
 			})
@@ -445,6 +454,140 @@ var _ = Describe("Store", func() {
 		})
 	})
 
+	Describe("encryption", func() {
+		var stateFile string
+
+		BeforeEach(func() {
+			stateFile = filepath.Join(tempDir, "bbl-state.json")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("BBL_STATE_KEY")
+		})
+
+		Context("when a state key is configured and there is no state file", func() {
+			It("writes an encrypted state file readable only by the owner", func() {
+				os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+				err := store.Set(storage.State{
+					IAAS: "gcp",
+					BOSH: storage.BOSH{DirectorPassword: "some-director-password"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(storage.IsEncrypted(contents)).To(BeTrue())
+				Expect(string(contents)).NotTo(ContainSubstring("some-director-password"))
+
+				info, err := os.Stat(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+				state, err := storage.GetState(tempDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state.IAAS).To(Equal("gcp"))
+				Expect(state.BOSH.DirectorPassword).To(Equal("some-director-password"))
+			})
+		})
+
+		Context("when a plaintext state file already exists", func() {
+			It("keeps writing plaintext until the state is encrypted", func() {
+				err := store.Set(storage.State{IAAS: "gcp"})
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+				err = store.Set(storage.State{IAAS: "aws"})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(storage.IsEncrypted(contents)).To(BeFalse())
+			})
+		})
+
+		Describe("Encrypt and Decrypt", func() {
+			BeforeEach(func() {
+				err := store.Set(storage.State{IAAS: "gcp", EnvID: "some-env-id"})
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Setenv("BBL_STATE_KEY", "some-passphrase")
+			})
+
+			It("converts the state file in both directions", func() {
+				err := store.Encrypt()
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(storage.IsEncrypted(contents)).To(BeTrue())
+
+				err = store.Set(storage.State{IAAS: "gcp", EnvID: "some-other-env-id"})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err = ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(storage.IsEncrypted(contents)).To(BeTrue())
+
+				err = store.Decrypt()
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err = ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(storage.IsEncrypted(contents)).To(BeFalse())
+				Expect(string(contents)).To(ContainSubstring("some-other-env-id"))
+
+				info, err := os.Stat(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0644)))
+			})
+
+			Context("failure cases", func() {
+				It("returns an error when the state is already encrypted", func() {
+					err := store.Encrypt()
+					Expect(err).NotTo(HaveOccurred())
+
+					err = store.Encrypt()
+					Expect(err).To(MatchError("bbl-state.json is already encrypted"))
+				})
+
+				It("returns an error when the state is not encrypted", func() {
+					err := store.Decrypt()
+					Expect(err).To(MatchError("bbl-state.json is not encrypted"))
+				})
+
+				It("returns an error when encrypting without a state key", func() {
+					os.Unsetenv("BBL_STATE_KEY")
+
+					err := store.Encrypt()
+					Expect(err).To(MatchError("set BBL_STATE_KEY or BBL_STATE_KEY_FILE to encrypt bbl-state.json"))
+				})
+			})
+		})
+
+		Context("when the state file is encrypted and no state key is configured", func() {
+			BeforeEach(func() {
+				os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+				err := store.Set(storage.State{IAAS: "gcp"})
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Unsetenv("BBL_STATE_KEY")
+			})
+
+			It("fails to read the state", func() {
+				_, err := storage.GetState(tempDir)
+				Expect(err).To(Equal(storage.StateKeyMissing))
+			})
+
+			It("fails to write the state", func() {
+				err := store.Set(storage.State{IAAS: "aws"})
+				Expect(err).To(Equal(storage.StateKeyMissing))
+			})
+		})
+	})
+
 	Describe("GCP", func() {
 		Describe("Empty", func() {
 			It("returns true when all fields are blank", func() {
@@ -462,13 +605,6 @@ var _ = Describe("Store", func() {
 	})
 
 	Describe("GetState", func() {
-		var logger *fakes.Logger
-
-		BeforeEach(func() {
-			logger = &fakes.Logger{}
-			storage.GetStateLogger = logger
-		})
-
 		Context("when there is a completely empty state file", func() {
 			BeforeEach(func() {
 				err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{}`), os.ModePerm)
@@ -479,7 +615,7 @@ var _ = Describe("Store", func() {
 				state, err := storage.GetState(tempDir)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(state).To(Equal(storage.State{
-					Version: 9,
+					Version: 10,
 				}))
 			})
 		})
@@ -498,10 +634,10 @@ var _ = Describe("Store", func() {
 			})
 		})
 
-		Context("when there is a v9 state file", func() {
+		Context("when there is a v10 state file", func() {
 			BeforeEach(func() {
 				err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{
-					"version": 9,
+					"version": 10,
 					"iaas": "aws",
 					"aws": {
 						"accessKeyId": "some-aws-access-key-id",
@@ -534,7 +670,7 @@ var _ = Describe("Store", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(state).To(Equal(storage.State{
-					Version: 9,
+					Version: 10,
 					IAAS:    "aws",
 					AWS: storage.AWS{
 						AccessKeyID:     "some-aws-access-key-id",
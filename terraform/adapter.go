@@ -0,0 +1,128 @@
+package terraform
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+// Adapter builds the arguments for one range of terraform versions. There
+// is nothing to adapt for outputs, since bbl reads them from the state file
+// and ParseOutputs understands the formats of every supported version.
+type Adapter struct {
+	Version string
+
+	// Terraform 0.11 asks for confirmation before applying.
+	autoApprove bool
+}
+
+type versionRange struct {
+	min     string
+	max     string
+	adapter Adapter
+}
+
+// supportedVersions lists the terraform releases bbl works with, oldest first.
+// Each range includes min and excludes max, and each starts where the previous
+// one ends: 0.10.x needs no special arguments and 0.11.x needs -auto-approve
+// to apply without asking. Terraform 0.10 is the oldest release that
+// understands the provider version pins in the templates, and 0.11 the newest
+// that understands their syntax and the 1.x providers they pin.
+var supportedVersions = []versionRange{
+	{min: "0.10.0", max: "0.11.0", adapter: Adapter{}},
+	{min: "0.11.0", max: "0.12.0", adapter: Adapter{autoApprove: true}},
+}
+
+// NewAdapter returns the adapter for the given terraform version, or an error
+// when bbl does not support it.
+func NewAdapter(version string) (Adapter, error) {
+	currentVersion, err := semver.NewVersion(version)
+	if err != nil {
+		return Adapter{}, err
+	}
+
+	for _, supported := range supportedVersions {
+		if !currentVersion.LessThan(*semver.New(supported.min)) && currentVersion.LessThan(*semver.New(supported.max)) {
+			adapter := supported.adapter
+			adapter.Version = version
+			return adapter, nil
+		}
+	}
+
+	// The ranges leave no gaps, so the version is either older than the
+	// oldest or at least as new as the newest.
+	oldest := supportedVersions[0]
+	if currentVersion.LessThan(*semver.New(oldest.min)) {
+		return Adapter{}, fmt.Errorf("Terraform version must be at least v%s", oldest.min)
+	}
+
+	newest := supportedVersions[len(supportedVersions)-1]
+	return Adapter{}, fmt.Errorf("Version %s of terraform is not supported by bbl yet, please use a version before v%s.", version, newest.max)
+}
+
+// InitArgs installs providers from pluginDir instead of downloading them
+// when pluginDir is set.
+func (a Adapter) InitArgs(pluginDir string) []string {
+	args := []string{"init"}
+	if pluginDir != "" {
+		args = append(args, "-plugin-dir", pluginDir)
+	}
+	return args
+}
+
+func (a Adapter) ApplyArgs(varFile string) []string {
+	args := []string{"apply"}
+	if a.autoApprove {
+		args = append(args, "-auto-approve")
+	}
+	return append(args, "-var-file", varFile)
+}
+
+func (a Adapter) DestroyArgs(varFile string) []string {
+	return []string{"destroy", "-force", "-var-file", varFile}
+}
+
+func (a Adapter) PlanArgs(varFile string) []string {
+	return []string{"plan", "-input=false", "-no-color", "-var-file", varFile}
+}
+
+func (a Adapter) RefreshArgs(varFile string) []string {
+	return []string{"refresh", "-input=false", "-no-color", "-var-file", varFile}
+}
+
+// ImportArgs leaves out the var file when varFile is empty.
+func (a Adapter) ImportArgs(varFile, address, id string) []string {
+	args := []string{"import"}
+	if varFile != "" {
+		args = append(args, "-var-file", varFile)
+	}
+	return append(args, address, id)
+}
+
+// adapterCache detects the installed terraform version once per bbl
+// invocation.
+type adapterCache struct {
+	mutex   sync.Mutex
+	adapter *Adapter
+}
+
+func (c *adapterCache) get(version func() (string, error)) (Adapter, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.adapter == nil {
+		currentVersion, err := version()
+		if err != nil {
+			return Adapter{}, err
+		}
+
+		adapter, err := NewAdapter(currentVersion)
+		if err != nil {
+			return Adapter{}, err
+		}
+		c.adapter = &adapter
+	}
+
+	return *c.adapter, nil
+}
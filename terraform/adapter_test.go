@@ -0,0 +1,96 @@
+package terraform_test
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Adapter", func() {
+	DescribeTable("building arguments for each supported version",
+		func(version string, applyArgs, destroyArgs, refreshArgs []string) {
+			adapter, err := terraform.NewAdapter(version)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(adapter.Version).To(Equal(version))
+
+			Expect(adapter.InitArgs("")).To(Equal([]string{"init"}))
+			Expect(adapter.ApplyArgs("some-var-file")).To(Equal(applyArgs))
+			Expect(adapter.DestroyArgs("some-var-file")).To(Equal(destroyArgs))
+			Expect(adapter.RefreshArgs("some-var-file")).To(Equal(refreshArgs))
+			Expect(adapter.PlanArgs("some-var-file")).To(Equal([]string{"plan", "-input=false", "-no-color", "-var-file", "some-var-file"}))
+		},
+		Entry("0.10.x", "0.10.8",
+			[]string{"apply", "-var-file", "some-var-file"},
+			[]string{"destroy", "-force", "-var-file", "some-var-file"},
+			[]string{"refresh", "-input=false", "-no-color", "-var-file", "some-var-file"},
+		),
+		Entry("0.11.x", "0.11.14",
+			[]string{"apply", "-auto-approve", "-var-file", "some-var-file"},
+			[]string{"destroy", "-force", "-var-file", "some-var-file"},
+			[]string{"refresh", "-input=false", "-no-color", "-var-file", "some-var-file"},
+		),
+		Entry("the first 0.10 release", "0.10.0",
+			[]string{"apply", "-var-file", "some-var-file"},
+			[]string{"destroy", "-force", "-var-file", "some-var-file"},
+			[]string{"refresh", "-input=false", "-no-color", "-var-file", "some-var-file"},
+		),
+		Entry("the first 0.11 release", "0.11.0",
+			[]string{"apply", "-auto-approve", "-var-file", "some-var-file"},
+			[]string{"destroy", "-force", "-var-file", "some-var-file"},
+			[]string{"refresh", "-input=false", "-no-color", "-var-file", "some-var-file"},
+		),
+	)
+
+	Describe("InitArgs", func() {
+		It("installs providers from the plugin directory when there is one", func() {
+			adapter, err := terraform.NewAdapter("0.11.14")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(adapter.InitArgs("/some/plugin/dir")).To(Equal([]string{"init", "-plugin-dir", "/some/plugin/dir"}))
+		})
+	})
+
+	Describe("ImportArgs", func() {
+		It("passes the var file before the address and id", func() {
+			adapter, err := terraform.NewAdapter("0.11.14")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(adapter.ImportArgs("some-var-file", "some-address", "some-id")).To(Equal([]string{
+				"import", "-var-file", "some-var-file", "some-address", "some-id",
+			}))
+		})
+
+		It("leaves out the var file when there is none", func() {
+			adapter, err := terraform.NewAdapter("0.11.14")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(adapter.ImportArgs("", "some-address", "some-id")).To(Equal([]string{"import", "some-address", "some-id"}))
+		})
+	})
+
+	Describe("unsupported versions", func() {
+		It("returns an error for versions older than the oldest supported one", func() {
+			_, err := terraform.NewAdapter("0.9.11")
+			Expect(err).To(MatchError("Terraform version must be at least v0.10.0"))
+		})
+
+		DescribeTable("returns an error for versions newer than the newest supported one",
+			func(version string) {
+				_, err := terraform.NewAdapter(version)
+				Expect(err).To(MatchError(fmt.Sprintf("Version %s of terraform is not supported by bbl yet, please use a version before v0.12.0.", version)))
+			},
+			Entry("0.12.0", "0.12.0"),
+			Entry("0.15.5", "0.15.5"),
+			Entry("1.5.7", "1.5.7"),
+		)
+
+		It("returns an error when the version cannot be parsed", func() {
+			_, err := terraform.NewAdapter("lol.5.2")
+			Expect(err.Error()).To(ContainSubstring("invalid syntax"))
+		})
+	})
+})
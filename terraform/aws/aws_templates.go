@@ -171,7 +171,7 @@ resource "aws_security_group" "nat_security_group" {
 }
 
 resource "aws_instance" "nat" {
-  private_ip             = "10.0.0.7"
+  private_ip             = "${cidrhost(var.bosh_subnet_cidr, 7)}"
   instance_type          = "t2.medium"
   subnet_id              = "${aws_subnet.bosh_subnet.id}"
   source_dest_check      = false
@@ -207,11 +207,16 @@ variable "region" {
 }
 
 provider "aws" {
+  version    = "~> 1.0"
   access_key = "${var.access_key}"
   secret_key = "${var.secret_key}"
   region     = "${var.region}"
 }
 
+provider "tls" {
+  version = "~> 1.0"
+}
+
 resource "aws_default_security_group" "default_security_group" {
 	vpc_id = "${aws_vpc.vpc.id}"
 }
@@ -457,10 +462,14 @@ variable "availability_zones" {
   type = "list"
 }
 
+variable "internal_subnet_cidrs" {
+  type = "list"
+}
+
 resource "aws_subnet" "internal_subnets" {
   count             = "${length(var.availability_zones)}"
   vpc_id            = "${aws_vpc.vpc.id}"
-  cidr_block        = "${cidrsubnet("10.0.0.0/16", 4, count.index+1)}"
+  cidr_block        = "${element(var.internal_subnet_cidrs, count.index)}"
   availability_zone = "${element(var.availability_zones, count.index)}"
 
   tags {
@@ -595,10 +604,14 @@ output "kms_key_arn" {
 }
 `
 
-const LBSubnetTemplate = `resource "aws_subnet" "lb_subnets" {
+const LBSubnetTemplate = `variable "lb_subnet_cidrs" {
+  type = "list"
+}
+
+resource "aws_subnet" "lb_subnets" {
   count             = "${length(var.availability_zones)}"
   vpc_id            = "${aws_vpc.vpc.id}"
-  cidr_block        = "${cidrsubnet("10.0.0.0/20", 4, count.index+2)}"
+  cidr_block        = "${element(var.lb_subnet_cidrs, count.index)}"
   availability_zone = "${element(var.availability_zones, count.index)}"
 
   tags {
@@ -1829,3 +1842,123 @@ resource "aws_route53_record" "tcp" {
   records = ["${aws_elb.cf_tcp_lb.dns_name}"]
 }
 `
+
+const ExternalDatabaseTemplate = `provider "random" {
+  version = "~> 1.0"
+}
+
+resource "random_string" "director_db_password" {
+  length  = 32
+  special = false
+}
+
+resource "aws_db_subnet_group" "director_db" {
+  name       = "${lower(var.short_env_id)}-director-db"
+  subnet_ids = ["${aws_subnet.internal_subnets.*.id}"]
+
+  tags {
+    Name = "${var.env_id}-director-db"
+  }
+}
+
+resource "aws_security_group" "director_db_security_group" {
+  description = "Director database"
+  vpc_id      = "${aws_vpc.vpc.id}"
+
+  tags {
+    Name = "${var.env_id}-director-db-security-group"
+  }
+}
+
+resource "aws_security_group_rule" "director_db_security_group_rule_postgres" {
+  security_group_id        = "${aws_security_group.director_db_security_group.id}"
+  type                     = "ingress"
+  protocol                 = "tcp"
+  from_port                = 5432
+  to_port                  = 5432
+  source_security_group_id = "${aws_security_group.bosh_security_group.id}"
+}
+
+resource "aws_db_instance" "director_db" {
+  identifier              = "${lower(var.short_env_id)}-director"
+  engine                  = "postgres"
+  engine_version          = "9.6"
+  instance_class          = "db.t2.medium"
+  allocated_storage       = 20
+  storage_type            = "gp2"
+  storage_encrypted       = true
+  backup_retention_period = 7
+  skip_final_snapshot     = true
+
+  name     = "bosh"
+  username = "bosh"
+  password = "${random_string.director_db_password.result}"
+
+  db_subnet_group_name   = "${aws_db_subnet_group.director_db.name}"
+  vpc_security_group_ids = ["${aws_security_group.director_db_security_group.id}"]
+}
+
+output "external_db_host" {
+  value = "${aws_db_instance.director_db.address}"
+}
+
+output "external_db_user" {
+  value = "${aws_db_instance.director_db.username}"
+}
+
+output "external_db_password" {
+  value     = "${random_string.director_db_password.result}"
+  sensitive = true
+}
+
+output "external_db_name" {
+  value = "${aws_db_instance.director_db.name}"
+}
+`
+
+const ExternalBlobstoreTemplate = `resource "aws_s3_bucket" "director_blobstore" {
+  bucket_prefix = "${lower(var.short_env_id)}-blobstore-"
+  force_destroy = true
+
+  tags {
+    Name = "${var.env_id}-director-blobstore"
+  }
+}
+
+resource "aws_s3_bucket_policy" "director_blobstore" {
+  bucket = "${aws_s3_bucket.director_blobstore.id}"
+  policy = <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "AWS": "${aws_iam_role.bosh.arn}"
+      },
+      "Action": [
+        "s3:ListBucket"
+      ],
+      "Resource": "${aws_s3_bucket.director_blobstore.arn}"
+    },
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "AWS": "${aws_iam_role.bosh.arn}"
+      },
+      "Action": [
+        "s3:GetObject",
+        "s3:PutObject",
+        "s3:DeleteObject"
+      ],
+      "Resource": "${aws_s3_bucket.director_blobstore.arn}/*"
+    }
+  ]
+}
+EOF
+}
+
+output "director_blobstore_bucket" {
+  value = "${aws_s3_bucket.director_blobstore.id}"
+}
+`
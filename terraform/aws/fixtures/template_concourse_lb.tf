@@ -180,7 +180,7 @@ resource "aws_security_group" "nat_security_group" {
 }
 
 resource "aws_instance" "nat" {
-  private_ip             = "10.0.0.7"
+  private_ip             = "${cidrhost(var.bosh_subnet_cidr, 7)}"
   instance_type          = "t2.medium"
   subnet_id              = "${aws_subnet.bosh_subnet.id}"
   source_dest_check      = false
@@ -216,11 +216,16 @@ variable "region" {
 }
 
 provider "aws" {
+  version    = "~> 1.0"
   access_key = "${var.access_key}"
   secret_key = "${var.secret_key}"
   region     = "${var.region}"
 }
 
+provider "tls" {
+  version = "~> 1.0"
+}
+
 resource "aws_default_security_group" "default_security_group" {
 	vpc_id = "${aws_vpc.vpc.id}"
 }
@@ -466,10 +471,14 @@ variable "availability_zones" {
   type = "list"
 }
 
+variable "internal_subnet_cidrs" {
+  type = "list"
+}
+
 resource "aws_subnet" "internal_subnets" {
   count             = "${length(var.availability_zones)}"
   vpc_id            = "${aws_vpc.vpc.id}"
-  cidr_block        = "${cidrsubnet("10.0.0.0/16", 4, count.index+1)}"
+  cidr_block        = "${element(var.internal_subnet_cidrs, count.index)}"
   availability_zone = "${element(var.availability_zones, count.index)}"
 
   tags {
@@ -603,10 +612,14 @@ output "kms_key_arn" {
   value = "${aws_kms_key.kms_key.arn}"
 }
 
+variable "lb_subnet_cidrs" {
+  type = "list"
+}
+
 resource "aws_subnet" "lb_subnets" {
   count             = "${length(var.availability_zones)}"
   vpc_id            = "${aws_vpc.vpc.id}"
-  cidr_block        = "${cidrsubnet("10.0.0.0/20", 4, count.index+2)}"
+  cidr_block        = "${element(var.lb_subnet_cidrs, count.index)}"
   availability_zone = "${element(var.availability_zones, count.index)}"
 
   tags {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 )
 
@@ -37,6 +38,21 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		return map[string]string{}, err
 	}
 
+	network, err := bosh.NewNetwork(state.Network)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	internalSubnets, err := network.InternalSubnets(len(azs))
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	internalSubnetsString, err := jsonMarshal(internalSubnets)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
 	shortEnvID := state.EnvID
 	if len(shortEnvID) > terraformNameCharLimit {
 		sha1 := fmt.Sprintf("%x", sha1.Sum([]byte(state.EnvID)))
@@ -51,9 +67,23 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		"region":                 state.AWS.Region,
 		"bosh_availability_zone": state.Stack.BOSHAZ,
 		"availability_zones":     string(azsString),
+		"vpc_cidr":               network.CIDR.String(),
+		"bosh_subnet_cidr":       network.BOSHSubnet.String(),
+		"internal_subnet_cidrs":  string(internalSubnetsString),
 	}
 
 	if state.LB.Type == "cf" || state.LB.Type == "concourse" {
+		lbSubnets, err := network.LBSubnets(len(azs))
+		if err != nil {
+			return map[string]string{}, err
+		}
+
+		lbSubnetsString, err := jsonMarshal(lbSubnets)
+		if err != nil {
+			return map[string]string{}, err
+		}
+		inputs["lb_subnet_cidrs"] = string(lbSubnetsString)
+
 		inputs["ssl_certificate_name_prefix"] = ""
 		inputs["ssl_certificate_name"] = state.Stack.CertificateName
 		if state.Stack.CertificateName == "" {
@@ -84,6 +84,9 @@ var _ = Describe("InputGenerator", func() {
 				"region":                 "some-region",
 				"bosh_availability_zone": "some-zone",
 				"availability_zones":     `["z1","z2","z3"]`,
+				"vpc_cidr":               "10.0.0.0/16",
+				"bosh_subnet_cidr":       "10.0.0.0/24",
+				"internal_subnet_cidrs":  `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
 			}))
 		})
 	})
@@ -130,6 +133,10 @@ var _ = Describe("InputGenerator", func() {
 				"region":                      "some-region",
 				"bosh_availability_zone":      "some-zone",
 				"availability_zones":          `["z1","z2","z3"]`,
+				"vpc_cidr":                    "10.0.0.0/16",
+				"bosh_subnet_cidr":            "10.0.0.0/24",
+				"internal_subnet_cidrs":       `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
+				"lb_subnet_cidrs":             `["10.0.2.0/24","10.0.3.0/24","10.0.4.0/24"]`,
 				"ssl_certificate_name_prefix": "",
 				"ssl_certificate_name":        "some-certificate-name",
 			}))
@@ -154,6 +161,10 @@ var _ = Describe("InputGenerator", func() {
 					"region":                      "some-region",
 					"bosh_availability_zone":      "some-zone",
 					"availability_zones":          `["z1","z2","z3"]`,
+					"vpc_cidr":                    "10.0.0.0/16",
+					"bosh_subnet_cidr":            "10.0.0.0/24",
+					"internal_subnet_cidrs":       `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
+					"lb_subnet_cidrs":             `["10.0.2.0/24","10.0.3.0/24","10.0.4.0/24"]`,
 					"ssl_certificate_name":        "some-certificate-name",
 					"ssl_certificate_name_prefix": "",
 					"system_domain":               "some-domain",
@@ -203,6 +214,10 @@ var _ = Describe("InputGenerator", func() {
 				"region":                      "some-region",
 				"bosh_availability_zone":      "some-zone",
 				"availability_zones":          `["z1","z2","z3"]`,
+				"vpc_cidr":                    "10.0.0.0/16",
+				"bosh_subnet_cidr":            "10.0.0.0/24",
+				"internal_subnet_cidrs":       `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
+				"lb_subnet_cidrs":             `["10.0.2.0/24","10.0.3.0/24","10.0.4.0/24"]`,
 				"ssl_certificate":             "some-cert",
 				"ssl_certificate_chain":       "some-chain",
 				"ssl_certificate_private_key": "some-key",
@@ -212,6 +227,28 @@ var _ = Describe("InputGenerator", func() {
 		})
 	})
 
+	Context("when a network has been configured", func() {
+		It("returns the configured ranges", func() {
+			inputs, err := inputGenerator.Generate(storage.State{
+				EnvID: "some-env-id",
+				Network: storage.Network{
+					CIDR:                "192.168.0.0/16",
+					BOSHSubnetCIDR:      "192.168.1.0/24",
+					InternalSubnetCIDRs: []string{"192.168.128.0/20", "192.168.144.0/20", "192.168.160.0/20"},
+				},
+				LB: storage.LB{
+					Type: "cf",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inputs["vpc_cidr"]).To(Equal("192.168.0.0/16"))
+			Expect(inputs["bosh_subnet_cidr"]).To(Equal("192.168.1.0/24"))
+			Expect(inputs["internal_subnet_cidrs"]).To(Equal(`["192.168.128.0/20","192.168.144.0/20","192.168.160.0/20"]`))
+			Expect(inputs["lb_subnet_cidrs"]).To(Equal(`["192.168.2.0/24","192.168.3.0/24","192.168.4.0/24"]`))
+		})
+	})
+
 	Context("failure cases", func() {
 		Context("when the availability zone retriever fails", func() {
 			It("returns an error", func() {
@@ -222,6 +259,15 @@ var _ = Describe("InputGenerator", func() {
 			})
 		})
 
+		Context("when the network is invalid", func() {
+			It("returns an error", func() {
+				_, err := inputGenerator.Generate(storage.State{
+					Network: storage.Network{CIDR: "10.0.0.0/24"},
+				})
+				Expect(err).To(MatchError("network cidr 10.0.0.0/24 is too small, use a /20 or larger network"))
+			})
+		})
+
 		Context("when the azs failed to marshal", func() {
 			BeforeEach(func() {
 				aws.SetJSONMarshal(func(interface{}) ([]byte, error) {
@@ -61,6 +61,14 @@ func (tg TemplateGenerator) Generate(state storage.State) string {
 		}
 	}
 
+	if state.BOSH.ExternalDatabase {
+		t = strings.Join([]string{t, ExternalDatabaseTemplate}, "\n")
+	}
+
+	if state.BOSH.ExternalBlobstore {
+		t = strings.Join([]string{t, ExternalBlobstoreTemplate}, "\n")
+	}
+
 	// if state.Jumpbox.Enabled {
 	// 	t = strings.Join([]string{t, JumpboxTemplate}, "\n")
 	// }
@@ -2,6 +2,7 @@ package aws_test
 
 import (
 	"io/ioutil"
+	"regexp"
 
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 	"github.com/cloudfoundry/bosh-bootloader/terraform/aws"
@@ -41,6 +42,39 @@ var _ = Describe("TemplateGenerator", func() {
 			Entry("when a cf lb type is provided with a system domain", "fixtures/template_cf_lb_with_domain.tf", "cf", "some-domain"),
 		)
 
+		It("adds the director database and blobstore when they are external", func() {
+			expectedTemplate, err := ioutil.ReadFile("fixtures/template_external_db_and_blobstore.tf")
+			Expect(err).NotTo(HaveOccurred())
+
+			template := templateGenerator.Generate(storage.State{
+				BOSH: storage.BOSH{
+					ExternalDatabase:  true,
+					ExternalBlobstore: true,
+				},
+			})
+
+			Expect(template).To(Equal(string(expectedTemplate)))
+		})
+
+		It("pins every provider the template uses", func() {
+			template := templateGenerator.Generate(storage.State{
+				LB: storage.LB{Type: "cf", Domain: "some-domain"},
+				BOSH: storage.BOSH{
+					ExternalDatabase:  true,
+					ExternalBlobstore: true,
+				},
+			})
+
+			pinned := map[string]bool{}
+			for _, match := range regexp.MustCompile(`provider "(\w+)" {\n\s+version\s+= "~> \d+\.\d+"`).FindAllStringSubmatch(template, -1) {
+				pinned[match[1]] = true
+			}
+
+			for _, match := range regexp.MustCompile(`(?m)^(?:resource|data) "([a-z0-9]+)_`).FindAllStringSubmatch(template, -1) {
+				Expect(pinned).To(HaveKey(match[1]), "provider %q is not pinned", match[1])
+			}
+		})
+
 		Context("when migrated from CloudFormation", func() {
 			It("changes the security group descriptions", func() {
 				template := templateGenerator.Generate(storage.State{
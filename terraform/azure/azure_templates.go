@@ -24,7 +24,12 @@ variable "client_secret" {
 	type = "string"
 }
 
+variable "network_cidr" {
+	type = "string"
+}
+
 provider "azurerm" {
+  version          = "~> 1.0"
   subscription_id  = "${var.subscription_id}"
   tenant_id        = "${var.tenant_id}"
   client_id        = "${var.client_id}"
@@ -55,14 +60,14 @@ resource "azurerm_public_ip" "bosh" {
 
 const NetworkTemplate = `resource "azurerm_virtual_network" "bosh" {
   name                = "${var.env_id}-bosh"
-  address_space       = ["10.0.0.0/16"]
+  address_space       = ["${var.network_cidr}"]
   location            = "West US"
   resource_group_name = "${azurerm_resource_group.bosh.name}"
 }
 
 resource "azurerm_subnet" "bosh" {
   name                 = "${var.env_id}-bosh"
-  address_prefix       = "10.0.0.0/16"
+  address_prefix       = "${var.network_cidr}"
   resource_group_name  = "${azurerm_resource_group.bosh.name}"
   virtual_network_name = "${azurerm_virtual_network.bosh.name}"
 }
@@ -132,3 +137,77 @@ output "director_address" {
 	value = "https://${azurerm_public_ip.bosh.ip_address}:25555"
 }
 `
+
+const ExternalDatabaseTemplate = `provider "random" {
+  version = "~> 1.0"
+}
+
+resource "random_string" "director_db_password" {
+  length      = 32
+  special     = false
+  min_upper   = 1
+  min_lower   = 1
+  min_numeric = 1
+}
+
+resource "azurerm_postgresql_server" "director" {
+  name                = "${var.simple_env_id}-director"
+  location            = "West US"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+
+  sku {
+    name     = "GP_Gen5_2"
+    capacity = 2
+    tier     = "GeneralPurpose"
+    family   = "Gen5"
+  }
+
+  storage_profile {
+    storage_mb            = 51200
+    backup_retention_days = 7
+    geo_redundant_backup  = "Disabled"
+  }
+
+  administrator_login          = "bosh"
+  administrator_login_password = "${random_string.director_db_password.result}"
+  version                      = "9.6"
+  ssl_enforcement              = "Disabled"
+
+  tags {
+    environment = "${var.env_id}"
+  }
+}
+
+resource "azurerm_postgresql_database" "director" {
+  name                = "bosh"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+  server_name         = "${azurerm_postgresql_server.director.name}"
+  charset             = "UTF8"
+  collation           = "English_United States.1252"
+}
+
+resource "azurerm_postgresql_firewall_rule" "director" {
+  name                = "${var.env_id}-director"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+  server_name         = "${azurerm_postgresql_server.director.name}"
+  start_ip_address    = "${azurerm_public_ip.bosh.ip_address}"
+  end_ip_address      = "${azurerm_public_ip.bosh.ip_address}"
+}
+
+output "external_db_host" {
+  value = "${azurerm_postgresql_server.director.fqdn}"
+}
+
+output "external_db_user" {
+  value = "${azurerm_postgresql_server.director.administrator_login}@${azurerm_postgresql_server.director.name}"
+}
+
+output "external_db_password" {
+  value     = "${random_string.director_db_password.result}"
+  sensitive = true
+}
+
+output "external_db_name" {
+  value = "${azurerm_postgresql_database.director.name}"
+}
+`
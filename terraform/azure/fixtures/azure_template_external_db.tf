@@ -0,0 +1,204 @@
+variable "env_id" {
+	type = "string"
+}
+
+variable "simple_env_id" {
+	type = "string"
+}
+
+variable "subscription_id" {
+	type = "string"
+}
+
+variable "tenant_id" {
+	type = "string"
+}
+
+variable "client_id" {
+	type = "string"
+}
+
+variable "client_secret" {
+	type = "string"
+}
+
+variable "network_cidr" {
+	type = "string"
+}
+
+provider "azurerm" {
+  version          = "~> 1.0"
+  subscription_id  = "${var.subscription_id}"
+  tenant_id        = "${var.tenant_id}"
+  client_id        = "${var.client_id}"
+  client_secret    = "${var.client_secret}"
+}
+
+resource "azurerm_resource_group" "bosh" {
+  name     = "${var.env_id}-bosh"
+  location = "West US"
+
+  tags {
+    environment = "${var.env_id}"
+  }
+}
+
+resource "azurerm_public_ip" "bosh" {
+  name                         = "${var.env_id}-bosh"
+  location                     = "West US"
+  resource_group_name          = "${azurerm_resource_group.bosh.name}"
+  public_ip_address_allocation = "static"
+
+  tags {
+    environment = "${var.env_id}"
+  }
+}
+
+resource "azurerm_virtual_network" "bosh" {
+  name                = "${var.env_id}-bosh"
+  address_space       = ["${var.network_cidr}"]
+  location            = "West US"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+}
+
+resource "azurerm_subnet" "bosh" {
+  name                 = "${var.env_id}-bosh"
+  address_prefix       = "${var.network_cidr}"
+  resource_group_name  = "${azurerm_resource_group.bosh.name}"
+  virtual_network_name = "${azurerm_virtual_network.bosh.name}"
+}
+
+resource "azurerm_storage_account" "bosh" {
+  name                = "${var.simple_env_id}"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+
+  location     = "westus"
+  account_type = "Standard_GRS"
+
+  tags {
+    environment = "${var.env_id}"
+  }
+}
+
+resource "azurerm_network_security_group" "bosh" {
+  name                = "${var.env_id}-bosh"
+  location            = "West US"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+
+  security_rule {
+    name                       = "nsg-bosh"
+    priority                   = 100
+    direction                  = "Inbound"
+    access                     = "Allow"
+    protocol                   = "Tcp"
+    source_port_range          = "*"
+    destination_port_range     = "*"
+    source_address_prefix      = "*"
+    destination_address_prefix = "*"
+  }
+
+  tags {
+    environment = "${var.env_id}"
+  }
+}
+
+output "bosh_network_name" {
+    value = "${azurerm_virtual_network.bosh.name}"
+}
+
+output "bosh_subnet_name" {
+    value = "${azurerm_subnet.bosh.name}"
+}
+
+output "bosh_resource_group_name" {
+    value = "${azurerm_resource_group.bosh.name}"
+}
+
+output "bosh_storage_account_name" {
+    value = "${azurerm_storage_account.bosh.name}"
+}
+
+output "bosh_default_security_group" {
+    value = "${azurerm_network_security_group.bosh.name}"
+}
+
+output "external_ip" {
+    value = "${azurerm_public_ip.bosh.ip_address}"
+}
+
+output "director_address" {
+	value = "https://${azurerm_public_ip.bosh.ip_address}:25555"
+}
+
+provider "random" {
+  version = "~> 1.0"
+}
+
+resource "random_string" "director_db_password" {
+  length      = 32
+  special     = false
+  min_upper   = 1
+  min_lower   = 1
+  min_numeric = 1
+}
+
+resource "azurerm_postgresql_server" "director" {
+  name                = "${var.simple_env_id}-director"
+  location            = "West US"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+
+  sku {
+    name     = "GP_Gen5_2"
+    capacity = 2
+    tier     = "GeneralPurpose"
+    family   = "Gen5"
+  }
+
+  storage_profile {
+    storage_mb            = 51200
+    backup_retention_days = 7
+    geo_redundant_backup  = "Disabled"
+  }
+
+  administrator_login          = "bosh"
+  administrator_login_password = "${random_string.director_db_password.result}"
+  version                      = "9.6"
+  ssl_enforcement              = "Disabled"
+
+  tags {
+    environment = "${var.env_id}"
+  }
+}
+
+resource "azurerm_postgresql_database" "director" {
+  name                = "bosh"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+  server_name         = "${azurerm_postgresql_server.director.name}"
+  charset             = "UTF8"
+  collation           = "English_United States.1252"
+}
+
+resource "azurerm_postgresql_firewall_rule" "director" {
+  name                = "${var.env_id}-director"
+  resource_group_name = "${azurerm_resource_group.bosh.name}"
+  server_name         = "${azurerm_postgresql_server.director.name}"
+  start_ip_address    = "${azurerm_public_ip.bosh.ip_address}"
+  end_ip_address      = "${azurerm_public_ip.bosh.ip_address}"
+}
+
+output "external_db_host" {
+  value = "${azurerm_postgresql_server.director.fqdn}"
+}
+
+output "external_db_user" {
+  value = "${azurerm_postgresql_server.director.administrator_login}@${azurerm_postgresql_server.director.name}"
+}
+
+output "external_db_password" {
+  value     = "${random_string.director_db_password.result}"
+  sensitive = true
+}
+
+output "external_db_name" {
+  value = "${azurerm_postgresql_database.director.name}"
+}
@@ -22,7 +22,12 @@ variable "client_secret" {
 	type = "string"
 }
 
+variable "network_cidr" {
+	type = "string"
+}
+
 provider "azurerm" {
+  version          = "~> 1.0"
   subscription_id  = "${var.subscription_id}"
   tenant_id        = "${var.tenant_id}"
   client_id        = "${var.client_id}"
@@ -51,14 +56,14 @@ resource "azurerm_public_ip" "bosh" {
 
 resource "azurerm_virtual_network" "bosh" {
   name                = "${var.env_id}-bosh"
-  address_space       = ["10.0.0.0/16"]
+  address_space       = ["${var.network_cidr}"]
   location            = "West US"
   resource_group_name = "${azurerm_resource_group.bosh.name}"
 }
 
 resource "azurerm_subnet" "bosh" {
   name                 = "${var.env_id}-bosh"
-  address_prefix       = "10.0.0.0/16"
+  address_prefix       = "${var.network_cidr}"
   resource_group_name  = "${azurerm_resource_group.bosh.name}"
   virtual_network_name = "${azurerm_virtual_network.bosh.name}"
 }
@@ -3,6 +3,7 @@ package azure
 import (
 	"strings"
 
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 )
 
@@ -14,6 +15,11 @@ func NewInputGenerator() InputGenerator {
 }
 
 func (i InputGenerator) Generate(state storage.State) (map[string]string, error) {
+	network, err := bosh.NewNetwork(state.Network)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
 	simpleEnvId := strings.Replace(state.EnvID, "-", "", -1)
 	if len(simpleEnvId) > 20 {
 		simpleEnvId = simpleEnvId[:20]
@@ -25,6 +31,7 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		"tenant_id":       state.Azure.TenantID,
 		"client_id":       state.Azure.ClientID,
 		"client_secret":   state.Azure.ClientSecret,
+		"network_cidr":    network.CIDR.String(),
 	}
 
 	return input, nil
@@ -40,6 +40,7 @@ var _ = Describe("InputGenerator", func() {
 			"tenant_id":       state.Azure.TenantID,
 			"client_id":       state.Azure.ClientID,
 			"client_secret":   state.Azure.ClientSecret,
+			"network_cidr":    "10.0.0.0/16",
 		}))
 	})
 
@@ -56,6 +57,7 @@ var _ = Describe("InputGenerator", func() {
 				"tenant_id":       state.Azure.TenantID,
 				"client_id":       state.Azure.ClientID,
 				"client_secret":   state.Azure.ClientSecret,
+				"network_cidr":    "10.0.0.0/16",
 			}))
 		})
 	})
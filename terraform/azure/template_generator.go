@@ -13,5 +13,11 @@ func NewTemplateGenerator() TemplateGenerator {
 }
 
 func (t TemplateGenerator) Generate(state storage.State) string {
-	return strings.Join([]string{VarsTemplate, ResourceGroupTemplate, NetworkTemplate, StorageTemplate, NetworkSecurityGroupTemplate, OutputTemplate}, "\n")
+	template := strings.Join([]string{VarsTemplate, ResourceGroupTemplate, NetworkTemplate, StorageTemplate, NetworkSecurityGroupTemplate, OutputTemplate}, "\n")
+
+	if state.BOSH.ExternalDatabase {
+		template = strings.Join([]string{template, ExternalDatabaseTemplate}, "\n")
+	}
+
+	return template
 }
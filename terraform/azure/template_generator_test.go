@@ -34,5 +34,18 @@ var _ = Describe("TemplateGenerator", func() {
 			})
 			Expect(template).To(Equal(string(expectedTemplate)))
 		})
+
+		It("adds the director database when it is external", func() {
+			expectedTemplate, err := ioutil.ReadFile("fixtures/azure_template_external_db.tf")
+			Expect(err).NotTo(HaveOccurred())
+
+			template := templateGenerator.Generate(storage.State{
+				EnvID: "azure-environment",
+				BOSH: storage.BOSH{
+					ExternalDatabase: true,
+				},
+			})
+			Expect(template).To(Equal(string(expectedTemplate)))
+		})
 	})
 })
@@ -2,18 +2,24 @@ package terraform
 
 import (
 	"io"
+	"os"
 	"os/exec"
 )
 
 type Cmd struct {
-	stderr       io.Writer
-	outputBuffer io.Writer
+	stderr         io.Writer
+	outputBuffer   io.Writer
+	pluginCacheDir string
 }
 
-func NewCmd(stderr, outputBuffer io.Writer) Cmd {
+// NewCmd returns a command that lets terraform reuse the providers it has
+// downloaded before from pluginCacheDir, unless it is empty or the user has
+// set TF_PLUGIN_CACHE_DIR themselves.
+func NewCmd(stderr, outputBuffer io.Writer, pluginCacheDir string) Cmd {
 	return Cmd{
-		stderr:       stderr,
-		outputBuffer: outputBuffer,
+		stderr:         stderr,
+		outputBuffer:   outputBuffer,
+		pluginCacheDir: pluginCacheDir,
 	}
 }
 
@@ -21,6 +27,15 @@ func (c Cmd) Run(stdout io.Writer, workingDirectory string, args []string, debug
 	command := exec.Command("terraform", args...)
 	command.Dir = workingDirectory
 
+	if c.pluginCacheDir != "" && os.Getenv("TF_PLUGIN_CACHE_DIR") == "" {
+		// Terraform ignores a cache directory that does not exist.
+		err := os.MkdirAll(c.pluginCacheDir, os.ModePerm)
+		if err != nil {
+			return err
+		}
+		command.Env = append(os.Environ(), "TF_PLUGIN_CACHE_DIR="+c.pluginCacheDir)
+	}
+
 	if debug {
 		command.Stdout = io.MultiWriter(stdout, c.outputBuffer)
 		command.Stderr = io.MultiWriter(c.stderr, c.outputBuffer)
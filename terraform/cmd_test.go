@@ -25,7 +25,8 @@ var _ = Describe("Run", func() {
 		stderr       *bytes.Buffer
 		outputBuffer *bytes.Buffer
 
-		cmd terraform.Cmd
+		cmd            terraform.Cmd
+		pluginCacheDir string
 
 		fakeTerraformBackendServer *httptest.Server
 		pathToTerraform            string
@@ -53,7 +54,12 @@ var _ = Describe("Run", func() {
 		stderr = bytes.NewBuffer([]byte{})
 		outputBuffer = bytes.NewBuffer([]byte{})
 
-		cmd = terraform.NewCmd(stderr, outputBuffer)
+		var err error
+		pluginCacheDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		pluginCacheDir = filepath.Join(pluginCacheDir, "plugin-cache")
+
+		cmd = terraform.NewCmd(stderr, outputBuffer, pluginCacheDir)
 
 		fakeTerraformBackendServer = httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
 			if getFastFailTerraform() {
@@ -75,7 +81,6 @@ var _ = Describe("Run", func() {
 			}
 		}))
 
-		var err error
 		pathToTerraform, err = gexec.Build("github.com/cloudfoundry/bosh-bootloader/fakes/terraform",
 			"--ldflags", fmt.Sprintf("-X main.backendURL=%s", fakeTerraformBackendServer.URL))
 		Expect(err).NotTo(HaveOccurred())
@@ -120,6 +125,36 @@ var _ = Describe("Run", func() {
 		Expect(stdout).To(ContainSubstring("apply some-arg"))
 	})
 
+	Context("plugin cache", func() {
+		It("creates the plugin cache directory and points terraform at it", func() {
+			err := cmd.Run(stdout, "/tmp", []string{"apply", "some-arg"}, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(pluginCacheDir).To(BeADirectory())
+			Expect(outputBuffer.String()).To(ContainSubstring(fmt.Sprintf("plugin cache directory: %s\n", pluginCacheDir)))
+		})
+
+		It("leaves a plugin cache directory set by the user alone", func() {
+			os.Setenv("TF_PLUGIN_CACHE_DIR", "/some/user/plugin-cache")
+			defer os.Unsetenv("TF_PLUGIN_CACHE_DIR")
+
+			err := cmd.Run(stdout, "/tmp", []string{"apply", "some-arg"}, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(pluginCacheDir).NotTo(BeADirectory())
+			Expect(outputBuffer.String()).To(ContainSubstring("plugin cache directory: /some/user/plugin-cache\n"))
+		})
+
+		It("does not use a plugin cache when there is none", func() {
+			cmd = terraform.NewCmd(stderr, outputBuffer, "")
+
+			err := cmd.Run(stdout, "/tmp", []string{"apply", "some-arg"}, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(outputBuffer.String()).To(ContainSubstring("plugin cache directory: \n"))
+		})
+	})
+
 	Context("failure case", func() {
 		BeforeEach(func() {
 			setFastFailTerraform(true)
@@ -0,0 +1,191 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResourceDrift describes a resource whose live attributes no longer match
+// the terraform state bbl stored.
+type ResourceDrift struct {
+	Address    string           `json:"address"`
+	Deleted    bool             `json:"deleted"`
+	Attributes []AttributeDrift `json:"attributes,omitempty"`
+}
+
+type AttributeDrift struct {
+	Name   string `json:"name"`
+	Stored string `json:"stored"`
+	Live   string `json:"live"`
+}
+
+// Versions 1 to 3 keep flattened string attributes under each module.
+type tfStateResourcesV3 struct {
+	Modules []struct {
+		Path      []string `json:"path"`
+		Resources map[string]struct {
+			Primary struct {
+				Attributes map[string]string `json:"attributes"`
+			} `json:"primary"`
+		} `json:"resources"`
+	} `json:"modules"`
+}
+
+type tfStateResourcesV4 struct {
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey   interface{}            `json:"index_key"`
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// DiffResources compares the managed resources of the stored terraform state
+// with a refreshed copy of it. Data sources are left out, since reading them
+// again is expected to give new results.
+func DiffResources(storedTFState, refreshedTFState string) ([]ResourceDrift, error) {
+	stored, err := resourceAttributes(storedTFState)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed, err := resourceAttributes(refreshedTFState)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+	for address := range stored {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	drift := []ResourceDrift{}
+	for _, address := range addresses {
+		storedAttributes := stored[address]
+
+		refreshedAttributes, ok := refreshed[address]
+		if !ok {
+			drift = append(drift, ResourceDrift{Address: address, Deleted: true})
+			continue
+		}
+
+		var attributes []AttributeDrift
+		for _, name := range attributeNames(storedAttributes, refreshedAttributes) {
+			if storedAttributes[name] != refreshedAttributes[name] {
+				attributes = append(attributes, AttributeDrift{
+					Name:   name,
+					Stored: storedAttributes[name],
+					Live:   refreshedAttributes[name],
+				})
+			}
+		}
+
+		if len(attributes) > 0 {
+			drift = append(drift, ResourceDrift{Address: address, Attributes: attributes})
+		}
+	}
+
+	return drift, nil
+}
+
+// resourceAttributes returns the attributes of every managed resource in a
+// terraform state, keyed by resource address.
+func resourceAttributes(tfState string) (map[string]map[string]string, error) {
+	resources := map[string]map[string]string{}
+	if tfState == "" {
+		return resources, nil
+	}
+
+	var version tfStateVersion
+	err := json.Unmarshal([]byte(tfState), &version)
+	if err != nil {
+		return nil, fmt.Errorf("parse terraform state: %s", err)
+	}
+
+	switch version.Version {
+	case 1, 2, 3:
+		var state tfStateResourcesV3
+		err = json.Unmarshal([]byte(tfState), &state)
+		if err != nil {
+			return nil, fmt.Errorf("parse terraform state: %s", err)
+		}
+
+		for _, module := range state.Modules {
+			prefix := ""
+			for i, name := range module.Path {
+				if i > 0 {
+					prefix += fmt.Sprintf("module.%s.", name)
+				}
+			}
+
+			for key, resource := range module.Resources {
+				if strings.HasPrefix(key, "data.") {
+					continue
+				}
+				resources[prefix+key] = resource.Primary.Attributes
+			}
+		}
+	case 4:
+		var state tfStateResourcesV4
+		err = json.Unmarshal([]byte(tfState), &state)
+		if err != nil {
+			return nil, fmt.Errorf("parse terraform state: %s", err)
+		}
+
+		for _, resource := range state.Resources {
+			if resource.Mode != "managed" {
+				continue
+			}
+
+			address := fmt.Sprintf("%s.%s", resource.Type, resource.Name)
+			if resource.Module != "" {
+				address = fmt.Sprintf("%s.%s", resource.Module, address)
+			}
+
+			for _, instance := range resource.Instances {
+				instanceAddress := address
+				switch key := instance.IndexKey.(type) {
+				case float64:
+					instanceAddress = fmt.Sprintf("%s[%d]", address, int(key))
+				case string:
+					instanceAddress = fmt.Sprintf("%s[%q]", address, key)
+				}
+
+				attributes := map[string]string{}
+				for name, value := range instance.Attributes {
+					contents, err := json.Marshal(value)
+					if err != nil {
+						return nil, err
+					}
+					attributes[name] = string(contents)
+				}
+				resources[instanceAddress] = attributes
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported terraform state version %d", version.Version)
+	}
+
+	return resources, nil
+}
+
+func attributeNames(stored, refreshed map[string]string) []string {
+	names := []string{}
+	for name := range stored {
+		names = append(names, name)
+	}
+	for name := range refreshed {
+		if _, ok := stored[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,169 @@
+package terraform_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffResources", func() {
+	Context("with state version 3", func() {
+		var storedTFState string
+
+		BeforeEach(func() {
+			storedTFState = `{
+				"version": 3,
+				"modules": [
+					{
+						"path": ["root"],
+						"resources": {
+							"aws_security_group.internal_security_group": {
+								"type": "aws_security_group",
+								"primary": {"id": "sg-1", "attributes": {"id": "sg-1", "ingress.#": "2"}}
+							},
+							"aws_elb.cf_router_lb": {
+								"type": "aws_elb",
+								"primary": {"id": "some-lb", "attributes": {"id": "some-lb"}}
+							},
+							"data.aws_ami.nat": {
+								"type": "aws_ami",
+								"primary": {"id": "ami-1", "attributes": {"id": "ami-1"}}
+							}
+						}
+					},
+					{
+						"path": ["root", "vpc"],
+						"resources": {
+							"aws_vpc.vpc": {
+								"type": "aws_vpc",
+								"primary": {"id": "vpc-1", "attributes": {"id": "vpc-1", "tags.%": "1"}}
+							}
+						}
+					}
+				]
+			}`
+		})
+
+		It("reports changed and deleted resources, ignoring data sources", func() {
+			drift, err := terraform.DiffResources(storedTFState, `{
+				"version": 3,
+				"modules": [
+					{
+						"path": ["root"],
+						"resources": {
+							"aws_security_group.internal_security_group": {
+								"type": "aws_security_group",
+								"primary": {"id": "sg-1", "attributes": {"id": "sg-1", "ingress.#": "3", "description": "added"}}
+							},
+							"data.aws_ami.nat": {
+								"type": "aws_ami",
+								"primary": {"id": "ami-2", "attributes": {"id": "ami-2"}}
+							}
+						}
+					},
+					{
+						"path": ["root", "vpc"],
+						"resources": {
+							"aws_vpc.vpc": {
+								"type": "aws_vpc",
+								"primary": {"id": "vpc-1", "attributes": {"id": "vpc-1"}}
+							}
+						}
+					}
+				]
+			}`)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(drift).To(Equal([]terraform.ResourceDrift{
+				{
+					Address: "aws_elb.cf_router_lb",
+					Deleted: true,
+				},
+				{
+					Address: "aws_security_group.internal_security_group",
+					Attributes: []terraform.AttributeDrift{
+						{Name: "description", Stored: "", Live: "added"},
+						{Name: "ingress.#", Stored: "2", Live: "3"},
+					},
+				},
+				{
+					Address: "module.vpc.aws_vpc.vpc",
+					Attributes: []terraform.AttributeDrift{
+						{Name: "tags.%", Stored: "1", Live: ""},
+					},
+				},
+			}))
+		})
+
+		It("reports nothing when the refreshed state matches", func() {
+			drift, err := terraform.DiffResources(storedTFState, storedTFState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drift).To(BeEmpty())
+		})
+	})
+
+	Context("with state version 4", func() {
+		It("compares the attributes of every instance", func() {
+			drift, err := terraform.DiffResources(`{
+				"version": 4,
+				"resources": [
+					{
+						"mode": "managed", "type": "google_compute_firewall", "name": "internal",
+						"instances": [{"attributes": {"id": "internal", "source_ranges": ["10.0.0.0/16"]}}]
+					},
+					{
+						"module": "module.network", "mode": "managed", "type": "google_compute_subnetwork", "name": "subnet",
+						"instances": [{"index_key": 0, "attributes": {"id": "subnet-0"}}, {"index_key": 1, "attributes": {"id": "subnet-1"}}]
+					},
+					{
+						"mode": "data", "type": "google_compute_zones", "name": "available",
+						"instances": [{"attributes": {"names": ["a"]}}]
+					}
+				]
+			}`, `{
+				"version": 4,
+				"resources": [
+					{
+						"mode": "managed", "type": "google_compute_firewall", "name": "internal",
+						"instances": [{"attributes": {"id": "internal", "source_ranges": ["0.0.0.0/0"]}}]
+					},
+					{
+						"module": "module.network", "mode": "managed", "type": "google_compute_subnetwork", "name": "subnet",
+						"instances": [{"index_key": 0, "attributes": {"id": "subnet-0"}}]
+					},
+					{
+						"mode": "data", "type": "google_compute_zones", "name": "available",
+						"instances": [{"attributes": {"names": ["a", "b"]}}]
+					}
+				]
+			}`)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(drift).To(Equal([]terraform.ResourceDrift{
+				{
+					Address: "google_compute_firewall.internal",
+					Attributes: []terraform.AttributeDrift{
+						{Name: "source_ranges", Stored: `["10.0.0.0/16"]`, Live: `["0.0.0.0/0"]`},
+					},
+				},
+				{
+					Address: "module.network.google_compute_subnetwork.subnet[1]",
+					Deleted: true,
+				},
+			}))
+		})
+	})
+
+	Context("failure cases", func() {
+		It("returns an error when the stored state cannot be parsed", func() {
+			_, err := terraform.DiffResources("%%%", `{"version": 3}`)
+			Expect(err).To(MatchError("parse terraform state: invalid character '%' looking for beginning of value"))
+		})
+
+		It("returns an error when the refreshed state version is not supported", func() {
+			_, err := terraform.DiffResources(`{"version": 3}`, `{"version": 5}`)
+			Expect(err).To(MatchError("unsupported terraform state version 5"))
+		})
+	})
+})
@@ -10,25 +10,32 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
 
-	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/workspace"
 )
 
-var tempDir func(dir, prefix string) (string, error) = ioutil.TempDir
+var tempDir func(dir, prefix string) (string, error) = workspace.Create
 var writeFile func(file string, data []byte, perm os.FileMode) error = ioutil.WriteFile
 var readFile func(filename string) ([]byte, error) = ioutil.ReadFile
 
 type Executor struct {
-	cmd   terraformCmd
-	debug bool
+	cmd       terraformCmd
+	debug     bool
+	pluginDir string
+	outputs   *outputCache
+	adapters  *adapterCache
 }
 
+// ImportInput describes a resource to import into TFState. Template and
+// Overrides must declare the resource at TerraformAddr and configure its
+// provider, using the variables in Inputs.
 type ImportInput struct {
 	TerraformAddr string
-	AWSResourceID string
+	ResourceID    string
 	TFState       string
-	Creds         storage.AWS
+	Template      string
+	Overrides     map[string]string
+	Inputs        map[string]string
 }
 
 type tfOutput struct {
@@ -41,136 +48,122 @@ type terraformCmd interface {
 	Run(stdout io.Writer, workingDirectory string, args []string, debug bool) error
 }
 
-func NewExecutor(cmd terraformCmd, debug bool) Executor {
-	return Executor{cmd: cmd, debug: debug}
+// NewExecutor returns an executor that installs providers from pluginDir
+// when it is set, and downloads them otherwise.
+func NewExecutor(cmd terraformCmd, debug bool, pluginDir string) Executor {
+	return Executor{cmd: cmd, debug: debug, pluginDir: pluginDir, outputs: newOutputCache(), adapters: &adapterCache{}}
 }
 
-func (e Executor) Apply(input map[string]string, template, prevTFState string) (string, error) {
-	tempDir, err := tempDir("", "")
+func (e Executor) Apply(input map[string]string, template string, overrides map[string]string, prevTFState string) (string, error) {
+	adapter, err := e.adapters.get(e.Version)
 	if err != nil {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), os.ModePerm)
+	dir, err := e.prepareWorkingDir(os.Stdout, input, template, overrides, prevTFState)
 	if err != nil {
 		return "", err
 	}
+	defer workspace.Remove(dir)
 
-	if prevTFState != "" {
-		err = writeFile(filepath.Join(tempDir, "terraform.tfstate"), []byte(prevTFState), os.ModePerm)
-		if err != nil {
-			return "", err
-		}
+	err = e.cmd.Run(os.Stdout, dir, adapter.ApplyArgs(varFilePath(dir)), e.debug)
+	if err != nil {
+		return "", NewExecutorError(filepath.Join(dir, "terraform.tfstate"), err, e.debug)
 	}
 
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"init"}, e.debug)
+	return readTFState(dir)
+}
+
+func (e Executor) Destroy(input map[string]string, template string, overrides map[string]string, prevTFState string) (string, error) {
+	adapter, err := e.adapters.get(e.Version)
 	if err != nil {
 		return "", err
 	}
 
-	args := []string{"apply"}
-	for k, v := range input {
-		args = append(args, makeVar(k, v)...)
-	}
-	err = e.cmd.Run(os.Stdout, tempDir, args, e.debug)
+	dir, err := e.prepareWorkingDir(os.Stdout, input, template, overrides, prevTFState)
 	if err != nil {
-		return "", NewExecutorError(filepath.Join(tempDir, "terraform.tfstate"), err, e.debug)
+		return "", err
 	}
+	defer workspace.Remove(dir)
 
-	tfState, err := readFile(filepath.Join(tempDir, "terraform.tfstate"))
+	err = e.cmd.Run(os.Stdout, dir, adapter.DestroyArgs(varFilePath(dir)), e.debug)
 	if err != nil {
-		return "", err
+		return "", NewExecutorError(filepath.Join(dir, "terraform.tfstate"), err, e.debug)
 	}
 
-	return string(tfState), nil
+	return readTFState(dir)
 }
 
-func (e Executor) Destroy(input map[string]string, template, prevTFState string) (string, error) {
-	tempDir, err := tempDir("", "")
+// Plan runs terraform plan against a copy of the state and returns its
+// output. The state is never written back.
+func (e Executor) Plan(input map[string]string, template string, overrides map[string]string, prevTFState string) (string, error) {
+	adapter, err := e.adapters.get(e.Version)
 	if err != nil {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), os.ModePerm)
+	dir, err := e.prepareWorkingDir(os.Stdout, input, template, overrides, prevTFState)
 	if err != nil {
 		return "", err
 	}
+	defer workspace.Remove(dir)
 
-	if prevTFState != "" {
-		err = writeFile(filepath.Join(tempDir, "terraform.tfstate"), []byte(prevTFState), os.ModePerm)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"init"}, e.debug)
+	buffer := bytes.NewBuffer([]byte{})
+	err = e.cmd.Run(buffer, dir, adapter.PlanArgs(varFilePath(dir)), true)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("terraform plan: %s", err)
 	}
 
-	args := []string{"destroy", "-force"}
-	for k, v := range input {
-		args = append(args, makeVar(k, v)...)
-	}
-	err = e.cmd.Run(os.Stdout, tempDir, args, e.debug)
-	if err != nil {
-		return "", NewExecutorError(filepath.Join(tempDir, "terraform.tfstate"), err, e.debug)
-	}
+	return buffer.String(), nil
+}
 
-	tfState, err := readFile(filepath.Join(tempDir, "terraform.tfstate"))
+// Refresh updates a copy of the state with the live attributes of its
+// resources and returns it. The stored state is never written back, and
+// nothing is printed, not even with --debug, so that drift can print
+// machine readable output.
+func (e Executor) Refresh(input map[string]string, template string, overrides map[string]string, prevTFState string) (string, error) {
+	adapter, err := e.adapters.get(e.Version)
 	if err != nil {
 		return "", err
 	}
 
-	return string(tfState), nil
-}
-
-func (e Executor) Import(input ImportInput) (string, error) {
-	tempDir, err := tempDir("", "")
+	dir, err := e.prepareWorkingDir(ioutil.Discard, input, template, overrides, prevTFState)
 	if err != nil {
 		return "", err
 	}
+	defer workspace.Remove(dir)
 
-	resourceType := strings.Split(input.TerraformAddr, ".")[0]
-	resourceName := strings.Split(input.TerraformAddr, ".")[1]
-	resourceName = strings.Split(resourceName, "[")[0]
+	err = e.cmd.Run(ioutil.Discard, dir, adapter.RefreshArgs(varFilePath(dir)), e.debug)
+	if err != nil {
+		return "", fmt.Errorf("terraform refresh: %s", err)
+	}
 
-	template := fmt.Sprintf(`
-provider "aws" {
-	region     = %q
-	access_key = %q
-	secret_key = %q
+	return readTFState(dir)
 }
 
-resource %q %q {
-}`, input.Creds.Region, input.Creds.AccessKeyID, input.Creds.SecretAccessKey, resourceType, resourceName)
-
-	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), os.ModePerm)
+func (e Executor) Import(input ImportInput) (string, error) {
+	adapter, err := e.adapters.get(e.Version)
 	if err != nil {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(tempDir, "terraform.tfstate"), []byte(input.TFState), os.ModePerm)
+	dir, err := e.prepareWorkingDir(os.Stdout, input.Inputs, input.Template, input.Overrides, input.TFState)
 	if err != nil {
 		return "", err
 	}
+	defer workspace.Remove(dir)
 
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"init"}, e.debug)
-	if err != nil {
-		return "", err
+	var varFile string
+	if len(input.Inputs) > 0 {
+		varFile = varFilePath(dir)
 	}
 
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"import", input.TerraformAddr, input.AWSResourceID}, e.debug)
+	err = e.cmd.Run(os.Stdout, dir, adapter.ImportArgs(varFile, input.TerraformAddr, input.ResourceID), e.debug)
 	if err != nil {
 		return "", fmt.Errorf("failed to import: %s", err)
 	}
 
-	tfStateContents, err := readFile(filepath.Join(tempDir, "terraform.tfstate"))
-	if err != nil {
-		return "", err
-	}
-
-	return string(tfStateContents), nil
+	return readTFState(dir)
 }
 
 func (e Executor) Version() (string, error) {
@@ -190,70 +183,125 @@ func (e Executor) Version() (string, error) {
 	return version, nil
 }
 
+// Output returns a single output of the root module. Strings are returned
+// as they are, other values as JSON.
 func (e Executor) Output(tfState, outputName string) (string, error) {
-	templateDir, err := tempDir("", "")
+	outputs, err := e.Outputs(tfState)
 	if err != nil {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(templateDir, "terraform.tfstate"), []byte(tfState), os.ModePerm)
-	if err != nil {
-		return "", err
+	value, ok := outputs[outputName]
+	if !ok {
+		return "", fmt.Errorf("output %q not found in the terraform state", outputName)
 	}
 
-	err = e.cmd.Run(os.Stdout, templateDir, []string{"init"}, e.debug)
-	if err != nil {
-		return "", err
+	if s, ok := value.(string); ok {
+		return s, nil
 	}
 
-	args := []string{"output", outputName}
-	buffer := bytes.NewBuffer([]byte{})
-	err = e.cmd.Run(buffer, templateDir, args, true)
+	contents, err := json.Marshal(value)
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSuffix(buffer.String(), "\n"), nil
+	return string(contents), nil
 }
 
+// Outputs reads the outputs of the root module straight from the terraform
+// state, without running terraform.
 func (e Executor) Outputs(tfState string) (map[string]interface{}, error) {
-	templateDir, err := tempDir("", "")
+	return e.outputs.get(tfState)
+}
+
+// writeTemplate writes the generated template and the user's override files
+// to the working directory, where terraform loads them together.
+func writeTemplate(dir, template string, overrides map[string]string) error {
+	err := writeFile(filepath.Join(dir, "template.tf"), []byte(template), workspace.FileMode)
 	if err != nil {
-		return map[string]interface{}{}, err
+		return err
 	}
 
-	err = writeFile(filepath.Join(templateDir, "terraform.tfstate"), []byte(tfState), os.ModePerm)
+	for name, contents := range overrides {
+		err = writeFile(filepath.Join(dir, name), []byte(contents), workspace.FileMode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepareWorkingDir creates a temp dir with the template, the overrides, the
+// state and the var file, and runs terraform init in it, writing the output
+// of init to stdout. The caller removes the dir once it is done with it.
+func (e Executor) prepareWorkingDir(stdout io.Writer, input map[string]string, template string, overrides map[string]string, tfState string) (dir string, err error) {
+	adapter, err := e.adapters.get(e.Version)
 	if err != nil {
-		return map[string]interface{}{}, err
+		return "", err //not tested
 	}
 
-	err = e.cmd.Run(os.Stdout, templateDir, []string{"init"}, false)
+	workingDir, err := tempDir("", "")
 	if err != nil {
-		return map[string]interface{}{}, err
+		return "", err
 	}
+	defer func() {
+		if err != nil {
+			workspace.Remove(workingDir)
+		}
+	}()
 
-	args := []string{"output", "--json"}
-	buffer := bytes.NewBuffer([]byte{})
-	err = e.cmd.Run(buffer, templateDir, args, true)
+	err = writeTemplate(workingDir, template, overrides)
 	if err != nil {
-		return map[string]interface{}{}, err
+		return "", err
 	}
 
-	var tfOutputs map[string]tfOutput
-	err = json.Unmarshal(buffer.Bytes(), &tfOutputs)
+	if tfState != "" {
+		err = writeFile(filepath.Join(workingDir, "terraform.tfstate"), []byte(tfState), workspace.FileMode)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = e.cmd.Run(stdout, workingDir, adapter.InitArgs(e.pluginDir), e.debug)
 	if err != nil {
-		return map[string]interface{}{}, err
+		return "", err
 	}
 
-	outputs := map[string]interface{}{}
+	err = writeVarFile(workingDir, input)
+	if err != nil {
+		return "", err
+	}
+
+	return workingDir, nil
+}
 
-	for tfKey, tfValue := range tfOutputs {
-		outputs[tfKey] = tfValue.Value
+func readTFState(dir string) (string, error) {
+	tfState, err := readFile(filepath.Join(dir, "terraform.tfstate"))
+	if err != nil {
+		return "", err
 	}
 
-	return outputs, nil
+	return string(tfState), nil
 }
 
-func makeVar(name string, value string) []string {
-	return []string{"-var", fmt.Sprintf("%s=%s", name, value)}
+// varFilePath is where writeVarFile writes the inputs.
+func varFilePath(dir string) string {
+	return filepath.Join(dir, "terraform.tfvars.json")
+}
+
+// writeVarFile writes the inputs to a terraform.tfvars.json only the current
+// user can read, so that credentials are not passed on the command line where
+// any user on the machine could see them.
+func writeVarFile(dir string, input map[string]string) error {
+	if input == nil {
+		input = map[string]string{}
+	}
+
+	contents, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(varFilePath(dir), contents, workspace.FileMode)
 }
@@ -6,16 +6,22 @@ import (
 )
 
 type ExecutorError struct {
-	tfStateFilename string
-	err             error
-	debug           bool
+	tfState    string
+	tfStateErr error
+	err        error
+	debug      bool
 }
 
+// NewExecutorError reads the terraform state straight away, because the
+// workspace holding it is removed as soon as the executor returns.
 func NewExecutorError(tfStateFilename string, err error, debug bool) ExecutorError {
+	tfStateContents, tfStateErr := ioutil.ReadFile(tfStateFilename)
+
 	return ExecutorError{
-		tfStateFilename: tfStateFilename,
-		err:             err,
-		debug:           debug,
+		tfState:    string(tfStateContents),
+		tfStateErr: tfStateErr,
+		err:        err,
+		debug:      debug,
 	}
 }
 
@@ -28,9 +34,8 @@ func (t ExecutorError) Error() string {
 }
 
 func (t ExecutorError) TFState() (string, error) {
-	tfStateContents, err := ioutil.ReadFile(t.tfStateFilename)
-	if err != nil {
-		return "", err
+	if t.tfStateErr != nil {
+		return "", t.tfStateErr
 	}
-	return string(tfStateContents), nil
+	return t.tfState, nil
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 
 	"github.com/cloudfoundry/bosh-bootloader/terraform"
 	. "github.com/onsi/ginkgo"
@@ -53,6 +54,17 @@ var _ = Describe("ExecutorError", func() {
 			Expect(actualTFState).To(Equal(tfState))
 		})
 
+		It("returns the tfState after the file has been removed", func() {
+			executorError := terraform.NewExecutorError(tfStateFilename, nil, true)
+
+			err := os.Remove(tfStateFilename)
+			Expect(err).NotTo(HaveOccurred())
+
+			actualTFState, err := executorError.TFState()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualTFState).To(Equal(tfState))
+		})
+
 		Context("failure cases", func() {
 			It("returns an error when tf state file does not exist", func() {
 				executorError := terraform.NewExecutorError("/fake/file/name", nil, true)
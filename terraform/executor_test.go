@@ -2,7 +2,6 @@ package terraform_test
 
 import (
 	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -10,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
-	"github.com/cloudfoundry/bosh-bootloader/storage"
 	"github.com/cloudfoundry/bosh-bootloader/terraform"
 
 	. "github.com/onsi/ginkgo"
@@ -29,7 +27,8 @@ var _ = Describe("Executor", func() {
 	BeforeEach(func() {
 		cmd = &fakes.TerraformCmd{}
 
-		executor = terraform.NewExecutor(cmd, true)
+		executor = terraform.NewExecutor(cmd, true, "")
+		terraform.SetAdapter(executor, "0.10.0")
 
 		var err error
 		tempDir, err = ioutil.TempDir("", "")
@@ -63,7 +62,7 @@ var _ = Describe("Executor", func() {
 
 	Describe("Apply", func() {
 		It("writes the terraform template to a file", func() {
-			_, err := executor.Apply(input, "some-template", "")
+			_, err := executor.Apply(input, "some-template", map[string]string{}, "")
 			Expect(err).NotTo(HaveOccurred())
 
 			fileContents, err := ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
@@ -72,25 +71,143 @@ var _ = Describe("Executor", func() {
 			Expect(string(fileContents)).To(Equal("some-template"))
 		})
 
+		It("writes the override files next to the template", func() {
+			_, err := executor.Apply(input, "some-template", map[string]string{
+				"extra.tf":            "some-extra-resources",
+				"network_override.tf": "some-network-override",
+			}, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			fileContents, err := ioutil.ReadFile(filepath.Join(tempDir, "extra.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fileContents)).To(Equal("some-extra-resources"))
+
+			fileContents, err = ioutil.ReadFile(filepath.Join(tempDir, "network_override.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fileContents)).To(Equal("some-network-override"))
+		})
+
 		It("passes the correct args and dir to run command", func() {
-			_, err := executor.Apply(input, "some-template", "")
+			_, err := executor.Apply(input, "some-template", map[string]string{}, "")
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
-			Expect(cmd.RunCall.Receives.Args).To(ConsistOf([]string{
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
 				"apply",
-				"-var", "project_id=some-project-id",
-				"-var", "env_id=some-env-id",
-				"-var", "region=some-region",
-				"-var", "zone=some-zone",
-				"-var", "ssl_certificate=some/certificate/path",
-				"-var", "ssl_certificate_private_key=some/key/path",
-				"-var", "credentials=some/credentials/path",
-				"-var", "system_domain=some-domain",
+				"-var-file", filepath.Join(tempDir, "terraform.tfvars.json"),
 			}))
 			Expect(cmd.RunCall.Receives.Debug).To(BeTrue())
 		})
 
+		It("builds the args for the installed version of terraform", func() {
+			terraform.SetAdapter(executor, "0.11.14")
+
+			_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
+				"apply",
+				"-auto-approve",
+				"-var-file", filepath.Join(tempDir, "terraform.tfvars.json"),
+			}))
+		})
+
+		It("installs providers from the plugin directory when there is one", func() {
+			executor = terraform.NewExecutor(cmd, true, "/some/plugin/dir")
+			terraform.SetAdapter(executor, "0.10.0")
+
+			_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmd.RunCall.InitArgs).To(Equal([]string{"init", "-plugin-dir", "/some/plugin/dir"}))
+		})
+
+		Context("when the terraform version has not been detected yet", func() {
+			BeforeEach(func() {
+				executor = terraform.NewExecutor(cmd, true, "")
+			})
+
+			It("detects it once and reuses it for later operations", func() {
+				cmd.RunCall.Stub = func(stdout io.Writer) {
+					if cmd.RunCall.Receives.Args[0] == "version" {
+						stdout.Write([]byte("Terraform v0.11.14"))
+					}
+				}
+
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = executor.Apply(input, "some-template", map[string]string{}, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmd.RunCall.CallCount).To(Equal(5))
+				Expect(cmd.RunCall.Receives.Args).To(ContainElement("-auto-approve"))
+			})
+
+			It("returns an error when the version is not supported", func() {
+				cmd.RunCall.Stub = func(stdout io.Writer) {
+					if cmd.RunCall.Receives.Args[0] == "version" {
+						stdout.Write([]byte("Terraform v0.9.11"))
+					}
+				}
+
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+				Expect(err).To(MatchError("Terraform version must be at least v0.10.0"))
+				Expect(cmd.RunCall.CallCount).To(Equal(1))
+			})
+		})
+
+		It("writes the inputs to a var file only the current user can read", func() {
+			_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			varFile := filepath.Join(tempDir, "terraform.tfvars.json")
+			contents, err := ioutil.ReadFile(varFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{
+				"project_id": "some-project-id",
+				"env_id": "some-env-id",
+				"region": "some-region",
+				"zone": "some-zone",
+				"ssl_certificate": "some/certificate/path",
+				"ssl_certificate_private_key": "some/key/path",
+				"credentials": "some/credentials/path",
+				"system_domain": "some-domain"
+			}`))
+
+			info, err := os.Stat(varFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("does not pass any input on the command line", func() {
+			_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, arg := range cmd.RunCall.Receives.Args {
+				Expect(arg).NotTo(ContainSubstring("some/key/path"))
+			}
+		})
+
+		It("runs in a private workspace that is removed afterwards, even when terraform fails", func() {
+			terraform.ResetTempDir()
+
+			var workingDirMode os.FileMode
+			cmd.RunCall.Stub = func(io.Writer) {
+				info, err := os.Stat(cmd.RunCall.Receives.WorkingDirectory)
+				Expect(err).NotTo(HaveOccurred())
+				workingDirMode = info.Mode().Perm()
+			}
+			cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to apply")}
+
+			_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+			Expect(err).To(BeAssignableToTypeOf(terraform.ExecutorError{}))
+
+			Expect(workingDirMode).To(Equal(os.FileMode(0700)))
+			_, err = os.Stat(cmd.RunCall.Receives.WorkingDirectory)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
 		It("reads and returns the terraform state written by the command", func() {
 			var actualFilename string
 
@@ -99,7 +216,7 @@ var _ = Describe("Executor", func() {
 				return []byte("some-terraform-state"), nil
 			})
 
-			terraformState, err := executor.Apply(input, "some-template", "")
+			terraformState, err := executor.Apply(input, "some-template", map[string]string{}, "")
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(actualFilename).To(ContainSubstring("terraform.tfstate"))
@@ -130,7 +247,7 @@ var _ = Describe("Executor", func() {
 			})
 
 			It("does not write the previous tf state file", func() {
-				_, err := executor.Apply(input, "some-template", "")
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(writeTFStateFileCallCount).To(Equal(0))
@@ -139,7 +256,7 @@ var _ = Describe("Executor", func() {
 
 		Context("when previous tf state is not blank", func() {
 			It("writes the tf state to a file", func() {
-				_, err := executor.Apply(input, "some-template", "some-tf-state")
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "some-tf-state")
 				Expect(err).NotTo(HaveOccurred())
 
 				fileContents, err := ioutil.ReadFile(filepath.Join(tempDir, "terraform.tfstate"))
@@ -154,7 +271,7 @@ var _ = Describe("Executor", func() {
 				terraform.SetTempDir(func(dir, prefix string) (string, error) {
 					return "", errors.New("failed to make temp dir")
 				})
-				_, err := executor.Apply(input, "some-template", "")
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to make temp dir"))
 			})
 
@@ -167,7 +284,7 @@ var _ = Describe("Executor", func() {
 					return nil
 				})
 
-				_, err := executor.Apply(input, "some-template", "")
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to write template file"))
 			})
 
@@ -180,14 +297,27 @@ var _ = Describe("Executor", func() {
 					return nil
 				})
 
-				_, err := executor.Apply(input, "some-template", "some-tf-state")
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "some-tf-state")
 				Expect(err).To(MatchError("failed to write tf state file"))
 			})
 
+			It("returns an error when it fails to write the var file", func() {
+				terraform.SetWriteFile(func(file string, data []byte, perm os.FileMode) error {
+					if file == filepath.Join(tempDir, "terraform.tfvars.json") {
+						return errors.New("failed to write var file")
+					}
+
+					return nil
+				})
+
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
+				Expect(err).To(MatchError("failed to write var file"))
+			})
+
 			It("returns an error when terraform init fails", func() {
 				cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
 
-				_, err := executor.Apply(input, "some-template", "")
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to initialize terraform"))
 			})
 
@@ -197,7 +327,7 @@ var _ = Describe("Executor", func() {
 
 				cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to run terraform command")}
 
-				_, err = executor.Apply(input, "some-template", "")
+				_, err = executor.Apply(input, "some-template", map[string]string{}, "")
 				taErr := err.(terraform.ExecutorError)
 				Expect(taErr).To(MatchError("failed to run terraform command"))
 
@@ -211,13 +341,14 @@ var _ = Describe("Executor", func() {
 					return []byte{}, errors.New("failed to read tf state file")
 				})
 
-				_, err := executor.Apply(input, "some-template", "")
+				_, err := executor.Apply(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to read tf state file"))
 			})
 
 			Context("when --debug is false", func() {
 				BeforeEach(func() {
-					executor = terraform.NewExecutor(cmd, false)
+					executor = terraform.NewExecutor(cmd, false, "")
+					terraform.SetAdapter(executor, "0.10.0")
 				})
 
 				It("returns an error and the current tf state when it fails to call terraform command run", func() {
@@ -226,7 +357,7 @@ var _ = Describe("Executor", func() {
 
 					cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to run terraform command")}
 
-					_, err = executor.Apply(input, "some-template", "")
+					_, err = executor.Apply(input, "some-template", map[string]string{}, "")
 					taErr := err.(terraform.ExecutorError)
 
 					tfState, err := taErr.TFState()
@@ -237,9 +368,136 @@ var _ = Describe("Executor", func() {
 		})
 	})
 
+	Describe("Plan", func() {
+		BeforeEach(func() {
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				stdout.Write([]byte("Plan: 1 to add, 0 to change, 0 to destroy."))
+			}
+		})
+
+		It("writes the template and tf state to a temp dir", func() {
+			_, err := executor.Plan(input, "some-template", map[string]string{}, "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+
+			templateContents, err := ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(templateContents)).To(Equal("some-template"))
+
+			tfStateContents, err := ioutil.ReadFile(filepath.Join(tempDir, "terraform.tfstate"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(tfStateContents)).To(Equal("some-tf-state"))
+		})
+
+		It("runs terraform plan and returns its output", func() {
+			plan, err := executor.Plan(input, "some-template", map[string]string{}, "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan).To(Equal("Plan: 1 to add, 0 to change, 0 to destroy."))
+
+			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
+				"plan",
+				"-input=false",
+				"-no-color",
+				"-var-file", filepath.Join(tempDir, "terraform.tfvars.json"),
+			}))
+		})
+
+		Context("when an error occurs", func() {
+			It("returns an error when it fails to create a temp dir", func() {
+				terraform.SetTempDir(func(dir, prefix string) (string, error) {
+					return "", errors.New("failed to make temp dir")
+				})
+
+				_, err := executor.Plan(input, "some-template", map[string]string{}, "")
+				Expect(err).To(MatchError("failed to make temp dir"))
+			})
+
+			It("returns an error when terraform plan fails", func() {
+				cmd.RunCall.Returns.Errors = []error{nil, errors.New("exit status 1")}
+
+				_, err := executor.Plan(input, "some-template", map[string]string{}, "")
+				Expect(err).To(MatchError("terraform plan: exit status 1"))
+			})
+		})
+	})
+
+	Describe("Refresh", func() {
+		BeforeEach(func() {
+			terraform.SetReadFile(func(filename string) ([]byte, error) {
+				return []byte("some-refreshed-tf-state"), nil
+			})
+		})
+
+		It("writes the template and tf state to a temp dir", func() {
+			_, err := executor.Refresh(input, "some-template", map[string]string{"extra.tf": "some-extra-resources"}, "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+
+			templateContents, err := ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(templateContents)).To(Equal("some-template"))
+
+			overrideContents, err := ioutil.ReadFile(filepath.Join(tempDir, "extra.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(overrideContents)).To(Equal("some-extra-resources"))
+
+			tfStateContents, err := ioutil.ReadFile(filepath.Join(tempDir, "terraform.tfstate"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(tfStateContents)).To(Equal("some-tf-state"))
+		})
+
+		It("runs terraform refresh and returns the refreshed tf state", func() {
+			tfState, err := executor.Refresh(input, "some-template", map[string]string{}, "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tfState).To(Equal("some-refreshed-tf-state"))
+
+			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
+				"refresh",
+				"-input=false",
+				"-no-color",
+				"-var-file", filepath.Join(tempDir, "terraform.tfvars.json"),
+			}))
+			Expect(cmd.RunCall.Receives.Stdout).To(Equal(ioutil.Discard))
+		})
+
+		Context("when an error occurs", func() {
+			It("returns an error when it fails to create a temp dir", func() {
+				terraform.SetTempDir(func(dir, prefix string) (string, error) {
+					return "", errors.New("failed to make temp dir")
+				})
+
+				_, err := executor.Refresh(input, "some-template", map[string]string{}, "some-tf-state")
+				Expect(err).To(MatchError("failed to make temp dir"))
+			})
+
+			It("returns an error when terraform init fails", func() {
+				cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
+
+				_, err := executor.Refresh(input, "some-template", map[string]string{}, "some-tf-state")
+				Expect(err).To(MatchError("failed to initialize terraform"))
+			})
+
+			It("returns an error when terraform refresh fails", func() {
+				cmd.RunCall.Returns.Errors = []error{nil, errors.New("exit status 1")}
+
+				_, err := executor.Refresh(input, "some-template", map[string]string{}, "some-tf-state")
+				Expect(err).To(MatchError("terraform refresh: exit status 1"))
+			})
+
+			It("returns an error when it fails to read the refreshed tf state", func() {
+				terraform.SetReadFile(func(filename string) ([]byte, error) {
+					return nil, errors.New("failed to read tf state")
+				})
+
+				_, err := executor.Refresh(input, "some-template", map[string]string{}, "some-tf-state")
+				Expect(err).To(MatchError("failed to read tf state"))
+			})
+		})
+	})
+
 	Describe("Destroy", func() {
 		It("writes the template and tf state to a temp dir", func() {
-			_, err := executor.Destroy(input, "some-template", "some-tf-state")
+			_, err := executor.Destroy(input, "some-template", map[string]string{}, "some-tf-state")
 			Expect(err).NotTo(HaveOccurred())
 
 			templateContents, err := ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
@@ -252,21 +510,14 @@ var _ = Describe("Executor", func() {
 		})
 
 		It("passes the correct args and dir to run command", func() {
-			_, err := executor.Destroy(input, "some-template", "some-tf-state")
+			_, err := executor.Destroy(input, "some-template", map[string]string{}, "some-tf-state")
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
-			Expect(cmd.RunCall.Receives.Args).To(ConsistOf([]string{
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
 				"destroy",
 				"-force",
-				"-var", "project_id=some-project-id",
-				"-var", "env_id=some-env-id",
-				"-var", "region=some-region",
-				"-var", "zone=some-zone",
-				"-var", "ssl_certificate=some/certificate/path",
-				"-var", "ssl_certificate_private_key=some/key/path",
-				"-var", "credentials=some/credentials/path",
-				"-var", "system_domain=some-domain",
+				"-var-file", filepath.Join(tempDir, "terraform.tfvars.json"),
 			}))
 			Expect(cmd.RunCall.Receives.Debug).To(BeTrue())
 		})
@@ -276,7 +527,7 @@ var _ = Describe("Executor", func() {
 				return []byte{}, nil
 			})
 
-			tfState, err := executor.Destroy(input, "some-template", "some-tf-state")
+			tfState, err := executor.Destroy(input, "some-template", map[string]string{}, "some-tf-state")
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(tfState).To(Equal(""))
@@ -288,7 +539,7 @@ var _ = Describe("Executor", func() {
 					return "", errors.New("failed to make temp dir")
 				})
 
-				_, err := executor.Destroy(input, "some-template", "")
+				_, err := executor.Destroy(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to make temp dir"))
 			})
 
@@ -301,7 +552,7 @@ var _ = Describe("Executor", func() {
 					return nil
 				})
 
-				_, err := executor.Destroy(input, "some-template", "")
+				_, err := executor.Destroy(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to write template file"))
 			})
 
@@ -314,14 +565,14 @@ var _ = Describe("Executor", func() {
 					return nil
 				})
 
-				_, err := executor.Destroy(input, "some-template", "some-tf-state")
+				_, err := executor.Destroy(input, "some-template", map[string]string{}, "some-tf-state")
 				Expect(err).To(MatchError("failed to write tf state file"))
 			})
 
 			It("returns an error when terraform init fails", func() {
 				cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
 
-				_, err := executor.Destroy(input, "some-template", "")
+				_, err := executor.Destroy(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to initialize terraform"))
 			})
 
@@ -330,7 +581,7 @@ var _ = Describe("Executor", func() {
 				Expect(err).NotTo(HaveOccurred())
 				cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to run terraform command")}
 
-				_, err = executor.Destroy(input, "some-template", "")
+				_, err = executor.Destroy(input, "some-template", map[string]string{}, "")
 				tdErr := err.(terraform.ExecutorError)
 				Expect(tdErr).To(MatchError("failed to run terraform command"))
 
@@ -344,13 +595,14 @@ var _ = Describe("Executor", func() {
 					return []byte{}, errors.New("failed to read tf state file")
 				})
 
-				_, err := executor.Destroy(input, "some-template", "")
+				_, err := executor.Destroy(input, "some-template", map[string]string{}, "")
 				Expect(err).To(MatchError("failed to read tf state file"))
 			})
 
 			Context("when --debug is false", func() {
 				BeforeEach(func() {
-					executor = terraform.NewExecutor(cmd, false)
+					executor = terraform.NewExecutor(cmd, false, "")
+					terraform.SetAdapter(executor, "0.10.0")
 				})
 
 				It("returns an error and the current tf state when it fails to call terraform command run", func() {
@@ -359,7 +611,7 @@ var _ = Describe("Executor", func() {
 
 					cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to run terraform command")}
 
-					_, err = executor.Destroy(input, "some-template", "")
+					_, err = executor.Destroy(input, "some-template", map[string]string{}, "")
 					tdErr := err.(terraform.ExecutorError)
 
 					tfState, err := tdErr.TFState()
@@ -395,45 +647,53 @@ var _ = Describe("Executor", func() {
 		It("writes the tfState to a file", func() {
 			_, err := executor.Import(terraform.ImportInput{
 				TerraformAddr: "some-resource-type.some-addr",
-				AWSResourceID: "some-id",
+				ResourceID:    "some-id",
 				TFState:       "some-tf-state",
-				Creds:         storage.AWS{},
 			})
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(receivedTFState).To(Equal("some-tf-state"))
 		})
 
-		It("writes a terraform template to a file", func() {
+		It("writes the template and overrides to files", func() {
 			_, err := executor.Import(terraform.ImportInput{
-				TerraformAddr: "some-resource-type.some-addr[i]",
-				AWSResourceID: "some-id",
+				TerraformAddr: "some-resource-type.some-addr[0]",
+				ResourceID:    "some-id",
 				TFState:       "some-tf-state",
-				Creds: storage.AWS{
-					Region:          "some-region",
-					AccessKeyID:     "some-access-key",
-					SecretAccessKey: "some-secret",
-				},
+				Template:      "some-template",
+				Overrides:     map[string]string{"extra.tf": "some-extra-resources"},
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(receivedTFTemplate).To(Equal(`
-provider "aws" {
-	region     = "some-region"
-	access_key = "some-access-key"
-	secret_key = "some-secret"
-}
+			Expect(receivedTFTemplate).To(Equal("some-template"))
 
-resource "some-resource-type" "some-addr" {
-}`))
+			overrideContents, err := ioutil.ReadFile(filepath.Join(tempDir, "extra.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(overrideContents)).To(Equal("some-extra-resources"))
+		})
+
+		It("passes the inputs to terraform import in a var file", func() {
+			_, err := executor.Import(terraform.ImportInput{
+				TerraformAddr: "some-resource-type.some-addr",
+				ResourceID:    "some-id",
+				TFState:       "some-tf-state",
+				Inputs:        map[string]string{"env_id": "some-env-id"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			varFile := filepath.Join(tempDir, "terraform.tfvars.json")
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"import", "-var-file", varFile, "some-resource-type.some-addr", "some-id"}))
+
+			contents, err := ioutil.ReadFile(varFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{"env_id": "some-env-id"}`))
 		})
 
 		It("shells out to terraform import and returns the tfState", func() {
 			tfState, err := executor.Import(terraform.ImportInput{
 				TerraformAddr: "some-resource-type.some-addr",
-				AWSResourceID: "some-id",
+				ResourceID:    "some-id",
 				TFState:       "some-tf-state",
-				Creds:         storage.AWS{},
 			})
 			Expect(err).NotTo(HaveOccurred())
 
@@ -450,9 +710,8 @@ resource "some-resource-type" "some-addr" {
 					})
 					_, err := executor.Import(terraform.ImportInput{
 						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
+						ResourceID:    "some-id",
 						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
 					})
 					Expect(err).To(MatchError("failed to make temp dir"))
 				})
@@ -470,9 +729,8 @@ resource "some-resource-type" "some-addr" {
 
 					_, err := executor.Import(terraform.ImportInput{
 						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
+						ResourceID:    "some-id",
 						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
 					})
 					Expect(err).To(MatchError("failed to write tfstate"))
 				})
@@ -484,9 +742,8 @@ resource "some-resource-type" "some-addr" {
 
 					_, err := executor.Import(terraform.ImportInput{
 						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
+						ResourceID:    "some-id",
 						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
 					})
 					Expect(err).To(MatchError("failed to initialize terraform"))
 				})
@@ -498,9 +755,8 @@ resource "some-resource-type" "some-addr" {
 
 					_, err := executor.Import(terraform.ImportInput{
 						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
+						ResourceID:    "some-id",
 						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
 					})
 					Expect(err).To(MatchError("failed to import: bad import"))
 				})
@@ -518,9 +774,8 @@ resource "some-resource-type" "some-addr" {
 
 					_, err := executor.Import(terraform.ImportInput{
 						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
+						ResourceID:    "some-id",
 						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
 					})
 					Expect(err).To(MatchError("failed to read tf state file"))
 				})
@@ -567,74 +822,60 @@ resource "some-resource-type" "some-addr" {
 	})
 
 	Describe("Output", func() {
-		It("returns an output from the terraform state", func() {
-			cmd.RunCall.Stub = func(stdout io.Writer) {
-				fmt.Fprintf(stdout, "some-external-ip\n")
-			}
-			output, err := executor.Output("some-tf-state", "external_ip")
-			Expect(err).NotTo(HaveOccurred())
-			Expect(output).To(Equal("some-external-ip"))
-
-			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
-			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"output", "external_ip"}))
-			Expect(cmd.RunCall.Receives.Debug).To(BeTrue())
-		})
+		var tfState string
 
-		Context("when an error occurs", func() {
-			It("returns an error when it fails to create a temp dir", func() {
-				terraform.SetTempDir(func(dir, prefix string) (string, error) {
-					return "", errors.New("failed to make temp dir")
-				})
-				_, err := executor.Output("some-tf-state", "external_ip")
-				Expect(err).To(MatchError("failed to make temp dir"))
-			})
-
-			It("returns an error when it fails to write the tfstate file", func() {
-				terraform.SetWriteFile(func(file string, data []byte, perm os.FileMode) error {
-					if strings.Contains(file, "terraform.tfstate") {
-						return errors.New("failed to write tf state file")
+		BeforeEach(func() {
+			tfState = `{
+				"version": 3,
+				"modules": [{
+					"path": ["root"],
+					"outputs": {
+						"external_ip": {"sensitive": false, "type": "string", "value": "some-external-ip"},
+						"network_cidrs": {"sensitive": false, "type": "list", "value": ["10.0.0.0/24", "10.0.1.0/24"]}
 					}
+				}]
+			}`
+		})
 
-					return nil
-				})
+		It("returns a string output from the terraform state without running terraform", func() {
+			output, err := executor.Output(tfState, "external_ip")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal("some-external-ip"))
 
-				_, err := executor.Output("some-tf-state", "external_ip")
-				Expect(err).To(MatchError("failed to write tf state file"))
-			})
+			Expect(cmd.RunCall.CallCount).To(Equal(0))
+		})
 
-			It("returns an error when terraform init fails", func() {
-				cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
+		It("returns other outputs as json", func() {
+			output, err := executor.Output(tfState, "network_cidrs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(MatchJSON(`["10.0.0.0/24", "10.0.1.0/24"]`))
+		})
 
-				_, err := executor.Output("some-template", "external_ip")
-				Expect(err).To(MatchError("failed to initialize terraform"))
+		Context("when an error occurs", func() {
+			It("returns an error when the output does not exist", func() {
+				_, err := executor.Output(tfState, "some-missing-output")
+				Expect(err).To(MatchError(`output "some-missing-output" not found in the terraform state`))
 			})
 
-			It("returns an error when it fails to call terraform command run", func() {
-				cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to run terraform command")}
-
-				_, err := executor.Output("some-tf-state", "external_ip")
-				Expect(err).To(MatchError("failed to run terraform command"))
+			It("returns an error when the terraform state cannot be parsed", func() {
+				_, err := executor.Output("%%%", "external_ip")
+				Expect(err).To(MatchError("parse terraform state: invalid character '%' looking for beginning of value"))
 			})
 		})
 	})
 
 	Describe("Outputs", func() {
-		It("returns all outputs from the terraform state", func() {
-			cmd.RunCall.Stub = func(stdout io.Writer) {
-				fmt.Fprintf(stdout, `{
-					"director_address": {
-						"sensitive": false,
-						"type": "string",
-						"value": "some-director-address"
-					},
-					"external_ip": {
-						"sensitive": false,
-						"type": "string",
-						"value": "some-external-ip"
+		It("returns all outputs from the terraform state without running terraform", func() {
+			outputs, err := executor.Outputs(`{
+				"version": 3,
+				"modules": [{
+					"path": ["root"],
+					"outputs": {
+						"director_address": {"sensitive": false, "type": "string", "value": "some-director-address"},
+						"external_ip": {"sensitive": false, "type": "string", "value": "some-external-ip"}
 					}
-				}`)
-			}
-			outputs, err := executor.Outputs("some-tf-state")
+				}]
+			}`)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(outputs).To(Equal(map[string]interface{}{
@@ -642,53 +883,27 @@ resource "some-resource-type" "some-addr" {
 				"external_ip":      "some-external-ip",
 			}))
 
-			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
-			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"output", "--json"}))
-			Expect(cmd.RunCall.Receives.Debug).To(BeTrue())
+			Expect(cmd.RunCall.CallCount).To(Equal(0))
 		})
 
-		Context("when an error occurs", func() {
-			It("returns an error when it fails to create a temp dir", func() {
-				terraform.SetTempDir(func(dir, prefix string) (string, error) {
-					return "", errors.New("failed to make temp dir")
-				})
-				_, err := executor.Outputs("some-tf-state")
-				Expect(err).To(MatchError("failed to make temp dir"))
-			})
-
-			It("returns an error when it fails to write the tfstate file", func() {
-				terraform.SetWriteFile(func(file string, data []byte, perm os.FileMode) error {
-					if strings.Contains(file, "terraform.tfstate") {
-						return errors.New("failed to write tf state file")
-					}
+		It("hands out a copy of the cached outputs on every call", func() {
+			tfState := `{"version": 4, "outputs": {"external_ip": {"type": "string", "value": "some-external-ip"}}}`
 
-					return nil
-				})
-
-				_, err := executor.Outputs("some-tf-state")
-				Expect(err).To(MatchError("failed to write tf state file"))
-			})
-
-			It("returns an error when terraform init fails", func() {
-				cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
-
-				_, err := executor.Outputs("some-tf-state")
-				Expect(err).To(MatchError("failed to initialize terraform"))
-			})
-
-			It("returns an error when it fails to call terraform command run", func() {
-				cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to run terraform command")}
+			outputs, err := executor.Outputs(tfState)
+			Expect(err).NotTo(HaveOccurred())
+			outputs["external_ip"] = "some-modified-ip"
 
-				_, err := executor.Outputs("some-tf-state")
-				Expect(err).To(MatchError("failed to run terraform command"))
-			})
+			outputs, err = executor.Outputs(tfState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputs).To(Equal(map[string]interface{}{
+				"external_ip": "some-external-ip",
+			}))
+		})
 
-			It("returns an error when it fails to unmarshal the terraform outputs", func() {
-				cmd.RunCall.Stub = func(stdout io.Writer) {
-					fmt.Fprintf(stdout, "%%%")
-				}
-				_, err := executor.Outputs("some-tf-state")
-				Expect(err).To(MatchError("invalid character '%' looking for beginning of value"))
+		Context("when an error occurs", func() {
+			It("returns an error when the terraform state cannot be parsed", func() {
+				_, err := executor.Outputs("%%%")
+				Expect(err).To(MatchError("parse terraform state: invalid character '%' looking for beginning of value"))
 			})
 		})
 	})
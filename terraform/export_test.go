@@ -3,6 +3,9 @@ package terraform
 import (
 	"io/ioutil"
 	"os"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/workspace"
 )
 
 func SetTempDir(f func(dir, prefix string) (string, error)) {
@@ -10,7 +13,7 @@ func SetTempDir(f func(dir, prefix string) (string, error)) {
 }
 
 func ResetTempDir() {
-	tempDir = ioutil.TempDir
+	tempDir = workspace.Create
 }
 
 func SetWriteFile(f func(file string, data []byte, perm os.FileMode) error) {
@@ -28,3 +31,19 @@ func SetReadFile(f func(filename string) ([]byte, error)) {
 func ResetReadFile() {
 	readFile = ioutil.ReadFile
 }
+
+func SetAdapter(executor Executor, version string) {
+	adapter, err := NewAdapter(version)
+	if err != nil {
+		panic(err)
+	}
+	executor.adapters.adapter = &adapter
+}
+
+func SetSleep(f func(time.Duration)) {
+	sleep = f
+}
+
+func ResetSleep() {
+	sleep = time.Sleep
+}
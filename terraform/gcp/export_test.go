@@ -3,6 +3,8 @@ package gcp
 import (
 	"io/ioutil"
 	"os"
+
+	"github.com/cloudfoundry/bosh-bootloader/workspace"
 )
 
 func SetTempDir(f func(dir, prefix string) (string, error)) {
@@ -10,7 +12,7 @@ func SetTempDir(f func(dir, prefix string) (string, error)) {
 }
 
 func ResetTempDir() {
-	tempDir = ioutil.TempDir
+	tempDir = workspace.Create
 }
 
 func SetWriteFile(f func(file string, data []byte, perm os.FileMode) error) {
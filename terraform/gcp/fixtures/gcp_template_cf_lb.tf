@@ -18,7 +18,12 @@ variable "credentials" {
 	type = "string"
 }
 
+variable "network_cidr" {
+	type = "string"
+}
+
 provider "google" {
+	version = "~> 1.0"
 	credentials = "${file("${var.credentials}")}"
 	project = "${var.project_id}"
 	region = "${var.region}"
@@ -58,7 +63,7 @@ resource "google_compute_network" "bbl-network" {
 
 resource "google_compute_subnetwork" "bbl-subnet" {
   name			= "${var.env_id}-subnet"
-  ip_cidr_range = "10.0.0.0/16"
+  ip_cidr_range = "${var.network_cidr}"
   network		= "${google_compute_network.bbl-network.self_link}"
 }
 
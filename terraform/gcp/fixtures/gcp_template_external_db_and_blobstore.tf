@@ -0,0 +1,242 @@
+variable "project_id" {
+	type = "string"
+}
+
+variable "region" {
+	type = "string"
+}
+
+variable "zone" {
+	type = "string"
+}
+
+variable "env_id" {
+	type = "string"
+}
+
+variable "credentials" {
+	type = "string"
+}
+
+variable "network_cidr" {
+	type = "string"
+}
+
+provider "google" {
+	version = "~> 1.0"
+	credentials = "${file("${var.credentials}")}"
+	project = "${var.project_id}"
+	region = "${var.region}"
+}
+
+output "external_ip" {
+    value = "${google_compute_address.bosh-external-ip.address}"
+}
+
+output "network_name" {
+    value = "${google_compute_network.bbl-network.name}"
+}
+
+output "subnetwork_name" {
+    value = "${google_compute_subnetwork.bbl-subnet.name}"
+}
+
+output "bosh_open_tag_name" {
+    value = "${google_compute_firewall.bosh-open.name}"
+}
+
+output "bosh_director_tag_name" {
+	value = "${google_compute_firewall.bosh-director.name}"
+}
+
+output "internal_tag_name" {
+    value = "${google_compute_firewall.internal.name}"
+}
+
+output "director_address" {
+	value = "https://${google_compute_address.bosh-external-ip.address}:25555"
+}
+
+resource "google_compute_network" "bbl-network" {
+  name		 = "${var.env_id}-network"
+}
+
+resource "google_compute_subnetwork" "bbl-subnet" {
+  name			= "${var.env_id}-subnet"
+  ip_cidr_range = "${var.network_cidr}"
+  network		= "${google_compute_network.bbl-network.self_link}"
+}
+
+resource "google_compute_address" "bosh-external-ip" {
+  name = "${var.env_id}-bosh-external-ip"
+}
+
+resource "google_compute_firewall" "external" {
+  name    = "${var.env_id}-external"
+  network = "${google_compute_network.bbl-network.name}"
+
+  source_ranges = ["0.0.0.0/0"]
+
+  allow {
+    ports = ["22", "6868", "25555"]
+    protocol = "tcp"
+  }
+
+  target_tags = ["${var.env_id}-bosh-open"]
+}
+
+resource "google_compute_firewall" "bosh-open" {
+  name    = "${var.env_id}-bosh-open"
+  network = "${google_compute_network.bbl-network.name}"
+
+  source_tags = ["${var.env_id}-bosh-open"]
+
+  allow {
+    ports = ["22", "6868", "8443", "25555"]
+    protocol = "tcp"
+  }
+
+  target_tags = ["${var.env_id}-bosh-director"]
+}
+
+resource "google_compute_firewall" "bosh-director" {
+  name    = "${var.env_id}-bosh-director"
+  network = "${google_compute_network.bbl-network.name}"
+
+  source_tags = ["${var.env_id}-bosh-director"]
+
+  allow {
+    protocol = "tcp"
+  }
+
+  target_tags = ["${var.env_id}-internal"]
+}
+
+resource "google_compute_firewall" "internal-to-director" {
+  name    = "${var.env_id}-internal-to-director"
+  network = "${google_compute_network.bbl-network.name}"
+
+  source_tags = ["${var.env_id}-internal"]
+
+  allow {
+    ports = ["4222", "25250", "25777"]
+    protocol = "tcp"
+  }
+
+  target_tags = ["${var.env_id}-bosh-director"]
+}
+
+resource "google_compute_firewall" "internal" {
+  name    = "${var.env_id}-internal"
+  network = "${google_compute_network.bbl-network.name}"
+
+  source_tags = ["${var.env_id}-internal"]
+
+  allow {
+    protocol = "icmp"
+  }
+
+  allow {
+    protocol = "tcp"
+  }
+
+  allow {
+    protocol = "udp"
+  }
+
+  target_tags = ["${var.env_id}-internal"]
+}
+
+output "jumpbox_url" {
+    value = "${google_compute_address.bosh-external-ip.address}:22"
+}
+
+provider "random" {
+  version = "~> 1.0"
+}
+
+resource "random_string" "director_db_password" {
+  length  = 32
+  special = false
+}
+
+resource "random_id" "director_db" {
+  byte_length = 4
+}
+
+resource "google_compute_global_address" "director-db-private-ip" {
+  name          = "${var.env_id}-director-db-private-ip"
+  purpose       = "VPC_PEERING"
+  address_type  = "INTERNAL"
+  prefix_length = 16
+  network       = "${google_compute_network.bbl-network.self_link}"
+}
+
+resource "google_service_networking_connection" "director-db" {
+  network                 = "${google_compute_network.bbl-network.self_link}"
+  service                 = "servicenetworking.googleapis.com"
+  reserved_peering_ranges = ["${google_compute_global_address.director-db-private-ip.name}"]
+}
+
+resource "google_sql_database_instance" "director-db" {
+  name             = "${var.env_id}-director-${random_id.director_db.hex}"
+  database_version = "POSTGRES_9_6"
+  region           = "${var.region}"
+  depends_on       = ["google_service_networking_connection.director-db"]
+
+  settings {
+    tier = "db-custom-1-3840"
+
+    ip_configuration {
+      ipv4_enabled    = false
+      private_network = "${google_compute_network.bbl-network.self_link}"
+    }
+
+    backup_configuration {
+      enabled = true
+    }
+  }
+}
+
+resource "google_sql_database" "director-db" {
+  name     = "bosh"
+  instance = "${google_sql_database_instance.director-db.name}"
+}
+
+resource "google_sql_user" "director-db" {
+  name     = "bosh"
+  instance = "${google_sql_database_instance.director-db.name}"
+  password = "${random_string.director_db_password.result}"
+}
+
+output "external_db_host" {
+  value = "${google_sql_database_instance.director-db.ip_address.0.ip_address}"
+}
+
+output "external_db_user" {
+  value = "${google_sql_user.director-db.name}"
+}
+
+output "external_db_password" {
+  value     = "${random_string.director_db_password.result}"
+  sensitive = true
+}
+
+output "external_db_name" {
+  value = "${google_sql_database.director-db.name}"
+}
+
+resource "random_id" "director_blobstore" {
+  byte_length = 4
+}
+
+resource "google_storage_bucket" "director-blobstore" {
+  name          = "${var.env_id}-blobstore-${random_id.director_blobstore.hex}"
+  location      = "${var.region}"
+  storage_class = "REGIONAL"
+  force_destroy = true
+}
+
+output "director_blobstore_bucket" {
+  value = "${google_storage_bucket.director-blobstore.name}"
+}
@@ -20,7 +20,12 @@ variable "credentials" {
 	type = "string"
 }
 
+variable "network_cidr" {
+	type = "string"
+}
+
 provider "google" {
+	version = "~> 1.0"
 	credentials = "${file("${var.credentials}")}"
 	project = "${var.project_id}"
 	region = "${var.region}"
@@ -61,7 +66,7 @@ resource "google_compute_network" "bbl-network" {
 
 resource "google_compute_subnetwork" "bbl-subnet" {
   name			= "${var.env_id}-subnet"
-  ip_cidr_range = "10.0.0.0/16"
+  ip_cidr_range = "${var.network_cidr}"
   network		= "${google_compute_network.bbl-network.self_link}"
 }
 
@@ -520,3 +525,95 @@ resource "google_dns_record_set" "wildcard-ws-dns" {
   rrdatas = ["${google_compute_address.cf-ws.address}"]
 }
 `
+
+const ExternalDatabaseTemplate = `provider "random" {
+  version = "~> 1.0"
+}
+
+resource "random_string" "director_db_password" {
+  length  = 32
+  special = false
+}
+
+resource "random_id" "director_db" {
+  byte_length = 4
+}
+
+resource "google_compute_global_address" "director-db-private-ip" {
+  name          = "${var.env_id}-director-db-private-ip"
+  purpose       = "VPC_PEERING"
+  address_type  = "INTERNAL"
+  prefix_length = 16
+  network       = "${google_compute_network.bbl-network.self_link}"
+}
+
+resource "google_service_networking_connection" "director-db" {
+  network                 = "${google_compute_network.bbl-network.self_link}"
+  service                 = "servicenetworking.googleapis.com"
+  reserved_peering_ranges = ["${google_compute_global_address.director-db-private-ip.name}"]
+}
+
+resource "google_sql_database_instance" "director-db" {
+  name             = "${var.env_id}-director-${random_id.director_db.hex}"
+  database_version = "POSTGRES_9_6"
+  region           = "${var.region}"
+  depends_on       = ["google_service_networking_connection.director-db"]
+
+  settings {
+    tier = "db-custom-1-3840"
+
+    ip_configuration {
+      ipv4_enabled    = false
+      private_network = "${google_compute_network.bbl-network.self_link}"
+    }
+
+    backup_configuration {
+      enabled = true
+    }
+  }
+}
+
+resource "google_sql_database" "director-db" {
+  name     = "bosh"
+  instance = "${google_sql_database_instance.director-db.name}"
+}
+
+resource "google_sql_user" "director-db" {
+  name     = "bosh"
+  instance = "${google_sql_database_instance.director-db.name}"
+  password = "${random_string.director_db_password.result}"
+}
+
+output "external_db_host" {
+  value = "${google_sql_database_instance.director-db.ip_address.0.ip_address}"
+}
+
+output "external_db_user" {
+  value = "${google_sql_user.director-db.name}"
+}
+
+output "external_db_password" {
+  value     = "${random_string.director_db_password.result}"
+  sensitive = true
+}
+
+output "external_db_name" {
+  value = "${google_sql_database.director-db.name}"
+}
+`
+
+const ExternalBlobstoreTemplate = `resource "random_id" "director_blobstore" {
+  byte_length = 4
+}
+
+resource "google_storage_bucket" "director-blobstore" {
+  name          = "${var.env_id}-blobstore-${random_id.director_blobstore.hex}"
+  location      = "${var.region}"
+  storage_class = "REGIONAL"
+  force_destroy = true
+}
+
+output "director_blobstore_bucket" {
+  value = "${google_storage_bucket.director-blobstore.name}"
+}
+`
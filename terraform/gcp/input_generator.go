@@ -5,10 +5,14 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/workspace"
 )
 
-var tempDir func(dir, prefix string) (string, error) = ioutil.TempDir
+// The files are read by terraform after Generate returns, so the workspace
+// is only removed when bbl exits.
+var tempDir func(dir, prefix string) (string, error) = workspace.Create
 var writeFile func(file string, data []byte, perm os.FileMode) error = ioutil.WriteFile
 
 type InputGenerator struct {
@@ -19,13 +23,18 @@ func NewInputGenerator() InputGenerator {
 }
 
 func (i InputGenerator) Generate(state storage.State) (map[string]string, error) {
+	network, err := bosh.NewNetwork(state.Network)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
 	dir, err := tempDir("", "")
 	if err != nil {
 		return map[string]string{}, err
 	}
 
 	credentialsPath := filepath.Join(dir, "credentials.json")
-	err = writeFile(credentialsPath, []byte(state.GCP.ServiceAccountKey), os.ModePerm)
+	err = writeFile(credentialsPath, []byte(state.GCP.ServiceAccountKey), workspace.FileMode)
 	if err != nil {
 		return map[string]string{}, err
 	}
@@ -37,18 +46,19 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		"zone":          state.GCP.Zone,
 		"credentials":   credentialsPath,
 		"system_domain": state.LB.Domain,
+		"network_cidr":  network.CIDR.String(),
 	}
 
 	if state.LB.Cert != "" && state.LB.Key != "" {
 		certPath := filepath.Join(dir, "cert")
-		err = writeFile(certPath, []byte(state.LB.Cert), os.ModePerm)
+		err = writeFile(certPath, []byte(state.LB.Cert), workspace.FileMode)
 		if err != nil {
 			return map[string]string{}, err
 		}
 		input["ssl_certificate"] = certPath
 
 		keyPath := filepath.Join(dir, "key")
-		err = writeFile(keyPath, []byte(state.LB.Key), os.ModePerm)
+		err = writeFile(keyPath, []byte(state.LB.Key), workspace.FileMode)
 		if err != nil {
 			return map[string]string{}, err
 		}
@@ -65,6 +65,7 @@ var _ = Describe("InputGenerator", func() {
 			"zone":          state.GCP.Zone,
 			"credentials":   filepath.Join(tempDir, "credentials.json"),
 			"system_domain": state.LB.Domain,
+			"network_cidr":  "10.0.0.0/16",
 		}))
 
 		credentials, err := ioutil.ReadFile(inputs["credentials"])
@@ -88,6 +89,7 @@ var _ = Describe("InputGenerator", func() {
 			"ssl_certificate":             filepath.Join(tempDir, "cert"),
 			"ssl_certificate_private_key": filepath.Join(tempDir, "key"),
 			"system_domain":               state.LB.Domain,
+			"network_cidr":                "10.0.0.0/16",
 		}))
 
 		sslCertificate, err := ioutil.ReadFile(inputs["ssl_certificate"])
@@ -99,7 +101,22 @@ var _ = Describe("InputGenerator", func() {
 		Expect(string(sslCertificatePrivateKey)).To(Equal("some-key"))
 	})
 
+	It("returns the configured network cidr", func() {
+		state.Network.CIDR = "192.168.0.0/16"
+
+		inputs, err := inputGenerator.Generate(state)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inputs["network_cidr"]).To(Equal("192.168.0.0/16"))
+	})
+
 	Context("failure cases", func() {
+		It("returns an error if the network is invalid", func() {
+			state.Network.CIDR = "banana"
+
+			_, err := inputGenerator.Generate(state)
+			Expect(err).To(MatchError(`network cidr: "banana" cannot parse CIDR block`))
+		})
+
 		It("returns an error if temp dir cannot be created", func() {
 			gcp.SetTempDir(func(dir, prefix string) (string, error) {
 				return "", errors.New("failed to create temp dir")
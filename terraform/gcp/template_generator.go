@@ -40,6 +40,15 @@ func (t TemplateGenerator) Generate(state storage.State) string {
 			template = strings.Join([]string{template, CFDNSTemplate}, "\n")
 		}
 	}
+
+	if state.BOSH.ExternalDatabase {
+		template = strings.Join([]string{template, ExternalDatabaseTemplate}, "\n")
+	}
+
+	if state.BOSH.ExternalBlobstore {
+		template = strings.Join([]string{template, ExternalBlobstoreTemplate}, "\n")
+	}
+
 	return template
 }
 
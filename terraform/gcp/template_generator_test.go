@@ -45,6 +45,23 @@ var _ = Describe("TemplateGenerator", func() {
 			Entry("when a cf lb type is provided", "fixtures/gcp_template_cf_lb.tf", "some-region", "cf", ""),
 			Entry("when a cf lb type is provided with a domain", "fixtures/gcp_template_cf_lb_dns.tf", "some-region", "cf", "some-domain"),
 		)
+
+		It("adds the director database and blobstore when they are external", func() {
+			expectedTemplate, err := ioutil.ReadFile("fixtures/gcp_template_external_db_and_blobstore.tf")
+			Expect(err).NotTo(HaveOccurred())
+
+			template := templateGenerator.Generate(storage.State{
+				GCP: storage.GCP{
+					Region: "some-region",
+					Zones:  zones,
+				},
+				BOSH: storage.BOSH{
+					ExternalDatabase:  true,
+					ExternalBlobstore: true,
+				},
+			})
+			Expect(template).To(Equal(string(expectedTemplate)))
+		})
 	})
 
 	Describe("GenerateBackendService", func() {
@@ -2,11 +2,9 @@ package terraform
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 
 	"github.com/cloudfoundry/bosh-bootloader/storage"
-	"github.com/coreos/go-semver/semver"
 )
 
 type Manager struct {
@@ -19,12 +17,16 @@ type Manager struct {
 	terraformOutputBuffer *bytes.Buffer
 	logger                logger
 	stackMigrator         stackMigrator
+	retries               int
 }
 
 type executor interface {
 	Version() (string, error)
-	Destroy(inputs map[string]string, terraformTemplate, tfState string) (string, error)
-	Apply(inputs map[string]string, terraformTemplate, tfState string) (string, error)
+	Destroy(inputs map[string]string, terraformTemplate string, overrides map[string]string, tfState string) (string, error)
+	Apply(inputs map[string]string, terraformTemplate string, overrides map[string]string, tfState string) (string, error)
+	Plan(inputs map[string]string, terraformTemplate string, overrides map[string]string, tfState string) (string, error)
+	Refresh(inputs map[string]string, terraformTemplate string, overrides map[string]string, tfState string) (string, error)
+	Import(input ImportInput) (string, error)
 }
 
 type templateGenerator interface {
@@ -58,6 +60,7 @@ type NewManagerArgs struct {
 	TerraformOutputBuffer *bytes.Buffer
 	Logger                logger
 	StackMigrator         stackMigrator
+	Retries               int
 }
 
 func NewManager(args NewManagerArgs) Manager {
@@ -71,6 +74,7 @@ func NewManager(args NewManagerArgs) Manager {
 		terraformOutputBuffer: args.TerraformOutputBuffer,
 		logger:                args.Logger,
 		stackMigrator:         args.StackMigrator,
+		retries:               args.Retries,
 	}
 }
 
@@ -84,32 +88,8 @@ func (m Manager) ValidateVersion() error {
 		return err
 	}
 
-	currentVersion, err := semver.NewVersion(version)
-	if err != nil {
-		return err
-	}
-
-	// This shouldn't fail, so there is no test for capturing the error.
-	minimumVersion, err := semver.NewVersion("0.8.5")
-	if err != nil {
-		return err
-	}
-
-	if currentVersion.LessThan(*minimumVersion) {
-		return errors.New("Terraform version must be at least v0.8.5")
-	}
-
-	// This shouldn't fail, so there is no test for capturing the error.
-	blacklistedVersion, err := semver.NewVersion("0.9.0")
-	if err != nil {
-		return err
-	}
-
-	if currentVersion.Equal(*blacklistedVersion) {
-		return errors.New("Version 0.9.0 of terraform is incompatible with bbl, please try a later version.")
-	}
-
-	return nil
+	_, err = NewAdapter(version)
+	return err
 }
 
 func (m Manager) Apply(bblState storage.State) (storage.State, error) {
@@ -135,24 +115,72 @@ func (m Manager) Apply(bblState storage.State) (storage.State, error) {
 		return storage.State{}, err
 	}
 
-	m.logger.Step("applying terraform template")
-	tfState, err := m.executor.Apply(
-		input,
-		template,
-		bblState.TFState,
-	)
+	tfState := bblState.TFState
+	for attempt := 0; ; attempt++ {
+		if attempt == 0 {
+			m.logger.Step("applying terraform template")
+		} else {
+			m.logger.Step("applying terraform template (retry %d of %d)", attempt, m.retries)
+		}
+
+		updatedTFState, err := m.executor.Apply(
+			input,
+			template,
+			bblState.TerraformOverrides,
+			tfState,
+		)
+
+		bblState.LatestTFOutput = readAndReset(m.terraformOutputBuffer)
+
+		if err == nil {
+			bblState.TFState = updatedTFState
+			return bblState, nil
+		}
+
+		applyError, ok := err.(executorError)
+		if !ok {
+			return storage.State{}, err
+		}
+
+		if attempt >= m.retries || !IsTransient(bblState.LatestTFOutput) {
+			return storage.State{}, NewManagerError(bblState, applyError)
+		}
+
+		// Retry from the partially applied state, so that resources created
+		// by the failed attempt are not created twice. An attempt that failed
+		// before terraform wrote any state changed nothing, so the next one
+		// starts from the same state.
+		partialTFState, err := applyError.TFState()
+		if err == nil {
+			tfState = partialTFState
+		}
+
+		delay := backoff(attempt)
+		m.logger.Step("terraform apply failed with a transient error, retrying in %s", delay)
+		sleep(delay)
+	}
+}
 
-	bblState.LatestTFOutput = readAndReset(m.terraformOutputBuffer)
+// Plan reports the changes Apply would make to the infrastructure without
+// making them. Unlike Apply it does not migrate CloudFormation stacks.
+func (m Manager) Plan(bblState storage.State) (string, error) {
+	m.logger.Step("generating terraform template")
+	template := m.templateGenerator.Generate(bblState)
 
-	switch err.(type) {
-	case executorError:
-		return storage.State{}, NewManagerError(bblState, err.(executorError))
-	case error:
-		return storage.State{}, err
+	m.logger.Step("generating terraform variables")
+	input, err := m.inputGenerator.Generate(bblState)
+	if err != nil {
+		return "", err
 	}
 
-	bblState.TFState = tfState
-	return bblState, nil
+	m.logger.Step("planning terraform changes")
+	plan, err := m.executor.Plan(input, template, bblState.TerraformOverrides, bblState.TFState)
+	readAndReset(m.terraformOutputBuffer)
+	if err != nil {
+		return "", err
+	}
+
+	return plan, nil
 }
 
 func (m Manager) Destroy(bblState storage.State) (storage.State, error) {
@@ -171,6 +199,7 @@ func (m Manager) Destroy(bblState storage.State) (storage.State, error) {
 	tfState, err := m.executor.Destroy(
 		input,
 		template,
+		bblState.TerraformOverrides,
 		bblState.TFState)
 
 	bblState.LatestTFOutput = readAndReset(m.terraformOutputBuffer)
@@ -187,6 +216,58 @@ func (m Manager) Destroy(bblState storage.State) (storage.State, error) {
 	return bblState, nil
 }
 
+// Import brings an existing resource under the management of the
+// environment's terraform state. The resource must be declared at address by
+// the generated template or by one of the terraform overrides.
+func (m Manager) Import(bblState storage.State, address, id string) (storage.State, error) {
+	m.logger.Step("generating terraform template")
+	template := m.templateGenerator.Generate(bblState)
+
+	m.logger.Step("generating terraform variables")
+	input, err := m.inputGenerator.Generate(bblState)
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	m.logger.Step("importing %s", address)
+	tfState, err := m.executor.Import(ImportInput{
+		TerraformAddr: address,
+		ResourceID:    id,
+		TFState:       bblState.TFState,
+		Template:      template,
+		Overrides:     bblState.TerraformOverrides,
+		Inputs:        input,
+	})
+	bblState.LatestTFOutput = readAndReset(m.terraformOutputBuffer)
+	if err != nil {
+		return storage.State{}, err
+	}
+	m.logger.Step("finished importing %s", address)
+
+	bblState.TFState = tfState
+	return bblState, nil
+}
+
+// Drift refreshes a copy of the terraform state and reports the resources
+// that were changed or deleted outside of bbl. It does not log any steps, so
+// that its callers can print machine readable output.
+func (m Manager) Drift(bblState storage.State) ([]ResourceDrift, error) {
+	template := m.templateGenerator.Generate(bblState)
+
+	input, err := m.inputGenerator.Generate(bblState)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshedTFState, err := m.executor.Refresh(input, template, bblState.TerraformOverrides, bblState.TFState)
+	readAndReset(m.terraformOutputBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffResources(bblState.TFState, refreshedTFState)
+}
+
 func (m Manager) GetOutputs(state storage.State) (map[string]interface{}, error) {
 	switch state.IAAS {
 	case "gcp":
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
@@ -134,6 +135,16 @@ var _ = Describe("Manager", func() {
 			Expect(state).To(Equal(expectedState))
 		})
 
+		It("passes the terraform overrides to the executor", func() {
+			incomingState.TerraformOverrides = map[string]string{"extra.tf": "some-extra-resources"}
+			migrator.MigrateReturns(incomingState, nil)
+
+			_, err := manager.Apply(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.ApplyCall.Receives.Overrides).To(Equal(map[string]string{"extra.tf": "some-extra-resources"}))
+		})
+
 		Context("when an error occurs", func() {
 			Context("when the stack cannot be migrated", func() {
 				It("returns an error", func() {
@@ -173,6 +184,163 @@ var _ = Describe("Manager", func() {
 				})
 			})
 
+			Context("when applying fails with a transient error", func() {
+				var (
+					applyError *fakes.TerraformExecutorError
+					delays     []time.Duration
+				)
+
+				BeforeEach(func() {
+					manager = terraform.NewManager(terraform.NewManagerArgs{
+						Executor:              executor,
+						TemplateGenerator:     templateGenerator,
+						InputGenerator:        inputGenerator,
+						TerraformOutputBuffer: &terraformOutputBuffer,
+						Logger:                logger,
+						StackMigrator:         migrator,
+						Retries:               2,
+					})
+
+					applyError = &fakes.TerraformExecutorError{}
+					applyError.TFStateCall.Returns.TFState = "some-partial-tf-state"
+
+					delays = []time.Duration{}
+					terraform.SetSleep(func(delay time.Duration) {
+						delays = append(delays, delay)
+					})
+				})
+
+				AfterEach(func() {
+					terraform.ResetSleep()
+				})
+
+				It("applies again from the partially applied state with backoff", func() {
+					executor.ApplyCall.Stub = func() (string, error) {
+						if executor.ApplyCall.CallCount < 3 {
+							terraformOutputBuffer.Write([]byte("Error 400: some resource is not ready, resourceNotReady"))
+							return "", applyError
+						}
+						terraformOutputBuffer.Write([]byte(expectedTFOutput))
+						return expectedTFState, nil
+					}
+
+					state, err := manager.Apply(incomingState)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(state).To(Equal(expectedState))
+
+					Expect(executor.ApplyCall.CallCount).To(Equal(3))
+					Expect(executor.ApplyCall.Receives.TFState).To(Equal("some-partial-tf-state"))
+					Expect(delays).To(Equal([]time.Duration{10 * time.Second, 20 * time.Second}))
+
+					Expect(logger.StepCall.Messages).To(gomegamatchers.ContainSequence([]string{
+						"applying terraform template",
+						"terraform apply failed with a transient error, retrying in 10s",
+						"applying terraform template (retry 1 of 2)",
+						"terraform apply failed with a transient error, retrying in 20s",
+						"applying terraform template (retry 2 of 2)",
+					}))
+				})
+
+				It("returns a ManagerError once it runs out of retries", func() {
+					executor.ApplyCall.Stub = func() (string, error) {
+						terraformOutputBuffer.Write([]byte("RequestLimitExceeded: Request limit exceeded."))
+						return "", applyError
+					}
+
+					_, err := manager.Apply(incomingState)
+					Expect(err).To(BeAssignableToTypeOf(terraform.ManagerError{}))
+
+					Expect(executor.ApplyCall.CallCount).To(Equal(3))
+					Expect(delays).To(HaveLen(2))
+
+					bblState, err := err.(terraform.ManagerError).BBLState()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(bblState.TFState).To(Equal("some-partial-tf-state"))
+					Expect(bblState.LatestTFOutput).To(Equal("RequestLimitExceeded: Request limit exceeded."))
+				})
+
+				It("waits at most five minutes between retries", func() {
+					manager = terraform.NewManager(terraform.NewManagerArgs{
+						Executor:              executor,
+						TemplateGenerator:     templateGenerator,
+						InputGenerator:        inputGenerator,
+						TerraformOutputBuffer: &terraformOutputBuffer,
+						Logger:                logger,
+						StackMigrator:         migrator,
+						Retries:               70,
+					})
+					executor.ApplyCall.Stub = func() (string, error) {
+						terraformOutputBuffer.Write([]byte("RequestLimitExceeded: Request limit exceeded."))
+						return "", applyError
+					}
+
+					_, err := manager.Apply(incomingState)
+					Expect(err).To(BeAssignableToTypeOf(terraform.ManagerError{}))
+
+					Expect(executor.ApplyCall.CallCount).To(Equal(71))
+					Expect(delays).To(HaveLen(70))
+					Expect(delays[:6]).To(Equal([]time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, 5 * time.Minute}))
+					for _, delay := range delays[6:] {
+						Expect(delay).To(Equal(5 * time.Minute))
+					}
+				})
+
+				It("does not retry when the retry count is negative", func() {
+					manager = terraform.NewManager(terraform.NewManagerArgs{
+						Executor:              executor,
+						TemplateGenerator:     templateGenerator,
+						InputGenerator:        inputGenerator,
+						TerraformOutputBuffer: &terraformOutputBuffer,
+						Logger:                logger,
+						StackMigrator:         migrator,
+						Retries:               -1,
+					})
+					executor.ApplyCall.Stub = func() (string, error) {
+						terraformOutputBuffer.Write([]byte("RequestLimitExceeded: Request limit exceeded."))
+						return "", applyError
+					}
+
+					_, err := manager.Apply(incomingState)
+					Expect(err).To(BeAssignableToTypeOf(terraform.ManagerError{}))
+
+					Expect(executor.ApplyCall.CallCount).To(Equal(1))
+					Expect(delays).To(BeEmpty())
+				})
+
+				It("does not retry failures that are not transient", func() {
+					executor.ApplyCall.Stub = func() (string, error) {
+						terraformOutputBuffer.Write([]byte(expectedTFOutput))
+						return "", applyError
+					}
+
+					_, err := manager.Apply(incomingState)
+					Expect(err).To(BeAssignableToTypeOf(terraform.ManagerError{}))
+
+					Expect(executor.ApplyCall.CallCount).To(Equal(1))
+					Expect(delays).To(BeEmpty())
+				})
+
+				It("applies again from the previous state when the failed attempt left no state", func() {
+					applyError.TFStateCall.Returns.Error = errors.New("failed to read tf state")
+					executor.ApplyCall.Stub = func() (string, error) {
+						if executor.ApplyCall.CallCount < 2 {
+							terraformOutputBuffer.Write([]byte("resourceNotReady"))
+							return "", applyError
+						}
+						terraformOutputBuffer.Write([]byte(expectedTFOutput))
+						return expectedTFState, nil
+					}
+
+					state, err := manager.Apply(incomingState)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(state).To(Equal(expectedState))
+
+					Expect(executor.ApplyCall.CallCount).To(Equal(2))
+					Expect(executor.ApplyCall.Receives.TFState).To(Equal(incomingState.TFState))
+					Expect(delays).To(Equal([]time.Duration{10 * time.Second}))
+				})
+			})
+
 			Context("when migrating causes an executor error", func() {
 				BeforeEach(func() {
 					migrator.MigrateReturns(storage.State{}, &fakes.TerraformExecutorError{})
@@ -204,6 +372,166 @@ var _ = Describe("Manager", func() {
 		})
 	})
 
+	Describe("Plan", func() {
+		var incomingState storage.State
+
+		BeforeEach(func() {
+			incomingState = storage.State{
+				IAAS:               "gcp",
+				EnvID:              "some-env-id",
+				TFState:            "some-tf-state",
+				TerraformOverrides: map[string]string{"extra.tf": "some-extra-resources"},
+			}
+
+			templateGenerator.GenerateCall.Returns.Template = "some-gcp-terraform-template"
+			inputGenerator.GenerateCall.Returns.Inputs = map[string]string{"env_id": "some-env-id"}
+			executor.PlanCall.Returns.Plan = "some-plan"
+		})
+
+		It("plans the generated template against the stored tf state", func() {
+			plan, err := manager.Plan(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan).To(Equal("some-plan"))
+
+			Expect(templateGenerator.GenerateCall.Receives.State).To(Equal(incomingState))
+			Expect(inputGenerator.GenerateCall.Receives.State).To(Equal(incomingState))
+			Expect(executor.PlanCall.Receives.Inputs).To(Equal(map[string]string{"env_id": "some-env-id"}))
+			Expect(executor.PlanCall.Receives.Template).To(Equal("some-gcp-terraform-template"))
+			Expect(executor.PlanCall.Receives.Overrides).To(Equal(map[string]string{"extra.tf": "some-extra-resources"}))
+			Expect(executor.PlanCall.Receives.TFState).To(Equal("some-tf-state"))
+
+			Expect(migrator.MigrateCallCount()).To(Equal(0))
+			Expect(executor.ApplyCall.CallCount).To(Equal(0))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the inputs cannot be generated", func() {
+				inputGenerator.GenerateCall.Returns.Error = errors.New("failed to generate inputs")
+
+				_, err := manager.Plan(incomingState)
+				Expect(err).To(MatchError("failed to generate inputs"))
+			})
+
+			It("returns an error when the plan fails", func() {
+				executor.PlanCall.Returns.Error = errors.New("failed to plan")
+
+				_, err := manager.Plan(incomingState)
+				Expect(err).To(MatchError("failed to plan"))
+			})
+		})
+	})
+
+	Describe("Import", func() {
+		var incomingState storage.State
+
+		BeforeEach(func() {
+			incomingState = storage.State{
+				IAAS:               "gcp",
+				EnvID:              "some-env-id",
+				TFState:            "some-tf-state",
+				TerraformOverrides: map[string]string{"dns.tf": "some-dns-zone"},
+			}
+
+			templateGenerator.GenerateCall.Returns.Template = "some-gcp-terraform-template"
+			inputGenerator.GenerateCall.Returns.Inputs = map[string]string{"env_id": "some-env-id"}
+			executor.ImportCall.Returns.TFState = "some-imported-tf-state"
+			executor.ImportCall.Stub = func() {
+				terraformOutputBuffer.Write([]byte(expectedTFOutput))
+			}
+		})
+
+		It("imports the resource against the generated template and returns the updated state", func() {
+			state, err := manager.Import(incomingState, "google_dns_managed_zone.env_dns_zone", "some-zone-id")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.ImportCall.Receives.Input).To(Equal(terraform.ImportInput{
+				TerraformAddr: "google_dns_managed_zone.env_dns_zone",
+				ResourceID:    "some-zone-id",
+				TFState:       "some-tf-state",
+				Template:      "some-gcp-terraform-template",
+				Overrides:     map[string]string{"dns.tf": "some-dns-zone"},
+				Inputs:        map[string]string{"env_id": "some-env-id"},
+			}))
+
+			expectedState := incomingState
+			expectedState.TFState = "some-imported-tf-state"
+			expectedState.LatestTFOutput = expectedTFOutput
+			Expect(state).To(Equal(expectedState))
+
+			Expect(logger.StepCall.Messages).To(ContainElement("importing google_dns_managed_zone.env_dns_zone"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the inputs cannot be generated", func() {
+				inputGenerator.GenerateCall.Returns.Error = errors.New("failed to generate inputs")
+
+				_, err := manager.Import(incomingState, "google_dns_managed_zone.env_dns_zone", "some-zone-id")
+				Expect(err).To(MatchError("failed to generate inputs"))
+			})
+
+			It("returns an error when the import fails", func() {
+				executor.ImportCall.Returns.Error = errors.New("failed to import")
+
+				_, err := manager.Import(incomingState, "google_dns_managed_zone.env_dns_zone", "some-zone-id")
+				Expect(err).To(MatchError("failed to import"))
+			})
+		})
+	})
+
+	Describe("Drift", func() {
+		var incomingState storage.State
+
+		BeforeEach(func() {
+			incomingState = storage.State{
+				IAAS:               "aws",
+				EnvID:              "some-env-id",
+				TFState:            `{"version": 3, "modules": [{"path": ["root"], "resources": {"aws_elb.cf_router_lb": {"primary": {"attributes": {"idle_timeout": "60"}}}}}]}`,
+				TerraformOverrides: map[string]string{"extra.tf": "some-extra-resources"},
+			}
+
+			templateGenerator.GenerateCall.Returns.Template = "some-aws-terraform-template"
+			inputGenerator.GenerateCall.Returns.Inputs = map[string]string{"env_id": "some-env-id"}
+			executor.RefreshCall.Returns.TFState = `{"version": 3, "modules": [{"path": ["root"], "resources": {"aws_elb.cf_router_lb": {"primary": {"attributes": {"idle_timeout": "300"}}}}}]}`
+		})
+
+		It("refreshes the stored tf state and reports the resources that changed", func() {
+			drift, err := manager.Drift(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drift).To(Equal([]terraform.ResourceDrift{
+				{
+					Address: "aws_elb.cf_router_lb",
+					Attributes: []terraform.AttributeDrift{
+						{Name: "idle_timeout", Stored: "60", Live: "300"},
+					},
+				},
+			}))
+
+			Expect(executor.RefreshCall.Receives.Inputs).To(Equal(map[string]string{"env_id": "some-env-id"}))
+			Expect(executor.RefreshCall.Receives.Template).To(Equal("some-aws-terraform-template"))
+			Expect(executor.RefreshCall.Receives.Overrides).To(Equal(map[string]string{"extra.tf": "some-extra-resources"}))
+			Expect(executor.RefreshCall.Receives.TFState).To(Equal(incomingState.TFState))
+
+			Expect(executor.ApplyCall.CallCount).To(Equal(0))
+			Expect(logger.StepCall.CallCount).To(Equal(0))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the inputs cannot be generated", func() {
+				inputGenerator.GenerateCall.Returns.Error = errors.New("failed to generate inputs")
+
+				_, err := manager.Drift(incomingState)
+				Expect(err).To(MatchError("failed to generate inputs"))
+			})
+
+			It("returns an error when the refresh fails", func() {
+				executor.RefreshCall.Returns.Error = errors.New("failed to refresh")
+
+				_, err := manager.Drift(incomingState)
+				Expect(err).To(MatchError("failed to refresh"))
+			})
+		})
+	})
+
 	Describe("Destroy", func() {
 		Context("when the bbl state contains a non-empty TFState", func() {
 			var (
@@ -416,9 +744,9 @@ var _ = Describe("Manager", func() {
 	})
 
 	Describe("ValidateVersion", func() {
-		Context("when terraform version is greater than v0.8.5", func() {
+		Context("when terraform version is at least v0.10.0", func() {
 			BeforeEach(func() {
-				executor.VersionCall.Returns.Version = "0.9.1"
+				executor.VersionCall.Returns.Version = "0.10.0"
 			})
 
 			It("validates the version of terraform and returns no error", func() {
@@ -427,23 +755,19 @@ var _ = Describe("Manager", func() {
 			})
 		})
 
-		Context("when terraform version is v0.9.0", func() {
-			BeforeEach(func() {
-				executor.VersionCall.Returns.Version = "0.9.0"
-			})
+		Context("failure cases", func() {
+			It("returns an error when the terraform installed is less than v0.10.0", func() {
+				executor.VersionCall.Returns.Version = "0.9.11"
 
-			It("returns a helpful error message", func() {
 				err := manager.ValidateVersion()
-				Expect(err).To(MatchError("Version 0.9.0 of terraform is incompatible with bbl, please try a later version."))
+				Expect(err).To(MatchError("Terraform version must be at least v0.10.0"))
 			})
-		})
 
-		Context("failure cases", func() {
-			It("returns an error when the terraform installed is less than v0.8.5", func() {
-				executor.VersionCall.Returns.Version = "0.8.4"
+			It("returns an error when the terraform installed is newer than bbl supports", func() {
+				executor.VersionCall.Returns.Version = "0.12.0"
 
 				err := manager.ValidateVersion()
-				Expect(err).To(MatchError("Terraform version must be at least v0.8.5"))
+				Expect(err).To(MatchError("Version 0.12.0 of terraform is not supported by bbl yet, please use a version before v0.12.0."))
 			})
 
 			It("fast fails if the terraform executor fails to get the version", func() {
@@ -0,0 +1,139 @@
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+type tfStateVersion struct {
+	Version int `json:"version"`
+}
+
+// Versions 1 and 2 are written by terraform 0.6 and older and store every
+// output as a string.
+type tfStateV1 struct {
+	Modules []struct {
+		Path    []string          `json:"path"`
+		Outputs map[string]string `json:"outputs"`
+	} `json:"modules"`
+}
+
+// Version 3 is written by terraform 0.7 to 0.11.
+type tfStateV3 struct {
+	Modules []struct {
+		Path    []string            `json:"path"`
+		Outputs map[string]tfOutput `json:"outputs"`
+	} `json:"modules"`
+}
+
+// Version 4 is written by terraform 0.12 and newer, which only keeps the
+// outputs of the root module.
+type tfStateV4 struct {
+	Outputs map[string]tfOutput `json:"outputs"`
+}
+
+// ParseOutputs returns the outputs of the root module in a terraform state
+// file, the way `terraform output --json` reports their values.
+func ParseOutputs(tfState string) (map[string]interface{}, error) {
+	outputs := map[string]interface{}{}
+	if tfState == "" {
+		return outputs, nil
+	}
+
+	var version tfStateVersion
+	err := json.Unmarshal([]byte(tfState), &version)
+	if err != nil {
+		return map[string]interface{}{}, fmt.Errorf("parse terraform state: %s", err)
+	}
+
+	switch version.Version {
+	case 1, 2:
+		var state tfStateV1
+		err = json.Unmarshal([]byte(tfState), &state)
+		if err != nil {
+			return map[string]interface{}{}, fmt.Errorf("parse terraform state: %s", err)
+		}
+
+		for _, module := range state.Modules {
+			if !isRootModule(module.Path) {
+				continue
+			}
+			for name, value := range module.Outputs {
+				outputs[name] = value
+			}
+		}
+	case 3:
+		var state tfStateV3
+		err = json.Unmarshal([]byte(tfState), &state)
+		if err != nil {
+			return map[string]interface{}{}, fmt.Errorf("parse terraform state: %s", err)
+		}
+
+		for _, module := range state.Modules {
+			if !isRootModule(module.Path) {
+				continue
+			}
+			for name, output := range module.Outputs {
+				outputs[name] = output.Value
+			}
+		}
+	case 4:
+		var state tfStateV4
+		err = json.Unmarshal([]byte(tfState), &state)
+		if err != nil {
+			return map[string]interface{}{}, fmt.Errorf("parse terraform state: %s", err)
+		}
+
+		for name, output := range state.Outputs {
+			outputs[name] = output.Value
+		}
+	default:
+		return map[string]interface{}{}, fmt.Errorf("unsupported terraform state version %d", version.Version)
+	}
+
+	return outputs, nil
+}
+
+func isRootModule(path []string) bool {
+	return len(path) == 1 && path[0] == "root"
+}
+
+// outputCache keeps the parsed outputs of every terraform state seen by one
+// bbl invocation, since a single command can ask for them many times.
+type outputCache struct {
+	mutex   sync.Mutex
+	outputs map[[sha256.Size]byte]map[string]interface{}
+}
+
+func newOutputCache() *outputCache {
+	return &outputCache{
+		outputs: map[[sha256.Size]byte]map[string]interface{}{},
+	}
+}
+
+func (c *outputCache) get(tfState string) (map[string]interface{}, error) {
+	key := sha256.Sum256([]byte(tfState))
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	outputs, ok := c.outputs[key]
+	if !ok {
+		var err error
+		outputs, err = ParseOutputs(tfState)
+		if err != nil {
+			return map[string]interface{}{}, err
+		}
+		c.outputs[key] = outputs
+	}
+
+	// Callers post-process the outputs in place, so each gets its own map.
+	outputsCopy := map[string]interface{}{}
+	for name, value := range outputs {
+		outputsCopy[name] = value
+	}
+
+	return outputsCopy, nil
+}
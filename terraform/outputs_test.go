@@ -0,0 +1,90 @@
+package terraform_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseOutputs", func() {
+	DescribeTable("returns the outputs of the root module",
+		func(tfState string) {
+			outputs, err := terraform.ParseOutputs(tfState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputs).To(Equal(map[string]interface{}{
+				"external_ip": "some-external-ip",
+			}))
+		},
+		Entry("state version 1", `{
+			"version": 1,
+			"modules": [
+				{"path": ["root"], "outputs": {"external_ip": "some-external-ip"}},
+				{"path": ["root", "some-module"], "outputs": {"module_ip": "some-module-ip"}}
+			]
+		}`),
+		Entry("state version 2", `{
+			"version": 2,
+			"modules": [
+				{"path": ["root"], "outputs": {"external_ip": "some-external-ip"}}
+			]
+		}`),
+		Entry("state version 3", `{
+			"version": 3,
+			"terraform_version": "0.10.8",
+			"modules": [
+				{"path": ["root"], "outputs": {"external_ip": {"sensitive": false, "type": "string", "value": "some-external-ip"}}},
+				{"path": ["root", "some-module"], "outputs": {"module_ip": {"sensitive": false, "type": "string", "value": "some-module-ip"}}}
+			]
+		}`),
+		Entry("state version 4", `{
+			"version": 4,
+			"terraform_version": "0.12.0",
+			"outputs": {"external_ip": {"type": "string", "value": "some-external-ip"}}
+		}`),
+	)
+
+	It("keeps the types of list and map outputs", func() {
+		outputs, err := terraform.ParseOutputs(`{
+			"version": 3,
+			"modules": [{
+				"path": ["root"],
+				"outputs": {
+					"name_servers": {"sensitive": false, "type": "list", "value": ["ns1", "ns2"]},
+					"tags": {"sensitive": false, "type": "map", "value": {"env": "some-env"}},
+					"password": {"sensitive": true, "type": "string", "value": "some-password"}
+				}
+			}]
+		}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outputs).To(Equal(map[string]interface{}{
+			"name_servers": []interface{}{"ns1", "ns2"},
+			"tags":         map[string]interface{}{"env": "some-env"},
+			"password":     "some-password",
+		}))
+	})
+
+	It("returns no outputs for an empty terraform state", func() {
+		outputs, err := terraform.ParseOutputs("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outputs).To(BeEmpty())
+	})
+
+	Context("failure cases", func() {
+		It("returns an error when the state is not json", func() {
+			_, err := terraform.ParseOutputs("%%%")
+			Expect(err).To(MatchError("parse terraform state: invalid character '%' looking for beginning of value"))
+		})
+
+		It("returns an error when the outputs do not match the state version", func() {
+			_, err := terraform.ParseOutputs(`{"version": 1, "modules": [{"path": ["root"], "outputs": {"external_ip": {"value": "some-external-ip"}}}]}`)
+			Expect(err).To(MatchError(ContainSubstring("parse terraform state: json: cannot unmarshal object")))
+		})
+
+		It("returns an error when the state version is not supported", func() {
+			_, err := terraform.ParseOutputs(`{"version": 5}`)
+			Expect(err).To(MatchError("unsupported terraform state version 5"))
+		})
+	})
+})
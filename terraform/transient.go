@@ -0,0 +1,67 @@
+package terraform
+
+import (
+	"regexp"
+	"time"
+)
+
+var sleep = time.Sleep
+
+// retryDelay is how long Apply waits before the first retry. It doubles for
+// every retry after that, up to maxRetryDelay.
+const (
+	retryDelay    = 10 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+// backoff returns the delay before the retry that follows attempt.
+func backoff(attempt int) time.Duration {
+	delay := retryDelay
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+
+	return delay
+}
+
+// transientFailures matches terraform output for errors that go away on
+// their own, because the IaaS has not caught up with a change yet or is
+// throttling requests.
+var transientFailures = []*regexp.Regexp{
+	// AWS eventual consistency
+	regexp.MustCompile(`(?i)invalid iam instance profile`),
+	regexp.MustCompile(`iamInstanceProfile\.name is invalid`),
+	regexp.MustCompile(`InvalidGroup\.NotFound`),
+	regexp.MustCompile(`InvalidSubnetID\.NotFound`),
+	regexp.MustCompile(`InvalidRouteTableID\.NotFound`),
+
+	// GCP eventual consistency
+	regexp.MustCompile(`resourceNotReady`),
+	regexp.MustCompile(`The resource '[^']+' is not ready`),
+
+	// Azure eventual consistency
+	regexp.MustCompile(`AnotherOperationInProgress`),
+	regexp.MustCompile(`RetryableError`),
+
+	// Rate limits
+	regexp.MustCompile(`RequestLimitExceeded`),
+	regexp.MustCompile(`Throttling: Rate exceeded`),
+	regexp.MustCompile(`(?i)rateLimitExceeded`),
+	regexp.MustCompile(`TooManyRequests`),
+}
+
+// IsTransient reports whether the terraform output of a failed run shows
+// an error that is likely to go away when the run is repeated.
+func IsTransient(tfOutput string) bool {
+	for _, failure := range transientFailures {
+		if failure.MatchString(tfOutput) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,36 @@
+package terraform_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsTransient", func() {
+	DescribeTable("failures that go away on their own",
+		func(tfOutput string) {
+			Expect(terraform.IsTransient(tfOutput)).To(BeTrue())
+		},
+		Entry("aws instance profile not visible yet",
+			"* aws_instance.nat: Error launching source instance: InvalidParameterValue: Value (some-env-bosh-iam-instance-profile) for parameter iamInstanceProfile.name is invalid. Invalid IAM Instance Profile name"),
+		Entry("aws security group not visible yet",
+			"* aws_security_group_rule.internal: Error authorizing security group rule: InvalidGroup.NotFound: The security group 'sg-123' does not exist"),
+		Entry("aws rate limit",
+			"* aws_subnet.bosh_subnet: Error creating subnet: RequestLimitExceeded: Request limit exceeded."),
+		Entry("gcp resource not ready",
+			"* google_compute_subnetwork.bbl-subnet: googleapi: Error 400: The resource 'projects/some-project/global/networks/some-env-network' is not ready, resourceNotReady"),
+		Entry("gcp rate limit",
+			"* google_compute_firewall.internal: googleapi: Error 403: Rate Limit Exceeded, rateLimitExceeded"),
+		Entry("azure operation in progress",
+			"* azurerm_subnet.bosh-subnet: network.SubnetsClient#CreateOrUpdate: Failure sending request: StatusCode=0 -- Original Error: Code=\"AnotherOperationInProgress\""),
+		Entry("azure rate limit",
+			"* azurerm_public_ip.bosh: compute.Client: StatusCode=429 -- Original Error: Code=\"TooManyRequests\""),
+	)
+
+	It("does not treat other failures as transient", func() {
+		Expect(terraform.IsTransient("* google_compute_network.bbl-network: googleapi: Error 409: The resource 'projects/some-project/global/networks/some-env-network' already exists, alreadyExists")).To(BeFalse())
+		Expect(terraform.IsTransient("")).To(BeFalse())
+	})
+})
@@ -0,0 +1,101 @@
+package testhelpers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// FakeObjectStore is an in-memory, path-style S3-compatible server that
+// supports the GET, PUT (including conditional If-None-Match) and DELETE
+// object calls used by the state backend.
+type FakeObjectStore struct {
+	Server *httptest.Server
+
+	mutex   sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func NewFakeObjectStore() *FakeObjectStore {
+	store := &FakeObjectStore{objects: map[string][]byte{}}
+	store.Server = httptest.NewServer(http.HandlerFunc(store.serveHTTP))
+	return store
+}
+
+func (f *FakeObjectStore) URL() string {
+	return f.Server.URL
+}
+
+func (f *FakeObjectStore) Close() {
+	f.Server.Close()
+}
+
+// Object returns the contents stored at "bucket/key".
+func (f *FakeObjectStore) Object(path string) ([]byte, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	contents, ok := f.objects[path]
+	return contents, ok
+}
+
+func (f *FakeObjectStore) SetObject(path string, contents []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.objects[path] = contents
+}
+
+// FailRequests makes every subsequent request return a 500.
+func (f *FakeObjectStore) FailRequests() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.fail = true
+}
+
+func (f *FakeObjectStore) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	if f.fail {
+		http.Error(w, "InternalError", http.StatusInternalServerError)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+
+	switch r.Method {
+	case "GET":
+		contents, ok := f.objects[path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(contents)
+	case "PUT":
+		if _, ok := f.objects[path]; ok && r.Header.Get("If-None-Match") == "*" {
+			http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+			return
+		}
+		contents, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.objects[path] = contents
+	case "DELETE":
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
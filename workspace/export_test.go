@@ -0,0 +1,47 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+)
+
+func SetTempDir(f func(dir, prefix string) (string, error)) {
+	tempDir = f
+}
+
+func ResetTempDir() {
+	tempDir = ioutil.TempDir
+}
+
+func SetChmod(f func(name string, mode os.FileMode) error) {
+	chmod = f
+}
+
+func ResetChmod() {
+	chmod = os.Chmod
+}
+
+func SetRemoveAll(f func(path string) error) {
+	removeAll = f
+}
+
+func ResetRemoveAll() {
+	removeAll = os.RemoveAll
+}
+
+func SetNotify(f func(c chan<- os.Signal, sig ...os.Signal)) {
+	notify = f
+}
+
+func ResetNotify() {
+	notify = signal.Notify
+}
+
+func SetExit(f func(code int)) {
+	exit = f
+}
+
+func ResetExit() {
+	exit = os.Exit
+}
@@ -0,0 +1,120 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+const (
+	// DirMode and FileMode keep workspaces private to the current user, since
+	// they hold credentials, vars stores and state.
+	DirMode  = os.FileMode(0700)
+	FileMode = os.FileMode(0600)
+)
+
+var (
+	tempDir   = ioutil.TempDir
+	chmod     = os.Chmod
+	removeAll = os.RemoveAll
+	notify    = signal.Notify
+	exit      = os.Exit
+)
+
+var (
+	mutex sync.Mutex
+	dirs  = map[string]struct{}{}
+	keep  bool
+)
+
+// Create makes a new workspace directory in dir, or in the default temporary
+// directory when dir is empty. It has the same signature as ioutil.TempDir.
+func Create(dir, prefix string) (string, error) {
+	path, err := tempDir(dir, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	err = chmod(path, DirMode)
+	if err != nil {
+		removeAll(path)
+		return "", err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	dirs[path] = struct{}{}
+
+	return path, nil
+}
+
+// Remove deletes a workspace once the operation using it has finished.
+// Directories that were not made by Create are left alone, as are all
+// workspaces when they are being kept.
+func Remove(path string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, ok := dirs[path]; !ok || keep {
+		return nil
+	}
+
+	err := removeAll(path)
+	if err != nil {
+		return err
+	}
+
+	delete(dirs, path)
+	return nil
+}
+
+// RemoveAll deletes every workspace that is still around, including the
+// ones that have to live until bbl exits.
+func RemoveAll() error {
+	for _, path := range Active() {
+		err := Remove(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Active returns the workspaces that have not been removed yet.
+func Active() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	paths := []string{}
+	for path := range dirs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// Keep turns removal off so that workspaces can be inspected after bbl
+// exits.
+func Keep(enabled bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	keep = enabled
+}
+
+// RemoveOnInterrupt removes all workspaces before bbl exits on SIGINT or
+// SIGTERM.
+func RemoveOnInterrupt() {
+	signals := make(chan os.Signal, 1)
+	notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		RemoveAll()
+		exit(130)
+	}()
+}
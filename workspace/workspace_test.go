@@ -0,0 +1,179 @@
+package workspace_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"syscall"
+
+	"github.com/cloudfoundry/bosh-bootloader/workspace"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Workspace", func() {
+	AfterEach(func() {
+		workspace.Keep(false)
+		workspace.RemoveAll()
+
+		workspace.ResetTempDir()
+		workspace.ResetChmod()
+		workspace.ResetRemoveAll()
+		workspace.ResetNotify()
+		workspace.ResetExit()
+	})
+
+	Describe("Create", func() {
+		It("creates a directory only the current user can access", func() {
+			dir, err := workspace.Create("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := os.Stat(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+
+			Expect(workspace.Active()).To(ContainElement(dir))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the directory cannot be created", func() {
+				workspace.SetTempDir(func(string, string) (string, error) {
+					return "", errors.New("failed to create temp dir")
+				})
+
+				_, err := workspace.Create("", "")
+				Expect(err).To(MatchError("failed to create temp dir"))
+			})
+
+			It("removes the directory when its permissions cannot be set", func() {
+				var dir string
+				workspace.SetTempDir(func(parent, prefix string) (string, error) {
+					var err error
+					dir, err = ioutil.TempDir(parent, prefix)
+					return dir, err
+				})
+				workspace.SetChmod(func(string, os.FileMode) error {
+					return errors.New("failed to chmod")
+				})
+
+				_, err := workspace.Create("", "")
+				Expect(err).To(MatchError("failed to chmod"))
+
+				_, err = os.Stat(dir)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+				Expect(workspace.Active()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Remove", func() {
+		It("removes the workspace and its contents", func() {
+			dir, err := workspace.Create("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(dir+"/some-file", []byte("some-contents"), workspace.FileMode)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = workspace.Remove(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(dir)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			Expect(workspace.Active()).NotTo(ContainElement(dir))
+		})
+
+		It("leaves directories it did not create alone", func() {
+			dir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			err = workspace.Remove(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(dir)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("keeps the workspace when workspaces are being kept", func() {
+			workspace.Keep(true)
+
+			dir, err := workspace.Create("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			err = workspace.Remove(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(workspace.Active()).To(ContainElement(dir))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the directory cannot be removed", func() {
+				dir, err := workspace.Create("", "")
+				Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(dir)
+
+				workspace.SetRemoveAll(func(string) error {
+					return errors.New("failed to remove")
+				})
+
+				err = workspace.Remove(dir)
+				Expect(err).To(MatchError("failed to remove"))
+				Expect(workspace.Active()).To(ContainElement(dir))
+			})
+		})
+	})
+
+	Describe("RemoveAll", func() {
+		It("removes every workspace", func() {
+			firstDir, err := workspace.Create("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			secondDir, err := workspace.Create("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = workspace.RemoveAll()
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, dir := range []string{firstDir, secondDir} {
+				_, err = os.Stat(dir)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			}
+			Expect(workspace.Active()).To(BeEmpty())
+		})
+	})
+
+	Describe("RemoveOnInterrupt", func() {
+		It("removes every workspace and exits when bbl is interrupted", func() {
+			var (
+				signals        chan<- os.Signal
+				notifiedOfSigs []os.Signal
+			)
+			workspace.SetNotify(func(c chan<- os.Signal, sigs ...os.Signal) {
+				signals = c
+				notifiedOfSigs = sigs
+			})
+
+			exitCodes := make(chan int, 1)
+			workspace.SetExit(func(code int) {
+				exitCodes <- code
+			})
+
+			dir, err := workspace.Create("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			workspace.RemoveOnInterrupt()
+			Expect(notifiedOfSigs).To(ConsistOf(os.Interrupt, syscall.SIGTERM))
+
+			signals <- os.Interrupt
+
+			Eventually(exitCodes).Should(Receive(Equal(130)))
+
+			_, err = os.Stat(dir)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})